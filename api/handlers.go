@@ -1,13 +1,26 @@
 package api
 
 import (
+	"bufio"
+	"bytes"
 	"cgap/internal/embedding"
+	"cgap/internal/ingestion"
 	"cgap/internal/media"
 	"cgap/internal/model"
 	"cgap/internal/queue"
+	"cgap/internal/storage"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
 	"strings"
 	"time"
 
@@ -17,6 +30,8 @@ import (
 	"strconv"
 
 	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pgvector/pgvector-go"
 	"github.com/redis/go-redis/v9"
@@ -62,13 +77,165 @@ func ChatHandler(c fiber.Ctx) error {
 
 	// Call chat service
 	resp, err := services.Chat.Chat(context.Background(), req)
+	if err != nil {
+		return chatError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// ThreadCreateHandler handles POST /v1/threads
+func ThreadCreateHandler(c fiber.Ctx) error {
+	var req ThreadCreateRequest
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if req.ProjectID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "project_id required"})
+	}
+
+	thread, err := services.Chat.CreateThread(context.Background(), req)
+	if errors.Is(err, storage.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "project not found"})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	return c.Status(fiber.StatusCreated).JSON(thread)
+}
+
+// ThreadChatHandler handles POST /v1/threads/:id/chat
+func ThreadChatHandler(c fiber.Ctx) error {
+	var req ChatRequest
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	req.ThreadID = c.Params("id")
+	if req.ProjectID == "" || req.Query == "" || req.ThreadID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "project_id, query and thread id required"})
+	}
+
+	if req.TopK == 0 {
+		req.TopK = 5
+	}
+
+	resp, err := services.Chat.Chat(context.Background(), req)
+	if err != nil {
+		return chatError(c, err)
+	}
+
 	return c.Status(fiber.StatusOK).JSON(resp)
 }
 
+// sseHeartbeatInterval is how often an SSE comment is written to keep idle
+// connections (and intermediate proxies) from timing out.
+var sseHeartbeatInterval = 15 * time.Second
+
+// ChatStreamHandler handles POST /v1/chat/stream
+func ChatStreamHandler(c fiber.Ctx) error {
+	var req ChatRequest
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if req.ProjectID == "" || req.Query == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "project_id and query required"})
+	}
+
+	return streamChat(c, req)
+}
+
+// ThreadChatStreamHandler handles POST /v1/threads/:id/chat/stream
+func ThreadChatStreamHandler(c fiber.Ctx) error {
+	var req ChatRequest
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	req.ThreadID = c.Params("id")
+	if req.ProjectID == "" || req.Query == "" || req.ThreadID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "project_id, query and thread id required"})
+	}
+
+	return streamChat(c, req)
+}
+
+// streamChat writes ChatStream frames to the client as Server-Sent Events.
+// Each frame is sent as an event named after its type ("token", "done",
+// "error"). The stream context is cancelled as soon as a write to the client
+// fails, which stops the upstream LLM request.
+func streamChat(c fiber.Ctx, req ChatRequest) error {
+	if req.TopK == 0 {
+		req.TopK = 5
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	frames, err := services.Chat.ChatStream(ctx, req)
+	if err != nil {
+		cancel()
+		return chatError(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+
+		heartbeat := time.NewTicker(sseHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case frame, ok := <-frames:
+				if !ok {
+					return
+				}
+				if err := writeSSE(w, frame); err != nil {
+					slog.Info("chat stream client disconnected", "error", err)
+					return
+				}
+			case <-heartbeat.C:
+				if _, err := w.WriteString(": ping\n\n"); err != nil {
+					return
+				}
+				if err := w.Flush(); err != nil {
+					slog.Info("chat stream client disconnected", "error", err)
+					return
+				}
+			}
+		}
+	})
+}
+
+// writeSSE encodes a single frame as an SSE event and flushes it.
+func writeSSE(w *bufio.Writer, frame StreamFrame) error {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", frame.Type, data); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// chatError maps chat service errors to HTTP responses.
+func chatError(c fiber.Ctx, err error) error {
+	if errors.Is(err, ErrThreadNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "project not found"})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+}
+
 // SearchHandler handles POST /v1/search
 func SearchHandler(c fiber.Ctx) error {
 	var req SearchRequest
@@ -84,10 +251,23 @@ func SearchHandler(c fiber.Ctx) error {
 		req.Limit = 10
 	}
 
+	if req.Fusion != nil {
+		if err := validateFusionOptions(req.Fusion); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if req.Filters == nil {
+			req.Filters = map[string]any{}
+		}
+		req.Filters[FusionFilterKey] = req.Fusion
+	}
+
 	start := time.Now()
 
 	// Call search service
 	hits, err := services.Search.Search(context.Background(), req.ProjectID, req.Query, req.Limit, req.Filters)
+	if errors.Is(err, ErrInvalidFusion) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -168,7 +348,7 @@ func OCRHandler(c fiber.Ctx) error {
 	}
 
 	// Initialize OCR handler
-	ocrHandler, err := media.NewGoogleVisionOCR(ctx, nil)
+	ocrHandler, err := media.NewOCRHandler(media.ConfigFromEnv(), nil)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": fmt.Sprintf("Failed to initialize OCR handler: %v", err),
@@ -177,7 +357,7 @@ func OCRHandler(c fiber.Ctx) error {
 	defer ocrHandler.Close()
 
 	// Extract text from image URL
-	result, err := ocrHandler.ExtractFromURL(ctx, req.ImageURL)
+	result, err := ocrHandler.ExtractFromURL(media.WithOCRLanguages(ctx, media.ParseLanguageHints(req.OCRLang)...), req.ImageURL)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": fmt.Sprintf("OCR extraction failed: %v", err),
@@ -250,6 +430,9 @@ func YouTubeHandler(c fiber.Ctx) error {
 
 	// Get transcript
 	result, err := youtubeHandler.GetTranscript(ctx, videoID)
+	if errors.Is(err, media.ErrNoTranscript) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": fmt.Sprintf("Transcript extraction failed: %v", err),
@@ -364,12 +547,37 @@ func MediaProcessHandler(c fiber.Ctx) error {
 	}
 
 	// Validate required fields
-	if req.ProjectID == "" || req.SourceID == "" || req.MediaURL == "" {
+	if req.ProjectID == "" || req.SourceID == "" || (req.MediaURL == "") == (req.UploadID == "") {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "project_id, source_id, and media_url are required",
+			"error": "project_id, source_id, and one of media_url or upload_id are required",
 		})
 	}
 
+	// Uploaded media is processed from a local copy
+	var filePath, uploadType string
+	if req.UploadID != "" {
+		if services == nil || services.Uploads == nil || services.Blobs == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "uploads not configured"})
+		}
+		upload, tmp, err := openUpload(ctx, req.UploadID, req.ProjectID)
+		if errors.Is(err, storage.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "upload not found"})
+		}
+		if err != nil {
+			slog.Error("Failed to open upload", "upload_id", req.UploadID, "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to read upload"})
+		}
+		defer os.Remove(tmp)
+		filePath = tmp
+		req.MediaURL = upload.URI()
+		switch {
+		case strings.HasPrefix(upload.ContentType, "image/"):
+			uploadType = "image"
+		case strings.HasPrefix(upload.ContentType, "video/"), strings.HasPrefix(upload.ContentType, "audio/"):
+			uploadType = "video"
+		}
+	}
+
 	// Initialize media orchestrator
 	orchestrator, err := media.NewMediaOrchestrator(slog.Default())
 	if err != nil {
@@ -381,6 +589,9 @@ func MediaProcessHandler(c fiber.Ctx) error {
 
 	// Detect media type if not provided
 	mediaType := req.MediaType
+	if mediaType == "" {
+		mediaType = uploadType
+	}
 	if mediaType == "" {
 		mediaType = orchestrator.DetectMediaType(req.MediaURL)
 		slog.Info("Auto-detected media type", "url", req.MediaURL, "type", mediaType)
@@ -409,6 +620,7 @@ func MediaProcessHandler(c fiber.Ctx) error {
 		SourceID:  req.SourceID,
 		Type:      mediaType,
 		URL:       req.MediaURL,
+		FilePath:  filePath,
 		CreatedAt: time.Now().UTC().Format(time.RFC3339),
 	}
 
@@ -429,7 +641,7 @@ func MediaProcessHandler(c fiber.Ctx) error {
 	}
 
 	// Process media
-	result, err := orchestrator.ProcessMediaItem(ctx, mediaItem)
+	result, err := orchestrator.ProcessMediaItem(media.WithOCRLanguages(ctx, media.ParseLanguageHints(req.OCRLang)...), mediaItem)
 	if err != nil {
 		// Update status to failed if DB is available
 		if services != nil && services.DB != nil {
@@ -458,6 +670,15 @@ func MediaProcessHandler(c fiber.Ctx) error {
 		}
 	}
 
+	// The item is ingested like a media source so its text becomes searchable.
+	jobID, err := queueAdHocIngest(ctx, mediaIngestPayload(req, mediaType))
+	if errors.Is(err, storage.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "project not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "enqueue failed", "details": err.Error()})
+	}
+
 	// Build response
 	response := MediaProcessResponse{
 		MediaItemID:      result.MediaItemID,
@@ -469,11 +690,26 @@ func MediaProcessHandler(c fiber.Ctx) error {
 		Metadata:         result.Metadata,
 		ProcessedAt:      result.ExtractedAt,
 		ExtractionStatus: result.Status,
+		JobID:            jobID,
 	}
 
 	return c.Status(fiber.StatusOK).JSON(response)
 }
 
+// mediaIngestPayload is the ingest of a media item processed by
+// MediaProcessHandler: an upload source for uploads, otherwise a source of
+// the media type with the item's URL.
+func mediaIngestPayload(req MediaProcessRequest, mediaType string) IngestTaskPayload {
+	src := SourceSpec{Type: mediaType, URL: req.MediaURL}
+	if req.UploadID != "" {
+		src = SourceSpec{Type: model.SourceTypeUpload, UploadID: req.UploadID}
+	}
+	if req.OCRLang != "" {
+		src.Media = &MediaSpec{OCRLang: req.OCRLang}
+	}
+	return IngestTaskPayload{ProjectID: req.ProjectID, Source: src}
+}
+
 // ExtensionChatHandler handles POST /v1/extension/chat - browser extension endpoint
 func ExtensionChatHandler(c fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -494,32 +730,22 @@ func ExtensionChatHandler(c fiber.Ctx) error {
 	// Build DOM context for LLM
 	domContext := buildDOMContextString(req.DOM, 20)
 
-	// Perform hybrid search to find relevant docs
-	var searchResults []SearchHit
-	if services != nil && services.Search != nil {
-		results, err := services.Search.Search(ctx, req.ProjectID, req.Question, 5, nil)
-		if err != nil {
-			slog.Warn("Search failed in extension chat", "error", err)
-		} else {
-			searchResults = results
-		}
-	}
-
-	// Build context from search results
-	docsContext := buildDocsContext(searchResults)
-
-	// Generate LLM prompt
-	prompt := buildExtensionPrompt(req.URL, req.Question, domContext, docsContext)
+	// Page context goes to the LLM as instructions; the chat service retrieves
+	// documentation for the question itself.
+	instructions := buildExtensionInstructions(req.URL, domContext)
 
 	// Call LLM
 	var guidance string
 	var steps []GuidanceStep
-	var confidence float32 = 0.8
+	var confidence float32
+	var citations []Citation
+	isUncertain := true
 
 	if services != nil && services.Chat != nil {
 		chatReq := ChatRequest{
-			ProjectID: req.ProjectID,
-			Query:     prompt,
+			ProjectID:    req.ProjectID,
+			Query:        req.Question,
+			Instructions: instructions,
 		}
 
 		chatResp, err := services.Chat.Chat(ctx, chatReq)
@@ -532,6 +758,18 @@ func ExtensionChatHandler(c fiber.Ctx) error {
 
 		guidance = chatResp.Answer
 		confidence = chatResp.Confidence
+		isUncertain = chatResp.IsUncertain
+
+		// Sources are the documentation the answer cites
+		for _, ac := range chatResp.Citations {
+			citations = append(citations, Citation{
+				ChunkID:   ac.ChunkID,
+				Quote:     ac.Quote,
+				Score:     ac.Score,
+				StartChar: ac.StartChar,
+				EndChar:   ac.EndChar,
+			})
+		}
 
 		// Parse steps from LLM response
 		steps = parseStepsFromGuidance(guidance, req.DOM)
@@ -546,20 +784,11 @@ func ExtensionChatHandler(c fiber.Ctx) error {
 		}
 	}
 
-	// Extract citations from search results
-	var citations []Citation
-	for _, hit := range searchResults {
-		citations = append(citations, Citation{
-			ChunkID: hit.ChunkID,
-			Quote:   hit.Text,
-			Score:   hit.Confidence,
-		})
-	}
-
 	response := ExtensionChatResponse{
 		Guidance:    guidance,
 		Steps:       steps,
 		Confidence:  confidence,
+		IsUncertain: isUncertain,
 		Sources:     citations,
 		NextActions: generateNextActions(req.Question),
 	}
@@ -615,43 +844,18 @@ func filterInteractiveElements(entities []DOMEntity) []DOMEntity {
 	return result
 }
 
-func buildDocsContext(results []SearchHit) string {
-	if len(results) == 0 {
-		return "No relevant documentation found."
-	}
-
-	var parts []string
-	parts = append(parts, "Relevant documentation:")
-
-	for i, hit := range results {
-		if i >= 3 {
-			break
-		}
-		content := hit.Text
-		if len(content) > 200 {
-			content = content[:200] + "..."
-		}
-		parts = append(parts, fmt.Sprintf("\n%d. Document %s\n   %s", i+1, hit.DocumentID, content))
-	}
-
-	return strings.Join(parts, "\n")
-}
-
-func buildExtensionPrompt(url, question, domContext, docsContext string) string {
+// buildExtensionInstructions describes the user's current page to the LLM.
+func buildExtensionInstructions(url, domContext string) string {
 	return fmt.Sprintf(`You are helping a user navigate a web application.
 
 Current Page: %s
-User Question: %s
 
 Available Elements on Page:
 %s
 
-Relevant Documentation:
-%s
-
-Provide clear, step-by-step guidance to answer the user's question. 
+Provide clear, step-by-step guidance to answer the user's question.
 For each step, specify which element to interact with using CSS selectors when possible.
-Format your response as numbered steps.`, url, question, domContext, docsContext)
+Format your response as numbered steps.`, url, domContext)
 }
 
 func parseStepsFromGuidance(guidance string, domEntities []DOMEntity) []GuidanceStep {
@@ -757,151 +961,884 @@ func IngestHandler(c fiber.Ctx) error {
 	if req.ProjectID == "" || req.Source.Type == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "project_id and source.type required"})
 	}
+	if err := validateIngestOptions(&req.Source, req.ChunkStrategy, req.ChunkSizeToken, req.FullSync); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := validateSourceSpec(&req.Source); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	ctx := context.Background()
+	if req.Source.UploadID != "" && services != nil && services.Uploads != nil {
+		upload, err := services.Uploads.GetByID(ctx, req.Source.UploadID)
+		if errors.Is(err, storage.ErrNotFound) || (err == nil && looksLikeUUID(req.ProjectID) && upload.ProjectID != req.ProjectID) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "upload not found"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to look up upload"})
+		}
+	}
+
+	// Build task payload
+	payload := IngestTaskPayload{
+		ProjectID:      req.ProjectID,
+		Source:         req.Source,
+		ChunkStrategy:  req.ChunkStrategy,
+		ChunkSizeToken: req.ChunkSizeToken,
+		FailFast:       req.FailFast,
+		FullSync:       req.FullSync,
+	}
+
+	jobID, err := queueAdHocIngest(ctx, payload)
+	if errors.Is(err, storage.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "project not found"})
+	}
+	if err != nil {
+		// If enqueue fails, return 500 with error
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "enqueue failed", "details": err.Error()})
+	}
+
+	// Return accepted response
+	return c.Status(fiber.StatusAccepted).JSON(IngestResponse{
+		JobID:     jobID,
+		Status:    "queued",
+		ProjectID: req.ProjectID,
+	})
+}
+
+// queueAdHocIngest queues payload as an ad-hoc ingest. Ad-hoc ingests are
+// recorded as (unscheduled) sources so documents link to one. A missing
+// project is reported as storage.ErrNotFound.
+func queueAdHocIngest(ctx context.Context, payload IngestTaskPayload) (string, error) {
+	var src *model.Source
+	if services != nil && services.Sources != nil {
+		src = &model.Source{
+			ProjectID:      payload.ProjectID,
+			Type:           payload.Source.Type,
+			Config:         sourceConfig(payload.Source),
+			ChunkStrategy:  payload.ChunkStrategy,
+			ChunkSizeToken: payload.ChunkSizeToken,
+			FullSync:       payload.FullSync,
+		}
+		if err := services.Sources.FindOrCreate(ctx, src); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return "", err
+			}
+			slog.Warn("Failed to record ingest source", "project_id", payload.ProjectID, "error", err)
+			src = nil
+		} else {
+			payload.SourceID = src.ID
+		}
+	}
+
+	var prod *queue.Producer
+	if p, ok := queueProducer(); ok {
+		prod = p
+	}
+	var jobs storage.JobRepo
+	if services != nil {
+		jobs = services.Jobs
+	}
+	// Live job status is best-effort
+	rdb, err := jobRedis()
+	if err != nil {
+		rdb = nil
+	} else {
+		defer rdb.Close()
+	}
 
+	jobID, err := EnqueueIngest(ctx, prod, jobs, rdb, payload)
+	if err != nil {
+		return "", err
+	}
+	if src != nil {
+		if err := services.Sources.RecordSync(ctx, src.ID, jobID); err != nil {
+			slog.Warn("Failed to record source sync", "source_id", src.ID, "error", err)
+		}
+	}
+	return jobID, nil
+}
+
+// EnqueueIngest records a job for payload in job history (when jobs is set),
+// queues it for the worker (when prod is set) and initializes its live status
+// in Redis (when rdb is set). It returns the job ID; a missing project is
+// reported as storage.ErrNotFound.
+func EnqueueIngest(ctx context.Context, prod *queue.Producer, jobs storage.JobRepo, rdb *redis.Client, payload IngestTaskPayload) (string, error) {
+	jobID := fmt.Sprintf("job_%s_%d", payload.ProjectID, time.Now().UnixNano())
+
+	// Record the job in durable history before a worker can pick it up
+	if jobs != nil {
+		job := &model.Job{ID: jobID, ProjectID: payload.ProjectID, SourceID: payload.SourceID, Type: "ingest", Payload: jobPayload(payload)}
+		if err := jobs.Create(ctx, job); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return "", err
+			}
+			// History is best-effort like the live status; do not block ingestion on it.
+			slog.Warn("Failed to record job history", "job_id", jobID, "error", err)
+		}
+	}
+
+	if prod != nil {
+		if err := prod.Enqueue(ctx, queue.Task{Type: "ingest", Payload: payload, ID: jobID}); err != nil {
+			return "", err
+		}
+	}
+
+	// Initialize job status in Redis (best-effort)
+	if rdb != nil {
+		key := "cgap:job:" + jobID
+		now := time.Now().UTC().Format(time.RFC3339)
+		_ = rdb.HSet(ctx, key, map[string]any{
+			"job_id":     jobID,
+			"project_id": payload.ProjectID,
+			"status":     model.JobQueued,
+			"processed":  0,
+			"total":      0,
+			"started_at": now,
+			"error":      "",
+			"updated_at": now,
+		}).Err()
+		// TTL to avoid leaking forever (24h)
+		_ = rdb.Expire(ctx, key, 24*time.Hour).Err()
+	}
+
+	return jobID, nil
+}
+
+// validateIngestOptions checks chunking and full-sync options against a source.
+func validateIngestOptions(src *SourceSpec, chunkStrategy string, chunkSizeToken int, fullSync bool) error {
+	if !ingestion.ValidChunkStrategy(chunkStrategy) {
+		return errors.New("chunk_strategy must be heading, semantic or fixed")
+	}
+	if chunkSizeToken < 0 {
+		return errors.New("chunk_size_token must be positive")
+	}
+	if fullSync && src.Type != model.SourceTypeGitHub && src.Type != model.SourceTypeArchive && (src.Type != model.SourceTypeCrawl || src.Crawl == nil || src.Crawl.Mode == "single") {
+		return errors.New("full_sync requires a github or archive source, or a crawl source with mode sitemap or crawl")
+	}
+	return nil
+}
+
+// validateFusionOptions checks per-request search fusion overrides.
+func validateFusionOptions(opts *FusionOptions) error {
+	if opts.Strategy != "" && opts.Strategy != "rrf" && opts.Strategy != "weighted" {
+		return errors.New("fusion.strategy must be rrf or weighted")
+	}
+	if opts.K < 0 {
+		return errors.New("fusion.k must be positive")
+	}
+	if (opts.SemanticWeight != nil && *opts.SemanticWeight < 0) || (opts.LexicalWeight != nil && *opts.LexicalWeight < 0) {
+		return errors.New("fusion weights must be non-negative")
+	}
+	if opts.SemanticWeight != nil && opts.LexicalWeight != nil && *opts.SemanticWeight+*opts.LexicalWeight == 0 {
+		return errors.New("fusion weights must not both be zero")
+	}
+	return nil
+}
+
+// validateSourceSpec checks the fields each source type requires. A crawl
+// without a mode defaults to mode=crawl.
+func validateSourceSpec(src *SourceSpec) error {
 	// Basic source validation by type
-	switch req.Source.Type {
+	switch src.Type {
 	case "url":
-		if req.Source.URL == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "source.url required for type=url"})
+		if src.URL == "" {
+			return errors.New("source.url required for type=url")
 		}
 	case model.SourceTypeCrawl:
-		if req.Source.Crawl == nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "source.crawl required for type=crawl"})
+		if src.Crawl == nil {
+			return errors.New("source.crawl required for type=crawl")
 		}
-		mode := req.Source.Crawl.Mode
+		mode := src.Crawl.Mode
 		if mode == "" {
 			mode = model.SourceTypeCrawl
-			req.Source.Crawl.Mode = mode
+			src.Crawl.Mode = mode
 		}
 		switch mode {
 		case "single":
-			if req.Source.Crawl.StartURL == "" {
-				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "crawl.start_url required for mode=single"})
+			if src.Crawl.StartURL == "" {
+				return errors.New("crawl.start_url required for mode=single")
 			}
 		case "sitemap":
-			if req.Source.Crawl.SitemapURL == "" {
-				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "crawl.sitemap_url required for mode=sitemap"})
+			if src.Crawl.SitemapURL == "" {
+				return errors.New("crawl.sitemap_url required for mode=sitemap")
 			}
 		case model.SourceTypeCrawl:
-			if req.Source.Crawl.StartURL == "" {
-				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "crawl.start_url required for mode=crawl"})
+			if src.Crawl.StartURL == "" {
+				return errors.New("crawl.start_url required for mode=crawl")
 			}
 		default:
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unsupported crawl.mode"})
+			return errors.New("unsupported crawl.mode")
+		}
+	case model.SourceTypeOpenAPI:
+		if src.OpenAPIURL == "" && src.URL == "" {
+			return errors.New("source.openapi_url or source.url required for openapi")
 		}
-	case "openapi":
-		if req.Source.OpenAPIURL == "" && req.Source.URL == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "source.openapi_url or source.url required for openapi"})
+	case model.SourceTypeGitHub:
+		if src.Owner == "" || src.Repo == "" {
+			return errors.New("source.owner and source.repo required for github")
 		}
-	case "github":
-		if req.Source.Owner == "" || req.Source.Repo == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "source.owner and source.repo required for github"})
+		if src.GitHub != nil {
+			for _, g := range src.GitHub.Paths {
+				if _, err := path.Match(strings.ReplaceAll(g, "**", "*"), ""); err != nil {
+					return fmt.Errorf("invalid github.paths glob %q", g)
+				}
+			}
 		}
 	case "document", "documents", "file", "files", "pdf", "markdown", "md", "txt":
 		// Accept either single source.url or files.urls
-		if req.Source.URL == "" && (req.Source.Files == nil || len(req.Source.Files.URLs) == 0) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "source.url or files.urls required for document ingestion"})
+		if src.URL == "" && (src.Files == nil || len(src.Files.URLs) == 0) {
+			return errors.New("source.url or files.urls required for document ingestion")
+		}
+		if src.Files != nil && (!ingestion.ValidFileFormat(src.Files.Format) || !ingestion.ValidFileFormat(src.Files.Extract)) {
+			return errors.New("files.format and files.extract must be auto, html, pdf, docx, markdown or text")
 		}
 	case "image", "images":
 		// Allow either single URL or media.urls
-		if req.Source.URL == "" && (req.Source.Media == nil || len(req.Source.Media.URLs) == 0) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "source.url or media.urls required for image ingestion"})
+		if src.URL == "" && (src.Media == nil || len(src.Media.URLs) == 0) {
+			return errors.New("source.url or media.urls required for image ingestion")
 		}
 	case "video", "videos":
-		if (req.Source.Media == nil || (len(req.Source.Media.URLs) == 0 && len(req.Source.Media.YouTubeIDs) == 0)) && req.Source.URL == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "provide media.urls or media.youtube_ids or source.url for video ingestion"})
+		if (src.Media == nil || (len(src.Media.URLs) == 0 && len(src.Media.YouTubeIDs) == 0)) && src.URL == "" {
+			return errors.New("provide media.urls or media.youtube_ids or source.url for video ingestion")
 		}
 		// If youtube IDs are provided, prefer transcript flow.
 		// Worker will decide provider based on transcript_provider.
 	case "youtube":
-		if req.Source.Media == nil || len(req.Source.Media.YouTubeIDs) == 0 {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "media.youtube_ids required for type=youtube"})
+		if src.Media == nil || len(src.Media.YouTubeIDs) == 0 {
+			return errors.New("media.youtube_ids required for type=youtube")
+		}
+	case model.SourceTypeUpload:
+		if src.UploadID == "" {
+			return errors.New("source.upload_id required for upload")
+		}
+	case model.SourceTypeArchive:
+		if (src.URL == "") == (src.UploadID == "") {
+			return errors.New("one of source.url or source.upload_id required for archive")
+		}
+		if a := src.Archive; a != nil {
+			if a.BaseURL != "" {
+				if u, err := url.Parse(a.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					return errors.New("archive.base_url must be an http(s) URL")
+				}
+			}
+			for _, g := range a.Paths {
+				if _, err := path.Match(strings.ReplaceAll(g, "**", "*"), ""); err != nil {
+					return fmt.Errorf("invalid archive.paths glob %q", g)
+				}
+			}
 		}
-	case "upload":
-		if req.Source.UploadID == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "source.upload_id required for upload"})
+		if src.Files != nil && !ingestion.ValidFileFormat(src.Files.Extract) {
+			return errors.New("files.extract must be auto, html, pdf, docx, markdown or text")
 		}
 	case "slack", "discord":
 		// allow minimal config; worker can validate tokens/channels later
 	default:
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unsupported source.type"})
+		return errors.New("unsupported source.type")
 	}
 
-	// Build task payload
-	payload := IngestTaskPayload(req)
+	return nil
+}
 
-	jobID := fmt.Sprintf("job_%s_%d", req.ProjectID, time.Now().UnixNano())
+// IngestStatusHandler handles GET /v1/ingest/:job_id
+func IngestStatusHandler(c fiber.Ctx) error {
+	jobID := c.Params("job_id")
+	if jobID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "job_id required"})
+	}
+	ctx := context.Background()
 
-	// Try to enqueue via Redis producer if wired
-	if services != nil && services.Queue != nil {
-		if prod, ok := services.Queue.(*queue.Producer); ok && prod != nil {
-			t := queue.Task{Type: "ingest", Payload: payload, ID: jobID}
-			if err := prod.Enqueue(context.Background(), t); err != nil {
-				// If enqueue fails, return 500 with error
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "enqueue failed", "details": err.Error()})
+	if services != nil && services.Jobs != nil {
+		job, err := services.Jobs.GetByID(ctx, jobID)
+		switch {
+		case err == nil:
+			resp := jobStatusResponse(job)
+			if resp.URLErrors, err = services.Jobs.ListURLErrors(ctx, jobID, MaxJobURLErrors); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load job errors"})
 			}
+			return c.Status(fiber.StatusOK).JSON(resp)
+		case !errors.Is(err, storage.ErrNotFound):
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load job"})
 		}
+		// Not in history (e.g. enqueued before it existed): fall back to the live status.
 	}
 
-	// Initialize job status in Redis (best-effort)
-	if redisURL := os.Getenv("REDIS_URL"); redisURL != "" {
-		if opts, err := redis.ParseURL(redisURL); err == nil {
-			rdb := redis.NewClient(opts)
-			defer rdb.Close()
-			key := "cgap:job:" + jobID
-			now := time.Now().UTC().Format(time.RFC3339)
-			_ = rdb.HSet(context.Background(), key, map[string]any{
-				"job_id":     jobID,
-				"project_id": req.ProjectID,
-				"status":     "queued",
-				"processed":  0,
-				"total":      0,
-				"started_at": now,
-				"error":      "",
-				"updated_at": now,
-			}).Err()
-			// TTL to avoid leaking forever (24h)
-			_ = rdb.Expire(context.Background(), key, 24*time.Hour).Err()
-		}
+	rdb, err := jobRedis()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+	defer rdb.Close()
 
-	// Return accepted response
-	return c.Status(fiber.StatusAccepted).JSON(IngestResponse{
-		JobID:     jobID,
-		Status:    "queued",
-		ProjectID: req.ProjectID,
-	})
+	m, err := rdb.HGetAll(ctx, "cgap:job:"+jobID).Result()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "redis error"})
+	}
+	if len(m) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "job not found"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(jobStatusFromHash(m))
 }
 
-// IngestStatusHandler handles GET /v1/ingest/:job_id
-func IngestStatusHandler(c fiber.Ctx) error {
+// CancelIngestHandler handles POST /v1/ingest/:job_id/cancel. A job that has
+// not started is removed from the queue and cancelled at once; a running job
+// is stopped by its worker, so the response only flags the request.
+func CancelIngestHandler(c fiber.Ctx) error {
 	jobID := c.Params("job_id")
-	if jobID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "job_id required"})
+	prod, ok := queueProducer()
+	if !ok {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "queue not configured"})
+	}
+	ctx := context.Background()
+
+	var resp *IngestStatusResponse
+	if services.Jobs != nil {
+		job, err := services.Jobs.RequestCancel(ctx, jobID)
+		if err == nil {
+			r := jobStatusResponse(job)
+			resp = &r
+		} else if !errors.Is(err, storage.ErrNotFound) {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load job"})
+		}
 	}
 
-	redisURL := os.Getenv("REDIS_URL")
-	if redisURL == "" {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "REDIS_URL not set"})
-	}
-	opts, err := redis.ParseURL(redisURL)
+	// The worker checks this flag when it starts the task.
+	rdb, err := jobRedis()
 	if err != nil {
-		// fallback: treat as host:port
-		opts = &redis.Options{Addr: redisURL}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
-	rdb := redis.NewClient(opts)
 	defer rdb.Close()
-
 	key := "cgap:job:" + jobID
-	m, err := rdb.HGetAll(context.Background(), key).Result()
+	m, err := rdb.HGetAll(ctx, key).Result()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "redis error"})
 	}
-	if len(m) == 0 {
+	if resp == nil && len(m) > 0 {
+		r := jobStatusFromHash(m)
+		resp = &r
+	}
+	if resp == nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "job not found"})
 	}
+	if model.IsTerminalJobStatus(resp.Status) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "job already " + resp.Status})
+	}
+	if len(m) > 0 {
+		_ = rdb.HSet(ctx, key, "cancel_requested", 1).Err()
+	}
+
+	removed, err := prod.Cancel(ctx, jobID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !removed {
+		resp.CancelRequested = true
+		return c.Status(fiber.StatusAccepted).JSON(resp)
+	}
+
+	// The task never reached a worker, so nobody else will finish the job.
+	now := time.Now().UTC().Format(time.RFC3339)
+	if services.Jobs != nil {
+		if err := services.Jobs.SetStatus(ctx, jobID, model.JobCancelled, ""); err != nil {
+			slog.Warn("Failed to record job cancellation", "job_id", jobID, "error", err)
+		}
+	}
+	if len(m) > 0 {
+		_ = rdb.HSet(ctx, key, map[string]any{
+			"status":      model.JobCancelled,
+			"finished_at": now,
+			"updated_at":  now,
+		}).Err()
+	}
+	resp.Status, resp.FinishedAt, resp.CancelRequested = model.JobCancelled, now, false
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// ProjectJobsHandler handles GET /v1/projects/:id/jobs
+func ProjectJobsHandler(c fiber.Ctx) error {
+	if services == nil || services.Jobs == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "job history not configured"})
+	}
+
+	limit := fiber.Query[int](c, "limit", 50)
+	offset := fiber.Query[int](c, "offset", 0)
+	if limit <= 0 || limit > 500 || offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "limit must be 1-500 and offset non-negative"})
+	}
+	status := c.Query("status")
+	switch status {
+	case "", model.JobQueued, model.JobRunning, model.JobRetrying, model.JobCompleted, model.JobFailed, model.JobCancelled:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unsupported status"})
+	}
+
+	jobs, err := services.Jobs.ListByProject(context.Background(), c.Params("id"), status, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to list jobs"})
+	}
+
+	resp := JobsResponse{Jobs: make([]IngestStatusResponse, 0, len(jobs))}
+	for _, j := range jobs {
+		resp.Jobs = append(resp.Jobs, jobStatusResponse(j))
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// MinSyncInterval keeps scheduled re-syncs from hammering documentation sites.
+const MinSyncInterval = 15 * time.Minute
+
+// ParseSchedule parses a source schedule: @hourly, @daily, @weekly or a Go
+// duration of at least MinSyncInterval. An empty schedule returns 0 (on demand).
+func ParseSchedule(s string) (time.Duration, error) {
+	switch s {
+	case "":
+		return 0, nil
+	case "@hourly":
+		return time.Hour, nil
+	case "@daily":
+		return 24 * time.Hour, nil
+	case "@weekly":
+		return 7 * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errors.New("schedule must be @hourly, @daily, @weekly or a duration such as 6h")
+	}
+	if d < MinSyncInterval {
+		return 0, fmt.Errorf("schedule must be at least %s", MinSyncInterval)
+	}
+	return d, nil
+}
+
+// CreateSourceHandler handles POST /v1/sources
+func CreateSourceHandler(c fiber.Ctx) error {
+	if services == nil || services.Sources == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "sources not configured"})
+	}
+	var req SourceRequest
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.ProjectID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "project_id required"})
+	}
+
+	src, err := sourceFromRequest(&req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	src.ProjectID = req.ProjectID
+	if err := services.Sources.Create(context.Background(), src); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "project not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create source"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(sourceView(src))
+}
+
+// ListSourcesHandler handles GET /v1/sources?project_id=
+func ListSourcesHandler(c fiber.Ctx) error {
+	if services == nil || services.Sources == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "sources not configured"})
+	}
+	projectID := c.Query("project_id")
+	if projectID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "project_id required"})
+	}
+	limit := fiber.Query[int](c, "limit", 50)
+	offset := fiber.Query[int](c, "offset", 0)
+	if limit <= 0 || limit > 500 || offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "limit must be 1-500 and offset non-negative"})
+	}
+
+	sources, err := services.Sources.List(context.Background(), projectID, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to list sources"})
+	}
+
+	resp := SourcesResponse{Sources: make([]Source, 0, len(sources))}
+	for _, src := range sources {
+		resp.Sources = append(resp.Sources, *sourceView(src))
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetSourceHandler handles GET /v1/sources/:id
+func GetSourceHandler(c fiber.Ctx) error {
+	if services == nil || services.Sources == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "sources not configured"})
+	}
+	src, err := services.Sources.GetByID(context.Background(), c.Params("id"))
+	if err != nil {
+		return sourceError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(sourceView(src))
+}
+
+// UpdateSourceHandler handles PUT /v1/sources/:id. The project cannot change.
+func UpdateSourceHandler(c fiber.Ctx) error {
+	if services == nil || services.Sources == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "sources not configured"})
+	}
+	var req SourceRequest
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	ctx := context.Background()
+	existing, err := services.Sources.GetByID(ctx, c.Params("id"))
+	if err != nil {
+		return sourceError(c, err)
+	}
+	src, err := sourceFromRequest(&req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	src.ID, src.ProjectID, src.CreatedAt = existing.ID, existing.ProjectID, existing.CreatedAt
+	src.LastSyncedAt, src.LastJobID, src.LastJobStatus, src.LastError = existing.LastSyncedAt, existing.LastJobID, existing.LastJobStatus, existing.LastError
+	if err := services.Sources.Update(ctx, src); err != nil {
+		return sourceError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(sourceView(src))
+}
+
+// DeleteSourceHandler handles DELETE /v1/sources/:id. Documents of the source
+// are kept and unlinked; its media items are deleted.
+func DeleteSourceHandler(c fiber.Ctx) error {
+	if services == nil || services.Sources == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "sources not configured"})
+	}
+	if err := services.Sources.Delete(context.Background(), c.Params("id")); err != nil {
+		return sourceError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// SyncSourceHandler handles POST /v1/sources/:id/sync, queueing an ingest of
+// the source now regardless of its schedule.
+func SyncSourceHandler(c fiber.Ctx) error {
+	if services == nil || services.Sources == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "sources not configured"})
+	}
+	prod, ok := queueProducer()
+	if !ok {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "queue not configured"})
+	}
+
+	ctx := context.Background()
+	src, err := services.Sources.GetByID(ctx, c.Params("id"))
+	if err != nil {
+		return sourceError(c, err)
+	}
+	if src.LastJobStatus != "" && !model.IsTerminalJobStatus(src.LastJobStatus) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "sync already in progress", "job_id": src.LastJobID})
+	}
+
+	payload, err := SourcePayload(src)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	rdb, err := jobRedis()
+	if err != nil {
+		rdb = nil
+	} else {
+		defer rdb.Close()
+	}
+	jobID, err := EnqueueIngest(ctx, prod, services.Jobs, rdb, payload)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "enqueue failed", "details": err.Error()})
+	}
+	if err := services.Sources.RecordSync(ctx, src.ID, jobID); err != nil {
+		slog.Warn("Failed to record source sync", "source_id", src.ID, "error", err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(IngestResponse{JobID: jobID, Status: model.JobQueued, ProjectID: src.ProjectID})
+}
+
+// SourcePayload builds the ingest task that syncs a stored source.
+func SourcePayload(src *model.Source) (IngestTaskPayload, error) {
+	var spec SourceSpec
+	b, err := json.Marshal(src.Config)
+	if err != nil {
+		return IngestTaskPayload{}, fmt.Errorf("failed to encode source config: %w", err)
+	}
+	if err := json.Unmarshal(b, &spec); err != nil {
+		return IngestTaskPayload{}, fmt.Errorf("invalid source config: %w", err)
+	}
+	return IngestTaskPayload{
+		ProjectID:      src.ProjectID,
+		Source:         spec,
+		ChunkStrategy:  src.ChunkStrategy,
+		ChunkSizeToken: src.ChunkSizeToken,
+		FullSync:       src.FullSync,
+		SourceID:       src.ID,
+	}, nil
+}
 
-	// Map fields into response
+// sourceFromRequest validates a SourceRequest and converts it to a source.
+func sourceFromRequest(req *SourceRequest) (*model.Source, error) {
+	if req.Source.Type == "" {
+		return nil, errors.New("source.type required")
+	}
+	if err := validateIngestOptions(&req.Source, req.ChunkStrategy, req.ChunkSizeToken, req.FullSync); err != nil {
+		return nil, err
+	}
+	if err := validateSourceSpec(&req.Source); err != nil {
+		return nil, err
+	}
+	interval, err := ParseSchedule(req.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	status := req.Status
+	switch status {
+	case "":
+		status = model.SourceActive
+	case model.SourceActive, model.SourcePaused:
+	default:
+		return nil, errors.New("status must be active or paused")
+	}
+	return &model.Source{
+		Name:           req.Name,
+		Type:           req.Source.Type,
+		Config:         sourceConfig(req.Source),
+		Status:         status,
+		Schedule:       req.Schedule,
+		SyncInterval:   interval,
+		ChunkStrategy:  req.ChunkStrategy,
+		ChunkSizeToken: req.ChunkSizeToken,
+		FullSync:       req.FullSync,
+	}, nil
+}
+
+// sourceConfig converts a SourceSpec to the JSON object stored as source config.
+func sourceConfig(spec SourceSpec) map[string]any {
+	b, err := json.Marshal(spec)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	_ = json.Unmarshal(b, &m)
+	return m
+}
+
+// sourceView hides provider tokens stored in a source's config.
+func sourceView(src *model.Source) *model.Source {
+	if _, ok := src.Config["token"]; !ok {
+		return src
+	}
+	view := *src
+	view.Config = make(map[string]any, len(src.Config))
+	for k, v := range src.Config {
+		if k != "token" {
+			view.Config[k] = v
+		}
+	}
+	return &view
+}
+
+func sourceError(c fiber.Ctx, err error) error {
+	if errors.Is(err, storage.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "source not found"})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "source storage error"})
+}
+
+// DefaultUploadMaxBytes caps uploads when Services.UploadMaxBytes is unset.
+const DefaultUploadMaxBytes = 100 << 20
+
+// UploadHandler handles POST /v1/uploads: a multipart form with project_id and
+// file. The content type is sniffed from the file rather than taken from the
+// client.
+func UploadHandler(c fiber.Ctx) error {
+	if services == nil || services.Uploads == nil || services.Blobs == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "uploads not configured"})
+	}
+	projectID := c.FormValue("project_id")
+	if projectID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "project_id required"})
+	}
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "file required"})
+	}
+	limit := services.UploadMaxBytes
+	if limit <= 0 {
+		limit = DefaultUploadMaxBytes
+	}
+	if fh.Size > limit {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": fmt.Sprintf("file exceeds %d bytes", limit)})
+	}
+	if fh.Size == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "file is empty"})
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "failed to read file"})
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "failed to read file"})
+	}
+	head = head[:n]
+
+	filename := uploadFilename(fh.Filename)
+	upload := &model.Upload{
+		ID:          uuid.NewString(),
+		ProjectID:   projectID,
+		Filename:    filename,
+		ContentType: sniffContentType(filename, head),
+		SizeBytes:   fh.Size,
+	}
+	upload.StorageKey = upload.ID + "/" + filename
+
+	ctx := context.Background()
+	sum := sha256.New()
+	body := io.TeeReader(io.MultiReader(bytes.NewReader(head), f), sum)
+	if err := services.Blobs.Put(ctx, upload.StorageKey, body, upload.SizeBytes, upload.ContentType); err != nil {
+		slog.Error("Failed to store upload", "project_id", projectID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to store upload"})
+	}
+	upload.SHA256 = hex.EncodeToString(sum.Sum(nil))
+
+	if err := services.Uploads.Create(ctx, upload); err != nil {
+		if derr := services.Blobs.Delete(ctx, upload.StorageKey); derr != nil {
+			slog.Warn("Failed to remove orphaned upload", "key", upload.StorageKey, "error", derr)
+		}
+		if errors.Is(err, storage.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "project not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to record upload"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(UploadResponse{
+		UploadID:    upload.ID,
+		ProjectID:   upload.ProjectID,
+		Filename:    upload.Filename,
+		ContentType: upload.ContentType,
+		SizeBytes:   upload.SizeBytes,
+		SHA256:      upload.SHA256,
+		CreatedAt:   upload.CreatedAt,
+	})
+}
+
+// uploadFilename reduces a client-supplied filename to a safe final path
+// element.
+func uploadFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "upload"
+	}
+	return name
+}
+
+// refinedContentTypes narrows generic sniffed types by file extension: DOCX
+// files sniff as zip, Markdown as plain text and tar archives not at all.
+var refinedContentTypes = map[string]map[string]string{
+	"application/octet-stream": {
+		".tar": "application/x-tar",
+	},
+	"application/zip": {
+		".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	},
+	"text/plain": {
+		".md":       "text/markdown",
+		".markdown": "text/markdown",
+		".csv":      "text/csv",
+		".json":     "application/json",
+		".yaml":     "application/yaml",
+		".yml":      "application/yaml",
+	},
+}
+
+// sniffContentType detects the type of an uploaded file from its first bytes,
+// falling back to the extension only when the content is unrecognised.
+func sniffContentType(filename string, head []byte) string {
+	sniffed := http.DetectContentType(head)
+	mt, _, _ := mime.ParseMediaType(sniffed)
+	ext := strings.ToLower(path.Ext(filename))
+	if refined, ok := refinedContentTypes[mt][ext]; ok {
+		return refined
+	}
+	if mt == "application/octet-stream" {
+		if byExt := mime.TypeByExtension(ext); byExt != "" && !strings.HasPrefix(byExt, "text/") {
+			return byExt
+		}
+	}
+	return sniffed
+}
+
+// openUpload looks up an upload of projectID (a UUID or slug) and copies its
+// content to a temporary file, for handlers that need a local path. Uploads of
+// other projects are reported as storage.ErrNotFound. The caller removes the file.
+func openUpload(ctx context.Context, id, projectID string) (*model.Upload, string, error) {
+	upload, err := services.Uploads.GetByID(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if !looksLikeUUID(projectID) {
+		// Slugs are resolved here as no worker checks the upload later.
+		if services.DB == nil {
+			return nil, "", fmt.Errorf("upload %s: cannot resolve project %q: %w", id, projectID, storage.ErrNotFound)
+		}
+		if err := services.DB.QueryRow(ctx, `SELECT id FROM projects WHERE slug = $1`, projectID).Scan(&projectID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, "", fmt.Errorf("upload %s: project %q: %w", id, projectID, storage.ErrNotFound)
+			}
+			return nil, "", fmt.Errorf("failed to resolve project: %w", err)
+		}
+	}
+	if upload.ProjectID != projectID {
+		return nil, "", fmt.Errorf("upload %s: %w", id, storage.ErrNotFound)
+	}
+	rc, err := services.Blobs.Get(ctx, upload.StorageKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read upload: %w", err)
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp("", "upload-*"+path.Ext(upload.Filename))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	if _, err := io.Copy(tmp, rc); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, "", fmt.Errorf("failed to read upload: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return nil, "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	return upload, tmp.Name(), nil
+}
+
+// jobRedis connects to the Redis instance holding live job status.
+func jobRedis() (*redis.Client, error) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		return nil, errors.New("REDIS_URL not set")
+	}
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		// fallback: treat as host:port
+		opts = &redis.Options{Addr: redisURL}
+	}
+	return redis.NewClient(opts), nil
+}
+
+// jobStatusFromHash maps the live Redis job hash into a status response.
+func jobStatusFromHash(m map[string]string) IngestStatusResponse {
 	resp := IngestStatusResponse{
 		JobID:      m["job_id"],
 		ProjectID:  m["project_id"],
@@ -910,18 +1847,56 @@ func IngestStatusHandler(c fiber.Ctx) error {
 		FinishedAt: m["finished_at"],
 		Error:      m["error"],
 	}
-	if v, ok := m["processed"]; ok {
-		if n, perr := strconv.Atoi(v); perr == nil {
-			resp.Processed = n
-		}
-	}
-	if v, ok := m["total"]; ok {
-		if n, perr := strconv.Atoi(v); perr == nil {
-			resp.Total = n
+	for field, dst := range map[string]*int{
+		"processed": &resp.Processed,
+		"total":     &resp.Total,
+		"unchanged": &resp.Unchanged,
+		"deleted":   &resp.Deleted,
+		"retries":   &resp.Retries,
+		"failed":    &resp.Failed,
+	} {
+		if n, err := strconv.Atoi(m[field]); err == nil {
+			*dst = n
 		}
 	}
+	resp.CancelRequested = m["cancel_requested"] == "1" && !model.IsTerminalJobStatus(resp.Status)
+	return resp
+}
 
-	return c.Status(fiber.StatusOK).JSON(resp)
+// jobStatusResponse maps a job history record into a status response.
+func jobStatusResponse(j *model.Job) IngestStatusResponse {
+	resp := IngestStatusResponse{
+		JobID:           j.ID,
+		ProjectID:       j.ProjectID,
+		Status:          j.Status,
+		Processed:       j.Processed,
+		Total:           j.Total,
+		Unchanged:       j.Unchanged,
+		Deleted:         j.Deleted,
+		Retries:         j.Retries,
+		Failed:          j.Failed,
+		Error:           j.Error,
+		CancelRequested: j.CancelRequestedAt != nil && !model.IsTerminalJobStatus(j.Status),
+	}
+	if j.StartedAt != nil {
+		resp.StartedAt = j.StartedAt.UTC().Format(time.RFC3339)
+	}
+	if j.FinishedAt != nil {
+		resp.FinishedAt = j.FinishedAt.UTC().Format(time.RFC3339)
+	}
+	return resp
+}
+
+// jobPayload converts a task payload for job history, without provider tokens.
+func jobPayload(p IngestTaskPayload) map[string]any {
+	p.Source.Token = ""
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	_ = json.Unmarshal(b, &m)
+	return m
 }
 
 // DevSeedHandler handles POST /v1/dev/seed to insert a document, chunk, and embedding
@@ -1077,6 +2052,54 @@ func GapsHandler(c fiber.Ctx) error {
 	})
 }
 
+// DeadTasksHandler handles GET /v1/queue/dead
+func DeadTasksHandler(c fiber.Ctx) error {
+	prod, ok := queueProducer()
+	if !ok {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "queue not configured"})
+	}
+
+	limit := fiber.Query[int](c, "limit", 50)
+	offset := fiber.Query[int](c, "offset", 0)
+	if limit <= 0 || limit > 500 || offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "limit must be 1-500 and offset non-negative"})
+	}
+
+	tasks, total, err := prod.DeadTasks(context.Background(), offset, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(DeadTasksResponse{Tasks: tasks, Total: int(total)})
+}
+
+// RequeueDeadTaskHandler handles POST /v1/queue/dead/:task_id/requeue
+func RequeueDeadTaskHandler(c fiber.Ctx) error {
+	prod, ok := queueProducer()
+	if !ok {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "queue not configured"})
+	}
+
+	task, err := prod.RequeueDead(context.Background(), c.Params("task_id"))
+	if errors.Is(err, queue.ErrTaskNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "dead task not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(task)
+}
+
+// queueProducer returns the Redis producer when one is wired.
+func queueProducer() (*queue.Producer, bool) {
+	if services == nil {
+		return nil, false
+	}
+	prod, ok := services.Queue.(*queue.Producer)
+	return prod, ok && prod != nil
+}
+
 // HealthHandler handles GET /health
 func HealthHandler(c fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
@@ -1137,6 +2160,10 @@ func RegisterRoutesWithServices(app *fiber.App, svc *Services, deps *HealthDeps)
 
 	// Chat
 	app.Post("/v1/chat", ChatHandler)
+	app.Post("/v1/chat/stream", ChatStreamHandler)
+	app.Post("/v1/threads", ThreadCreateHandler)
+	app.Post("/v1/threads/:id/chat", ThreadChatHandler)
+	app.Post("/v1/threads/:id/chat/stream", ThreadChatStreamHandler)
 
 	// Search
 	app.Post("/v1/search", SearchHandler)
@@ -1157,6 +2184,23 @@ func RegisterRoutesWithServices(app *fiber.App, svc *Services, deps *HealthDeps)
 	// Ingest
 	app.Post("/v1/ingest", IngestHandler)
 	app.Get("/v1/ingest/:job_id", IngestStatusHandler)
+	app.Post("/v1/ingest/:job_id/cancel", CancelIngestHandler)
+	app.Get("/v1/projects/:id/jobs", ProjectJobsHandler)
+
+	// Sources
+	app.Post("/v1/sources", CreateSourceHandler)
+	app.Get("/v1/sources", ListSourcesHandler)
+	app.Get("/v1/sources/:id", GetSourceHandler)
+	app.Put("/v1/sources/:id", UpdateSourceHandler)
+	app.Delete("/v1/sources/:id", DeleteSourceHandler)
+	app.Post("/v1/sources/:id/sync", SyncSourceHandler)
+
+	// Uploads
+	app.Post("/v1/uploads", UploadHandler)
+
+	// Task queue dead-letter inspection
+	app.Get("/v1/queue/dead", DeadTasksHandler)
+	app.Post("/v1/queue/dead/:task_id/requeue", RequeueDeadTaskHandler)
 	// Dev seed
 	app.Post("/v1/dev/seed", DevSeedHandler)
 
@@ -1,10 +1,652 @@
 package api_test
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"cgap/api"
+	"cgap/internal/model"
+	"cgap/internal/objectstore"
+	"cgap/internal/storage"
+	"cgap/internal/testutil"
+
+	"github.com/gofiber/fiber/v3"
 )
 
 // Placeholder test to ensure api_test package compiles
 func TestAPIPackage(t *testing.T) {
 	t.Log("API package test placeholder")
 }
+
+func TestChatStreamHandler_WritesSSEFrames(t *testing.T) {
+	app := fiber.New()
+	api.RegisterRoutesWithServices(app, &api.Services{Chat: &testutil.MockChatService{
+		Frames: []api.StreamFrame{
+			{Type: "token", Data: map[string]any{"token": "Hello"}},
+			{Type: "done", Data: map[string]any{"citations": []string{"doc-1"}, "thread_id": "thread-1"}},
+		},
+	}}, nil)
+
+	body := strings.NewReader(`{"project_id":"proj","query":"hi"}`)
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/stream", body)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %q", ct)
+	}
+
+	raw, _ := io.ReadAll(resp.Body)
+	out := string(raw)
+	if !strings.Contains(out, "event: token\ndata: {\"type\":\"token\",\"data\":{\"token\":\"Hello\"}}\n\n") {
+		t.Errorf("Missing token event in %q", out)
+	}
+	if !strings.Contains(out, "event: done\n") || !strings.Contains(out, `"thread_id":"thread-1"`) {
+		t.Errorf("Missing done event with thread id in %q", out)
+	}
+}
+
+func TestThreadChatStreamHandler_UnknownThread(t *testing.T) {
+	app := fiber.New()
+	api.RegisterRoutesWithServices(app, &api.Services{Chat: &testutil.MockChatService{
+		Error: api.ErrThreadNotFound,
+	}}, nil)
+
+	body := strings.NewReader(`{"project_id":"proj","query":"hi"}`)
+	req := httptest.NewRequest(http.MethodPost, "/v1/threads/abc/chat/stream", body)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestChatHandlers_UnknownProject(t *testing.T) {
+	app := fiber.New()
+	api.RegisterRoutesWithServices(app, &api.Services{Chat: &testutil.MockChatService{
+		Error: fmt.Errorf("resolve project %q: %w", "nope", storage.ErrNotFound),
+	}}, nil)
+
+	for _, path := range []string{"/v1/chat", "/v1/chat/stream", "/v1/threads/abc/chat", "/v1/threads/abc/chat/stream"} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"project_id":"nope","query":"hi"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", path, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestExtensionChatHandler_SendsPageAsInstructions(t *testing.T) {
+	chat := &testutil.MockChatService{Response: api.ChatResponse{
+		Answer:    "1. Click Settings [1]",
+		Citations: []api.AnswerCitation{{Index: 1, ChunkID: "chunk-1", Quote: "Passwords are changed under Settings.", Score: 0.9}},
+	}}
+	// A separate search would cite other chunks than the answer does.
+	search := &testutil.MockSearchService{Hits: []api.SearchHit{{ChunkID: "chunk-2", Text: "Unrelated"}}}
+	app := fiber.New()
+	api.RegisterRoutesWithServices(app, &api.Services{Chat: chat, Search: search}, nil)
+
+	body := strings.NewReader(`{"project_id":"proj","url":"https://app.example.com/home","question":"How do I change my password?","dom":[{"type":"button","text":"Settings","selector":"#settings"}]}`)
+	req := httptest.NewRequest(http.MethodPost, "/v1/extension/chat", body)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	var out api.ExtensionChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(out.Sources) != 1 || out.Sources[0].ChunkID != "chunk-1" || out.Sources[0].Quote != "Passwords are changed under Settings." {
+		t.Errorf("Expected the answer's citations as sources, got %+v", out.Sources)
+	}
+	if len(chat.Requests) != 1 {
+		t.Fatalf("Expected one chat request, got %d", len(chat.Requests))
+	}
+	got := chat.Requests[0]
+	if got.Query != "How do I change my password?" {
+		t.Errorf("Expected the question as query, got %q", got.Query)
+	}
+	if !strings.Contains(got.Instructions, "https://app.example.com/home") || !strings.Contains(got.Instructions, "#settings") {
+		t.Errorf("Expected page URL and elements in instructions, got %q", got.Instructions)
+	}
+}
+
+func TestThreadCreateHandler_UnknownProject(t *testing.T) {
+	app := fiber.New()
+	api.RegisterRoutesWithServices(app, &api.Services{Chat: &testutil.MockChatService{
+		Error: fmt.Errorf("create thread: %w", storage.ErrNotFound),
+	}}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/threads", strings.NewReader(`{"project_id":"missing"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusNotFound || !strings.Contains(string(raw), "project not found") {
+		t.Errorf("Expected 404 project not found, got %d %s", resp.StatusCode, raw)
+	}
+}
+
+func TestIngestHandler_RejectsInvalidOptions(t *testing.T) {
+	app := fiber.New()
+	api.RegisterRoutesWithServices(app, &api.Services{}, nil)
+
+	for name, payload := range map[string]string{
+		"unknown chunk strategy": `{"project_id":"proj","source":{"type":"url","url":"https://docs.example.com"},"chunk_strategy":"sentences"}`,
+		"full sync of one url":   `{"project_id":"proj","source":{"type":"url","url":"https://docs.example.com"},"full_sync":true}`,
+		"full sync single page":  `{"project_id":"proj","source":{"type":"crawl","crawl":{"mode":"single","start_url":"https://docs.example.com"}},"full_sync":true}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/ingest", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", name, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, resp.StatusCode)
+		}
+	}
+}
+
+func TestIngestHandler_AcceptsPDFExtractorAliases(t *testing.T) {
+	t.Setenv("REDIS_URL", "")
+	app := fiber.New()
+	api.RegisterRoutesWithServices(app, &api.Services{}, nil)
+
+	for _, extract := range []string{"pdf", "pdfium", "tika"} {
+		payload := fmt.Sprintf(`{"project_id":"proj","source":{"type":"url","url":"https://docs.example.com/guide.pdf","files":{"extract":%q}}}`, extract)
+		req := httptest.NewRequest(http.MethodPost, "/v1/ingest", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", extract, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusAccepted {
+			t.Errorf("%s: expected 202, got %d", extract, resp.StatusCode)
+		}
+	}
+}
+
+func TestSearchHandler_RejectsInvalidFusion(t *testing.T) {
+	app := fiber.New()
+	api.RegisterRoutesWithServices(app, &api.Services{Search: &testutil.MockSearchService{}}, nil)
+
+	for name, payload := range map[string]string{
+		"unknown strategy": `{"project_id":"proj","query":"q","fusion":{"strategy":"magic"}}`,
+		"negative k":       `{"project_id":"proj","query":"q","fusion":{"k":-1}}`,
+		"negative weight":  `{"project_id":"proj","query":"q","fusion":{"semantic_weight":-0.5}}`,
+		"zero weights":     `{"project_id":"proj","query":"q","fusion":{"semantic_weight":0,"lexical_weight":0}}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/search", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", name, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, resp.StatusCode)
+		}
+	}
+}
+
+func TestSearchHandler_FusionRejectedBySearch(t *testing.T) {
+	app := fiber.New()
+	api.RegisterRoutesWithServices(app, &api.Services{Search: &testutil.MockSearchService{
+		Error: fmt.Errorf("%w: fusion weights must be non-negative and not both zero", api.ErrInvalidFusion),
+	}}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/search", strings.NewReader(`{"project_id":"proj","query":"q","fusion":{"semantic_weight":0}}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestDeadTasksHandler_QueueNotConfigured(t *testing.T) {
+	app := fiber.New()
+	api.RegisterRoutesWithServices(app, &api.Services{}, nil)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/v1/queue/dead", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a queue, got %d", resp.StatusCode)
+	}
+}
+
+// fakeJobRepo serves jobs from memory.
+type fakeJobRepo struct {
+	storage.JobRepo
+	jobs   map[string]*model.Job
+	errors map[string][]model.JobURLError
+}
+
+func (f *fakeJobRepo) GetByID(ctx context.Context, id string) (*model.Job, error) {
+	if j, ok := f.jobs[id]; ok {
+		return j, nil
+	}
+	return nil, fmt.Errorf("failed to get job: %w", storage.ErrNotFound)
+}
+
+func (f *fakeJobRepo) ListByProject(ctx context.Context, projectID, status string, limit, offset int) ([]*model.Job, error) {
+	var out []*model.Job
+	for _, j := range f.jobs {
+		if j.ProjectID == projectID && (status == "" || j.Status == status) {
+			out = append(out, j)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeJobRepo) Create(ctx context.Context, job *model.Job) error {
+	if f.jobs == nil {
+		f.jobs = map[string]*model.Job{}
+	}
+	f.jobs[job.ID] = job
+	return nil
+}
+
+func (f *fakeJobRepo) ListURLErrors(ctx context.Context, id string, limit int) ([]model.JobURLError, error) {
+	return f.errors[id], nil
+}
+
+func TestIngestStatusHandler_ReportsURLErrors(t *testing.T) {
+	started := time.Date(2025, 12, 16, 9, 0, 0, 0, time.UTC)
+	repo := &fakeJobRepo{
+		jobs: map[string]*model.Job{
+			"job_1": {ID: "job_1", ProjectID: "p1", Status: model.JobCompleted, Processed: 300, Total: 300, Failed: 1, StartedAt: &started},
+		},
+		errors: map[string][]model.JobURLError{
+			"job_1": {{URL: "https://docs.example.com/a", Error: "502 Bad Gateway", OccurredAt: started}},
+		},
+	}
+	app := fiber.New()
+	api.RegisterRoutesWithServices(app, &api.Services{Jobs: repo}, nil)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/v1/ingest/job_1", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+
+	var status api.IngestStatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if status.Failed != 1 || len(status.URLErrors) != 1 || status.URLErrors[0].URL != "https://docs.example.com/a" {
+		t.Errorf("Expected one URL error, got %+v", status)
+	}
+	if status.StartedAt != "2025-12-16T09:00:00Z" {
+		t.Errorf("Expected RFC3339 started_at, got %q", status.StartedAt)
+	}
+}
+
+func TestProjectJobsHandler(t *testing.T) {
+	repo := &fakeJobRepo{jobs: map[string]*model.Job{
+		"job_1": {ID: "job_1", ProjectID: "p1", Status: model.JobFailed},
+		"job_2": {ID: "job_2", ProjectID: "p2", Status: model.JobFailed},
+	}}
+	app := fiber.New()
+	api.RegisterRoutesWithServices(app, &api.Services{Jobs: repo}, nil)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/v1/projects/p1/jobs?status=failed", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var list api.JobsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if len(list.Jobs) != 1 || list.Jobs[0].JobID != "job_1" {
+		t.Errorf("Expected only job_1, got %+v", list.Jobs)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/v1/projects/p1/jobs?status=done", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown status, got %d", resp.StatusCode)
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	for in, want := range map[string]time.Duration{
+		"":        0,
+		"@hourly": time.Hour,
+		"@daily":  24 * time.Hour,
+		"6h":      6 * time.Hour,
+	} {
+		got, err := api.ParseSchedule(in)
+		if err != nil || got != want {
+			t.Errorf("ParseSchedule(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"5m", "* * * * *", "@monthly"} {
+		if _, err := api.ParseSchedule(in); err == nil {
+			t.Errorf("ParseSchedule(%q): expected error", in)
+		}
+	}
+}
+
+// fakeSourceRepo stores created sources in memory.
+type fakeSourceRepo struct {
+	storage.SourceRepo
+	created []*model.Source
+}
+
+func (f *fakeSourceRepo) Create(ctx context.Context, s *model.Source) error {
+	if s.ProjectID != "p1" {
+		return fmt.Errorf("failed to create source: %w", storage.ErrNotFound)
+	}
+	s.ID = "src_1"
+	f.created = append(f.created, s)
+	return nil
+}
+
+func TestCreateSourceHandler(t *testing.T) {
+	repo := &fakeSourceRepo{}
+	app := fiber.New()
+	api.RegisterRoutesWithServices(app, &api.Services{Sources: repo}, nil)
+
+	post := func(body string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, "/v1/sources", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := post(`{"project_id":"p1","name":"Docs","source":{"type":"crawl","crawl":{"start_url":"https://docs.example.com"},"token":"secret"},"schedule":"@daily"}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+	var src api.Source
+	if err := json.NewDecoder(resp.Body).Decode(&src); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if src.ID != "src_1" || src.Schedule != "@daily" || src.Status != model.SourceActive {
+		t.Errorf("Unexpected source %+v", src)
+	}
+	if _, ok := src.Config["token"]; ok {
+		t.Error("Expected token to be hidden from the response")
+	}
+	if len(repo.created) != 1 || repo.created[0].SyncInterval != 24*time.Hour || repo.created[0].Config["token"] != "secret" {
+		t.Errorf("Expected stored daily source with token, got %+v", repo.created)
+	}
+
+	for body, want := range map[string]int{
+		`{"project_id":"p1","source":{"type":"url","url":"https://docs.example.com"},"schedule":"1m"}`: http.StatusBadRequest,
+		`{"project_id":"p1","source":{"type":"url"}}`:                                                  http.StatusBadRequest,
+		`{"project_id":"p2","source":{"type":"url","url":"https://docs.example.com"}}`:                 http.StatusNotFound,
+	} {
+		resp := post(body)
+		_ = resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s: expected %d, got %d", body, want, resp.StatusCode)
+		}
+	}
+}
+
+// fakeUploadRepo stores uploads in memory; only project "p1" exists.
+type fakeUploadRepo struct {
+	byID map[string]*model.Upload
+}
+
+func (f *fakeUploadRepo) Create(ctx context.Context, u *model.Upload) error {
+	if u.ProjectID != "p1" {
+		return fmt.Errorf("failed to create upload: %w", storage.ErrNotFound)
+	}
+	f.byID[u.ID] = u
+	return nil
+}
+
+func (f *fakeUploadRepo) GetByID(ctx context.Context, id string) (*model.Upload, error) {
+	if u, ok := f.byID[id]; ok {
+		return u, nil
+	}
+	return nil, storage.ErrNotFound
+}
+
+func TestUploadHandler(t *testing.T) {
+	repo := &fakeUploadRepo{byID: map[string]*model.Upload{}}
+	blobs, err := objectstore.NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	api.RegisterRoutesWithServices(app, &api.Services{Uploads: repo, Blobs: blobs, UploadMaxBytes: 1024}, nil)
+
+	upload := func(project, filename string, content []byte) *http.Response {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		_ = mw.WriteField("project_id", project)
+		fw, _ := mw.CreateFormFile("file", filename)
+		_, _ = fw.Write(content)
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/v1/uploads", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+
+	content := []byte("%PDF-1.4 release notes")
+	resp := upload("p1", `..\..\notes.bin`, content)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+	var out api.UploadResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	sum := sha256.Sum256(content)
+	if out.UploadID == "" || out.Filename != "notes.bin" || out.ContentType != "application/pdf" ||
+		out.SizeBytes != int64(len(content)) || out.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Unexpected upload %+v", out)
+	}
+	stored := repo.byID[out.UploadID]
+	if stored == nil {
+		t.Fatal("Expected upload to be recorded")
+	}
+	rc, err := blobs.Get(context.Background(), stored.StorageKey)
+	if err != nil {
+		t.Fatalf("Expected blob at %q: %v", stored.StorageKey, err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if !bytes.Equal(data, content) {
+		t.Errorf("Stored %q, want %q", data, content)
+	}
+
+	// Markdown sniffs as plain text and is refined by extension
+	resp = upload("p1", "guide.md", []byte("# Guide\n"))
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	_ = resp.Body.Close()
+	if out.ContentType != "text/markdown" {
+		t.Errorf("Expected text/markdown, got %q", out.ContentType)
+	}
+
+	for name, tc := range map[string]struct {
+		project string
+		content []byte
+		want    int
+	}{
+		"too large":       {"p1", bytes.Repeat([]byte("x"), 2048), http.StatusRequestEntityTooLarge},
+		"empty":           {"p1", nil, http.StatusBadRequest},
+		"missing project": {"p2", []byte("hello"), http.StatusNotFound},
+	} {
+		resp := upload(tc.project, "a.txt", tc.content)
+		_ = resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s: expected %d, got %d", name, tc.want, resp.StatusCode)
+		}
+	}
+	if len(repo.byID) != 2 {
+		t.Errorf("Expected 2 recorded uploads, got %d", len(repo.byID))
+	}
+}
+
+func TestIngestHandler_UnknownUpload(t *testing.T) {
+	app := fiber.New()
+	api.RegisterRoutesWithServices(app, &api.Services{Uploads: &fakeUploadRepo{byID: map[string]*model.Upload{}}}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/ingest", strings.NewReader(`{"project_id":"p1","source":{"type":"upload","upload_id":"missing"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestMediaProcessHandler_ForeignUpload(t *testing.T) {
+	const owner, other = "00000000-0000-4000-8000-000000000001", "00000000-0000-4000-8000-000000000002"
+	blobs, err := objectstore.NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &fakeUploadRepo{byID: map[string]*model.Upload{
+		"up_1": {ID: "up_1", ProjectID: owner, Filename: "scan.png", ContentType: "image/png", StorageKey: "missing"},
+	}}
+	app := fiber.New()
+	api.RegisterRoutesWithServices(app, &api.Services{Uploads: repo, Blobs: blobs}, nil)
+
+	for name, project := range map[string]string{"other project": other, "unresolvable slug": "other-project"} {
+		body := fmt.Sprintf(`{"project_id":%q,"source_id":"s1","upload_id":"up_1"}`, project)
+		req := httptest.NewRequest(http.MethodPost, "/v1/media/process", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", name, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", name, resp.StatusCode)
+		}
+	}
+
+	// The owner gets past the check; the blob itself is missing here.
+	req := httptest.NewRequest(http.MethodPost, "/v1/media/process", strings.NewReader(fmt.Sprintf(`{"project_id":%q,"source_id":"s1","upload_id":"up_1"}`, owner)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected the owner's request to read the upload, got %d", resp.StatusCode)
+	}
+}
+
+func TestMediaProcessHandler_QueuesIngest(t *testing.T) {
+	// Without credentials the OCR backend returns placeholder text.
+	t.Setenv("OCR_BACKEND", "")
+	t.Setenv("GOOGLE_CLOUD_VISION_API_KEY", "")
+	t.Setenv("GOOGLE_API_KEY", "")
+	t.Setenv("REDIS_URL", "")
+	img := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png"))
+	}))
+	defer img.Close()
+
+	jobs := &fakeJobRepo{}
+	app := fiber.New()
+	api.RegisterRoutesWithServices(app, &api.Services{Jobs: jobs}, nil)
+
+	body := fmt.Sprintf(`{"project_id":"p1","source_id":"s1","media_url":%q,"media_type":"image","ocr_lang":"de"}`, img.URL+"/scan.png")
+	req := httptest.NewRequest(http.MethodPost, "/v1/media/process", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected 200, got %d: %s", resp.StatusCode, b)
+	}
+	var out api.MediaProcessResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	job, ok := jobs.jobs[out.JobID]
+	if !ok {
+		t.Fatalf("Expected the response job %q to be recorded, got %v", out.JobID, jobs.jobs)
+	}
+	src, _ := job.Payload["source"].(map[string]any)
+	media, _ := src["media"].(map[string]any)
+	if job.ProjectID != "p1" || src["type"] != "image" || src["url"] != img.URL+"/scan.png" || media["ocr_lang"] != "de" {
+		t.Errorf("Expected an image ingest of the processed URL, got %+v", job.Payload)
+	}
+}
@@ -2,9 +2,13 @@ package api
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"cgap/internal/model"
+	"cgap/internal/objectstore"
+	"cgap/internal/queue"
+	"cgap/internal/storage"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -34,8 +38,16 @@ type (
 type ChatService interface {
 	Chat(ctx context.Context, req ChatRequest) (ChatResponse, error)
 	ChatStream(ctx context.Context, req ChatRequest) (<-chan StreamFrame, error)
+	CreateThread(ctx context.Context, req ThreadCreateRequest) (Thread, error)
 }
 
+// ErrThreadNotFound is returned when a chat references a thread that does not
+// exist or belongs to another project.
+var ErrThreadNotFound = errors.New("thread not found")
+
+// ErrInvalidFusion is returned when per-request fusion options are unusable.
+var ErrInvalidFusion = errors.New("invalid fusion options")
+
 type SearchService interface {
 	Search(ctx context.Context, projectID, query string, topK int, filters map[string]any) ([]SearchHit, error)
 }
@@ -64,19 +76,43 @@ type ChatRequest struct {
 	ContextFilters map[string]any `json:"context_filters,omitempty"`
 	TopK           int            `json:"top_k,omitempty"`
 	ThreadID       string         `json:"thread_id,omitempty"`
+	// Instructions are sent to the LLM as an additional system message, e.g.
+	// the page context of the browser extension. They are not stored with the
+	// question and cannot be set by API clients.
+	Instructions string `json:"-"`
 }
 
 type ThreadCreateRequest struct {
-	ProjectID string `json:"project_id"`
-	UserID    string `json:"user_id,omitempty"`
+	ProjectID   string `json:"project_id"`
+	UserID      string `json:"user_id,omitempty"`
+	Integration string `json:"integration,omitempty"`  // widget|api|slack|discord|deflector|internal (default api)
+	ExternalRef string `json:"external_ref,omitempty"` // caller-side conversation reference
 }
 
 type ChatResponse struct {
-	ThreadID    string   `json:"thread_id"`
-	Answer      string   `json:"answer"`
-	IsUncertain bool     `json:"is_uncertain"`
-	Citations   []string `json:"citations"`
-	Confidence  float32  `json:"confidence"`
+	ThreadID    string           `json:"thread_id"`
+	Answer      string           `json:"answer"`
+	IsUncertain bool             `json:"is_uncertain"`
+	Citations   []AnswerCitation `json:"citations"`
+	Confidence  float32          `json:"confidence"`
+}
+
+// AnswerCitation is a numbered source referenced by an answer's [n] markers.
+// StartChar/EndChar locate Quote within the chunk text (in characters).
+type AnswerCitation struct {
+	Index       int     `json:"index"`
+	ChunkID     string  `json:"chunk_id"`
+	DocumentID  string  `json:"document_id"`
+	Title       string  `json:"title"`
+	URI         string  `json:"uri"`
+	SectionPath string  `json:"section_path,omitempty"`
+	Quote       string  `json:"quote"`
+	StartChar   int     `json:"start_char"`
+	EndChar     int     `json:"end_char"`
+	Score       float32 `json:"score"`
+	// Position of a transcript chunk in its video; URI then links to StartSeconds
+	StartSeconds *int `json:"start_seconds,omitempty"`
+	EndSeconds   *int `json:"end_seconds,omitempty"`
 }
 
 type StreamFrame struct {
@@ -89,6 +125,10 @@ type SearchHit struct {
 	Text       string  `json:"text"`
 	DocumentID string  `json:"document_id"`
 	Confidence float32 `json:"confidence"`
+	// Link to the document, at StartSeconds for transcript chunks
+	DocumentURI  string `json:"document_uri,omitempty"`
+	StartSeconds *int   `json:"start_seconds,omitempty"`
+	EndSeconds   *int   `json:"end_seconds,omitempty"`
 }
 
 type DeflectSuggestion struct {
@@ -111,6 +151,20 @@ type SearchRequest struct {
 	Query     string         `json:"query"`
 	Limit     int            `json:"limit,omitempty"`
 	Filters   map[string]any `json:"filters,omitempty"`
+	Fusion    *FusionOptions `json:"fusion,omitempty"`
+}
+
+// FusionFilterKey is the search filters entry carrying FusionOptions through to
+// hybrid search. Backends must not treat it as a field filter.
+const FusionFilterKey = "fusion"
+
+// FusionOptions overrides how hybrid search merges semantic and lexical results
+// for a single request. Unset fields keep the server defaults.
+type FusionOptions struct {
+	Strategy       string   `json:"strategy,omitempty"` // rrf, weighted
+	K              float64  `json:"k,omitempty"`        // RRF rank constant
+	SemanticWeight *float64 `json:"semantic_weight,omitempty"`
+	LexicalWeight  *float64 `json:"lexical_weight,omitempty"`
 }
 
 type SearchResponse struct {
@@ -140,10 +194,11 @@ type DeflectEventRequest struct {
 
 type IngestRequest struct {
 	ProjectID      string     `json:"project_id"`
-	Source         SourceSpec `json:"source"`                   // {"type": "url"|"crawl"|"github"|"openapi"|"slack"|"discord"|"upload", ...}
-	ChunkStrategy  string     `json:"chunk_strategy,omitempty"` // "semantic", "fixed", etc.
-	ChunkSizeToken int        `json:"chunk_size_token,omitempty"`
-	FailFast       bool       `json:"fail_fast,omitempty"` // If true, stop on first URL error
+	Source         SourceSpec `json:"source"`                     // {"type": "url"|"crawl"|"github"|"openapi"|"slack"|"discord"|"upload", ...}
+	ChunkStrategy  string     `json:"chunk_strategy,omitempty"`   // "heading" (default), "semantic" (alias of heading) or "fixed"
+	ChunkSizeToken int        `json:"chunk_size_token,omitempty"` // target chunk size in tokens (default 400, clamped to 64-2048)
+	FailFast       bool       `json:"fail_fast,omitempty"`        // If true, stop on first URL error
+	FullSync       bool       `json:"full_sync,omitempty"`        // If true, delete in-scope documents the crawl no longer lists
 }
 
 type IngestResponse struct {
@@ -152,29 +207,65 @@ type IngestResponse struct {
 	ProjectID string `json:"project_id"`
 }
 
+// UploadResponse describes a file stored by POST /v1/uploads. UploadID is
+// referenced by source.upload_id and media/process upload_id.
+type UploadResponse struct {
+	UploadID    string    `json:"upload_id"`
+	ProjectID   string    `json:"project_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"` // sniffed from the content
+	SizeBytes   int64     `json:"size_bytes"`
+	SHA256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
 // IngestStatusResponse represents the current status of an ingest job
 type IngestStatusResponse struct {
 	JobID      string `json:"job_id"`
 	ProjectID  string `json:"project_id"`
-	Status     string `json:"status"`      // queued|running|completed|failed
+	Status     string `json:"status"`      // queued|running|retrying|completed|failed|cancelled
 	Processed  int    `json:"processed"`   // processed units (pages or chunks)
 	Total      int    `json:"total"`       // total units if known
+	Unchanged  int    `json:"unchanged"`   // documents skipped because their content hash was unchanged
+	Deleted    int    `json:"deleted"`     // documents removed by a full sync
+	Retries    int    `json:"retries"`     // failed attempts so far
+	Failed     int    `json:"failed"`      // URLs that could not be ingested
 	StartedAt  string `json:"started_at"`  // RFC3339
 	FinishedAt string `json:"finished_at"` // RFC3339
 	Error      string `json:"error,omitempty"`
+	// CancelRequested is set once POST /v1/ingest/:job_id/cancel was accepted for a running job.
+	CancelRequested bool `json:"cancel_requested,omitempty"`
+	// URLErrors explains each failed URL (from job history; capped at MaxJobURLErrors).
+	URLErrors []model.JobURLError `json:"url_errors,omitempty"`
+}
+
+// MaxJobURLErrors caps the per-URL failures returned with a job status.
+const MaxJobURLErrors = 500
+
+// JobsResponse lists a project's ingest jobs, most recent first.
+type JobsResponse struct {
+	Jobs []IngestStatusResponse `json:"jobs"`
+}
+
+// DeadTasksResponse lists tasks that exhausted their retries.
+type DeadTasksResponse struct {
+	Tasks []queue.Task `json:"tasks"`
+	Total int          `json:"total"`
 }
 
 // SourceSpec describes an ingestion source.
 type SourceSpec struct {
-	Type       string         `json:"type"`             // url|crawl|github|openapi|slack|discord|upload
+	Type       string         `json:"type"`             // url|crawl|github|openapi|archive|slack|discord|upload
 	Config     map[string]any `json:"config,omitempty"` // arbitrary provider-specific config
-	URL        string         `json:"url,omitempty"`    // for url/crawl/openapi
+	URL        string         `json:"url,omitempty"`    // for url/crawl/openapi/archive
 	OpenAPIURL string         `json:"openapi_url,omitempty"`
 	Repo       string         `json:"repo,omitempty"` // for github
 	Owner      string         `json:"owner,omitempty"`
 	Token      string         `json:"token,omitempty"`     // optional access tokens for providers
-	UploadID   string         `json:"upload_id,omitempty"` // for upload
+	UploadID   string         `json:"upload_id,omitempty"` // for upload/archive
 	Crawl      *CrawlSpec     `json:"crawl,omitempty"`     // crawl configuration for type=crawl
+	GitHub     *GitHubSpec    `json:"github,omitempty"`    // repository options for type=github
+	Archive    *ArchiveSpec   `json:"archive,omitempty"`   // file mapping for type=archive
 	Media      *MediaSpec     `json:"media,omitempty"`     // media ingestion for type=image|video|youtube
 	Files      *FileSpec      `json:"files,omitempty"`     // document ingestion for type=document|pdf|markdown|txt
 }
@@ -186,6 +277,25 @@ type IngestTaskPayload struct {
 	ChunkStrategy  string     `json:"chunk_strategy,omitempty"`
 	ChunkSizeToken int        `json:"chunk_size_token,omitempty"`
 	FailFast       bool       `json:"fail_fast,omitempty"`
+	FullSync       bool       `json:"full_sync,omitempty"`
+	SourceID       string     `json:"source_id,omitempty"` // stored source the documents belong to
+}
+
+// SourceRequest creates or replaces an ingest source.
+type SourceRequest struct {
+	ProjectID      string     `json:"project_id"`
+	Name           string     `json:"name,omitempty"`
+	Source         SourceSpec `json:"source"`                     // stored as the source config
+	Schedule       string     `json:"schedule,omitempty"`         // @hourly|@daily|@weekly or a duration such as "6h"; empty syncs on demand only
+	Status         string     `json:"status,omitempty"`           // active (default) or paused
+	ChunkStrategy  string     `json:"chunk_strategy,omitempty"`   // as in IngestRequest
+	ChunkSizeToken int        `json:"chunk_size_token,omitempty"` // as in IngestRequest
+	FullSync       bool       `json:"full_sync,omitempty"`        // as in IngestRequest
+}
+
+// SourcesResponse lists a project's sources, most recent first.
+type SourcesResponse struct {
+	Sources []Source `json:"sources"`
 }
 
 // CrawlSpec describes how to fetch web content for web sources.
@@ -209,6 +319,21 @@ type CrawlSpec struct {
 	DelayMS       int  `json:"delay_ms,omitempty"`
 }
 
+// GitHubSpec selects what to ingest from a GitHub repository (type=github,
+// with source.owner, source.repo and optionally source.token).
+type GitHubSpec struct {
+	// Ref is the branch, tag or commit to ingest; empty uses the default branch.
+	Ref string `json:"ref,omitempty"`
+	// Paths are globs of files to include, e.g. "docs/**"; empty includes every path.
+	Paths []string `json:"paths,omitempty"`
+	// Extensions of documentation files to ingest; empty means .md, .mdx, .markdown, .rst and .txt.
+	Extensions []string `json:"extensions,omitempty"`
+	// IncludeCode also ingests source files, chunked along declarations.
+	IncludeCode bool `json:"include_code,omitempty"`
+	// APIURL overrides the REST API base, e.g. for GitHub Enterprise (https://host/api/v3).
+	APIURL string `json:"api_url,omitempty"`
+}
+
 // MediaSpec describes image/video ingestion parameters.
 type MediaSpec struct {
 	// Common
@@ -225,11 +350,25 @@ type MediaSpec struct {
 	MaxDurationSec     int      `json:"max_duration_sec,omitempty"`
 }
 
+// ArchiveSpec maps the files of a zip or tar archive (type=archive, read from
+// source.url or source.upload_id) to documents.
+type ArchiveSpec struct {
+	// BaseURL is the public URL the archive root is served at, e.g.
+	// https://docs.example.com/; empty uses "<archive URL>#<path>" URIs.
+	BaseURL string `json:"base_url,omitempty"`
+	// Root is the directory inside the archive served at BaseURL, e.g. "build"; files outside it are skipped.
+	Root string `json:"root,omitempty"`
+	// Paths are globs of files to include, relative to Root; empty includes every file with an extractor.
+	Paths []string `json:"paths,omitempty"`
+	// CleanURLs drops ".html" from URLs, as most static hosts serve pages without it.
+	CleanURLs bool `json:"clean_urls,omitempty"`
+}
+
 // FileSpec describes document/file ingestion parameters.
 type FileSpec struct {
 	URLs    []string `json:"urls,omitempty"`    // one or more document URLs
-	Format  string   `json:"format,omitempty"`  // pdf|txt|markdown|md|auto (default auto by extension/content-type)
-	Extract string   `json:"extract,omitempty"` // reader to use; e.g., pdfium, tika, md, plain
+	Format  string   `json:"format,omitempty"`  // html|pdf|docx|markdown|md|txt|auto (default auto by content, extension and content-type)
+	Extract string   `json:"extract,omitempty"` // extractor to use when it differs from format; same names as format, pdfium and tika read PDFs
 }
 
 // Dev-only seeding endpoint to insert a document, chunk and embedding
@@ -269,6 +408,7 @@ type OCRRequest struct {
 	ProjectID string `json:"project_id"`
 	SourceID  string `json:"source_id"`
 	ImageURL  string `json:"image_url"`
+	OCRLang   string `json:"ocr_lang,omitempty"` // Optional language hints, comma-separated (e.g. "en,de")
 }
 
 type TextRegion struct {
@@ -336,6 +476,8 @@ type MediaProcessRequest struct {
 	ProjectID string `json:"project_id"`
 	SourceID  string `json:"source_id"`
 	MediaURL  string `json:"media_url"`
+	UploadID  string `json:"upload_id,omitempty"`  // Alternative to media_url: a file from POST /v1/uploads
+	OCRLang   string `json:"ocr_lang,omitempty"`   // Optional OCR language hints for images, comma-separated
 	MediaType string `json:"media_type,omitempty"` // Optional: "image", "youtube", "video" - will auto-detect if empty
 }
 
@@ -349,6 +491,7 @@ type MediaProcessResponse struct {
 	Metadata         map[string]interface{} `json:"metadata,omitempty"`
 	ProcessedAt      string                 `json:"processed_at"`
 	ExtractionStatus string                 `json:"extraction_status"` // "success", "partial", "failed"
+	JobID            string                 `json:"job_id,omitempty"`  // Ingest that makes the text searchable
 }
 
 // ===== Browser Extension API Types =====
@@ -386,6 +529,7 @@ type ExtensionChatResponse struct {
 	Guidance    string         `json:"guidance"`               // Natural language explanation
 	Steps       []GuidanceStep `json:"steps"`                  // Actionable steps with selectors
 	Confidence  float32        `json:"confidence"`             // Overall confidence (0-1)
+	IsUncertain bool           `json:"is_uncertain"`           // Docs likely do not cover the question
 	Sources     []Citation     `json:"sources"`                // Supporting documentation
 	NextActions []string       `json:"next_actions,omitempty"` // Suggested follow-ups
 }
@@ -399,4 +543,10 @@ type Services struct {
 	Gaps      GapsService
 	Queue     interface{}   // queue.Producer
 	DB        *pgxpool.Pool // Database connection pool for media storage
+	Sources   storage.SourceRepo
+	Jobs      storage.JobRepo
+	Uploads   storage.UploadRepo
+	Blobs     objectstore.Store // upload contents
+	// UploadMaxBytes caps POST /v1/uploads; 0 uses DefaultUploadMaxBytes
+	UploadMaxBytes int64
 }
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 
 	"github.com/gofiber/fiber/v3"
@@ -15,6 +16,7 @@ import (
 	"cgap/internal/embedding"
 	"cgap/internal/llm"
 	"cgap/internal/meilisearch"
+	"cgap/internal/objectstore"
 	"cgap/internal/postgres"
 	"cgap/internal/queue"
 	"cgap/internal/search"
@@ -166,10 +168,21 @@ func main() {
 	case "meilisearch":
 		searchClient = meiliClient
 	case "hybrid":
-		searchClient = search.NewHybrid(search.NewPGVector(store, embedder), meiliClient)
+		searchClient = search.NewHybridWithFusion(search.NewPGVector(store, embedder), meiliClient, fusionConfigFromEnv())
 	default:
 		slog.Warn("Unknown SEARCH_PROVIDER, defaulting to hybrid", "provider", searchStrategy)
-		searchClient = search.NewHybrid(search.NewPGVector(store, embedder), meiliClient)
+		searchClient = search.NewHybridWithFusion(search.NewPGVector(store, embedder), meiliClient, fusionConfigFromEnv())
+	}
+
+	// Optional reranking stage: over-fetch candidates, rerank, keep top K
+	switch rerankProvider := os.Getenv("RERANK_PROVIDER"); rerankProvider {
+	case "", "none":
+	case "http":
+		searchClient = search.NewReranked(searchClient, search.NewHTTPReranker(os.Getenv("RERANK_URL"), os.Getenv("RERANK_API_KEY"), os.Getenv("RERANK_MODEL")), rerankCandidates())
+	case "llm":
+		searchClient = search.NewReranked(searchClient, search.NewLLMReranker(llmClient), rerankCandidates())
+	default:
+		slog.Warn("Unknown RERANK_PROVIDER, reranking disabled", "provider", rerankProvider)
 	}
 
 	// Initialize Redis client (URL or host:port)
@@ -181,15 +194,23 @@ func main() {
 	defer redisClient.Close()
 
 	// Wire up service implementations
-	chatService := service.NewChatService(store, llmClient, searchClient)
+	chatService := service.NewChatService(store, llmClient, searchClient).WithEmbedder(embedder)
 	searchService := service.NewSearchService(store, searchClient)
 	deflectService := service.NewDeflectService(store, searchClient, llmClient)
 	analyticsService := service.NewAnalyticsService(store)
 	gapsService := service.NewGapsService(store, llmClient)
 
-	// Create Fiber app
+	// Object store for uploaded files; uploads are disabled without one
+	blobs, err := objectstore.FromEnv()
+	if err != nil {
+		slog.Warn("Object store unavailable, uploads disabled", "error", err)
+	}
+	uploadMax := uploadMaxBytes()
+
+	// Create Fiber app; the body limit leaves room for multipart overhead
 	app := fiber.New(fiber.Config{
-		AppName: "cgap",
+		AppName:   "cgap",
+		BodyLimit: int(uploadMax) + 1<<20,
 	})
 
 	// Register handlers with injected services and health dependencies
@@ -201,6 +222,12 @@ func main() {
 		Gaps:      gapsService,
 		Queue:     queue.NewProducer(redisClient),
 		DB:        store.Pool(),
+		Jobs:      store.Jobs(),
+		Sources:   store.Sources(),
+		Uploads:   store.Uploads(),
+		Blobs:     blobs,
+
+		UploadMaxBytes: uploadMax,
 	}, &api.HealthDeps{
 		DB:    store.Pool(),
 		Redis: redisClient,
@@ -231,6 +258,55 @@ func main() {
 	slog.Info("API server stopped")
 }
 
+// fusionConfigFromEnv reads hybrid search fusion defaults:
+// SEARCH_FUSION (rrf|weighted), SEARCH_RRF_K, SEARCH_SEMANTIC_WEIGHT, SEARCH_LEXICAL_WEIGHT.
+func fusionConfigFromEnv() search.FusionConfig {
+	cfg := search.DefaultFusionConfig()
+	if v := os.Getenv("SEARCH_FUSION"); v != "" {
+		cfg.Strategy = search.FusionStrategy(v)
+	}
+	envFloat := func(key string, dst *float64) {
+		if v := os.Getenv(key); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				slog.Warn("Ignoring invalid search fusion setting", "key", key, "value", v)
+				return
+			}
+			*dst = f
+		}
+	}
+	envFloat("SEARCH_RRF_K", &cfg.RRFK)
+	envFloat("SEARCH_SEMANTIC_WEIGHT", &cfg.PrimaryWeight)
+	envFloat("SEARCH_LEXICAL_WEIGHT", &cfg.SecondaryWeight)
+	if err := cfg.Validate(); err != nil {
+		slog.Warn("Invalid search fusion settings, using defaults", "error", err)
+		return search.DefaultFusionConfig()
+	}
+	return cfg
+}
+
+// rerankCandidates reads RERANK_CANDIDATES, the number of results fetched before reranking.
+func rerankCandidates() int {
+	if v := os.Getenv("RERANK_CANDIDATES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+		slog.Warn("Ignoring invalid RERANK_CANDIDATES", "value", v)
+	}
+	return search.DefaultRerankCandidates
+}
+
+// uploadMaxBytes reads UPLOAD_MAX_BYTES, the largest file accepted by POST /v1/uploads.
+func uploadMaxBytes() int64 {
+	if v := os.Getenv("UPLOAD_MAX_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			return n
+		}
+		slog.Warn("Ignoring invalid UPLOAD_MAX_BYTES", "value", v)
+	}
+	return api.DefaultUploadMaxBytes
+}
+
 // printCGAPBanner prints the cgap startup banner with colors.
 func printCGAPBanner(port string) {
 	const (
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"cgap/api"
+	"cgap/internal/embedding"
+	"cgap/internal/ingestion"
+	"cgap/internal/model"
+	"cgap/internal/postgres"
+)
+
+// maxArchiveSize bounds the archive download; what it unpacks to is bounded
+// by ingestion.ArchiveLimits.
+const maxArchiveSize = 1 << 30
+
+// archiveWorkers bounds the files embedded concurrently.
+const archiveWorkers = 4
+
+// archiveContentTypes are the upload content types ingested as archives.
+var archiveContentTypes = map[string]bool{
+	"application/zip":    true,
+	"application/x-gzip": true,
+	"application/gzip":   true,
+	"application/x-tar":  true,
+}
+
+// archiveDoc is the extracted content of one archive file.
+type archiveDoc struct {
+	title, text string
+	err         error
+}
+
+// syncArchive ingests each file of a zip or tar archive, read from
+// source.url or an upload, as its own document. With archive.base_url, files
+// are mapped to the URLs they are published at; otherwise URIs are
+// "<archive URI>#<path>". The archive is a complete export, so with full_sync
+// documents of files it no longer contains are removed.
+func syncArchive(ctx context.Context, store *postgres.Store, client *http.Client, emb embedding.Embedder, chunker *ingestion.MarkdownChunker, idx *chunkIndexer, jobs *jobTracker, jobID string, project projectRef, p api.IngestTaskPayload) error {
+	spec := api.ArchiveSpec{}
+	if p.Source.Archive != nil {
+		spec = *p.Source.Archive
+	}
+	f, archiveURI, err := openArchive(ctx, store, client, project, p.Source)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	log := slog.With("job_id", jobID, "archive", archiveURI)
+
+	root := strings.Trim(path.Clean("/"+spec.Root), "/")
+	relative := func(name string) (string, bool) {
+		if root == "" {
+			return name, true
+		}
+		return strings.CutPrefix(name, root+"/")
+	}
+	want := func(name string) bool {
+		rel, ok := relative(name)
+		if !ok {
+			return false
+		}
+		if _, ok := extractors.ForPath(rel); !ok {
+			return false
+		}
+		if len(spec.Paths) == 0 {
+			return true
+		}
+		for _, g := range spec.Paths {
+			if ingestion.MatchGlob(g, rel) {
+				return true
+			}
+		}
+		return false
+	}
+	uriOf := func(rel string) string {
+		if spec.BaseURL != "" {
+			return ingestion.ArchiveURL(spec.BaseURL, rel, spec.CleanURLs)
+		}
+		return archiveURI + "#" + rel
+	}
+
+	// Files are extracted while unpacking; only their text is kept for embedding.
+	docs := make(map[string]archiveDoc)
+	var uris []string
+	err = ingestion.ReadArchive(f, info.Size(), ingestion.ArchiveLimits{}, want, func(name string, data []byte) error {
+		rel, _ := relative(name)
+		uri := uriOf(rel)
+		if _, dup := docs[uri]; dup {
+			log.Warn("ingest: archive files map to the same URL, keeping the first", "path", name, "url", uri)
+			return nil
+		}
+		format := fileFormat(p.Source)
+		if format == "" {
+			format, _ = extractors.ForPath(rel)
+		}
+		d := archiveDoc{}
+		doc, err := extractors.Extract(ctx, format, rel, "", data)
+		if err != nil {
+			d.err = err
+		} else {
+			d.title, d.text = doc.Title, doc.Text
+			if d.title == "" {
+				d.title = strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+			}
+		}
+		docs[uri] = d
+		uris = append(uris, uri)
+		return ctx.Err()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unpack archive: %w", err)
+	}
+	if len(uris) == 0 {
+		return errors.New("archive contains no files to ingest")
+	}
+	log.Info("ingest: unpacked archive", "files", len(uris))
+	jobs.running(ctx, jobID, project.ID, len(uris))
+
+	pool := store.Pool()
+	_, err = ingestURLs(ctx, jobs, jobID, uris, archiveWorkers, 0, p.FailFast, func(ctx context.Context, uri string) (bool, error) {
+		d := docs[uri]
+		if d.err != nil {
+			return false, d.err
+		}
+		return syncDocument(ctx, pool, emb, chunker, idx, project, p.SourceID, p.Source.Type, sourceDocument{
+			URI:   uri,
+			Title: d.title,
+			Text:  d.text,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if p.FullSync {
+		prefix := archiveURI + "#"
+		if spec.BaseURL != "" {
+			prefix = ingestion.ArchiveURL(spec.BaseURL, "", false)
+		}
+		deleted, err := deleteStaleDocuments(ctx, pool, idx, project.ID, p.SourceID, uris, func(uri string) bool {
+			return strings.HasPrefix(uri, prefix)
+		})
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			log.Info("ingest: deleted documents of files removed from the archive", "count", deleted)
+			jobs.add(ctx, jobID, model.JobCounters{Deleted: deleted})
+		}
+	}
+	return nil
+}
+
+// openArchive copies the archive of src, an upload or source.url, to a
+// temporary file and returns it with the archive's URI.
+func openArchive(ctx context.Context, store *postgres.Store, client *http.Client, project projectRef, src api.SourceSpec) (*os.File, string, error) {
+	if src.UploadID != "" {
+		if blobs == nil {
+			return nil, "", errors.New("object store not configured")
+		}
+		upload, err := store.Uploads().GetByID(ctx, src.UploadID)
+		if err != nil {
+			return nil, "", err
+		}
+		if upload.ProjectID != project.ID {
+			return nil, "", fmt.Errorf("upload %s belongs to another project", upload.ID)
+		}
+		rc, err := blobs.Get(ctx, upload.StorageKey)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read upload: %w", err)
+		}
+		defer rc.Close()
+		f, err := spoolArchive(rc)
+		return f, upload.URI(), err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.URL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if src.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+src.Token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch archive: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to fetch archive: status %d", resp.StatusCode)
+	}
+	f, err := spoolArchive(resp.Body)
+	return f, src.URL, err
+}
+
+// spoolArchive copies up to maxArchiveSize bytes of r to a temporary file,
+// since zip archives are read from the end.
+func spoolArchive(r io.Reader) (*os.File, error) {
+	f, err := os.CreateTemp("", "archive-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	n, err := io.Copy(f, io.LimitReader(r, maxArchiveSize+1))
+	if err == nil && n > maxArchiveSize {
+		err = fmt.Errorf("archive larger than %d bytes", maxArchiveSize)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pgvector/pgvector-go"
+
+	"cgap/internal/embedding"
+	"cgap/internal/ingestion"
+	"cgap/internal/meilisearch"
+	"cgap/worker"
+)
+
+// projectRef identifies the project documents are ingested into.
+type projectRef struct {
+	ID   string
+	Slug string
+}
+
+// sourceDocument is the current content of one document of a source.
+type sourceDocument struct {
+	URI     string
+	Title   string
+	Version string      // e.g. the commit SHA a repository file was read at
+	Text    string      // Markdown, or source code when Lang is set
+	Lang    string      // language of source code, which is chunked along declarations
+	Fetched *fetchState // validators of the fetch, stored for conditional requests
+	// Segments of a video transcript, chunked along segment boundaries
+	// instead of Text; chunks keep their position in the video.
+	Segments []ingestion.TranscriptSegment
+}
+
+// syncDocument stores doc as the current content of (project, doc.URI). It
+// returns false without touching the database when the content hash is
+// unchanged. Otherwise the document's chunks are updated in one transaction:
+// chunks whose text already existed keep their row, ID and embedding and only
+// move to their new position, so citations of them survive; only added text is
+// embedded and inserted, and chunks whose text is gone are deleted. The chunks
+// are then mirrored into the lexical index.
+func syncDocument(ctx context.Context, pool *pgxpool.Pool, emb embedding.Embedder, chunker *ingestion.MarkdownChunker, idx *chunkIndexer, project projectRef, sourceID, sourceType string, doc sourceDocument) (bool, error) {
+	projectID := project.ID
+	uri, text, fetched := doc.URI, doc.Text, doc.Fetched
+	hash := chunker.Hash(text)
+	if doc.Lang != "" {
+		// Code is chunked differently from the same text as Markdown.
+		hash = chunker.Hash("```" + doc.Lang + "\n" + text)
+	}
+	if len(doc.Segments) > 0 {
+		// Timings are part of a transcript's content.
+		var b strings.Builder
+		for _, seg := range doc.Segments {
+			fmt.Fprintf(&b, "%d-%d %s\n", seg.StartSeconds, seg.EndSeconds, seg.Text)
+		}
+		hash = chunker.Hash("transcript\n" + b.String())
+	}
+
+	var docID, oldHash string
+	err := pool.QueryRow(ctx, `
+		SELECT id, COALESCE(hash, '') FROM documents WHERE project_id = $1 AND uri = $2
+	`, projectID, uri).Scan(&docID, &oldHash)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		return false, fmt.Errorf("failed to look up document: %w", err)
+	case oldHash == hash:
+		// Same content under new validators; keep them so the next fetch can be
+		// conditional. The title is refreshed too, as it is not part of the hash.
+		if fetched != nil {
+			if _, err := pool.Exec(ctx, `
+				UPDATE documents SET etag = NULLIF($2, ''), last_modified = NULLIF($3, ''),
+					sitemap_lastmod = COALESCE($4, sitemap_lastmod), links = $5, fetched_at = now(),
+					title = COALESCE(NULLIF($6, ''), title), chunk_settings = $7
+				WHERE id = $1
+			`, docID, fetched.ETag, fetched.LastModified, fetched.SitemapLastMod, fetched.Links, doc.Title, chunker.Settings()); err != nil {
+				return false, fmt.Errorf("failed to update document fetch state: %w", err)
+			}
+		}
+		return false, nil
+	}
+
+	var stored []storedChunk
+	if docID != "" {
+		if stored, err = loadStoredChunks(ctx, pool, docID); err != nil {
+			return false, err
+		}
+	}
+
+	// Embed before opening the transaction so it is not held across provider calls.
+	var chunks []ingestion.Chunk
+	switch {
+	case len(doc.Segments) > 0:
+		chunks = chunker.SplitTranscript(doc.Segments)
+	case doc.Lang != "":
+		chunks = chunker.SplitCode(doc.Lang, text)
+	default:
+		chunks = chunker.Split(text)
+	}
+	diff := diffChunks(stored, chunks)
+	existing := make(map[string]pgvector.Vector, len(stored))
+	for _, sc := range stored {
+		if sc.Embedding != nil {
+			existing[sc.Text] = *sc.Embedding
+		}
+	}
+	// vecs[i] is nil when a kept chunk already has its embedding.
+	vecs := make([]*pgvector.Vector, len(chunks))
+	for i, c := range chunks {
+		if diff.kept[i] != nil && diff.kept[i].Embedding != nil {
+			continue
+		}
+		if v, ok := existing[c.Text]; ok {
+			vecs[i] = &v
+			continue
+		}
+		v, err := emb.Embed(ctx, c.Text)
+		if err != nil {
+			return false, fmt.Errorf("failed to embed chunk %d: %w", c.Ord, err)
+		}
+		vec := pgvector.NewVector(v)
+		vecs[i] = &vec
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var fetchedAt *time.Time
+	if fetched != nil {
+		now := time.Now()
+		fetchedAt = &now
+	} else {
+		fetched = &fetchState{}
+	}
+	// The stored title, which is kept when doc has none, is indexed with each chunk.
+	var title string
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO documents (project_id, source_id, uri, title, hash, etag, last_modified, sitemap_lastmod, links, fetched_at, version, chunk_settings)
+		VALUES ($1, NULLIF($5, '')::uuid, $2, COALESCE(NULLIF($3, ''), 'Untitled'), $4, NULLIF($6, ''), NULLIF($7, ''), $8, $9, $10, NULLIF($11, ''), $12)
+		ON CONFLICT (project_id, uri) DO UPDATE SET
+			source_id = COALESCE(EXCLUDED.source_id, documents.source_id),
+			title = CASE WHEN $3 = '' THEN documents.title ELSE EXCLUDED.title END,
+			version = COALESCE(EXCLUDED.version, documents.version),
+			hash = EXCLUDED.hash,
+			etag = EXCLUDED.etag,
+			last_modified = EXCLUDED.last_modified,
+			sitemap_lastmod = EXCLUDED.sitemap_lastmod,
+			links = EXCLUDED.links,
+			fetched_at = EXCLUDED.fetched_at,
+			chunk_settings = EXCLUDED.chunk_settings
+		RETURNING id, COALESCE(title, '')
+	`, projectID, uri, doc.Title, hash, sourceID, fetched.ETag, fetched.LastModified, fetched.SitemapLastMod, fetched.Links, fetchedAt, doc.Version, chunker.Settings()).Scan(&docID, &title); err != nil {
+		return false, fmt.Errorf("failed to upsert document: %w", err)
+	}
+	if len(diff.removed) > 0 {
+		if _, err := tx.Exec(ctx, `DELETE FROM chunks WHERE id = ANY($1)`, diff.removed); err != nil {
+			return false, fmt.Errorf("failed to delete old chunks: %w", err)
+		}
+	}
+	records := make([]worker.MeiliRecord, len(chunks))
+	for i, c := range chunks {
+		var chunkID string
+		if kept := diff.kept[i]; kept != nil {
+			chunkID = kept.ID
+			if _, err := tx.Exec(ctx, `
+				UPDATE chunks SET ord = $2, section_path = NULLIF($3, ''), start_seconds = $4, end_seconds = $5
+				WHERE id = $1
+			`, chunkID, c.Ord, c.SectionPath, c.StartSeconds, c.EndSeconds); err != nil {
+				return false, fmt.Errorf("failed to update chunk: %w", err)
+			}
+		} else if err := tx.QueryRow(ctx, `
+			INSERT INTO chunks (document_id, ord, text, token_count, section_path, start_seconds, end_seconds)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7)
+			RETURNING id
+		`, docID, c.Ord, c.Text, c.TokenCount, c.SectionPath, c.StartSeconds, c.EndSeconds).Scan(&chunkID); err != nil {
+			return false, fmt.Errorf("failed to insert chunk: %w", err)
+		}
+		if vecs[i] != nil {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO chunk_embeddings (chunk_id, embedding) VALUES ($1, $2)
+				ON CONFLICT (chunk_id) DO UPDATE SET embedding = EXCLUDED.embedding
+			`, chunkID, *vecs[i]); err != nil {
+				return false, fmt.Errorf("failed to insert chunk embedding: %w", err)
+			}
+		}
+		records[i] = worker.MeiliRecord{
+			ID:           chunkID,
+			ProjectID:    project.ID,
+			ProjectSlug:  project.Slug,
+			DocumentID:   docID,
+			DocumentURI:  uri,
+			Title:        title,
+			SourceType:   sourceType,
+			Text:         c.Text,
+			SectionPath:  c.SectionPath,
+			Ord:          c.Ord,
+			StartSeconds: c.StartSeconds,
+			EndSeconds:   c.EndSeconds,
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit document: %w", err)
+	}
+
+	if err := idx.replaceDocument(ctx, docID, records); err != nil {
+		// Forget the hash so the next sync retries indexing instead of skipping the document.
+		if _, herr := pool.Exec(ctx, `UPDATE documents SET hash = NULL WHERE id = $1`, docID); herr != nil {
+			return false, fmt.Errorf("%w (and failed to reset document hash: %v)", err, herr)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// chunkIndexer mirrors stored chunks into the Meilisearch chunks index. A nil
+// indexer (Meilisearch not configured) does nothing.
+type chunkIndexer struct {
+	client *meilisearch.Client
+}
+
+// replaceDocument swaps a document's indexed chunks for records. Meilisearch
+// applies tasks on an index in order, so only the final task is awaited.
+func (ix *chunkIndexer) replaceDocument(ctx context.Context, docID string, records []worker.MeiliRecord) error {
+	if ix == nil {
+		return nil
+	}
+	task, err := ix.client.DeleteDocumentsByFilter(ctx, meilisearch.ChunksIndex, "document_id = "+meilisearch.FilterValue(docID))
+	if err != nil {
+		return fmt.Errorf("failed to unindex document %s: %w", docID, err)
+	}
+	if len(records) > 0 {
+		if task, err = ix.client.AddDocuments(ctx, meilisearch.ChunksIndex, records); err != nil {
+			return fmt.Errorf("failed to index document %s: %w", docID, err)
+		}
+	}
+	if err := ix.client.WaitForTask(ctx, task); err != nil {
+		return fmt.Errorf("failed to index document %s: %w", docID, err)
+	}
+	return nil
+}
+
+// deleteDocuments removes all indexed chunks of the given documents.
+func (ix *chunkIndexer) deleteDocuments(ctx context.Context, docIDs []string) error {
+	if ix == nil || len(docIDs) == 0 {
+		return nil
+	}
+	values := make([]string, len(docIDs))
+	for i, id := range docIDs {
+		values[i] = meilisearch.FilterValue(id)
+	}
+	task, err := ix.client.DeleteDocumentsByFilter(ctx, meilisearch.ChunksIndex, "document_id IN ["+strings.Join(values, ", ")+"]")
+	if err != nil {
+		return fmt.Errorf("failed to unindex documents: %w", err)
+	}
+	if err := ix.client.WaitForTask(ctx, task); err != nil {
+		return fmt.Errorf("failed to unindex documents: %w", err)
+	}
+	return nil
+}
+
+// storedChunk is a chunk of a document as currently stored. Embedding is nil
+// for chunks that were stored without one.
+type storedChunk struct {
+	ID        string
+	Text      string
+	Embedding *pgvector.Vector
+}
+
+// loadStoredChunks returns a document's chunks in order.
+func loadStoredChunks(ctx context.Context, pool *pgxpool.Pool, docID string) ([]storedChunk, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT c.id, c.text, e.embedding
+		FROM chunks c LEFT JOIN chunk_embeddings e ON e.chunk_id = c.id
+		WHERE c.document_id = $1
+		ORDER BY c.ord
+	`, docID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var out []storedChunk
+	for rows.Next() {
+		var sc storedChunk
+		if err := rows.Scan(&sc.ID, &sc.Text, &sc.Embedding); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk: %w", err)
+		}
+		out = append(out, sc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return out, nil
+}
+
+// chunkDiff matches the new chunks of a document against its stored chunks.
+type chunkDiff struct {
+	kept    []*storedChunk // per new chunk, the stored chunk with the same text, or nil
+	removed []string       // IDs of stored chunks without a new counterpart
+}
+
+// diffChunks pairs each new chunk with a stored chunk of identical text.
+// Repeated texts are paired in order, so each stored chunk is kept at most once.
+func diffChunks(stored []storedChunk, chunks []ingestion.Chunk) chunkDiff {
+	byText := make(map[string][]int, len(stored))
+	for i, sc := range stored {
+		byText[sc.Text] = append(byText[sc.Text], i)
+	}
+	used := make([]bool, len(stored))
+	diff := chunkDiff{kept: make([]*storedChunk, len(chunks))}
+	for i, c := range chunks {
+		if idx := byText[c.Text]; len(idx) > 0 {
+			diff.kept[i] = &stored[idx[0]]
+			used[idx[0]] = true
+			byText[c.Text] = idx[1:]
+		}
+	}
+	for i, sc := range stored {
+		if !used[i] {
+			diff.removed = append(diff.removed, sc.ID)
+		}
+	}
+	return diff
+}
+
+// deleteStaleDocuments removes the project's documents that are in scope of a
+// fully synced source but were not listed by it. With a sourceID only that
+// source's documents are considered, so sources sharing URIs do not delete each
+// other's documents. Chunks and embeddings cascade; indexed chunks are removed
+// from Meilisearch.
+func deleteStaleDocuments(ctx context.Context, pool *pgxpool.Pool, idx *chunkIndexer, projectID, sourceID string, listed []string, inScope func(uri string) bool) (int, error) {
+	seen := make(map[string]bool, len(listed))
+	for _, u := range listed {
+		seen[u] = true
+	}
+
+	rows, err := pool.Query(ctx, `
+		SELECT id, uri FROM documents
+		WHERE project_id = $1 AND ($2 = '' OR source_id = NULLIF($2, '')::uuid)
+	`, projectID, sourceID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list documents: %w", err)
+	}
+	var stale []string
+	for rows.Next() {
+		var id, uri string
+		if err := rows.Scan(&id, &uri); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan document: %w", err)
+		}
+		if !seen[uri] && inScope(uri) {
+			stale = append(stale, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("row iteration error: %w", err)
+	}
+	if len(stale) == 0 {
+		return 0, nil
+	}
+
+	tag, err := pool.Exec(ctx, `DELETE FROM documents WHERE id = ANY($1)`, stale)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete stale documents: %w", err)
+	}
+	if err := idx.deleteDocuments(ctx, stale); err != nil {
+		return int(tag.RowsAffected()), err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// deleteDocumentsByURI removes the project's documents with the given URIs and
+// their indexed chunks.
+func deleteDocumentsByURI(ctx context.Context, pool *pgxpool.Pool, idx *chunkIndexer, projectID string, uris []string) (int, error) {
+	rows, err := pool.Query(ctx, `
+		DELETE FROM documents WHERE project_id = $1 AND uri = ANY($2) RETURNING id
+	`, projectID, uris)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete documents: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete documents: %w", err)
+	}
+	return len(ids), idx.deleteDocuments(ctx, ids)
+}
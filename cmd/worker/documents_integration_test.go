@@ -0,0 +1,214 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"cgap/internal/ingestion"
+	"cgap/internal/meilisearch"
+	"cgap/internal/postgres"
+	"cgap/worker"
+)
+
+// fixedEmbedder returns the same 768-dimensional vector for every text.
+type fixedEmbedder struct{ calls int }
+
+func (e *fixedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	e.calls++
+	v := make([]float32, 768)
+	v[0] = 1
+	return v, nil
+}
+
+// fakeMeili accepts Meilisearch writes as succeeded tasks and keeps the
+// records of the last document addition.
+type fakeMeili struct {
+	mu      sync.Mutex
+	records []worker.MeiliRecord
+}
+
+func (f *fakeMeili) indexer(t *testing.T) *chunkIndexer {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/documents") {
+			var records []worker.MeiliRecord
+			if err := json.NewDecoder(r.Body).Decode(&records); err != nil {
+				t.Errorf("decode indexed records: %v", err)
+			}
+			f.mu.Lock()
+			f.records = records
+			f.mu.Unlock()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"taskUid":1,"status":"succeeded"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return &chunkIndexer{client: meilisearch.New(srv.URL, "")}
+}
+
+// titles returns the titles of the last indexed records.
+func (f *fakeMeili) titles() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]string, len(f.records))
+	for i, r := range f.records {
+		out[i] = r.Title
+	}
+	return out
+}
+
+// Integration test requires a migrated Postgres with pgvector. Run with:
+// DATABASE_URL=... go test -tags=integration ./cmd/worker -run TestSyncDocumentKeepsCitations
+func TestSyncDocumentKeepsCitations(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL must be set for integration test")
+	}
+	ctx := context.Background()
+
+	store, err := postgres.New(dbURL)
+	if err != nil {
+		t.Fatalf("failed to init postgres: %v", err)
+	}
+	defer store.Close()
+	pool := store.Pool()
+
+	project := projectRef{ID: uuid.New().String(), Slug: "itest-" + uuid.New().String()}
+	if _, err := pool.Exec(ctx, `INSERT INTO projects (id, name, slug) VALUES ($1, 'Integration Test', $2)`, project.ID, project.Slug); err != nil {
+		t.Fatalf("insert project: %v", err)
+	}
+	defer pool.Exec(ctx, `DELETE FROM projects WHERE id = $1`, project.ID)
+
+	chunker := ingestion.NewMarkdownChunker("heading", ingestion.MinChunkTokens)
+	emb := &fixedEmbedder{}
+	meili := &fakeMeili{}
+	idx := meili.indexer(t)
+	doc := sourceDocument{URI: "test://guide", Title: "Guide", Text: "# Install\n\nRun the installer.\n\n# Configure\n\nEdit config.yaml."}
+	if _, err := syncDocument(ctx, pool, emb, chunker, idx, project, "", "url", doc); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	if titles := meili.titles(); len(titles) != 2 || titles[0] != "Guide" || titles[1] != "Guide" {
+		t.Errorf("Expected chunks indexed with the document title, got %q", titles)
+	}
+
+	var chunkID string
+	if err := pool.QueryRow(ctx, `
+		SELECT c.id FROM chunks c JOIN documents d ON d.id = c.document_id
+		WHERE d.project_id = $1 AND c.text LIKE '%installer%'
+	`, project.ID).Scan(&chunkID); err != nil {
+		t.Fatalf("find install chunk: %v", err)
+	}
+
+	// Cite the install chunk from an answer.
+	threadID, messageID := uuid.New().String(), uuid.New().String()
+	for _, q := range []struct {
+		sql  string
+		args []any
+	}{
+		{`INSERT INTO threads (id, project_id, integration) VALUES ($1, $2, 'api')`, []any{threadID, project.ID}},
+		{`INSERT INTO messages (id, thread_id, role, content) VALUES ($1, $2, 'assistant', 'Run the installer [1].')`, []any{messageID, threadID}},
+		{`INSERT INTO answers (message_id, model) VALUES ($1, 'test')`, []any{messageID}},
+		{`INSERT INTO citations (answer_id, chunk_id, quote) VALUES ($1, $2, 'Run the installer.')`, []any{messageID, chunkID}},
+	} {
+		if _, err := pool.Exec(ctx, q.sql, q.args...); err != nil {
+			t.Fatalf("insert citation fixture: %v", err)
+		}
+	}
+
+	// Without a title of its own the document keeps the stored one.
+	doc.Title = ""
+	doc.Text = "# Install\n\nRun the installer.\n\n# Configure\n\nEdit settings.yaml instead."
+	emb.calls = 0
+	changed, err := syncDocument(ctx, pool, emb, chunker, idx, project, "", "url", doc)
+	if err != nil || !changed {
+		t.Fatalf("second sync: changed=%v err=%v", changed, err)
+	}
+	if emb.calls != 1 {
+		t.Errorf("Expected only the changed chunk to be embedded, got %d calls", emb.calls)
+	}
+	if titles := meili.titles(); len(titles) != 2 || titles[0] != "Guide" || titles[1] != "Guide" {
+		t.Errorf("Expected re-indexed chunks to keep the stored title, got %q", titles)
+	}
+
+	var citations int
+	if err := pool.QueryRow(ctx, `SELECT count(*) FROM citations WHERE answer_id = $1 AND chunk_id = $2`, messageID, chunkID).Scan(&citations); err != nil {
+		t.Fatalf("count citations: %v", err)
+	}
+	if citations != 1 {
+		t.Errorf("Expected the citation of the unchanged chunk to survive, got %d", citations)
+	}
+	var stale int
+	if err := pool.QueryRow(ctx, `
+		SELECT count(*) FROM chunks c JOIN documents d ON d.id = c.document_id
+		WHERE d.project_id = $1 AND c.text LIKE '%config.yaml%'
+	`, project.ID).Scan(&stale); err != nil {
+		t.Fatalf("count chunks: %v", err)
+	}
+	if stale != 0 {
+		t.Errorf("Expected the replaced chunk to be deleted, got %d", stale)
+	}
+}
+
+// Integration test requires a migrated Postgres with pgvector. Run with:
+// DATABASE_URL=... go test -tags=integration ./cmd/worker -run TestDeleteStaleDocumentsKeepsOtherSources
+func TestDeleteStaleDocumentsKeepsOtherSources(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL must be set for integration test")
+	}
+	ctx := context.Background()
+
+	store, err := postgres.New(dbURL)
+	if err != nil {
+		t.Fatalf("failed to init postgres: %v", err)
+	}
+	defer store.Close()
+	pool := store.Pool()
+
+	project := projectRef{ID: uuid.New().String(), Slug: "itest-" + uuid.New().String()}
+	if _, err := pool.Exec(ctx, `INSERT INTO projects (id, name, slug) VALUES ($1, 'Integration Test', $2)`, project.ID, project.Slug); err != nil {
+		t.Fatalf("insert project: %v", err)
+	}
+	defer pool.Exec(ctx, `DELETE FROM projects WHERE id = $1`, project.ID)
+
+	// Two crawls of the same site, e.g. the guide and the API reference.
+	sources := []string{uuid.New().String(), uuid.New().String()}
+	for _, id := range sources {
+		if _, err := pool.Exec(ctx, `INSERT INTO sources (id, project_id, type, config) VALUES ($1, $2, 'crawl', '{}')`, id, project.ID); err != nil {
+			t.Fatalf("insert source: %v", err)
+		}
+	}
+	chunker := ingestion.NewMarkdownChunker("", 0)
+	emb := &fixedEmbedder{}
+	docs := map[string]string{"https://docs.example.com/guide": sources[0], "https://docs.example.com/api": sources[1]}
+	for uri, sourceID := range docs {
+		if _, err := syncDocument(ctx, pool, emb, chunker, nil, project, sourceID, "crawl", sourceDocument{URI: uri, Title: "Page", Text: "# Page\n\nSome text."}); err != nil {
+			t.Fatalf("sync %s: %v", uri, err)
+		}
+	}
+
+	// The guide crawl lists only its own page; the whole site is in its scope.
+	deleted, err := deleteStaleDocuments(ctx, pool, nil, project.ID, sources[0], []string{"https://docs.example.com/guide"}, func(string) bool { return true })
+	if err != nil {
+		t.Fatalf("deleteStaleDocuments failed: %v", err)
+	}
+	if deleted != 0 {
+		t.Errorf("Expected the other source's document to be kept, got %d deleted", deleted)
+	}
+	var count int
+	if err := pool.QueryRow(ctx, `SELECT count(*) FROM documents WHERE project_id = $1`, project.ID).Scan(&count); err != nil {
+		t.Fatalf("count documents: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 documents, got %d", count)
+	}
+}
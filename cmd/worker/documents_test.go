@@ -0,0 +1,47 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"cgap/internal/ingestion"
+)
+
+func TestDiffChunks(t *testing.T) {
+	stored := []storedChunk{
+		{ID: "c1", Text: "intro"},
+		{ID: "c2", Text: "install"},
+		{ID: "c3", Text: "see also"},
+		{ID: "c4", Text: "see also"},
+	}
+	chunks := []ingestion.Chunk{
+		{Ord: 0, Text: "intro"},
+		{Ord: 1, Text: "install, updated"},
+		{Ord: 2, Text: "see also"},
+		{Ord: 3, Text: "configure"},
+	}
+
+	diff := diffChunks(stored, chunks)
+
+	var kept []string
+	for _, k := range diff.kept {
+		id := ""
+		if k != nil {
+			id = k.ID
+		}
+		kept = append(kept, id)
+	}
+	if want := []string{"c1", "", "c3", ""}; !slices.Equal(kept, want) {
+		t.Errorf("Expected kept chunks %q, got %q", want, kept)
+	}
+	if want := []string{"c2", "c4"}; !slices.Equal(diff.removed, want) {
+		t.Errorf("Expected removed chunks %q, got %q", want, diff.removed)
+	}
+}
+
+func TestDiffChunks_NewDocument(t *testing.T) {
+	diff := diffChunks(nil, []ingestion.Chunk{{Text: "a"}, {Text: "b"}})
+	if len(diff.kept) != 2 || diff.kept[0] != nil || diff.kept[1] != nil || len(diff.removed) != 0 {
+		t.Errorf("Expected only inserts for a new document, got %+v", diff)
+	}
+}
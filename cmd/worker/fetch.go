@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxStoredLinks caps the links kept per document for not-modified crawls.
+const maxStoredLinks = 1000
+
+// fetchState is what the last fetch of a page recorded on its document. It is
+// loaded before a crawl and written back with the document.
+type fetchState struct {
+	ETag           string
+	LastModified   string
+	SitemapLastMod *time.Time
+	Links          []string
+	// Synced is set when the document has a content hash and was chunked with
+	// the current chunker settings, i.e. its chunks match the fetched content.
+	// Only then may a fetch be skipped.
+	Synced bool
+}
+
+// fetchStates maps a project's document URIs to their fetch state.
+type fetchStates map[string]fetchState
+
+// loadFetchStates reads the fetch state of every document of a project for a
+// sync chunking with settings (see ingestion.MarkdownChunker.Settings).
+func loadFetchStates(ctx context.Context, pool *pgxpool.Pool, projectID, settings string) (fetchStates, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT uri, COALESCE(etag, ''), COALESCE(last_modified, ''), sitemap_lastmod, COALESCE(links, '{}'),
+			hash IS NOT NULL AND chunk_settings IS NOT DISTINCT FROM $2
+		FROM documents WHERE project_id = $1
+	`, projectID, settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load document fetch state: %w", err)
+	}
+	defer rows.Close()
+
+	states := fetchStates{}
+	for rows.Next() {
+		var uri string
+		var st fetchState
+		if err := rows.Scan(&uri, &st.ETag, &st.LastModified, &st.SitemapLastMod, &st.Links, &st.Synced); err != nil {
+			return nil, fmt.Errorf("failed to scan document fetch state: %w", err)
+		}
+		states[uri] = st
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return states, nil
+}
+
+// unchangedSince reports whether the sitemap says a synced page has not been
+// modified since it was last fetched, so it need not be requested at all.
+func (st fetchState) unchangedSince(lastMod *time.Time) bool {
+	return st.Synced && lastMod != nil && st.SitemapLastMod != nil && !lastMod.After(*st.SitemapLastMod)
+}
+
+// conditionalGet requests u, revalidating against the stored validators of a
+// synced page. The caller must handle http.StatusNotModified.
+func conditionalGet(ctx context.Context, client *http.Client, u string, st fetchState) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if st.Synced {
+		if st.ETag != "" {
+			req.Header.Set("If-None-Match", st.ETag)
+		}
+		if st.LastModified != "" {
+			req.Header.Set("If-Modified-Since", st.LastModified)
+		}
+	}
+	return client.Do(req)
+}
+
+// fetchStateFrom builds the state to store for a page fetched with resp.
+func fetchStateFrom(resp *http.Response, lastMod *time.Time, links []string) *fetchState {
+	if len(links) > maxStoredLinks {
+		links = links[:maxStoredLinks]
+	}
+	return &fetchState{
+		ETag:           resp.Header.Get("ETag"),
+		LastModified:   resp.Header.Get("Last-Modified"),
+		SitemapLastMod: lastMod,
+		Links:          links,
+	}
+}
+
+// markNotModified records a fetch that found the page unchanged, keeping the
+// newest sitemap lastmod so the next crawl can skip the request.
+func markNotModified(ctx context.Context, pool *pgxpool.Pool, projectID, uri string, lastMod *time.Time) error {
+	if _, err := pool.Exec(ctx, `
+		UPDATE documents SET sitemap_lastmod = COALESCE($3, sitemap_lastmod), fetched_at = now()
+		WHERE project_id = $1 AND uri = $2
+	`, projectID, uri, lastMod); err != nil {
+		return fmt.Errorf("failed to record not modified fetch: %w", err)
+	}
+	return nil
+}
+
+// sitemapTimeLayouts are the W3C datetime forms allowed in <lastmod>.
+var sitemapTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+}
+
+// parseLastMod parses a sitemap <lastmod>, returning nil when absent or invalid.
+func parseLastMod(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	for _, layout := range sitemapTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"cgap/api"
+	"cgap/internal/ingestion"
+	"cgap/internal/postgres"
+)
+
+// Integration test requires a migrated Postgres with pgvector. Run with:
+// DATABASE_URL=... go test -tags=integration ./cmd/worker -run TestProcessURL_NotModified
+func TestProcessURL_NotModified(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL must be set for integration test")
+	}
+	ctx := context.Background()
+
+	store, err := postgres.New(dbURL)
+	if err != nil {
+		t.Fatalf("failed to init postgres: %v", err)
+	}
+	defer store.Close()
+	pool := store.Pool()
+
+	project := projectRef{ID: uuid.New().String(), Slug: "itest-" + uuid.New().String()}
+	if _, err := pool.Exec(ctx, `INSERT INTO projects (id, name, slug) VALUES ($1, 'Integration Test', $2)`, project.ID, project.Slug); err != nil {
+		t.Fatalf("insert project: %v", err)
+	}
+	defer pool.Exec(ctx, `DELETE FROM projects WHERE id = $1`, project.ID)
+
+	revalidated := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidated++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Guide</title></head><body><h1>Install</h1><p>Run the installer.</p></body></html>`))
+	}))
+	defer srv.Close()
+
+	u := srv.URL + "/guide"
+	src := api.SourceSpec{Type: "crawl"}
+	chunker := ingestion.NewMarkdownChunker("", 0)
+	emb := &fixedEmbedder{}
+	if changed, err := processURL(ctx, pool, srv.Client(), emb, chunker, nil, project, "", src, u, fetchState{}, nil); err != nil || !changed {
+		t.Fatalf("first fetch: changed=%v err=%v", changed, err)
+	}
+
+	states, err := loadFetchStates(ctx, pool, project.ID, chunker.Settings())
+	if err != nil {
+		t.Fatalf("loadFetchStates failed: %v", err)
+	}
+	emb.calls = 0
+	changed, err := processURL(ctx, pool, srv.Client(), emb, chunker, nil, project, "", src, u, states[u], nil)
+	if err != nil || changed {
+		t.Fatalf("Expected a not modified page, got changed=%v err=%v", changed, err)
+	}
+	if revalidated != 1 || emb.calls != 0 {
+		t.Errorf("Expected one revalidation and no re-chunking, got %d revalidations and %d embeddings", revalidated, emb.calls)
+	}
+
+	// Other chunker settings must re-chunk the page, so it is fetched again
+	// instead of being revalidated.
+	resized := ingestion.NewMarkdownChunker("", 200)
+	states, err = loadFetchStates(ctx, pool, project.ID, resized.Settings())
+	if err != nil {
+		t.Fatalf("loadFetchStates failed: %v", err)
+	}
+	changed, err = processURL(ctx, pool, srv.Client(), emb, resized, nil, project, "", src, u, states[u], nil)
+	if err != nil || !changed {
+		t.Fatalf("Expected the page to be re-chunked with new settings, got changed=%v err=%v", changed, err)
+	}
+	if revalidated != 1 {
+		t.Errorf("Expected no conditional request with new settings, got %d revalidations", revalidated)
+	}
+	states, err = loadFetchStates(ctx, pool, project.ID, resized.Settings())
+	if err != nil {
+		t.Fatalf("loadFetchStates failed: %v", err)
+	}
+	if !states[u].Synced {
+		t.Error("Expected the re-chunked page to be synced under the new settings")
+	}
+
+	// The page was listed by the crawl, so a full sync keeps its document.
+	deleted, err := deleteStaleDocuments(ctx, pool, nil, project.ID, "", []string{u}, func(string) bool { return true })
+	if err != nil || deleted != 0 {
+		t.Fatalf("Expected no stale documents, got %d (err %v)", deleted, err)
+	}
+	var chunks int
+	if err := pool.QueryRow(ctx, `
+		SELECT count(*) FROM chunks c JOIN documents d ON d.id = c.document_id
+		WHERE d.project_id = $1 AND d.uri = $2
+	`, project.ID, u).Scan(&chunks); err != nil {
+		t.Fatalf("count chunks: %v", err)
+	}
+	if chunks == 0 {
+		t.Error("Expected the not modified page to keep its chunks")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cgap/api"
+)
+
+func TestParseLastMod(t *testing.T) {
+	for in, want := range map[string]string{
+		"2024-05-01":                "2024-05-01T00:00:00Z",
+		" 2024-05-01T10:30:00Z ":    "2024-05-01T10:30:00Z",
+		"2024-05-01T10:30:00+02:00": "2024-05-01T08:30:00Z",
+		"2024-05-01T10:30+02:00":    "2024-05-01T08:30:00Z",
+		"":                          "",
+		"yesterday":                 "",
+		"2024-13-01":                "",
+	} {
+		got := ""
+		if tm := parseLastMod(in); tm != nil {
+			got = tm.UTC().Format(time.RFC3339)
+		}
+		if got != want {
+			t.Errorf("parseLastMod(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFetchState_UnchangedSince(t *testing.T) {
+	may1 := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	may2 := may1.AddDate(0, 0, 1)
+
+	for _, tc := range []struct {
+		name    string
+		st      fetchState
+		lastMod *time.Time
+		want    bool
+	}{
+		{"same lastmod", fetchState{Synced: true, SitemapLastMod: &may1}, &may1, true},
+		{"older lastmod", fetchState{Synced: true, SitemapLastMod: &may2}, &may1, true},
+		{"newer lastmod", fetchState{Synced: true, SitemapLastMod: &may1}, &may2, false},
+		{"no sitemap lastmod", fetchState{Synced: true, SitemapLastMod: &may1}, nil, false},
+		{"never had a lastmod", fetchState{Synced: true}, &may1, false},
+		{"not synced", fetchState{SitemapLastMod: &may1}, &may1, false},
+	} {
+		if got := tc.st.unchangedSince(tc.lastMod); got != tc.want {
+			t.Errorf("%s: unchangedSince = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name                         string
+		st                           fetchState
+		ifNoneMatch, ifModifiedSince string
+	}{
+		{"synced", fetchState{Synced: true, ETag: `"v1"`, LastModified: "Wed, 01 May 2024 10:00:00 GMT"}, `"v1"`, "Wed, 01 May 2024 10:00:00 GMT"},
+		{"etag only", fetchState{Synced: true, ETag: `"v1"`}, `"v1"`, ""},
+		{"not synced", fetchState{ETag: `"v1"`, LastModified: "Wed, 01 May 2024 10:00:00 GMT"}, "", ""},
+		{"unknown page", fetchState{}, "", ""},
+	} {
+		resp, err := conditionalGet(context.Background(), srv.Client(), srv.URL, tc.st)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", tc.name, err)
+		}
+		_ = resp.Body.Close()
+		if got := header.Get("If-None-Match"); got != tc.ifNoneMatch {
+			t.Errorf("%s: If-None-Match = %q, want %q", tc.name, got, tc.ifNoneMatch)
+		}
+		if got := header.Get("If-Modified-Since"); got != tc.ifModifiedSince {
+			t.Errorf("%s: If-Modified-Since = %q, want %q", tc.name, got, tc.ifModifiedSince)
+		}
+	}
+}
+
+func TestProcessURL_SkipsPageUnchangedInSitemap(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer srv.Close()
+
+	lastMod := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	prev := fetchState{Synced: true, SitemapLastMod: &lastMod}
+	changed, err := processURL(context.Background(), nil, srv.Client(), nil, nil, nil, projectRef{}, "", api.SourceSpec{Type: "crawl"}, srv.URL+"/guide", prev, &lastMod)
+	if err != nil || changed {
+		t.Fatalf("Expected an unchanged page, got changed=%v err=%v", changed, err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("Expected no request for a page unchanged since its lastmod, got %d", n)
+	}
+}
+
+func TestCrawlBFS_NotModifiedPageKeepsStoredLinks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			t.Errorf("Expected the start page to be revalidated")
+			w.Write([]byte(`<a href="/changed">changed</a>`))
+		case "/docs":
+			w.Write([]byte(`<a href="/docs/install">install</a>`))
+		default:
+			w.Write([]byte(`<p>leaf</p>`))
+		}
+	}))
+	defer srv.Close()
+
+	start := srv.URL + "/"
+	states := fetchStates{start: {Synced: true, ETag: `"v1"`, Links: []string{srv.URL + "/docs"}}}
+	urls, err := crawlBFS(context.Background(), &api.CrawlSpec{Mode: "crawl", StartURL: start, MaxDepth: 3}, states)
+	if err != nil {
+		t.Fatalf("crawlBFS failed: %v", err)
+	}
+	// The not-modified page is still listed, so a full sync does not delete it,
+	// and the crawl continues through its stored links.
+	if want := []string{start, srv.URL + "/docs", srv.URL + "/docs/install"}; !slices.Equal(urls, want) {
+		t.Errorf("Expected %q, got %q", want, urls)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"cgap/api"
+	"cgap/internal/embedding"
+	"cgap/internal/ingestion"
+	"cgap/internal/model"
+	"cgap/internal/postgres"
+)
+
+// maxGitHubFileSize skips repository files too large to be useful documents.
+const maxGitHubFileSize = 1 << 20
+
+// githubWorkers bounds concurrent blob downloads, well under GitHub's limits.
+const githubWorkers = 4
+
+// syncGitHub ingests the documentation files (and optionally source code) of
+// a GitHub repository at the head of a ref. A source that was synced before
+// only reads the files changed since its last synced commit; otherwise, or with
+// full_sync, the whole tree is listed and files whose blob did not change are
+// skipped. Documents of deleted files are removed either way. Each document's
+// version is the commit SHA it was read at.
+func syncGitHub(ctx context.Context, store *postgres.Store, emb embedding.Embedder, chunker *ingestion.MarkdownChunker, idx *chunkIndexer, jobs *jobTracker, jobID string, project projectRef, p api.IngestTaskPayload, states fetchStates) error {
+	spec := p.Source.GitHub
+	if spec == nil {
+		spec = &api.GitHubSpec{}
+	}
+	owner, repo := p.Source.Owner, p.Source.Repo
+	apiURL := spec.APIURL
+	if apiURL == "" {
+		apiURL = os.Getenv("GITHUB_API_URL")
+	}
+	token := p.Source.Token
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+	client := ingestion.NewGitHubClient(apiURL, token)
+	filter := ingestion.GitHubFilter{Paths: spec.Paths, Extensions: spec.Extensions, IncludeCode: spec.IncludeCode}
+
+	ref := spec.Ref
+	if ref == "" {
+		branch, err := client.DefaultBranch(ctx, owner, repo)
+		if err != nil {
+			return err
+		}
+		ref = branch
+	}
+	head, err := client.ResolveRef(ctx, owner, repo, ref)
+	if err != nil {
+		return err
+	}
+
+	// Documents link to the file on the ref, so URIs stay stable across commits.
+	prefix := fmt.Sprintf("%s/%s/%s/blob/%s/", client.WebURL(), owner, repo, ref)
+	pool := store.Pool()
+	log := slog.With("job_id", jobID, "repo", owner+"/"+repo, "commit", head)
+
+	// The cursor is only valid for the chunk settings it was synced with.
+	cursor := githubCursor(head, chunker)
+	var lastCursor string
+	if p.SourceID != "" && !p.FullSync {
+		if src, err := store.Sources().GetByID(ctx, p.SourceID); err != nil {
+			log.Warn("ingest: failed to load source cursor, listing whole tree", "error", err)
+		} else {
+			lastCursor = src.SyncCursor
+		}
+	}
+	if lastCursor == cursor {
+		log.Info("ingest: repository unchanged since last sync")
+		jobs.running(ctx, jobID, project.ID, 0)
+		return nil
+	}
+
+	var files []ingestion.GitHubFile
+	var removed []string
+	incremental := false
+	if base, ok := strings.CutSuffix(lastCursor, " "+chunker.Settings()); ok {
+		changes, err := client.Compare(ctx, owner, repo, base, head)
+		switch {
+		case errors.Is(err, ingestion.ErrCompareIncomplete):
+			log.Info("ingest: cannot sync incrementally, listing whole tree", "since", base)
+		case err != nil:
+			return err
+		default:
+			for _, c := range changes {
+				if c.PreviousPath != "" && filter.Match(c.PreviousPath) {
+					removed = append(removed, prefix+c.PreviousPath)
+				}
+				if !filter.Match(c.Path) {
+					continue
+				}
+				if c.Removed() {
+					removed = append(removed, prefix+c.Path)
+				} else {
+					files = append(files, ingestion.GitHubFile{Path: c.Path, SHA: c.SHA})
+				}
+			}
+			incremental = true
+			log.Info("ingest: syncing changes", "since", base, "files", len(files), "removed", len(removed))
+		}
+	}
+	treeListed := false
+	if !incremental {
+		tree, truncated, err := client.Tree(ctx, owner, repo, head)
+		if err != nil {
+			return err
+		}
+		for _, f := range tree {
+			if !filter.Match(f.Path) {
+				continue
+			}
+			if f.Size > maxGitHubFileSize {
+				log.Warn("ingest: skipping large file", "path", f.Path, "size", f.Size)
+				continue
+			}
+			files = append(files, f)
+		}
+		if truncated {
+			log.Warn("ingest: repository tree truncated, skipping removed file cleanup")
+		}
+		treeListed = !truncated
+	}
+
+	jobs.running(ctx, jobID, project.ID, len(files))
+
+	byURI := make(map[string]ingestion.GitHubFile, len(files))
+	uris := make([]string, 0, len(files))
+	for _, f := range files {
+		byURI[prefix+f.Path] = f
+		uris = append(uris, prefix+f.Path)
+	}
+	failed, err := ingestURLs(ctx, jobs, jobID, uris, githubWorkers, 0, p.FailFast, func(ctx context.Context, uri string) (bool, error) {
+		f := byURI[uri]
+		etag := githubETag(f.SHA, chunker)
+		if st := states[uri]; st.Synced && st.ETag == etag {
+			return false, nil
+		}
+		data, err := client.Blob(ctx, owner, repo, f.SHA)
+		if err != nil {
+			return false, err
+		}
+		doc := sourceDocument{
+			URI:     uri,
+			Title:   f.Path,
+			Version: head,
+			Text:    string(data),
+			Fetched: &fetchState{ETag: etag},
+		}
+		if lang := ingestion.CodeLanguage(f.Path); lang != "" && !filter.IsDoc(f.Path) {
+			doc.Lang = lang
+		} else {
+			doc.Text = ingestion.RepoDocToMarkdown(f.Path, doc.Text)
+		}
+		return syncDocument(ctx, pool, emb, chunker, idx, project, p.SourceID, p.Source.Type, doc)
+	})
+	if err != nil {
+		return err
+	}
+
+	deleted := 0
+	switch {
+	case treeListed:
+		deleted, err = deleteStaleDocuments(ctx, pool, idx, project.ID, p.SourceID, uris, func(uri string) bool {
+			rel, ok := strings.CutPrefix(uri, prefix)
+			return ok && filter.Match(rel)
+		})
+	case len(removed) > 0:
+		deleted, err = deleteDocumentsByURI(ctx, pool, idx, project.ID, removed)
+	}
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Info("ingest: deleted documents of removed files", "count", deleted)
+		jobs.add(ctx, jobID, model.JobCounters{Deleted: deleted})
+	}
+
+	// Failed files are retried by the next sync, which must then start from the old cursor.
+	if p.SourceID != "" && failed == 0 && (incremental || treeListed) {
+		if err := store.Sources().SaveCursor(ctx, p.SourceID, cursor); err != nil {
+			log.Warn("ingest: failed to save source cursor", "error", err)
+		}
+	}
+	return nil
+}
+
+// githubCursor is the sync cursor of a source synced at commit with chunker's
+// settings; a sync with other settings must re-chunk every file.
+func githubCursor(commit string, chunker *ingestion.MarkdownChunker) string {
+	return commit + " " + chunker.Settings()
+}
+
+// githubETag is stored as a repository document's ETag: the blob SHA
+// identifies the content, qualified by the settings it was chunked with.
+func githubETag(blobSHA string, chunker *ingestion.MarkdownChunker) string {
+	return blobSHA + " " + chunker.Settings()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"cgap/internal/model"
+	"cgap/internal/storage"
+)
+
+func jobKey(id string) string { return "cgap:job:" + id }
+
+// jobTracker records job progress in the live Redis hash read by
+// GET /v1/ingest/:job_id and in the durable jobs table. Writes are best-effort:
+// failing to record progress never fails the job itself.
+type jobTracker struct {
+	rdb  *redis.Client
+	repo storage.JobRepo
+}
+
+func (t *jobTracker) running(ctx context.Context, jobID, projectID string, total int) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	t.hset(ctx, jobID, map[string]any{
+		"job_id":     jobID,
+		"project_id": projectID,
+		"status":     model.JobRunning,
+		"processed":  0,
+		"unchanged":  0,
+		"deleted":    0,
+		"failed":     0,
+		"error":      "",
+		"total":      total,
+		"started_at": now,
+		"updated_at": now,
+	})
+	t.db(jobID, "start", t.repo.Start(ctx, jobID, total))
+}
+
+// add increments progress counters.
+func (t *jobTracker) add(ctx context.Context, jobID string, delta model.JobCounters) {
+	fields := map[string]int{
+		"processed": delta.Processed,
+		"unchanged": delta.Unchanged,
+		"deleted":   delta.Deleted,
+		"failed":    delta.Failed,
+	}
+	pipe := t.rdb.TxPipeline()
+	for field, n := range fields {
+		if n != 0 {
+			pipe.HIncrBy(ctx, jobKey(jobID), field, int64(n))
+		}
+	}
+	pipe.HSet(ctx, jobKey(jobID), "updated_at", time.Now().UTC().Format(time.RFC3339))
+	if _, err := pipe.Exec(ctx); err != nil {
+		slog.Debug("Failed to update job progress", "job_id", jobID, "error", err)
+	}
+	t.db(jobID, "update counters", t.repo.AddCounters(ctx, jobID, delta))
+}
+
+// urlFailed records why a URL could not be ingested and counts it as processed.
+func (t *jobTracker) urlFailed(ctx context.Context, jobID, url string, err error) {
+	t.add(ctx, jobID, model.JobCounters{Processed: 1, Failed: 1})
+	t.db(jobID, "record url error", t.repo.AddURLError(ctx, jobID, url, err.Error()))
+}
+
+func (t *jobTracker) completed(ctx context.Context, jobID string) {
+	t.finish(ctx, jobID, model.JobCompleted, "")
+}
+
+func (t *jobTracker) failed(ctx context.Context, jobID string, err error) {
+	t.finish(ctx, jobID, model.JobFailed, err.Error())
+}
+
+func (t *jobTracker) cancelled(ctx context.Context, jobID string) {
+	t.finish(ctx, jobID, model.JobCancelled, "")
+}
+
+// queued records a task that was returned to the queue unfinished.
+func (t *jobTracker) queued(ctx context.Context, jobID string) {
+	t.hset(ctx, jobID, map[string]any{
+		"status":     model.JobQueued,
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+	})
+	t.db(jobID, "set status", t.repo.SetStatus(ctx, jobID, model.JobQueued, ""))
+}
+
+// retrying records a failed attempt that will be retried after a backoff.
+func (t *jobTracker) retrying(ctx context.Context, jobID string, retries int, err error) {
+	t.hset(ctx, jobID, map[string]any{
+		"status":     model.JobRetrying,
+		"error":      err.Error(),
+		"retries":    retries,
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+	})
+	t.db(jobID, "set status", t.repo.SetStatus(ctx, jobID, model.JobRetrying, err.Error()))
+	t.db(jobID, "update counters", t.repo.AddCounters(ctx, jobID, model.JobCounters{Retries: 1}))
+}
+
+func (t *jobTracker) finish(ctx context.Context, jobID, status, errMsg string) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	t.hset(ctx, jobID, map[string]any{
+		"status":      status,
+		"error":       errMsg,
+		"finished_at": now,
+		"updated_at":  now,
+	})
+	t.db(jobID, "set status", t.repo.SetStatus(ctx, jobID, status, errMsg))
+}
+
+// cancelRequested reports whether the job was cancelled before its task started
+// running; cancellations of running tasks arrive through Consumer.WatchCancels.
+func (t *jobTracker) cancelRequested(ctx context.Context, jobID string) bool {
+	if v, err := t.rdb.HGet(ctx, jobKey(jobID), "cancel_requested").Result(); err == nil && v == "1" {
+		return true
+	}
+	job, err := t.repo.GetByID(ctx, jobID)
+	return err == nil && job.CancelRequestedAt != nil
+}
+
+func (t *jobTracker) hset(ctx context.Context, jobID string, fields map[string]any) {
+	if err := t.rdb.HSet(ctx, jobKey(jobID), fields).Err(); err != nil {
+		slog.Debug("Failed to update job status", "job_id", jobID, "error", err)
+	}
+}
+
+// db logs a failed write to the jobs table.
+func (t *jobTracker) db(jobID, op string, err error) {
+	if err != nil {
+		slog.Warn("Failed to write job history", "job_id", jobID, "op", op, "error", err)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"cgap/internal/model"
+	"cgap/internal/queue"
+)
+
+// processOne takes the next task off the pool's queue.
+func (p *testPool) processOne(t *testing.T) *queue.Task {
+	t.Helper()
+	task, err := p.consumer.Process(context.Background())
+	if err != nil || task == nil {
+		t.Fatalf("Process failed: task=%v err=%v", task, err)
+	}
+	return task
+}
+
+// assertSettled checks that a task left the queue without a retry.
+func (p *testPool) assertSettled(t *testing.T) {
+	t.Helper()
+	ctx := context.Background()
+	if n := p.rdb.LLen(ctx, "cgap:tasks").Val() + p.rdb.LLen(ctx, "cgap:tasks:processing").Val() + p.rdb.ZCard(ctx, "cgap:tasks:delayed").Val(); n != 0 {
+		t.Errorf("Expected the task to be acked, got %d entries left", n)
+	}
+}
+
+func TestTaskPool_CancelRunningTask(t *testing.T) {
+	started := make(chan struct{})
+	var cause error
+	p := newTestPool(t, poolConfig{Concurrency: 1, TaskTimeout: time.Minute, DrainTimeout: time.Second}, func(ctx context.Context, task *queue.Task) error {
+		close(started)
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+		return ctx.Err()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = p.consumer.WatchCancels(ctx, p.cancel) }()
+
+	p.enqueue(t, "job_1")
+	task := p.processOne(t)
+	done := make(chan struct{})
+	go func() {
+		p.execute(ctx, slog.Default(), task)
+		close(done)
+	}()
+	<-started
+
+	// What POST /v1/ingest/:job_id/cancel does for a running task. The
+	// notification is repeated until the subscription is established.
+	p.rdb.HSet(ctx, jobKey("job_1"), "cancel_requested", 1)
+	deadline := time.After(5 * time.Second)
+	for cancelled := false; !cancelled; {
+		if removed, err := p.producer.Cancel(ctx, "job_1"); err != nil || removed {
+			t.Fatalf("Expected a running task to be signalled, got removed=%v err=%v", removed, err)
+		}
+		select {
+		case <-done:
+			cancelled = true
+		case <-time.After(20 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("timed out waiting for the task to be cancelled")
+		}
+	}
+
+	if !errors.Is(cause, errJobCancelled) {
+		t.Errorf("Expected the handler context to be cancelled by the job, got %v", cause)
+	}
+	if status, _ := p.repo.status("job_1"); status != model.JobCancelled {
+		t.Errorf("Expected job status %q, got %q", model.JobCancelled, status)
+	}
+	if status := p.rdb.HGet(ctx, jobKey("job_1"), "status").Val(); status != model.JobCancelled {
+		t.Errorf("Expected live status %q, got %q", model.JobCancelled, status)
+	}
+	p.assertSettled(t)
+}
+
+func TestTaskPool_CancelledBeforeStart(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		flag func(p *testPool)
+	}{
+		{"redis flag", func(p *testPool) {
+			p.rdb.HSet(context.Background(), jobKey("job_1"), "cancel_requested", 1)
+		}},
+		{"job history", func(p *testPool) { p.repo.cancelled["job_1"] = true }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ran := false
+			p := newTestPool(t, poolConfig{Concurrency: 1, TaskTimeout: time.Minute}, func(ctx context.Context, task *queue.Task) error {
+				ran = true
+				return nil
+			})
+			p.enqueue(t, "job_1")
+			tc.flag(p)
+
+			p.execute(context.Background(), slog.Default(), p.processOne(t))
+
+			if ran {
+				t.Error("Expected a cancelled job not to run")
+			}
+			if status, _ := p.repo.status("job_1"); status != model.JobCancelled {
+				t.Errorf("Expected job status %q, got %q", model.JobCancelled, status)
+			}
+			p.assertSettled(t)
+		})
+	}
+}
+
+func TestTaskPool_HeartbeatKeepsLease(t *testing.T) {
+	var p *testPool
+	p = newTestPool(t, poolConfig{Concurrency: 1, TaskTimeout: time.Minute}, func(ctx context.Context, task *queue.Task) error {
+		// Outlive the visibility timeout several times while recovery runs.
+		for range 4 {
+			time.Sleep(50 * time.Millisecond)
+			if err := p.consumer.Recover(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	p.consumer = queue.NewConsumerWithOptions(p.rdb, queue.Options{
+		Visibility:  60 * time.Millisecond,
+		MaxRetries:  3,
+		BaseBackoff: time.Minute,
+		MaxBackoff:  time.Minute,
+	})
+	p.enqueue(t, "job_1")
+
+	p.execute(context.Background(), slog.Default(), p.processOne(t))
+
+	if status, errMsg := p.repo.status("job_1"); status != model.JobCompleted {
+		t.Errorf("Expected the task to complete without redelivery, got %q (%s)", status, errMsg)
+	}
+	p.assertSettled(t)
+}
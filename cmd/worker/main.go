@@ -15,22 +15,28 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/pgvector/pgvector-go"
 	"github.com/redis/go-redis/v9"
 
 	"github.com/gofiber/fiber/v3"
 
 	"cgap/api"
 	"cgap/internal/embedding"
+	"cgap/internal/ingestion"
+	"cgap/internal/meilisearch"
 	"cgap/internal/model"
+	"cgap/internal/objectstore"
 	"cgap/internal/postgres"
 	"cgap/internal/queue"
 )
 
+// extractors converts fetched files (HTML, PDF, DOCX, Markdown, text) to Markdown.
+var extractors = ingestion.NewExtractorRegistry()
+
 func main() {
 	// Print custom startup banner first
 	printCGAPBanner("worker")
@@ -57,11 +63,19 @@ func main() {
 	defer redisClient.Close()
 
 	// Initialize Redis queue consumer
-	consumer := queue.NewConsumer(redisClient)
+	consumer := queue.NewConsumerWithOptions(redisClient, queueOptionsFromEnv())
 
 	// Build embedder for ingestion
 	embedder := buildEmbedder()
 
+	// Mirror chunks into Meilisearch for lexical search
+	indexer := buildChunkIndexer()
+
+	// Uploaded files are read from the object store shared with the API
+	if blobs, err = objectstore.FromEnv(); err != nil {
+		slog.Warn("Object store unavailable, upload sources will fail", "error", err)
+	}
+
 	// Start HTTP health check server
 	healthPort := os.Getenv("HEALTH_PORT")
 	if healthPort == "" {
@@ -108,59 +122,88 @@ func main() {
 		}
 	}()
 
-	// Start job processor
-	go func() {
-		slog.Info("Starting job consumer...")
-		ctx := context.Background()
-		for {
-			// Get next task
-			task, err := consumer.Process(ctx)
-			if err != nil {
-				slog.Error("Consumer error", "error", err)
-				continue
-			}
-
-			// Nil task means timeout - no task available
-			if task == nil {
-				continue
-			}
-
-			// Route task to appropriate handler based on task.Type
-			slog.Info("Processing task", "type", task.Type, "id", task.ID)
-
-			switch task.Type {
-			case "ingest":
-				if err := handleIngest(ctx, store, embedder, redisClient, task.ID, task.Payload); err != nil {
-					slog.Error("ingest error", "task_id", task.ID, "error", err)
-					// Mark failed
-					_ = markJobFailed(ctx, redisClient, task.ID, err)
-				} else {
-					slog.Info("ingest completed", "task_id", task.ID)
-					// Mark completed
-					_ = markJobCompleted(ctx, redisClient, task.ID)
-				}
-			default:
-				// Not handled yet
-			}
+	// Root context, cancelled on SIGINT/SIGTERM to stop taking new tasks
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := poolConfigFromEnv()
+	jobs := &jobTracker{rdb: redisClient, repo: store.Jobs()}
+	pool := newTaskPool(cfg, consumer, jobs, func(ctx context.Context, task *queue.Task) error {
+		// Route task to appropriate handler based on task.Type
+		switch task.Type {
+		case "ingest":
+			return handleIngest(ctx, store, embedder, indexer, jobs, task.ID, task.Payload)
+		default:
+			slog.Warn("Dropping task of unknown type", "type", task.Type, "id", task.ID)
+			return nil
 		}
-	}()
+	})
 
-	slog.Info("Worker ready")
+	scheduler := &sourceScheduler{
+		sources:  store.Sources(),
+		jobs:     store.Jobs(),
+		producer: queue.NewProducer(redisClient),
+		rdb:      redisClient,
+		interval: schedulerIntervalFromEnv(),
+	}
+	go scheduler.Run(ctx)
+
+	slog.Info("Worker ready", "concurrency", cfg.Concurrency, "task_timeout", cfg.TaskTimeout)
 
-	// Wait for interrupt signal for graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+	// Blocks until shutdown is requested and in-flight tasks are drained
+	pool.Run(ctx)
 
 	slog.Info("Shutting down worker...")
 
-	if err := app.ShutdownWithContext(context.Background()); err != nil {
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := app.ShutdownWithContext(shutdownCtx); err != nil {
 		slog.Error("Health server shutdown error", "error", err)
 	}
 
 	slog.Info("Worker stopped")
 }
 
+// queueOptionsFromEnv reads QUEUE_MAX_RETRIES and QUEUE_VISIBILITY_TIMEOUT
+// (a Go duration such as "10m") over the queue defaults.
+func queueOptionsFromEnv() queue.Options {
+	opts := queue.DefaultOptions()
+	if v := os.Getenv("QUEUE_MAX_RETRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			opts.MaxRetries = n
+		} else {
+			slog.Warn("Invalid QUEUE_MAX_RETRIES, using default", "value", v)
+		}
+	}
+	if v := os.Getenv("QUEUE_VISIBILITY_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			opts.Visibility = d
+		} else {
+			slog.Warn("Invalid QUEUE_VISIBILITY_TIMEOUT, using default", "value", v)
+		}
+	}
+	return opts
+}
+
+// buildChunkIndexer connects to Meilisearch when MEILISEARCH_URL is set and makes
+// sure the chunks index exists with the expected settings.
+func buildChunkIndexer() *chunkIndexer {
+	meiliURL := os.Getenv("MEILISEARCH_URL")
+	if meiliURL == "" {
+		slog.Warn("MEILISEARCH_URL not set; chunks will not be indexed for lexical search")
+		return nil
+	}
+	client := meilisearch.New(meiliURL, os.Getenv("MEILISEARCH_KEY"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := client.EnsureIndex(ctx, meilisearch.ChunksIndex, meilisearch.ChunkSettings); err != nil {
+		// Keep going: indexing is retried per document and fails loudly there.
+		slog.Warn("Failed to prepare Meilisearch index", "index", meilisearch.IndexUID(meilisearch.ChunksIndex), "error", err)
+	}
+	return &chunkIndexer{client: client}
+}
+
 // buildEmbedder constructs an embedder from environment configuration.
 func buildEmbedder() embedding.Embedder {
 	provider := os.Getenv("EMBEDDING_PROVIDER")
@@ -227,7 +270,7 @@ func redisOptionsFromEnv() *redis.Options {
 
 // handleIngest performs a minimal ingestion: fetch content from URL(s),
 // create document and one-or-more chunks, embed and store in Postgres.
-func handleIngest(ctx context.Context, store *postgres.Store, emb embedding.Embedder, rdb *redis.Client, jobID string, payload any) error {
+func handleIngest(ctx context.Context, store *postgres.Store, emb embedding.Embedder, idx *chunkIndexer, jobs *jobTracker, jobID string, payload any) error {
 	// Decode payload into API DTO
 	mp, ok := payload.(map[string]any)
 	if !ok {
@@ -242,9 +285,41 @@ func handleIngest(ctx context.Context, store *postgres.Store, emb embedding.Embe
 	if v, ok := mp["fail_fast"].(bool); ok {
 		p.FailFast = v
 	}
+	if v, ok := mp["chunk_strategy"].(string); ok {
+		p.ChunkStrategy = v
+	}
+	if v, ok := mp["chunk_size_token"].(float64); ok {
+		p.ChunkSizeToken = int(v)
+	}
+	if v, ok := mp["full_sync"].(bool); ok {
+		p.FullSync = v
+	}
+	p.SourceID, _ = mp["source_id"].(string)
 	if src, ok := mp["source"].(map[string]any); ok {
 		p.Source.Type, _ = src["type"].(string)
 		p.Source.URL, _ = src["url"].(string)
+		p.Source.OpenAPIURL, _ = src["openapi_url"].(string)
+		p.Source.UploadID, _ = src["upload_id"].(string)
+		p.Source.Owner, _ = src["owner"].(string)
+		p.Source.Repo, _ = src["repo"].(string)
+		p.Source.Token, _ = src["token"].(string)
+		if gh, ok := src["github"].(map[string]any); ok {
+			gs := &api.GitHubSpec{}
+			gs.Ref, _ = gh["ref"].(string)
+			gs.Paths = stringList(gh["paths"])
+			gs.Extensions = stringList(gh["extensions"])
+			gs.IncludeCode, _ = gh["include_code"].(bool)
+			gs.APIURL, _ = gh["api_url"].(string)
+			p.Source.GitHub = gs
+		}
+		if ar, ok := src["archive"].(map[string]any); ok {
+			as := &api.ArchiveSpec{}
+			as.BaseURL, _ = ar["base_url"].(string)
+			as.Root, _ = ar["root"].(string)
+			as.Paths = stringList(ar["paths"])
+			as.CleanURLs, _ = ar["clean_urls"].(bool)
+			p.Source.Archive = as
+		}
 		if crawl, ok := src["crawl"].(map[string]any); ok {
 			cs := &api.CrawlSpec{}
 			if v, ok := crawl["mode"].(string); ok {
@@ -290,6 +365,13 @@ func handleIngest(ctx context.Context, store *postgres.Store, emb embedding.Embe
 			}
 			p.Source.Crawl = cs
 		}
+		if m, ok := src["media"].(map[string]any); ok {
+			ms := &api.MediaSpec{}
+			ms.URLs = stringList(m["urls"])
+			ms.OCRLang, _ = m["ocr_lang"].(string)
+			ms.YouTubeIDs = stringList(m["youtube_ids"])
+			p.Source.Media = ms
+		}
 		if files, ok := src["files"].(map[string]any); ok {
 			if urls, ok := files["urls"].([]any); ok {
 				for _, u := range urls {
@@ -301,7 +383,11 @@ func handleIngest(ctx context.Context, store *postgres.Store, emb embedding.Embe
 					}
 				}
 			}
+			if p.Source.Files == nil {
+				p.Source.Files = &api.FileSpec{}
+			}
 			p.Source.Files.Format, _ = files["format"].(string)
+			p.Source.Files.Extract, _ = files["extract"].(string)
 		}
 	}
 
@@ -309,14 +395,53 @@ func handleIngest(ctx context.Context, store *postgres.Store, emb embedding.Embe
 		return nil
 	}
 
+	pool := store.Pool()
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	chunker := ingestion.NewMarkdownChunker(p.ChunkStrategy, p.ChunkSizeToken)
+
+	// Resolve project slug <-> UUID; both are stored on indexed chunks
+	var project projectRef
+	if err := pool.QueryRow(ctx, `
+		SELECT id, slug FROM projects WHERE id::text = $1 OR slug = $1
+	`, p.ProjectID).Scan(&project.ID, &project.Slug); err != nil {
+		return err
+	}
+	pid := project.ID
+
+	// Validators of earlier fetches, for conditional requests. Documents chunked
+	// with other settings are fetched again so they are re-chunked.
+	states, err := loadFetchStates(ctx, pool, pid, chunker.Settings())
+	if err != nil {
+		return err
+	}
+
+	if p.Source.Type == model.SourceTypeGitHub {
+		return syncGitHub(ctx, store, emb, chunker, idx, jobs, jobID, project, p, states)
+	}
+	if p.Source.Type == model.SourceTypeOpenAPI {
+		return syncOpenAPI(ctx, store, httpClient, emb, chunker, idx, jobs, jobID, project, p)
+	}
+	if p.Source.Type == model.SourceTypeUpload {
+		return syncUpload(ctx, store, emb, chunker, idx, jobs, jobID, project, p)
+	}
+	if isMediaSource(p.Source.Type) {
+		return syncMedia(ctx, store, emb, chunker, idx, jobs, jobID, project, p, mediaEntries(p.Source))
+	}
+	if p.Source.Type == model.SourceTypeArchive {
+		// Archives may be far larger than a page
+		return syncArchive(ctx, store, &http.Client{Timeout: 10 * time.Minute}, emb, chunker, idx, jobs, jobID, project, p)
+	}
+
 	urls := make([]string, 0, 32)
+	var lastMods map[string]time.Time
 	if p.Source.Type == model.SourceTypeCrawl && p.Source.Crawl != nil {
 		// Build URL set based on crawl mode
-		list, err := buildCrawlURLList(ctx, p.Source.Crawl)
+		list, mods, err := buildCrawlURLList(ctx, p.Source.Crawl, states)
 		if err != nil {
 			return err
 		}
 		urls = append(urls, list...)
+		lastMods = mods
 	} else {
 		if p.Source.URL != "" {
 			urls = append(urls, p.Source.URL)
@@ -329,19 +454,8 @@ func handleIngest(ctx context.Context, store *postgres.Store, emb embedding.Embe
 		return nil
 	}
 
-	pool := store.Pool()
-	httpClient := &http.Client{Timeout: 30 * time.Second}
-
-	// Resolve project slug -> UUID if needed
-	pid := p.ProjectID
-	if !looksLikeUUID(pid) {
-		if err := pool.QueryRow(ctx, `SELECT id FROM projects WHERE slug = $1`, pid).Scan(&pid); err != nil {
-			return err
-		}
-	}
-
 	// Initialize running status
-	_ = markJobRunning(ctx, rdb, jobID, pid, len(urls))
+	jobs.running(ctx, jobID, pid, len(urls))
 
 	// Concurrency settings: default to 4; use crawl.concurrency when provided
 	maxWorkers := 4
@@ -355,15 +469,60 @@ func handleIngest(ctx context.Context, store *postgres.Store, emb embedding.Embe
 		maxWorkers = 16
 	}
 
-	sem := make(chan struct{}, maxWorkers)
+	var delay time.Duration
+	if p.Source.Crawl != nil {
+		delay = time.Duration(p.Source.Crawl.DelayMS) * time.Millisecond
+	}
+	if _, err := ingestURLs(ctx, jobs, jobID, urls, maxWorkers, delay, p.FailFast, func(ctx context.Context, u string) (bool, error) {
+		var lastMod *time.Time
+		if t, ok := lastMods[u]; ok {
+			lastMod = &t
+		}
+		return processURL(ctx, pool, httpClient, emb, chunker, idx, project, p.SourceID, p.Source, u, states[u], lastMod)
+	}); err != nil {
+		return err
+	}
+
+	if p.FullSync {
+		if full, reason := isCompleteListing(p.Source.Crawl, len(urls)); !full {
+			slog.Warn("ingest: skipping stale document cleanup", "job_id", jobID, "reason", reason)
+			return nil
+		}
+		base, err := neturl.Parse(crawlBaseURL(p.Source.Crawl, urls))
+		if err != nil {
+			return err
+		}
+		deleted, err := deleteStaleDocuments(ctx, pool, idx, pid, p.SourceID, urls, func(uri string) bool {
+			return withinScope(uri, base, p.Source.Crawl.Scope) && passesAllowDeny(uri, p.Source.Crawl.Allow, p.Source.Crawl.Deny)
+		})
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			slog.Info("ingest: deleted documents no longer in source", "job_id", jobID, "count", deleted)
+			jobs.add(ctx, jobID, model.JobCounters{Deleted: deleted})
+		}
+	}
+	return nil
+}
+
+// urlProcessor ingests one URL and reports whether its document changed.
+type urlProcessor func(ctx context.Context, u string) (bool, error)
+
+// ingestURLs runs process over urls, up to workers at a time and waiting delay
+// before each, and records every outcome on the job. It returns the number of
+// URLs that failed. With failFast the first failure stops the remaining URLs
+// and is returned; a cancelled ctx is returned as well, since URLs were skipped.
+func ingestURLs(ctx context.Context, jobs *jobTracker, jobID string, urls []string, workers int, delay time.Duration, failFast bool, process urlProcessor) (int, error) {
+	sem := make(chan struct{}, workers)
 	var wg sync.WaitGroup
 	workCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	var firstErr error
 	var once sync.Once
+	var failed atomic.Int64
 
 	for _, u := range urls {
-		u := u // capture
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
@@ -373,115 +532,137 @@ func handleIngest(ctx context.Context, store *postgres.Store, emb embedding.Embe
 				return
 			}
 			// politeness delay per-request if configured
-			if p.Source.Crawl != nil && p.Source.Crawl.DelayMS > 0 {
+			if delay > 0 {
 				select {
-				case <-time.After(time.Duration(p.Source.Crawl.DelayMS) * time.Millisecond):
+				case <-time.After(delay):
 				case <-workCtx.Done():
 					return
 				}
 			}
-			if err := processURL(workCtx, pool, httpClient, emb, pid, p.Source, u); err != nil {
+			changed, err := process(workCtx, u)
+			switch {
+			case err != nil && workCtx.Err() != nil:
+				// Cancelled, timed out or stopped by fail_fast; not a failure of this URL.
+			case err != nil:
 				slog.Error("ingest: error processing URL", "url", u, "error", err)
-				if p.FailFast {
+				failed.Add(1)
+				jobs.urlFailed(ctx, jobID, u, err)
+				if failFast {
 					once.Do(func() {
 						firstErr = err
 						cancel()
 					})
 				}
+			case !changed:
+				jobs.add(ctx, jobID, model.JobCounters{Processed: 1, Unchanged: 1})
+			default:
+				jobs.add(ctx, jobID, model.JobCounters{Processed: 1})
 			}
-			_ = incJobProcessed(ctx, rdb, jobID, 1)
 		}()
 	}
 	wg.Wait()
-	if p.FailFast && firstErr != nil {
-		return firstErr
+	if firstErr != nil {
+		return int(failed.Load()), firstErr
 	}
-	return nil
+	// Timed out or interrupted: the remaining URLs were skipped, so the task is not done.
+	return int(failed.Load()), ctx.Err()
+}
+
+// isCompleteListing reports whether a crawl listed the whole source, which is
+// required before documents missing from it may be deleted.
+func isCompleteListing(cs *api.CrawlSpec, n int) (bool, string) {
+	if cs == nil {
+		return false, "full sync requires a crawl source"
+	}
+	switch cs.Mode {
+	case "sitemap":
+		if cs.MaxPages > 0 && n >= cs.MaxPages {
+			return false, "sitemap listing truncated by max_pages"
+		}
+	case "crawl", "":
+		if n >= crawlPageLimit(cs) {
+			return false, "crawl stopped at max_pages"
+		}
+	default:
+		return false, "full sync requires crawl mode sitemap or crawl"
+	}
+	if n == 0 {
+		return false, "source listed no URLs"
+	}
+	return true, ""
+}
+
+// crawlBaseURL is the URL scope checks are relative to, matching filterURLs.
+func crawlBaseURL(cs *api.CrawlSpec, urls []string) string {
+	if cs.StartURL != "" {
+		return cs.StartURL
+	}
+	return urls[0]
 }
 
 // processURL fetches, normalizes, chunks, embeds, and stores a single URL.
-func processURL(ctx context.Context, pool *pgxpool.Pool, httpClient *http.Client, emb embedding.Embedder, projectID string, src api.SourceSpec, u string) error {
-	// Fetch content
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
-	if err != nil {
-		return err
+// It reports whether the stored document changed. Pages the sitemap reports
+// unmodified since the last fetch are not requested, and other known pages
+// are revalidated with their stored ETag and Last-Modified.
+func processURL(ctx context.Context, pool *pgxpool.Pool, httpClient *http.Client, emb embedding.Embedder, chunker *ingestion.MarkdownChunker, idx *chunkIndexer, project projectRef, sourceID string, src api.SourceSpec, u string, prev fetchState, lastMod *time.Time) (bool, error) {
+	if prev.unchangedSince(lastMod) {
+		return false, nil
 	}
-	resp, err := httpClient.Do(req)
+
+	// Fetch content
+	resp, err := conditionalGet(ctx, httpClient, u, prev)
 	if err != nil {
-		return err
+		return false, err
 	}
 	body, err := io.ReadAll(resp.Body)
 	_ = resp.Body.Close()
 	if err != nil {
-		return err
+		return false, err
 	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		slog.Warn("fetch failed", "url", u, "status", resp.StatusCode)
-		return nil
+	if resp.StatusCode == http.StatusNotModified {
+		return false, markNotModified(ctx, pool, project.ID, u, lastMod)
 	}
-
-	text := string(body)
-	// naive text cleanup for markdown/plain; leave HTML as-is for now
-	if strings.HasSuffix(strings.ToLower(u), ".md") || src.Files != nil && (src.Files.Format == "markdown" || src.Files.Format == "md") {
-		// keep as-is
-	} else if src.Files != nil && (src.Files.Format == "txt" || src.Files.Format == "text") {
-		// keep as-is
-	} else {
-		// basic fallback: strip tags lightly
-		text = stripBasicHTML(text)
-	}
-
-	// Upsert document (by project_id + uri)
-	var docID string
-	if err := pool.QueryRow(ctx, `
-		INSERT INTO documents (project_id, uri, title)
-		VALUES ($1, $2, COALESCE($3, 'Untitled'))
-		ON CONFLICT (project_id, uri) DO UPDATE SET title = COALESCE(EXCLUDED.title, documents.title)
-		RETURNING id
-	`, projectID, u, "").Scan(&docID); err != nil {
-		return err
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Reported as a URL failure rather than silently skipped.
+		return false, fmt.Errorf("fetch failed: %s", resp.Status)
 	}
 
-	// Very simple chunking: split by blank lines, cap to first N chunks
-	parts := splitIntoParagraphs(text)
-	if len(parts) == 0 {
-		return nil
+	// PDF, DOCX and HTML are converted to Markdown so the chunker can follow
+	// their structure; Markdown and plain text are chunked as-is.
+	doc, err := extractors.Extract(ctx, fileFormat(src), u, resp.Header.Get("Content-Type"), body)
+	if err != nil {
+		return false, err
 	}
-	const maxChunks = 20
-	if len(parts) > maxChunks {
-		parts = parts[:maxChunks]
+	var links []string
+	if doc.Extractor == ingestion.ExtractorHTML {
+		// Kept so a crawl can follow the page's links while it is not modified.
+		links = extractLinks(string(body), u)
 	}
 
-	// Insert chunks and embeddings
-	for i, t := range parts {
-		var chunkID string
-		if err := pool.QueryRow(ctx, `
-			INSERT INTO chunks (document_id, ord, text)
-			VALUES ($1, $2, $3)
-			RETURNING id
-		`, docID, i, t).Scan(&chunkID); err != nil {
-			return err
-		}
+	return syncDocument(ctx, pool, emb, chunker, idx, project, sourceID, src.Type, sourceDocument{
+		URI:     u,
+		Title:   doc.Title,
+		Text:    doc.Text,
+		Fetched: fetchStateFrom(resp, lastMod, links),
+	})
+}
 
-		vec, err := emb.Embed(ctx, t)
-		if err != nil {
-			return err
-		}
-		if _, err := pool.Exec(ctx, `
-			INSERT INTO chunk_embeddings (chunk_id, embedding)
-			VALUES ($1, $2)
-			ON CONFLICT (chunk_id) DO UPDATE SET embedding = EXCLUDED.embedding
-		`, chunkID, pgvector.NewVector(vec)); err != nil {
-			return err
-		}
+// fileFormat is the extractor forced by src.files, or "" to detect it.
+func fileFormat(src api.SourceSpec) string {
+	if src.Files == nil {
+		return ""
 	}
-	return nil
+	if src.Files.Extract != "" {
+		return src.Files.Extract
+	}
+	return src.Files.Format
 }
 
-// buildCrawlURLList expands a CrawlSpec to a list of URLs to fetch.
-func buildCrawlURLList(ctx context.Context, cs *api.CrawlSpec) ([]string, error) {
+// buildCrawlURLList expands a CrawlSpec to a list of URLs to fetch, with the
+// sitemap <lastmod> of the URLs that have one.
+func buildCrawlURLList(ctx context.Context, cs *api.CrawlSpec, states fetchStates) ([]string, map[string]time.Time, error) {
 	if cs == nil {
-		return nil, nil
+		return nil, nil, nil
 	}
 	mode := cs.Mode
 	if mode == "" {
@@ -490,36 +671,39 @@ func buildCrawlURLList(ctx context.Context, cs *api.CrawlSpec) ([]string, error)
 	switch mode {
 	case "single":
 		if cs.StartURL == "" {
-			return nil, nil
+			return nil, nil, nil
 		}
 		if cs.RespectRobots && !isAllowedByRobots(ctx, cs.StartURL) {
-			return nil, nil
+			return nil, nil, nil
 		}
-		return []string{cs.StartURL}, nil
+		return []string{cs.StartURL}, nil, nil
 	case "sitemap":
 		if cs.SitemapURL == "" {
-			return nil, nil
+			return nil, nil, nil
 		}
-		urls := parseSitemap(ctx, cs.SitemapURL)
+		urls, lastMods := parseSitemap(ctx, cs.SitemapURL)
 		urls = filterURLs(urls, cs)
 		if cs.MaxPages > 0 && len(urls) > cs.MaxPages {
 			urls = urls[:cs.MaxPages]
 		}
-		return urls, nil
+		return urls, lastMods, nil
 	case "crawl":
 		if cs.StartURL == "" {
-			return nil, nil
+			return nil, nil, nil
 		}
-		return crawlBFS(ctx, cs)
+		urls, err := crawlBFS(ctx, cs, states)
+		return urls, nil, err
 	default:
-		return nil, nil
+		return nil, nil, nil
 	}
 }
 
-// parseSitemap parses a simple sitemap.xml (urlset) and returns URL list.
-func parseSitemap(ctx context.Context, sitemapURL string) []string {
+// parseSitemap parses a simple sitemap.xml (urlset) and returns the URL list
+// and the <lastmod> of the entries that have a valid one.
+func parseSitemap(ctx context.Context, sitemapURL string) ([]string, map[string]time.Time) {
 	visited := map[string]bool{}
 	var out []string
+	lastMods := map[string]time.Time{}
 	client := &http.Client{Timeout: 30 * time.Second}
 	var fetch func(string, int) error
 	fetch = func(url string, depth int) error {
@@ -541,7 +725,8 @@ func parseSitemap(ctx context.Context, sitemapURL string) []string {
 			return nil
 		}
 		type urlEntry struct {
-			Loc string `xml:"loc"`
+			Loc     string `xml:"loc"`
+			LastMod string `xml:"lastmod"`
 		}
 		type urlSet struct {
 			URLs []urlEntry `xml:"url"`
@@ -556,7 +741,11 @@ func parseSitemap(ctx context.Context, sitemapURL string) []string {
 		if err := xml.Unmarshal(data, &u); err == nil && len(u.URLs) > 0 {
 			for _, e := range u.URLs {
 				if e.Loc != "" {
-					out = append(out, strings.TrimSpace(e.Loc))
+					loc := strings.TrimSpace(e.Loc)
+					out = append(out, loc)
+					if t := parseLastMod(e.LastMod); t != nil {
+						lastMods[loc] = *t
+					}
 				}
 			}
 			return nil
@@ -581,19 +770,26 @@ func parseSitemap(ctx context.Context, sitemapURL string) []string {
 		return nil
 	}
 	_ = fetch(sitemapURL, 0)
-	return dedup(out)
+	return dedup(out), lastMods
+}
+
+// crawlPageLimit is the crawl's max_pages, defaulting to 200.
+func crawlPageLimit(cs *api.CrawlSpec) int {
+	if cs.MaxPages > 0 {
+		return cs.MaxPages
+	}
+	return 200
 }
 
 // crawlBFS performs a simple single-threaded BFS crawl with dedup and limits.
-func crawlBFS(ctx context.Context, cs *api.CrawlSpec) ([]string, error) {
+// Known pages are revalidated; a page that is not modified contributes the
+// links stored with its document instead of being downloaded again.
+func crawlBFS(ctx context.Context, cs *api.CrawlSpec, states fetchStates) ([]string, error) {
 	maxDepth := cs.MaxDepth
 	if maxDepth <= 0 {
 		maxDepth = 2
 	}
-	maxPages := cs.MaxPages
-	if maxPages <= 0 {
-		maxPages = 200
-	}
+	maxPages := crawlPageLimit(cs)
 
 	start := cs.StartURL
 	base, err := neturl.Parse(start)
@@ -627,17 +823,22 @@ func crawlBFS(ctx context.Context, cs *api.CrawlSpec) ([]string, error) {
 		}
 
 		// Fetch page and extract links
-		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
-		resp, err := client.Do(req)
+		st := states[u]
+		resp, err := conditionalGet(ctx, client, u, st)
 		if err != nil {
 			continue
 		}
 		body, _ := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var links []string
+		switch {
+		case resp.StatusCode == http.StatusNotModified:
+			links = st.Links
+		case resp.StatusCode < 200 || resp.StatusCode >= 300:
 			continue
+		default:
+			links = extractLinks(string(body), u)
 		}
-		links := extractLinks(string(body), u)
 		for _, link := range links {
 			if !withinScope(link, base, cs.Scope) {
 				continue
@@ -899,6 +1100,18 @@ func extractLinks(html, baseURL string) []string {
 	return dedup(out)
 }
 
+// stringList decodes a JSON array of strings, skipping other values.
+func stringList(v any) []string {
+	items, _ := v.([]any)
+	var out []string
+	for _, it := range items {
+		if s, ok := it.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
 func dedup(in []string) []string {
 	seen := map[string]struct{}{}
 	out := make([]string, 0, len(in))
@@ -912,100 +1125,6 @@ func dedup(in []string) []string {
 	return out
 }
 
-// --- Job status helpers (Redis-backed) ---
-
-func jobKey(id string) string { return "cgap:job:" + id }
-
-func markJobRunning(ctx context.Context, rdb *redis.Client, jobID, projectID string, total int) error {
-	now := time.Now().UTC().Format(time.RFC3339)
-	return rdb.HSet(ctx, jobKey(jobID), map[string]any{
-		"job_id":     jobID,
-		"project_id": projectID,
-		"status":     "running",
-		"processed":  0,
-		"total":      total,
-		"started_at": now,
-		"updated_at": now,
-	}).Err()
-}
-
-func incJobProcessed(ctx context.Context, rdb *redis.Client, jobID string, delta int) error {
-	if delta == 0 {
-		return nil
-	}
-	if err := rdb.HIncrBy(ctx, jobKey(jobID), "processed", int64(delta)).Err(); err != nil {
-		return err
-	}
-	return rdb.HSet(ctx, jobKey(jobID), "updated_at", time.Now().UTC().Format(time.RFC3339)).Err()
-}
-
-func markJobCompleted(ctx context.Context, rdb *redis.Client, jobID string) error {
-	now := time.Now().UTC().Format(time.RFC3339)
-	return rdb.HSet(ctx, jobKey(jobID), map[string]any{
-		"status":      "completed",
-		"finished_at": now,
-		"updated_at":  now,
-	}).Err()
-}
-
-func markJobFailed(ctx context.Context, rdb *redis.Client, jobID string, err error) error {
-	now := time.Now().UTC().Format(time.RFC3339)
-	return rdb.HSet(ctx, jobKey(jobID), map[string]any{
-		"status":      "failed",
-		"error":       err.Error(),
-		"finished_at": now,
-		"updated_at":  now,
-	}).Err()
-}
-
-func splitIntoParagraphs(s string) []string {
-	// split on blank lines and trim
-	blocks := strings.Split(s, "\n\n")
-	out := make([]string, 0, len(blocks))
-	for _, b := range blocks {
-		t := strings.TrimSpace(b)
-		if t != "" {
-			out = append(out, t)
-		}
-	}
-	if len(out) == 0 && strings.TrimSpace(s) != "" {
-		// fallback to single chunk
-		return []string{strings.TrimSpace(s)}
-	}
-	return out
-}
-
-func stripBasicHTML(s string) string {
-	// very naive: remove <...> tags and collapse whitespace
-	b := make([]rune, 0, len(s))
-	inTag := false
-	for _, r := range s {
-		switch r {
-		case '<':
-			inTag = true
-			continue
-		case '>':
-			inTag = false
-			continue
-		}
-		if !inTag {
-			b = append(b, r)
-		}
-	}
-	t := strings.ReplaceAll(string(b), "\r", "")
-	t = strings.ReplaceAll(t, "\t", " ")
-	// collapse multiple newlines
-	for strings.Contains(t, "\n\n\n") {
-		t = strings.ReplaceAll(t, "\n\n\n", "\n\n")
-	}
-	return strings.TrimSpace(t)
-}
-
-// looksLikeUUID reports whether s matches canonical UUID v1-5 format.
-var uuidReWorker = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[1-5][0-9a-fA-F]{3}-[89abAB][0-9a-fA-F]{3}-[0-9a-fA-F]{12}$`)
-
-func looksLikeUUID(s string) bool { return uuidReWorker.MatchString(s) }
-
 // printCGAPBanner prints the cgap startup banner with colors.
 func printCGAPBanner(mode string) {
 	const (
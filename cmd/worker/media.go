@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	neturl "net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"cgap/api"
+	"cgap/internal/embedding"
+	"cgap/internal/ingestion"
+	"cgap/internal/media"
+	"cgap/internal/model"
+	"cgap/internal/postgres"
+)
+
+// mediaWorkers bounds the media items extracted concurrently; OCR and
+// transcription APIs are slow and rate limited.
+const mediaWorkers = 2
+
+// mediaEntry is one image or video of a source, ingested as the document at uri.
+type mediaEntry struct {
+	uri    string
+	kind   string        // media type: image, video or youtube
+	upload *model.Upload // set for uploaded files, which are read from the object store
+}
+
+// isMediaSource reports whether an ingest source type is handled by syncMedia.
+func isMediaSource(sourceType string) bool {
+	switch sourceType {
+	case "image", "images", "video", "videos", "youtube":
+		return true
+	}
+	return false
+}
+
+// uploadMediaType returns the media type an upload is processed as, or ""
+// when it is a document.
+func uploadMediaType(contentType string) string {
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		return "image"
+	case strings.HasPrefix(contentType, "video/"), strings.HasPrefix(contentType, "audio/"):
+		return "video"
+	}
+	return ""
+}
+
+// mediaEntries lists the items of an image, video or youtube source:
+// source.url, media.urls and media.youtube_ids as watch URLs. YouTube URLs
+// are ingested from their captions whatever the source type.
+func mediaEntries(src api.SourceSpec) []mediaEntry {
+	kind := "video"
+	if src.Type == "image" || src.Type == "images" {
+		kind = "image"
+	}
+	var urls []string
+	if src.URL != "" {
+		urls = append(urls, src.URL)
+	}
+	if src.Media != nil {
+		urls = append(urls, src.Media.URLs...)
+		for _, id := range src.Media.YouTubeIDs {
+			urls = append(urls, "https://www.youtube.com/watch?v="+neturl.QueryEscape(strings.TrimSpace(id)))
+		}
+	}
+
+	seen := make(map[string]bool, len(urls))
+	var entries []mediaEntry
+	for _, u := range urls {
+		u = strings.TrimSpace(u)
+		if u == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+		k := kind
+		if isYouTubeURL(u) {
+			k = "youtube"
+		}
+		entries = append(entries, mediaEntry{uri: u, kind: k})
+	}
+	return entries
+}
+
+// isYouTubeURL reports whether u points to youtube.com or youtu.be.
+func isYouTubeURL(u string) bool {
+	parsed, err := neturl.Parse(u)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "m.")
+	return host == "youtube.com" || host == "youtu.be"
+}
+
+// syncMedia ingests media items as one document each. OCR text is chunked
+// as Markdown; transcripts are chunked along their timed segments, so search
+// results and citations can link to the moment a passage is spoken.
+func syncMedia(ctx context.Context, store *postgres.Store, emb embedding.Embedder, chunker *ingestion.MarkdownChunker, idx *chunkIndexer, jobs *jobTracker, jobID string, project projectRef, p api.IngestTaskPayload, entries []mediaEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	proc, err := media.NewMediaOrchestrator(slog.Default())
+	if err != nil {
+		return fmt.Errorf("failed to initialize media processor: %w", err)
+	}
+	defer proc.Close()
+	if p.Source.Media != nil {
+		ctx = media.WithOCRLanguages(ctx, media.ParseLanguageHints(p.Source.Media.OCRLang)...)
+	}
+
+	slog.Info("ingest: processing media", "job_id", jobID, "items", len(entries))
+	jobs.running(ctx, jobID, project.ID, len(entries))
+
+	byURI := make(map[string]mediaEntry, len(entries))
+	uris := make([]string, len(entries))
+	for i, e := range entries {
+		byURI[e.uri] = e
+		uris[i] = e.uri
+	}
+	pool := store.Pool()
+	_, err = ingestURLs(ctx, jobs, jobID, uris, mediaWorkers, 0, p.FailFast, func(ctx context.Context, uri string) (bool, error) {
+		doc, err := extractMedia(ctx, proc, byURI[uri])
+		if err != nil {
+			return false, err
+		}
+		return syncDocument(ctx, pool, emb, chunker, idx, project, p.SourceID, p.Source.Type, doc)
+	})
+	return err
+}
+
+// extractMedia runs OCR or transcription on one media item.
+func extractMedia(ctx context.Context, proc *media.MediaOrchestrator, e mediaEntry) (sourceDocument, error) {
+	item := &media.MediaItem{
+		Type:      e.kind,
+		URL:       e.uri,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	title := ""
+	if u, err := neturl.Parse(e.uri); err == nil && e.kind != "youtube" {
+		if base := path.Base(u.Path); base != "/" && base != "." {
+			title = base
+		}
+	}
+	if e.upload != nil {
+		tmp, err := readUpload(ctx, e.upload)
+		if err != nil {
+			return sourceDocument{}, err
+		}
+		defer os.Remove(tmp)
+		item.FilePath = tmp
+		title = e.upload.Filename
+	}
+
+	content, err := proc.ProcessMediaItem(ctx, item)
+	if err != nil {
+		return sourceDocument{}, err
+	}
+	switch {
+	case strings.TrimSpace(content.Text) == "":
+		return sourceDocument{}, fmt.Errorf("no text extracted from %s", e.uri)
+	case media.IsPlaceholder(content.Text):
+		// Handlers without credentials return mock text, which must not become searchable.
+		return sourceDocument{}, fmt.Errorf("%s extraction returned placeholder text for %s; configure OCR or transcription credentials", e.kind, e.uri)
+	}
+
+	doc := sourceDocument{URI: e.uri, Title: content.Title, Text: content.Text}
+	if doc.Title == "" {
+		doc.Title = title
+	}
+	if id, _ := content.Metadata["video_id"].(string); doc.Title == "" && id != "" {
+		doc.Title = "YouTube video " + id
+	}
+	for _, seg := range content.Segments {
+		doc.Segments = append(doc.Segments, ingestion.TranscriptSegment{
+			Text:         seg.Text,
+			StartSeconds: seg.StartSeconds,
+			EndSeconds:   seg.EndSeconds,
+		})
+	}
+	return doc, nil
+}
+
+// readUpload copies an uploaded file to a temporary file, keeping its
+// extension for the OCR and transcription tools. The caller removes it.
+func readUpload(ctx context.Context, upload *model.Upload) (string, error) {
+	rc, err := blobs.Get(ctx, upload.StorageKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read upload: %w", err)
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp("", "upload-*"+path.Ext(upload.Filename))
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	if _, err := io.Copy(tmp, rc); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to read upload: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	return tmp.Name(), nil
+}
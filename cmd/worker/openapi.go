@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"cgap/api"
+	"cgap/internal/embedding"
+	"cgap/internal/ingestion"
+	"cgap/internal/model"
+	"cgap/internal/postgres"
+)
+
+// maxOpenAPISpecSize bounds the spec download; larger documents are rejected.
+const maxOpenAPISpecSize = 20 << 20
+
+// openAPIWorkers bounds the operations embedded concurrently.
+const openAPIWorkers = 4
+
+// syncOpenAPI ingests an OpenAPI 3.x or Swagger 2.0 spec as one document per
+// operation, with URIs "<spec URL>#operation/<operationId>". The spec is the
+// complete list of operations, so documents of operations it no longer
+// contains are always removed.
+func syncOpenAPI(ctx context.Context, store *postgres.Store, client *http.Client, emb embedding.Embedder, chunker *ingestion.MarkdownChunker, idx *chunkIndexer, jobs *jobTracker, jobID string, project projectRef, p api.IngestTaskPayload) error {
+	specURL := p.Source.OpenAPIURL
+	if specURL == "" {
+		specURL = p.Source.URL
+	}
+	log := slog.With("job_id", jobID, "spec", specURL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, specURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json, application/yaml;q=0.9, */*;q=0.5")
+	if p.Source.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Source.Token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch openapi spec: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch openapi spec: status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxOpenAPISpecSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read openapi spec: %w", err)
+	}
+	if len(data) > maxOpenAPISpecSize {
+		return fmt.Errorf("openapi spec larger than %d bytes", maxOpenAPISpecSize)
+	}
+	spec, err := ingestion.ParseOpenAPI(data)
+	if err != nil {
+		return err
+	}
+
+	ops := spec.Operations()
+	base, _, _ := strings.Cut(specURL, "#")
+	byURI := make(map[string]ingestion.APIOperation, len(ops))
+	uris := make([]string, 0, len(ops))
+	for _, op := range ops {
+		uri := base + "#" + op.Anchor()
+		if _, dup := byURI[uri]; dup {
+			log.Warn("ingest: duplicate operationId, keeping the first", "operation_id", op.OperationID, "path", op.Path)
+			continue
+		}
+		byURI[uri] = op
+		uris = append(uris, uri)
+	}
+	log.Info("ingest: parsed openapi spec", "title", spec.Title, "version", spec.Version, "operations", len(uris))
+	jobs.running(ctx, jobID, project.ID, len(uris))
+
+	pool := store.Pool()
+	// Operations that failed are listed, so they keep their old document.
+	_, err = ingestURLs(ctx, jobs, jobID, uris, openAPIWorkers, 0, p.FailFast, func(ctx context.Context, uri string) (bool, error) {
+		op := byURI[uri]
+		return syncDocument(ctx, pool, emb, chunker, idx, project, p.SourceID, p.Source.Type, sourceDocument{
+			URI:     uri,
+			Title:   op.Title(),
+			Version: spec.Version,
+			Text:    op.Text,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	deleted, err := deleteStaleDocuments(ctx, pool, idx, project.ID, p.SourceID, uris, func(uri string) bool {
+		return strings.HasPrefix(uri, base+"#operation/")
+	})
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Info("ingest: deleted documents of removed operations", "count", deleted)
+		jobs.add(ctx, jobID, model.JobCounters{Deleted: deleted})
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"cgap/internal/queue"
+)
+
+// Pool defaults, overridable with WORKER_CONCURRENCY, WORKER_TASK_TIMEOUT and
+// WORKER_DRAIN_TIMEOUT.
+const (
+	defaultConcurrency  = 2
+	defaultTaskTimeout  = 30 * time.Minute
+	defaultDrainTimeout = 60 * time.Second
+)
+
+// bookkeepingTimeout bounds acks and job status writes made after a task's
+// context may already be cancelled.
+const bookkeepingTimeout = 10 * time.Second
+
+// poolConfig sizes the task pool and its time limits.
+type poolConfig struct {
+	Concurrency  int
+	TaskTimeout  time.Duration // per task; exceeding it counts as a failed attempt
+	DrainTimeout time.Duration // how long shutdown waits for in-flight tasks
+}
+
+// poolConfigFromEnv reads the pool settings over the defaults.
+func poolConfigFromEnv() poolConfig {
+	cfg := poolConfig{
+		Concurrency:  defaultConcurrency,
+		TaskTimeout:  defaultTaskTimeout,
+		DrainTimeout: defaultDrainTimeout,
+	}
+	if v := os.Getenv("WORKER_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.Concurrency = n
+		} else {
+			slog.Warn("Invalid WORKER_CONCURRENCY, using default", "value", v)
+		}
+	}
+	if v := os.Getenv("WORKER_TASK_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.TaskTimeout = d
+		} else {
+			slog.Warn("Invalid WORKER_TASK_TIMEOUT, using default", "value", v)
+		}
+	}
+	if v := os.Getenv("WORKER_DRAIN_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.DrainTimeout = d
+		} else {
+			slog.Warn("Invalid WORKER_DRAIN_TIMEOUT, using default", "value", v)
+		}
+	}
+	return cfg
+}
+
+// taskHandler runs one task. Returning nil acks it; an error records a failed
+// attempt that is retried with backoff or dead-lettered.
+type taskHandler func(ctx context.Context, task *queue.Task) error
+
+// errJobCancelled is the cancellation cause of a task stopped through
+// POST /v1/ingest/:job_id/cancel.
+var errJobCancelled = errors.New("job cancelled")
+
+// taskPool runs a fixed number of processors against the queue.
+type taskPool struct {
+	cfg      poolConfig
+	consumer *queue.Consumer
+	jobs     *jobTracker
+	handle   taskHandler
+
+	mu      sync.Mutex
+	running map[string]context.CancelCauseFunc // by task ID
+}
+
+func newTaskPool(cfg poolConfig, consumer *queue.Consumer, jobs *jobTracker, handle taskHandler) *taskPool {
+	return &taskPool{
+		cfg:      cfg,
+		consumer: consumer,
+		jobs:     jobs,
+		handle:   handle,
+		running:  make(map[string]context.CancelCauseFunc),
+	}
+}
+
+// Run processes tasks until ctx is cancelled, then stops taking new tasks and
+// waits up to DrainTimeout for in-flight ones. Tasks still running after that
+// are cancelled and returned to the queue without counting an attempt.
+func (p *taskPool) Run(ctx context.Context) {
+	// Task contexts outlive ctx so in-flight work can finish during the drain.
+	workCtx, abort := context.WithCancel(context.Background())
+	defer abort()
+
+	go func() {
+		if err := p.consumer.WatchCancels(workCtx, p.cancel); err != nil && workCtx.Err() == nil {
+			slog.Error("Stopped watching job cancellations", "error", err)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := range p.cfg.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.loop(ctx, workCtx, i)
+		}()
+	}
+
+	<-ctx.Done()
+	slog.Info("Draining in-flight tasks", "timeout", p.cfg.DrainTimeout)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(p.cfg.DrainTimeout):
+		slog.Warn("Drain timeout reached, requeueing in-flight tasks")
+		abort()
+		<-done
+	}
+}
+
+func (p *taskPool) loop(ctx, workCtx context.Context, id int) {
+	log := slog.With("processor", id)
+	log.Debug("Task processor started")
+	for ctx.Err() == nil {
+		task, err := p.consumer.Process(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			log.Error("Consumer error", "error", err)
+			// Avoid a hot loop while Redis is unavailable.
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		// Nil task means timeout - no task available
+		if task == nil {
+			continue
+		}
+		p.execute(workCtx, log, task)
+	}
+	log.Debug("Task processor stopped")
+}
+
+// execute runs one task under the per-task timeout and settles it in the queue.
+func (p *taskPool) execute(workCtx context.Context, log *slog.Logger, task *queue.Task) {
+	log = log.With("task_id", task.ID, "type", task.Type)
+	log.Info("Processing task", "attempt", task.Retries+1)
+
+	cancelCtx, cancelTask := context.WithCancelCause(workCtx)
+	defer cancelTask(nil)
+	p.track(task.ID, cancelTask)
+	defer p.untrack(task.ID)
+
+	// Registered before the check so a cancellation cannot slip in between.
+	var err error
+	if p.jobs.cancelRequested(cancelCtx, task.ID) {
+		cancelTask(errJobCancelled)
+		err = errJobCancelled
+	} else {
+		taskCtx, cancel := context.WithTimeout(cancelCtx, p.cfg.TaskTimeout)
+		// Keep the lease alive; a crashed worker stops renewing it and the task is redelivered.
+		stop := p.consumer.Heartbeat(taskCtx, task)
+		err = p.handle(taskCtx, task)
+		stop()
+		if err != nil && errors.Is(taskCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("task timed out after %s: %w", p.cfg.TaskTimeout, err)
+		}
+		cancel()
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), bookkeepingTimeout)
+	defer done()
+
+	switch {
+	case err == nil:
+		if err := p.consumer.Ack(ctx, task); err != nil {
+			log.Error("Failed to ack task", "error", err)
+		}
+		p.jobs.completed(ctx, task.ID)
+		log.Info("Task completed")
+
+	case errors.Is(context.Cause(cancelCtx), errJobCancelled):
+		if err := p.consumer.Ack(ctx, task); err != nil {
+			log.Error("Failed to ack task", "error", err)
+		}
+		p.jobs.cancelled(ctx, task.ID)
+		log.Info("Task cancelled")
+
+	case workCtx.Err() != nil:
+		// Interrupted by shutdown, not a failure of the task itself.
+		if err := p.consumer.Requeue(ctx, task); err != nil {
+			log.Error("Failed to requeue task", "error", err)
+			return
+		}
+		p.jobs.queued(ctx, task.ID)
+		log.Warn("Task interrupted by shutdown and requeued")
+
+	default:
+		log.Error("Task failed", "attempt", task.Retries+1, "error", err)
+		dead, qerr := p.consumer.Fail(ctx, task, err)
+		if qerr != nil {
+			log.Error("Failed to record task failure", "error", qerr)
+		}
+		if dead {
+			p.jobs.failed(ctx, task.ID, err)
+		} else {
+			p.jobs.retrying(ctx, task.ID, task.Retries+1, err)
+		}
+	}
+}
+
+func (p *taskPool) track(id string, cancel context.CancelCauseFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running[id] = cancel
+}
+
+func (p *taskPool) untrack(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.running, id)
+}
+
+// cancel stops a running task if this worker owns it.
+func (p *taskPool) cancel(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cancel, ok := p.running[id]; ok {
+		slog.Info("Cancelling task", "task_id", id)
+		cancel(errJobCancelled)
+	}
+}
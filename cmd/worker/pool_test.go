@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"cgap/internal/model"
+	"cgap/internal/queue"
+	"cgap/internal/storage"
+)
+
+// fakeJobRepo records job status changes in memory.
+type fakeJobRepo struct {
+	storage.JobRepo
+	mu        sync.Mutex
+	jobs      map[string]*model.Job
+	statuses  map[string][]string // status history by job ID
+	errors    map[string]string
+	cancelled map[string]bool // jobs with cancel_requested_at set
+}
+
+func newFakeJobRepo() *fakeJobRepo {
+	return &fakeJobRepo{
+		jobs:      map[string]*model.Job{},
+		statuses:  map[string][]string{},
+		errors:    map[string]string{},
+		cancelled: map[string]bool{},
+	}
+}
+
+func (f *fakeJobRepo) Create(ctx context.Context, j *model.Job) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.jobs[j.ID] = j
+	f.statuses[j.ID] = append(f.statuses[j.ID], model.JobQueued)
+	return nil
+}
+
+func (f *fakeJobRepo) GetByID(ctx context.Context, id string) (*model.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	job := &model.Job{ID: id}
+	if f.cancelled[id] {
+		now := time.Now()
+		job.CancelRequestedAt = &now
+	}
+	return job, nil
+}
+
+func (f *fakeJobRepo) Start(ctx context.Context, id string, total int) error {
+	return f.SetStatus(ctx, id, model.JobRunning, "")
+}
+
+func (f *fakeJobRepo) SetStatus(ctx context.Context, id, status, errMsg string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statuses[id] = append(f.statuses[id], status)
+	f.errors[id] = errMsg
+	return nil
+}
+
+func (f *fakeJobRepo) AddCounters(ctx context.Context, id string, delta model.JobCounters) error {
+	return nil
+}
+
+// status returns the last recorded status of a job and its error.
+func (f *fakeJobRepo) status(id string) (string, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	h := f.statuses[id]
+	if len(h) == 0 {
+		return "", ""
+	}
+	return h[len(h)-1], f.errors[id]
+}
+
+// newTestRedis returns a client of an in-memory Redis.
+func newTestRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return rdb
+}
+
+// testPool is a task pool on an in-memory queue whose handler is set per test.
+type testPool struct {
+	*taskPool
+	rdb      *redis.Client
+	producer *queue.Producer
+	repo     *fakeJobRepo
+}
+
+func newTestPool(t *testing.T, cfg poolConfig, handle taskHandler) *testPool {
+	t.Helper()
+	rdb := newTestRedis(t)
+	repo := newFakeJobRepo()
+	consumer := queue.NewConsumerWithOptions(rdb, queue.Options{
+		Visibility:  time.Minute,
+		MaxRetries:  3,
+		BaseBackoff: time.Minute,
+		MaxBackoff:  time.Minute,
+	})
+	return &testPool{
+		taskPool: newTaskPool(cfg, consumer, &jobTracker{rdb: rdb, repo: repo}, handle),
+		rdb:      rdb,
+		producer: queue.NewProducer(rdb),
+		repo:     repo,
+	}
+}
+
+// start runs the pool until the returned stop function is called; stop
+// returns once Run has returned.
+func (p *testPool) start(t *testing.T) (stop func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("pool did not stop")
+		}
+	}
+}
+
+func (p *testPool) enqueue(t *testing.T, id string) {
+	t.Helper()
+	if err := p.producer.Enqueue(context.Background(), queue.Task{ID: id, Type: "ingest"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+}
+
+func TestTaskPool_DrainsInFlightTasksOnShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var taskErr error
+	p := newTestPool(t, poolConfig{Concurrency: 1, TaskTimeout: time.Minute, DrainTimeout: 5 * time.Second}, func(ctx context.Context, task *queue.Task) error {
+		close(started)
+		select {
+		case <-release:
+		case <-ctx.Done():
+			taskErr = ctx.Err()
+		}
+		return taskErr
+	})
+	stop := p.start(t)
+	p.enqueue(t, "job_1")
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("Expected shutdown to wait for the in-flight task")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-stopped
+
+	if taskErr != nil {
+		t.Errorf("Expected the task to finish undisturbed, got %v", taskErr)
+	}
+	if status, _ := p.repo.status("job_1"); status != model.JobCompleted {
+		t.Errorf("Expected the drained task to complete, got %q", status)
+	}
+	ctx := context.Background()
+	if n := p.rdb.LLen(ctx, "cgap:tasks").Val() + p.rdb.LLen(ctx, "cgap:tasks:processing").Val(); n != 0 {
+		t.Errorf("Expected the task to be acked, got %d queued", n)
+	}
+}
+
+func TestTaskPool_RequeuesTasksAfterDrainTimeout(t *testing.T) {
+	started := make(chan struct{})
+	p := newTestPool(t, poolConfig{Concurrency: 1, TaskTimeout: time.Minute, DrainTimeout: 50 * time.Millisecond}, func(ctx context.Context, task *queue.Task) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	stop := p.start(t)
+	p.enqueue(t, "job_1")
+	<-started
+	stop()
+
+	if status, _ := p.repo.status("job_1"); status != model.JobQueued {
+		t.Errorf("Expected the interrupted task to be queued again, got %q", status)
+	}
+	ctx := context.Background()
+	raws := p.rdb.LRange(ctx, "cgap:tasks", 0, -1).Val()
+	if len(raws) != 1 {
+		t.Fatalf("Expected the task back in the queue, got %q", raws)
+	}
+	var task queue.Task
+	if err := json.Unmarshal([]byte(raws[0]), &task); err != nil || task.ID != "job_1" || task.Retries != 0 {
+		t.Errorf("Expected job_1 requeued without counting an attempt, got %+v (%v)", task, err)
+	}
+	if n := p.rdb.LLen(ctx, "cgap:tasks:processing").Val() + p.rdb.ZCard(ctx, "cgap:tasks:leases").Val(); n != 0 {
+		t.Errorf("Expected the task to be released, got %d entries", n)
+	}
+}
+
+func TestTaskPool_TaskTimeoutCountsAsFailedAttempt(t *testing.T) {
+	p := newTestPool(t, poolConfig{Concurrency: 1, TaskTimeout: 50 * time.Millisecond, DrainTimeout: time.Second}, func(ctx context.Context, task *queue.Task) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	p.enqueue(t, "job_1")
+	task, err := p.consumer.Process(context.Background())
+	if err != nil || task == nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	p.execute(context.Background(), slog.Default(), task)
+
+	if status, _ := p.repo.status("job_1"); status != model.JobRetrying {
+		t.Errorf("Expected the timed out task to be retried, got %q", status)
+	}
+	if _, errMsg := p.repo.status("job_1"); !strings.Contains(errMsg, "task timed out after 50ms") {
+		t.Errorf("Expected a timeout error, got %q", errMsg)
+	}
+	if n := p.rdb.ZCard(context.Background(), "cgap:tasks:delayed").Val(); n != 1 {
+		t.Errorf("Expected the task to wait for a retry, got %d delayed", n)
+	}
+}
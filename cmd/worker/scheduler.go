@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"cgap/api"
+	"cgap/internal/model"
+	"cgap/internal/queue"
+	"cgap/internal/storage"
+)
+
+// defaultSchedulerInterval is how often due sources are checked, overridable
+// with SOURCE_SCHEDULER_INTERVAL ("0" disables the scheduler).
+const defaultSchedulerInterval = time.Minute
+
+// schedulerBatch bounds the sources claimed per tick.
+const schedulerBatch = 20
+
+// sourceScheduler queues re-syncs of sources whose schedule is due. Sources are
+// claimed in Postgres, so several workers can run it without double syncs.
+type sourceScheduler struct {
+	sources  storage.SourceRepo
+	jobs     storage.JobRepo
+	producer *queue.Producer
+	rdb      *redis.Client
+	interval time.Duration
+}
+
+// schedulerIntervalFromEnv reads SOURCE_SCHEDULER_INTERVAL over the default.
+func schedulerIntervalFromEnv() time.Duration {
+	v := os.Getenv("SOURCE_SCHEDULER_INTERVAL")
+	if v == "" {
+		return defaultSchedulerInterval
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		slog.Warn("Invalid SOURCE_SCHEDULER_INTERVAL, using default", "value", v)
+		return defaultSchedulerInterval
+	}
+	return d
+}
+
+// Run checks for due sources every interval until ctx is cancelled.
+func (s *sourceScheduler) Run(ctx context.Context) {
+	if s.interval <= 0 {
+		slog.Info("Source scheduler disabled")
+		return
+	}
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *sourceScheduler) tick(ctx context.Context) {
+	due, err := s.sources.ClaimDue(ctx, schedulerBatch)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("Failed to claim due sources", "error", err)
+		}
+		return
+	}
+	for _, src := range due {
+		log := slog.With("source_id", src.ID, "project_id", src.ProjectID)
+		// A slow crawl must not pile up behind itself; the next slot retries.
+		if src.LastJobStatus != "" && !model.IsTerminalJobStatus(src.LastJobStatus) {
+			log.Info("Skipping scheduled sync, previous sync still running", "job_id", src.LastJobID)
+			continue
+		}
+		payload, err := api.SourcePayload(src)
+		if err != nil {
+			log.Error("Skipping scheduled sync", "error", err)
+			continue
+		}
+		jobID, err := api.EnqueueIngest(ctx, s.producer, s.jobs, s.rdb, payload)
+		if err != nil {
+			log.Error("Failed to queue scheduled sync", "error", err)
+			continue
+		}
+		if err := s.sources.RecordSync(ctx, src.ID, jobID); err != nil {
+			log.Warn("Failed to record source sync", "job_id", jobID, "error", err)
+		}
+		log.Info("Queued scheduled sync", "job_id", jobID, "next_sync_at", src.NextSyncAt)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"cgap/internal/model"
+	"cgap/internal/queue"
+	"cgap/internal/storage"
+)
+
+// fakeSourceRepo hands out due sources and records started syncs.
+type fakeSourceRepo struct {
+	storage.SourceRepo
+	due    []*model.Source
+	synced map[string]string // job ID by source ID
+}
+
+func (f *fakeSourceRepo) ClaimDue(ctx context.Context, limit int) ([]*model.Source, error) {
+	due := f.due
+	f.due = nil
+	return due, nil
+}
+
+func (f *fakeSourceRepo) RecordSync(ctx context.Context, id, jobID string) error {
+	f.synced[id] = jobID
+	return nil
+}
+
+func TestSourceScheduler_Tick(t *testing.T) {
+	ctx := context.Background()
+	rdb := newTestRedis(t)
+	jobs := newFakeJobRepo()
+	config := map[string]any{"type": "url", "url": "https://docs.example.com"}
+	sources := &fakeSourceRepo{
+		due: []*model.Source{
+			{ID: "src_new", ProjectID: "p1", Type: "url", Config: config},
+			{ID: "src_done", ProjectID: "p1", Type: "url", Config: config, LastJobID: "job_old", LastJobStatus: model.JobCompleted},
+			{ID: "src_busy", ProjectID: "p1", Type: "url", Config: config, LastJobID: "job_busy", LastJobStatus: model.JobRunning},
+		},
+		synced: map[string]string{},
+	}
+	s := &sourceScheduler{sources: sources, jobs: jobs, producer: queue.NewProducer(rdb), rdb: rdb}
+
+	s.tick(ctx)
+
+	if _, ok := sources.synced["src_busy"]; ok {
+		t.Error("Expected a source with a running sync to be skipped")
+	}
+	if len(sources.synced) != 2 {
+		t.Fatalf("Expected syncs of the two idle sources, got %v", sources.synced)
+	}
+	consumer := queue.NewConsumer(rdb)
+	for _, id := range []string{"src_new", "src_done"} {
+		jobID := sources.synced[id]
+		job := jobs.jobs[jobID]
+		if job == nil || job.SourceID != id || job.ProjectID != "p1" {
+			t.Errorf("%s: expected a recorded job, got %+v", id, job)
+		}
+		task, err := consumer.Process(ctx)
+		if err != nil || task == nil {
+			t.Fatalf("%s: expected a queued task, got %v (%v)", id, task, err)
+		}
+		if task.ID != jobID || task.Type != "ingest" {
+			t.Errorf("%s: expected ingest task %s, got %+v", id, jobID, task)
+		}
+	}
+	if n := rdb.LLen(ctx, "cgap:tasks").Val(); n != 0 {
+		t.Errorf("Expected no other queued tasks, got %d", n)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"cgap/api"
+	"cgap/internal/embedding"
+	"cgap/internal/ingestion"
+	"cgap/internal/objectstore"
+	"cgap/internal/postgres"
+)
+
+// blobs holds the content of uploaded files; nil when no object store is configured.
+var blobs objectstore.Store
+
+// syncUpload ingests a file from POST /v1/uploads as one document with URI
+// upload://<id>/<filename>. The filename is the title unless the file has one.
+// Zip and tar uploads are ingested as archives, images and videos as media.
+func syncUpload(ctx context.Context, store *postgres.Store, emb embedding.Embedder, chunker *ingestion.MarkdownChunker, idx *chunkIndexer, jobs *jobTracker, jobID string, project projectRef, p api.IngestTaskPayload) error {
+	if blobs == nil {
+		return errors.New("object store not configured")
+	}
+	upload, err := store.Uploads().GetByID(ctx, p.Source.UploadID)
+	if err != nil {
+		return err
+	}
+	if upload.ProjectID != project.ID {
+		return fmt.Errorf("upload %s belongs to another project", upload.ID)
+	}
+	if archiveContentTypes[upload.ContentType] {
+		return syncArchive(ctx, store, nil, emb, chunker, idx, jobs, jobID, project, p)
+	}
+	if kind := uploadMediaType(upload.ContentType); kind != "" {
+		return syncMedia(ctx, store, emb, chunker, idx, jobs, jobID, project, p, []mediaEntry{{uri: upload.URI(), kind: kind, upload: upload}})
+	}
+	slog.Info("ingest: processing upload", "job_id", jobID, "upload_id", upload.ID, "filename", upload.Filename, "content_type", upload.ContentType)
+	jobs.running(ctx, jobID, project.ID, 1)
+
+	pool := store.Pool()
+	_, err = ingestURLs(ctx, jobs, jobID, []string{upload.URI()}, 1, 0, p.FailFast, func(ctx context.Context, uri string) (bool, error) {
+		rc, err := blobs.Get(ctx, upload.StorageKey)
+		if err != nil {
+			return false, fmt.Errorf("failed to read upload: %w", err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return false, fmt.Errorf("failed to read upload: %w", err)
+		}
+
+		doc, err := extractors.Extract(ctx, fileFormat(p.Source), uri, upload.ContentType, data)
+		if err != nil {
+			return false, err
+		}
+		title := doc.Title
+		if title == "" {
+			title = upload.Filename
+		}
+		return syncDocument(ctx, pool, emb, chunker, idx, project, p.SourceID, p.Source.Type, sourceDocument{
+			URI:   uri,
+			Title: title,
+			Text:  doc.Text,
+		})
+	})
+	return err
+}
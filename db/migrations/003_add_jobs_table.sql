@@ -0,0 +1,51 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- jobs table: Durable history of ingest jobs
+-- Redis (cgap:job:<id>) holds live progress for 24h; this table keeps it for good
+CREATE TABLE IF NOT EXISTS jobs (
+  -- Job ids are generated by the API, e.g. job_<project>_<unix nanos>
+  id text PRIMARY KEY,
+  project_id uuid NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  type text NOT NULL DEFAULT 'ingest',
+  status text NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'retrying', 'completed', 'failed', 'cancelled')),
+  -- The enqueued task payload (source, chunking options)
+  payload jsonb,
+  -- Progress counters
+  processed int NOT NULL DEFAULT 0,
+  total int NOT NULL DEFAULT 0,
+  unchanged int NOT NULL DEFAULT 0,
+  deleted int NOT NULL DEFAULT 0,
+  failed int NOT NULL DEFAULT 0,
+  retries int NOT NULL DEFAULT 0,
+  error text,
+  cancel_requested_at timestamptz,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  started_at timestamptz,
+  finished_at timestamptz,
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX jobs_project_created ON jobs(project_id, created_at DESC);
+CREATE INDEX jobs_status ON jobs(status);
+
+-- job_url_errors table: Per-URL failures of a job
+CREATE TABLE IF NOT EXISTS job_url_errors (
+  id bigserial PRIMARY KEY,
+  job_id text NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+  url text NOT NULL,
+  error text NOT NULL,
+  occurred_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX job_url_errors_job ON job_url_errors(job_id, occurred_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS job_url_errors CASCADE;
+DROP TABLE IF EXISTS jobs CASCADE;
+
+-- +goose StatementEnd
@@ -0,0 +1,60 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- sources: ingest sources managed through /v1/sources and re-synced on a schedule
+-- config holds the ingest SourceSpec, so any ingest source type is allowed
+ALTER TABLE sources DROP CONSTRAINT IF EXISTS sources_type_check;
+ALTER TABLE sources
+  ADD COLUMN IF NOT EXISTS name text,
+  -- Schedule as given by the user (@hourly, @daily, @weekly or a duration such as 6h)
+  ADD COLUMN IF NOT EXISTS schedule text,
+  -- Parsed schedule; 0 means the source is only synced on demand
+  ADD COLUMN IF NOT EXISTS sync_interval_seconds int NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS next_sync_at timestamptz,
+  ADD COLUMN IF NOT EXISTS last_synced_at timestamptz,
+  ADD COLUMN IF NOT EXISTS last_job_id text,
+  -- Ingest options applied to every sync
+  ADD COLUMN IF NOT EXISTS chunk_strategy text,
+  ADD COLUMN IF NOT EXISTS chunk_size_token int NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS full_sync boolean NOT NULL DEFAULT false;
+
+-- status: active sources are scheduled, paused ones are not
+UPDATE sources SET status = 'active' WHERE status IS NULL OR status = 'pending';
+ALTER TABLE sources ALTER COLUMN status SET DEFAULT 'active';
+
+CREATE INDEX IF NOT EXISTS sources_project ON sources(project_id);
+CREATE INDEX IF NOT EXISTS sources_due ON sources(next_sync_at) WHERE status = 'active' AND sync_interval_seconds > 0;
+
+-- jobs started for a source
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS source_id uuid REFERENCES sources(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS jobs_source ON jobs(source_id, created_at DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS jobs_source;
+ALTER TABLE jobs DROP COLUMN IF EXISTS source_id;
+DROP INDEX IF EXISTS sources_due;
+DROP INDEX IF EXISTS sources_project;
+ALTER TABLE sources ALTER COLUMN status SET DEFAULT 'pending';
+ALTER TABLE sources
+  DROP COLUMN IF EXISTS full_sync,
+  DROP COLUMN IF EXISTS chunk_size_token,
+  DROP COLUMN IF EXISTS chunk_strategy,
+  DROP COLUMN IF EXISTS last_job_id,
+  DROP COLUMN IF EXISTS last_synced_at,
+  DROP COLUMN IF EXISTS next_sync_at,
+  DROP COLUMN IF EXISTS sync_interval_seconds,
+  DROP COLUMN IF EXISTS schedule,
+  DROP COLUMN IF EXISTS name;
+
+-- Sources of types the baseline does not know cannot be kept under its check;
+-- their documents are unlinked like those of any deleted source.
+DELETE FROM sources
+WHERE type IS NOT NULL AND type NOT IN ('crawl','github','openapi','slack','discord','upload');
+ALTER TABLE sources ADD CONSTRAINT sources_type_check
+  CHECK (type IN ('crawl','github','openapi','slack','discord','upload'));
+
+-- +goose StatementEnd
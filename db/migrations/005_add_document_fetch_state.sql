@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- documents: what the last fetch of a crawled page returned, so recrawls can
+-- send conditional requests and skip pages that did not change
+ALTER TABLE documents
+  -- Validators from the response, sent back as If-None-Match / If-Modified-Since
+  ADD COLUMN IF NOT EXISTS etag text,
+  ADD COLUMN IF NOT EXISTS last_modified text,
+  -- <lastmod> of the page in its sitemap when it was last fetched
+  ADD COLUMN IF NOT EXISTS sitemap_lastmod timestamptz,
+  -- Links found on the page, followed by crawls when the page is not modified
+  ADD COLUMN IF NOT EXISTS links text[],
+  ADD COLUMN IF NOT EXISTS fetched_at timestamptz;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE documents
+  DROP COLUMN IF EXISTS fetched_at,
+  DROP COLUMN IF EXISTS links,
+  DROP COLUMN IF EXISTS sitemap_lastmod,
+  DROP COLUMN IF EXISTS last_modified,
+  DROP COLUMN IF EXISTS etag;
+
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- sources: where the last successful sync stopped, so the next one only reads
+-- what changed since (for GitHub sources, the last synced commit SHA)
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS sync_cursor text;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE sources DROP COLUMN IF EXISTS sync_cursor;
+
+-- +goose StatementEnd
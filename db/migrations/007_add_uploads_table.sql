@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- uploads table: Files uploaded through POST /v1/uploads
+-- The content lives in the object store under storage_key; ingest sources of
+-- type upload and /v1/media/process reference it by id
+CREATE TABLE IF NOT EXISTS uploads (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  project_id uuid NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  filename text NOT NULL,
+  -- Sniffed from the content, not taken from the client
+  content_type text NOT NULL,
+  size_bytes bigint NOT NULL,
+  sha256 text NOT NULL,
+  storage_key text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX uploads_project_created ON uploads(project_id, created_at DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS uploads CASCADE;
+
+-- +goose StatementEnd
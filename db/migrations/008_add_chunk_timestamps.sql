@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- chunks: position of transcript chunks within their video, so citations can
+-- link to the moment a passage is spoken; NULL for text documents
+ALTER TABLE chunks
+  ADD COLUMN IF NOT EXISTS start_seconds int,
+  ADD COLUMN IF NOT EXISTS end_seconds int;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE chunks
+  DROP COLUMN IF EXISTS end_seconds,
+  DROP COLUMN IF EXISTS start_seconds;
+
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- documents: chunker settings the stored chunks were made with, so a recrawl
+-- with another strategy or size fetches pages again instead of revalidating
+ALTER TABLE documents
+  ADD COLUMN IF NOT EXISTS chunk_settings text;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE documents
+  DROP COLUMN IF EXISTS chunk_settings;
+
+-- +goose StatementEnd
@@ -0,0 +1,254 @@
+package ingestion
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Archive errors; limit errors wrap ErrArchiveTooLarge.
+var (
+	ErrUnsupportedArchive = errors.New("not a zip or tar archive")
+	ErrUnsafeArchivePath  = errors.New("unsafe path in archive")
+	ErrArchiveTooLarge    = errors.New("archive exceeds limits")
+)
+
+// ArchiveLimits bound what ReadArchive unpacks, so a small archive cannot
+// expand into an unbounded amount of data (a zip or gzip bomb).
+type ArchiveLimits struct {
+	MaxFiles     int   // files passed to the callback; default 10000
+	MaxEntries   int   // entries of any kind, including skipped ones; default 100000
+	MaxFileSize  int64 // uncompressed bytes of one file; default 50 MiB
+	MaxTotalSize int64 // uncompressed bytes read in total; default 1 GiB
+}
+
+// DefaultArchiveLimits returns the limits used for zero fields.
+func DefaultArchiveLimits() ArchiveLimits {
+	return ArchiveLimits{MaxFiles: 10000, MaxEntries: 100000, MaxFileSize: 50 << 20, MaxTotalSize: 1 << 30}
+}
+
+func (l ArchiveLimits) withDefaults() ArchiveLimits {
+	d := DefaultArchiveLimits()
+	if l.MaxFiles <= 0 {
+		l.MaxFiles = d.MaxFiles
+	}
+	if l.MaxEntries <= 0 {
+		l.MaxEntries = d.MaxEntries
+	}
+	if l.MaxFileSize <= 0 {
+		l.MaxFileSize = d.MaxFileSize
+	}
+	if l.MaxTotalSize <= 0 {
+		l.MaxTotalSize = d.MaxTotalSize
+	}
+	return l
+}
+
+// IsArchive reports whether data starts like a zip, tar or gzipped tar
+// archive. DOCX files are zips too; callers check for them first.
+func IsArchive(data []byte) bool {
+	return archiveKind(data) != ""
+}
+
+func archiveKind(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return "zip"
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return "tar.gz"
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		return "tar"
+	}
+	return ""
+}
+
+// ReadArchive walks the regular files of a zip, tar or tar.gz archive of the
+// given size. want selects files by their cleaned slash-separated path; fn is
+// called with the content of each selected file. Symlinks and other special
+// entries are skipped. A path that is absolute or escapes the archive root
+// fails the whole archive with ErrUnsafeArchivePath, and exceeding lim fails
+// it with ErrArchiveTooLarge, whatever sizes the headers claim.
+func ReadArchive(r io.ReaderAt, size int64, lim ArchiveLimits, want func(name string) bool, fn func(name string, data []byte) error) error {
+	lim = lim.withDefaults()
+	head := make([]byte, 512)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	w := &archiveWalker{lim: lim, want: want, fn: fn}
+	switch archiveKind(head[:n]) {
+	case "zip":
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return fmt.Errorf("failed to open zip: %w", err)
+		}
+		return w.zip(zr)
+	case "tar.gz":
+		gz, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return fmt.Errorf("failed to open gzip: %w", err)
+		}
+		defer gz.Close()
+		return w.tar(tar.NewReader(gz))
+	case "tar":
+		return w.tar(tar.NewReader(io.NewSectionReader(r, 0, size)))
+	}
+	return ErrUnsupportedArchive
+}
+
+type archiveWalker struct {
+	lim            ArchiveLimits
+	want           func(string) bool
+	fn             func(string, []byte) error
+	entries, files int
+	total          int64
+}
+
+// entry counts an entry and returns its cleaned path, or "" to skip it.
+func (w *archiveWalker) entry(name string, regular bool) (string, error) {
+	w.entries++
+	if w.entries > w.lim.MaxEntries {
+		return "", fmt.Errorf("%w: more than %d entries", ErrArchiveTooLarge, w.lim.MaxEntries)
+	}
+	clean, err := CleanArchivePath(name)
+	if err != nil {
+		return "", err
+	}
+	if !regular || clean == "" || (w.want != nil && !w.want(clean)) {
+		return "", nil
+	}
+	w.files++
+	if w.files > w.lim.MaxFiles {
+		return "", fmt.Errorf("%w: more than %d files", ErrArchiveTooLarge, w.lim.MaxFiles)
+	}
+	return clean, nil
+}
+
+// read reads one file, counting what is actually decompressed against the limits.
+func (w *archiveWalker) read(name string, r io.Reader) ([]byte, error) {
+	limit := min(w.lim.MaxFileSize, w.lim.MaxTotalSize-w.total)
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if int64(len(data)) > limit {
+		if limit < w.lim.MaxFileSize {
+			return nil, fmt.Errorf("%w: more than %d bytes in total", ErrArchiveTooLarge, w.lim.MaxTotalSize)
+		}
+		return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrArchiveTooLarge, name, w.lim.MaxFileSize)
+	}
+	w.total += int64(len(data))
+	return data, nil
+}
+
+func (w *archiveWalker) zip(zr *zip.Reader) error {
+	for _, f := range zr.File {
+		name, err := w.entry(f.Name, f.Mode().IsRegular())
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		data, err := w.read(name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		if err := w.fn(name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *archiveWalker) tar(tr *tar.Reader) error {
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar: %w", err)
+		}
+		name, err := w.entry(hdr.Name, hdr.Typeflag == tar.TypeReg)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			// Skipped entries are still decompressed to reach the next header.
+			n, err := io.Copy(io.Discard, io.LimitReader(tr, w.lim.MaxTotalSize-w.total+1))
+			if err != nil {
+				return fmt.Errorf("failed to read tar: %w", err)
+			}
+			w.total += n
+			if w.total > w.lim.MaxTotalSize {
+				return fmt.Errorf("%w: more than %d bytes in total", ErrArchiveTooLarge, w.lim.MaxTotalSize)
+			}
+			continue
+		}
+		data, err := w.read(name, tr)
+		if err != nil {
+			return err
+		}
+		if err := w.fn(name, data); err != nil {
+			return err
+		}
+	}
+}
+
+// CleanArchivePath normalizes an archive entry name to a relative
+// slash-separated path ("./docs\a.md" becomes "docs/a.md"). Directories
+// clean to "". Absolute paths and paths with ".." segments are rejected,
+// since they would escape the archive root (zip slip).
+func CleanArchivePath(name string) (string, error) {
+	p := strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(p, "/") || (len(p) >= 2 && p[1] == ':') {
+		return "", fmt.Errorf("%w: %q", ErrUnsafeArchivePath, name)
+	}
+	for _, seg := range strings.Split(p, "/") {
+		if seg == ".." {
+			return "", fmt.Errorf("%w: %q", ErrUnsafeArchivePath, name)
+		}
+	}
+	if strings.HasSuffix(p, "/") {
+		return "", nil
+	}
+	p = path.Clean(p)
+	if p == "." {
+		return "", nil
+	}
+	return p, nil
+}
+
+// ArchiveURL maps the path of a file in a static site archive to the URL it
+// is served at under base: "index.html" files map to their directory and,
+// with cleanURLs, other ".html" files lose the extension.
+func ArchiveURL(base, p string, cleanURLs bool) string {
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	dir, file := path.Split(p)
+	switch {
+	case file == "index.html" || file == "index.htm":
+		file = ""
+	case cleanURLs && strings.HasSuffix(file, ".html"):
+		file = strings.TrimSuffix(file, ".html")
+	}
+	segs := strings.Split(dir+file, "/")
+	for i, s := range segs {
+		segs[i] = url.PathEscape(s)
+	}
+	return base + strings.Join(segs, "/")
+}
@@ -0,0 +1,138 @@
+package ingestion_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"strings"
+	"testing"
+
+	"cgap/internal/ingestion"
+)
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readAll(data []byte, lim ingestion.ArchiveLimits, want func(string) bool) (map[string]string, error) {
+	got := map[string]string{}
+	err := ingestion.ReadArchive(bytes.NewReader(data), int64(len(data)), lim, want, func(name string, b []byte) error {
+		got[name] = string(b)
+		return nil
+	})
+	return got, err
+}
+
+func TestReadArchive_Zip(t *testing.T) {
+	data := zipArchive(t, map[string]string{
+		"build/docs/intro/index.html": "<h1>Intro</h1>",
+		"./build/docs/setup.md":       "# Setup",
+		"build/assets/logo.png":       "png",
+		"build/docs/":                 "",
+	})
+	got, err := readAll(data, ingestion.ArchiveLimits{}, func(name string) bool { return !strings.HasSuffix(name, ".png") })
+	if err != nil {
+		t.Fatalf("ReadArchive failed: %v", err)
+	}
+	if len(got) != 2 || got["build/docs/intro/index.html"] != "<h1>Intro</h1>" || got["build/docs/setup.md"] != "# Setup" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestReadArchive_TarGz(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range []struct {
+		name, body string
+		typ        byte
+	}{
+		{"site/", "", tar.TypeDir},
+		{"site/index.html", "<p>home</p>", tar.TypeReg},
+		{"site/link.html", "", tar.TypeSymlink},
+	} {
+		hdr := &tar.Header{Name: f.name, Typeflag: f.typ, Size: int64(len(f.body)), Mode: 0o644, Linkname: "/etc/passwd"}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = tw.Write([]byte(f.body))
+	}
+	_ = tw.Close()
+	_ = gz.Close()
+
+	if !ingestion.IsArchive(buf.Bytes()) {
+		t.Error("IsArchive(tar.gz) = false")
+	}
+	got, err := readAll(buf.Bytes(), ingestion.ArchiveLimits{}, nil)
+	if err != nil {
+		t.Fatalf("ReadArchive failed: %v", err)
+	}
+	if len(got) != 1 || got["site/index.html"] != "<p>home</p>" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestReadArchive_ZipSlip(t *testing.T) {
+	for _, name := range []string{"../evil.md", "docs/../../evil.md", "/etc/passwd", `..\evil.md`, "C:/evil.md"} {
+		data := zipArchive(t, map[string]string{"ok.md": "fine", name: "evil"})
+		if _, err := readAll(data, ingestion.ArchiveLimits{}, nil); !errors.Is(err, ingestion.ErrUnsafeArchivePath) {
+			t.Errorf("%q: err = %v, want ErrUnsafeArchivePath", name, err)
+		}
+	}
+}
+
+func TestReadArchive_Limits(t *testing.T) {
+	// 1 MiB of zeros compresses to about 1 KiB
+	bomb := zipArchive(t, map[string]string{"a.txt": strings.Repeat("\x00", 1<<20)})
+	if len(bomb) > 16<<10 {
+		t.Fatalf("test archive unexpectedly large: %d bytes", len(bomb))
+	}
+	if _, err := readAll(bomb, ingestion.ArchiveLimits{MaxFileSize: 64 << 10}, nil); !errors.Is(err, ingestion.ErrArchiveTooLarge) {
+		t.Errorf("file size: err = %v, want ErrArchiveTooLarge", err)
+	}
+
+	many := zipArchive(t, map[string]string{"a.md": "aaaa", "b.md": "bbbb", "c.md": "cccc"})
+	if _, err := readAll(many, ingestion.ArchiveLimits{MaxTotalSize: 10}, nil); !errors.Is(err, ingestion.ErrArchiveTooLarge) {
+		t.Errorf("total size: err = %v, want ErrArchiveTooLarge", err)
+	}
+	if _, err := readAll(many, ingestion.ArchiveLimits{MaxFiles: 2}, nil); !errors.Is(err, ingestion.ErrArchiveTooLarge) {
+		t.Errorf("file count: err = %v, want ErrArchiveTooLarge", err)
+	}
+
+	if _, err := readAll([]byte("just text"), ingestion.ArchiveLimits{}, nil); !errors.Is(err, ingestion.ErrUnsupportedArchive) {
+		t.Errorf("plain text: err = %v, want ErrUnsupportedArchive", err)
+	}
+}
+
+func TestArchiveURL(t *testing.T) {
+	for _, tc := range []struct {
+		path  string
+		clean bool
+		want  string
+	}{
+		{"index.html", false, "https://docs.example.com/"},
+		{"docs/intro/index.html", false, "https://docs.example.com/docs/intro/"},
+		{"docs/setup.html", false, "https://docs.example.com/docs/setup.html"},
+		{"docs/setup.html", true, "https://docs.example.com/docs/setup"},
+		{"Team Space/Getting Started.md", true, "https://docs.example.com/Team%20Space/Getting%20Started.md"},
+	} {
+		if got := ingestion.ArchiveURL("https://docs.example.com", tc.path, tc.clean); got != tc.want {
+			t.Errorf("ArchiveURL(%q, %v) = %q, want %q", tc.path, tc.clean, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package ingestion
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"cgap/internal/model"
+)
+
+// Chunk strategies accepted in IngestRequest.ChunkStrategy.
+const (
+	// ChunkStrategyHeading starts a new chunk at every heading and packs the
+	// section's blocks up to the target size. It is the default.
+	ChunkStrategyHeading = "heading"
+	// ChunkStrategySemantic is accepted as an alias of ChunkStrategyHeading.
+	ChunkStrategySemantic = "semantic"
+	// ChunkStrategyFixed packs blocks to the target size across headings;
+	// section paths are still recorded.
+	ChunkStrategyFixed = "fixed"
+)
+
+// Chunk sizing defaults and bounds, in estimated tokens.
+const (
+	DefaultChunkTokens = 400
+	MinChunkTokens     = 64
+	MaxChunkTokens     = 2048
+)
+
+// sectionSeparator joins heading titles in Chunk.SectionPath.
+const sectionSeparator = " > "
+
+// ValidChunkStrategy reports whether s is a supported strategy ("" means default).
+func ValidChunkStrategy(s string) bool {
+	switch s {
+	case "", ChunkStrategyHeading, ChunkStrategySemantic, ChunkStrategyFixed:
+		return true
+	}
+	return false
+}
+
+// EstimateTokens approximates the token count of s (~4 characters per token).
+func EstimateTokens(s string) int {
+	return (utf8.RuneCountInString(s) + 3) / 4
+}
+
+// MarkdownChunker splits Markdown (or HTML converted with HTMLToMarkdown) into
+// chunks that follow the heading hierarchy. Fenced code blocks and tables are
+// never split; paragraphs are packed to the target size with a sentence-level
+// overlap between consecutive chunks of the same section.
+type MarkdownChunker struct {
+	strategy string
+	target   int
+	overlap  int
+}
+
+// NewMarkdownChunker creates a chunker for the given strategy and target size.
+// A zero size uses DefaultChunkTokens; sizes are clamped to [MinChunkTokens, MaxChunkTokens].
+// Overlap is 10% of the target size.
+func NewMarkdownChunker(strategy string, targetTokens int) *MarkdownChunker {
+	if targetTokens <= 0 {
+		targetTokens = DefaultChunkTokens
+	}
+	targetTokens = min(max(targetTokens, MinChunkTokens), MaxChunkTokens)
+	if strategy == ChunkStrategySemantic || strategy == "" {
+		strategy = ChunkStrategyHeading
+	}
+	return &MarkdownChunker{strategy: strategy, target: targetTokens, overlap: targetTokens / 10}
+}
+
+func (c *MarkdownChunker) Chunk(ctx context.Context, doc *model.Document, content string) ([]Chunk, error) {
+	return c.Split(content), nil
+}
+
+// Settings identifies the strategy and target size, e.g. "heading:400".
+func (c *MarkdownChunker) Settings() string {
+	return fmt.Sprintf("%s:%d", c.strategy, c.target)
+}
+
+// Hash fingerprints normalized content together with the chunker settings, so
+// unchanged documents can be skipped while a new strategy or size still re-chunks them.
+func (c *MarkdownChunker) Hash(content string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", c.Settings())
+	h.Write([]byte(normalizeContent(content)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizeContent drops line-ending and trailing-whitespace differences that
+// do not change the chunked text.
+func normalizeContent(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// Split chunks content; Ord is assigned sequentially from 0.
+func (c *MarkdownChunker) Split(content string) []Chunk {
+	p := packer{target: c.target, overlap: c.overlap}
+	var headings []string
+
+	for _, b := range parseBlocks(content) {
+		if b.kind == blockHeading {
+			if c.strategy == ChunkStrategyHeading {
+				p.flush(false)
+			}
+			for len(headings) >= b.level {
+				headings = headings[:len(headings)-1]
+			}
+			for len(headings) < b.level-1 {
+				headings = append(headings, "")
+			}
+			headings = append(headings, b.title)
+			p.section = joinSection(headings)
+		}
+		p.add(b)
+	}
+	p.flush(false)
+	return p.chunks
+}
+
+func joinSection(headings []string) string {
+	parts := make([]string, 0, len(headings))
+	for _, h := range headings {
+		if h != "" {
+			parts = append(parts, h)
+		}
+	}
+	return strings.Join(parts, sectionSeparator)
+}
+
+// packer accumulates blocks into chunks of roughly target tokens.
+type packer struct {
+	target  int
+	overlap int
+	section string // section path of the block being added
+
+	cur        []string
+	curTokens  int
+	curSection string
+	hasBody    bool // cur contains more than carried-over overlap
+	chunks     []Chunk
+}
+
+func (p *packer) add(b block) {
+	tokens := EstimateTokens(b.text)
+
+	// Prose larger than a chunk is split by sentences; code and tables stay whole.
+	if b.kind == blockText && tokens > p.target {
+		for _, piece := range splitProse(b.text, p.target) {
+			p.add(block{kind: blockText, text: piece})
+		}
+		return
+	}
+
+	if p.hasBody && p.curTokens+tokens > p.target {
+		p.flush(true)
+	}
+	if !p.hasBody {
+		p.curSection = p.section
+	}
+	p.cur = append(p.cur, b.text)
+	p.curTokens += tokens
+	if b.kind != blockHeading {
+		p.hasBody = true
+	}
+}
+
+// flush emits the current chunk. With carry, the tail of the chunk is kept as
+// overlap for the next one.
+func (p *packer) flush(carry bool) {
+	if !p.hasBody {
+		// Only headings (or overlap) so far: keep them for the next chunk unless the section changed.
+		if !carry {
+			p.cur, p.curTokens = nil, 0
+		}
+		return
+	}
+
+	text := strings.Join(p.cur, "\n\n")
+	p.chunks = append(p.chunks, Chunk{
+		Ord:         len(p.chunks),
+		Text:        text,
+		TokenCount:  EstimateTokens(text),
+		SectionPath: p.curSection,
+	})
+
+	p.cur, p.curTokens, p.hasBody = nil, 0, false
+	if carry && p.overlap > 0 {
+		if tail := overlapTail(text, p.overlap); tail != "" {
+			p.cur = []string{tail}
+			p.curTokens = EstimateTokens(tail)
+		}
+	}
+}
+
+// overlapTail returns the trailing sentences of a chunk's last prose block that
+// fit within budget tokens. Code and tables are never carried over.
+func overlapTail(text string, budget int) string {
+	paras := strings.Split(text, "\n\n")
+	last := paras[len(paras)-1]
+	if strings.HasPrefix(last, "```") || strings.HasPrefix(last, "~~~") || strings.HasPrefix(last, "|") || strings.HasPrefix(last, "#") {
+		return ""
+	}
+	sentences := splitSentences(last)
+	var tail []string
+	used := 0
+	for i := len(sentences) - 1; i >= 0; i-- {
+		t := EstimateTokens(sentences[i])
+		if used+t > budget {
+			break
+		}
+		used += t
+		tail = append([]string{sentences[i]}, tail...)
+	}
+	return strings.Join(tail, " ")
+}
+
+// splitProse breaks an oversized paragraph into pieces of at most target tokens,
+// on sentence boundaries where possible and word boundaries otherwise.
+func splitProse(text string, target int) []string {
+	var pieces []string
+	var cur []string
+	curTokens := 0
+	emit := func() {
+		if len(cur) > 0 {
+			pieces = append(pieces, strings.Join(cur, " "))
+			cur, curTokens = nil, 0
+		}
+	}
+	for _, s := range splitSentences(text) {
+		t := EstimateTokens(s)
+		if t > target {
+			emit()
+			pieces = append(pieces, splitWords(s, target)...)
+			continue
+		}
+		if curTokens+t > target {
+			emit()
+		}
+		cur = append(cur, s)
+		curTokens += t + 1
+	}
+	emit()
+	return pieces
+}
+
+func splitWords(text string, target int) []string {
+	var pieces []string
+	var b strings.Builder
+	for _, w := range strings.Fields(text) {
+		if b.Len() > 0 && EstimateTokens(b.String()+" "+w) > target {
+			pieces = append(pieces, b.String())
+			b.Reset()
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(w)
+	}
+	if b.Len() > 0 {
+		pieces = append(pieces, b.String())
+	}
+	return pieces
+}
+
+// splitSentences splits prose after ., ! or ? followed by whitespace.
+func splitSentences(text string) []string {
+	var out []string
+	start := 0
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '.', '!', '?':
+			if i+1 == len(runes) || runes[i+1] == ' ' || runes[i+1] == '\n' {
+				if s := strings.TrimSpace(string(runes[start : i+1])); s != "" {
+					out = append(out, s)
+				}
+				start = i + 1
+			}
+		}
+	}
+	if s := strings.TrimSpace(string(runes[start:])); s != "" {
+		out = append(out, s)
+	}
+	return out
+}
+
+type blockKind int
+
+const (
+	blockText blockKind = iota
+	blockHeading
+	blockCode
+	blockTable
+)
+
+type block struct {
+	kind  blockKind
+	text  string
+	level int    // heading level (1-6)
+	title string // heading text without markers
+}
+
+// parseBlocks splits Markdown into headings, fenced code, tables and paragraphs.
+func parseBlocks(content string) []block {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	var blocks []block
+	var para []string
+	flushPara := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, block{kind: blockText, text: strings.TrimSpace(strings.Join(para, "\n"))})
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flushPara()
+
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flushPara()
+			fence := trimmed[:3]
+			code := []string{line}
+			for i+1 < len(lines) {
+				i++
+				code = append(code, lines[i])
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+					break
+				}
+			}
+			blocks = append(blocks, block{kind: blockCode, text: strings.Join(code, "\n")})
+
+		case isTableLine(trimmed):
+			flushPara()
+			table := []string{trimmed}
+			for i+1 < len(lines) && isTableLine(strings.TrimSpace(lines[i+1])) {
+				i++
+				table = append(table, strings.TrimSpace(lines[i]))
+			}
+			blocks = append(blocks, block{kind: blockTable, text: strings.Join(table, "\n")})
+
+		case headingLevel(trimmed) > 0:
+			flushPara()
+			level := headingLevel(trimmed)
+			title := strings.TrimSpace(strings.TrimRight(trimmed[level:], "#"))
+			blocks = append(blocks, block{kind: blockHeading, text: trimmed, level: level, title: title})
+
+		default:
+			para = append(para, line)
+		}
+	}
+	flushPara()
+	return blocks
+}
+
+// headingLevel returns the ATX heading level of line ("## Foo" -> 2), or 0.
+func headingLevel(line string) int {
+	n := 0
+	for n < len(line) && line[n] == '#' {
+		n++
+	}
+	if n == 0 || n > 6 || n == len(line) || line[n] != ' ' {
+		return 0
+	}
+	return n
+}
+
+func isTableLine(line string) bool {
+	return strings.HasPrefix(line, "|") && strings.Count(line, "|") >= 2
+}
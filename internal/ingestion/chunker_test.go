@@ -0,0 +1,141 @@
+package ingestion_test
+
+import (
+	"strings"
+	"testing"
+
+	"cgap/internal/ingestion"
+)
+
+const guide = `# Guide
+
+Intro paragraph.
+
+## Install
+
+Run the installer.
+
+` + "```sh\nmake install\n\nmake test\n```" + `
+
+### Linux
+
+Use the package manager.
+
+## Configure
+
+| Key | Default |
+| --- | --- |
+| port | 8080 |
+`
+
+func TestMarkdownChunker_SectionPaths(t *testing.T) {
+	chunks := ingestion.NewMarkdownChunker("", 0).Split(guide)
+
+	want := []string{"Guide", "Guide > Install", "Guide > Install > Linux", "Guide > Configure"}
+	if len(chunks) != len(want) {
+		t.Fatalf("Expected %d chunks, got %d: %+v", len(want), len(chunks), chunks)
+	}
+	for i, c := range chunks {
+		if c.SectionPath != want[i] {
+			t.Errorf("chunk %d: expected section %q, got %q", i, want[i], c.SectionPath)
+		}
+		if c.Ord != i {
+			t.Errorf("chunk %d: expected ord %d, got %d", i, i, c.Ord)
+		}
+		if c.TokenCount != ingestion.EstimateTokens(c.Text) {
+			t.Errorf("chunk %d: token count %d does not match text", i, c.TokenCount)
+		}
+	}
+	if !strings.Contains(chunks[1].Text, "make install\n\nmake test") {
+		t.Errorf("Expected code block kept intact, got %q", chunks[1].Text)
+	}
+	if !strings.Contains(chunks[3].Text, "| port | 8080 |") {
+		t.Errorf("Expected table in Configure chunk, got %q", chunks[3].Text)
+	}
+}
+
+func TestMarkdownChunker_PacksToTargetWithOverlap(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("# Long\n\n")
+	for i := 0; i < 60; i++ {
+		b.WriteString("This sentence is roughly ten tokens long in total. ")
+		if i%5 == 4 {
+			b.WriteString("\n\n")
+		}
+	}
+
+	chunks := ingestion.NewMarkdownChunker(ingestion.ChunkStrategyHeading, 100).Split(b.String())
+	if len(chunks) < 3 {
+		t.Fatalf("Expected several chunks, got %d", len(chunks))
+	}
+	for i, c := range chunks {
+		// Carried-over overlap may push a chunk past the target by up to 10%.
+		if c.TokenCount > 111 {
+			t.Errorf("chunk %d exceeds target: %d tokens", i, c.TokenCount)
+		}
+		if c.SectionPath != "Long" {
+			t.Errorf("chunk %d: expected section Long, got %q", i, c.SectionPath)
+		}
+	}
+	if !strings.HasPrefix(chunks[1].Text, "This sentence") {
+		t.Errorf("Expected second chunk to start with overlap from the first, got %q", chunks[1].Text[:40])
+	}
+}
+
+func TestMarkdownChunker_NeverSplitsCode(t *testing.T) {
+	code := "```go\n" + strings.Repeat("fmt.Println(\"hello world\")\n", 100) + "```"
+	chunks := ingestion.NewMarkdownChunker("", ingestion.MinChunkTokens).Split("Before.\n\n" + code + "\n\nAfter.")
+
+	found := false
+	for _, c := range chunks {
+		if strings.Contains(c.Text, "```go") {
+			found = true
+			if !strings.Contains(c.Text, code) {
+				t.Errorf("Expected whole code block in one chunk, got %q", c.Text)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("Code block missing from chunks")
+	}
+}
+
+func TestMarkdownChunker_FixedStrategyPacksAcrossHeadings(t *testing.T) {
+	heading := ingestion.NewMarkdownChunker(ingestion.ChunkStrategyHeading, 0).Split(guide)
+	fixed := ingestion.NewMarkdownChunker(ingestion.ChunkStrategyFixed, 0).Split(guide)
+
+	if len(fixed) != 1 {
+		t.Fatalf("Expected fixed strategy to pack the short guide into 1 chunk, got %d", len(fixed))
+	}
+	if len(heading) <= len(fixed) {
+		t.Errorf("Expected heading strategy to produce more chunks than fixed")
+	}
+	if fixed[0].SectionPath != "Guide" {
+		t.Errorf("Expected section of first block, got %q", fixed[0].SectionPath)
+	}
+}
+
+func TestValidChunkStrategy(t *testing.T) {
+	for _, s := range []string{"", "heading", "semantic", "fixed"} {
+		if !ingestion.ValidChunkStrategy(s) {
+			t.Errorf("Expected %q to be valid", s)
+		}
+	}
+	if ingestion.ValidChunkStrategy("sentences") {
+		t.Error("Expected unknown strategy to be invalid")
+	}
+}
+
+func TestMarkdownChunker_Hash(t *testing.T) {
+	c := ingestion.NewMarkdownChunker("", 0)
+
+	if c.Hash("# A\r\n\r\nText  \n") != c.Hash("# A\n\nText") {
+		t.Error("Expected line endings and trailing whitespace to be ignored")
+	}
+	if c.Hash("# A\n\nText") == c.Hash("# A\n\nOther text") {
+		t.Error("Expected different content to hash differently")
+	}
+	if c.Hash("# A\n\nText") == ingestion.NewMarkdownChunker(ingestion.ChunkStrategyFixed, 0).Hash("# A\n\nText") {
+		t.Error("Expected chunker settings to be part of the hash")
+	}
+}
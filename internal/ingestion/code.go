@@ -0,0 +1,189 @@
+package ingestion
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// codeLanguages maps source file extensions to the language name used for
+// fenced code blocks and declaration detection.
+var codeLanguages = map[string]string{
+	".go":    "go",
+	".py":    "python",
+	".js":    "javascript",
+	".jsx":   "javascript",
+	".mjs":   "javascript",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".java":  "java",
+	".kt":    "kotlin",
+	".rb":    "ruby",
+	".rs":    "rust",
+	".c":     "c",
+	".h":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".hpp":   "cpp",
+	".cs":    "csharp",
+	".php":   "php",
+	".swift": "swift",
+	".scala": "scala",
+	".sh":    "bash",
+}
+
+// CodeLanguage returns the language of a source file path, or "" when the
+// extension is not a known programming language.
+func CodeLanguage(p string) string {
+	return codeLanguages[strings.ToLower(path.Ext(p))]
+}
+
+// declarationPatterns match the first line of a top-level declaration. A
+// language without an entry is split on blank lines only.
+var declarationPatterns = map[string]*regexp.Regexp{
+	"go":         regexp.MustCompile(`^(func|type|var|const)\b`),
+	"python":     regexp.MustCompile(`^(async\s+def|def|class)\s`),
+	"javascript": regexp.MustCompile(`^(export\s+)?(default\s+)?(async\s+)?(function|class|const|let|var)\b`),
+	"typescript": regexp.MustCompile(`^(export\s+)?(default\s+)?(declare\s+)?(abstract\s+)?(async\s+)?(function|class|interface|type|enum|const|let|namespace)\b`),
+	"java":       regexp.MustCompile(`^\s{0,4}(public|private|protected|static|final|abstract|class|interface|enum|record)\b`),
+	"kotlin":     regexp.MustCompile(`^(public\s+|private\s+|internal\s+)?(data\s+|sealed\s+|abstract\s+|open\s+)?(fun|class|object|interface|val|var)\b`),
+	"ruby":       regexp.MustCompile(`^\s{0,2}(def|class|module)\s`),
+	"rust":       regexp.MustCompile(`^(pub(\([^)]*\))?\s+)?(async\s+)?(fn|struct|enum|trait|impl|mod|type|const|static)\b`),
+	"csharp":     regexp.MustCompile(`^\s{0,4}(public|private|protected|internal|static|class|interface|enum|struct|record)\b`),
+	"php":        regexp.MustCompile(`^(abstract\s+|final\s+)?(function|class|interface|trait)\b`),
+	"swift":      regexp.MustCompile(`^(public\s+|private\s+|internal\s+|open\s+)?(func|class|struct|enum|protocol|extension)\b`),
+	"scala":      regexp.MustCompile(`^(case\s+)?(def|class|object|trait)\b`),
+	"bash":       regexp.MustCompile(`^(function\s+\w+|\w+\s*\(\)\s*\{)`),
+}
+
+// declarationName extracts the identifier following a declaration keyword.
+var declarationName = regexp.MustCompile(`\b(?:func|type|def|class|function|interface|enum|struct|trait|impl|fn|module|object|fun|protocol|record|namespace)\s+(?:\([^)]*\)\s*)?([A-Za-z_][\w.]*)`)
+
+// SplitCode chunks source code along top-level declarations. Each chunk is a
+// fenced code block tagged with lang, packed to the target size; comments
+// directly above a declaration stay with it, and declarations larger than a
+// chunk are split between lines. SectionPath names the first declaration in
+// the chunk.
+func (c *MarkdownChunker) SplitCode(lang, code string) []Chunk {
+	var chunks []Chunk
+	var cur []string
+	curTokens := 0
+	curName := ""
+	emit := func() {
+		body := strings.Trim(strings.Join(cur, "\n"), "\n")
+		cur, curTokens = nil, 0
+		if strings.TrimSpace(body) == "" {
+			return
+		}
+		text := "```" + lang + "\n" + body + "\n```"
+		chunks = append(chunks, Chunk{
+			Ord:         len(chunks),
+			Text:        text,
+			TokenCount:  EstimateTokens(text),
+			SectionPath: curName,
+		})
+		curName = ""
+	}
+
+	for _, seg := range codeSegments(lang, code) {
+		tokens := EstimateTokens(seg.text)
+		if curTokens > 0 && curTokens+tokens > c.target {
+			emit()
+		}
+		if tokens > c.target {
+			// Oversized declaration: split between lines, keeping its name on every piece.
+			for _, piece := range splitLines(seg.text, c.target) {
+				cur, curTokens, curName = []string{piece}, EstimateTokens(piece), seg.name
+				emit()
+			}
+			continue
+		}
+		if curName == "" {
+			curName = seg.name
+		}
+		cur = append(cur, seg.text)
+		curTokens += tokens
+	}
+	emit()
+	return chunks
+}
+
+type codeSegment struct {
+	name string
+	text string
+}
+
+// codeSegments splits code before each top-level declaration, attaching the
+// comment lines directly above it.
+func codeSegments(lang, code string) []codeSegment {
+	code = strings.ReplaceAll(code, "\r\n", "\n")
+	decl := declarationPatterns[lang]
+	if decl == nil {
+		return splitOnBlankLines(code)
+	}
+	lines := strings.Split(code, "\n")
+
+	var segs []codeSegment
+	start := 0
+	name := ""
+	for i, line := range lines {
+		if !decl.MatchString(line) {
+			continue
+		}
+		// Keep leading comments with the declaration they document.
+		cut := i
+		for cut > start && isCommentLine(lines[cut-1]) {
+			cut--
+		}
+		if cut > start {
+			segs = append(segs, codeSegment{name: name, text: strings.Join(lines[start:cut], "\n")})
+			start = cut
+		}
+		name = ""
+		if m := declarationName.FindStringSubmatch(line); m != nil {
+			name = m[1]
+		}
+	}
+	return append(segs, codeSegment{name: name, text: strings.Join(lines[start:], "\n")})
+}
+
+func splitOnBlankLines(code string) []codeSegment {
+	var segs []codeSegment
+	for _, part := range strings.Split(code, "\n\n") {
+		if strings.TrimSpace(part) != "" {
+			segs = append(segs, codeSegment{text: part})
+		}
+	}
+	return segs
+}
+
+func isCommentLine(line string) bool {
+	t := strings.TrimSpace(line)
+	for _, prefix := range []string{"//", "#", "/*", "*", "--", "@"} {
+		if strings.HasPrefix(t, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitLines breaks text between lines into pieces of at most target tokens.
+// A single line longer than target is kept whole.
+func splitLines(text string, target int) []string {
+	var pieces []string
+	var cur []string
+	curTokens := 0
+	for _, line := range strings.Split(text, "\n") {
+		t := EstimateTokens(line) + 1
+		if len(cur) > 0 && curTokens+t > target {
+			pieces = append(pieces, strings.Join(cur, "\n"))
+			cur, curTokens = nil, 0
+		}
+		cur = append(cur, line)
+		curTokens += t
+	}
+	if len(cur) > 0 {
+		pieces = append(pieces, strings.Join(cur, "\n"))
+	}
+	return pieces
+}
@@ -0,0 +1,94 @@
+package ingestion_test
+
+import (
+	"strings"
+	"testing"
+
+	"cgap/internal/ingestion"
+)
+
+const goSource = `package server
+
+import "net/http"
+
+// Server serves the API.
+type Server struct {
+	mux          *http.ServeMux
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	maxBodyBytes int64
+}
+
+// Start listens on addr.
+func (s *Server) Start(addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s.mux}
+	srv.ReadTimeout = s.readTimeout
+	return srv.ListenAndServe()
+}
+`
+
+func TestSplitCode_DeclarationBoundaries(t *testing.T) {
+	// With a small target the function no longer fits next to the type.
+	chunks := ingestion.NewMarkdownChunker("", ingestion.MinChunkTokens).SplitCode("go", goSource)
+
+	want := []string{"Server", "Start"}
+	if len(chunks) != len(want) {
+		t.Fatalf("Expected %d chunks, got %d: %+v", len(want), len(chunks), chunks)
+	}
+	for i, c := range chunks {
+		if c.SectionPath != want[i] {
+			t.Errorf("chunk %d: expected section %q, got %q", i, want[i], c.SectionPath)
+		}
+		if !strings.HasPrefix(c.Text, "```go\n") || !strings.HasSuffix(c.Text, "\n```") {
+			t.Errorf("chunk %d: expected a fenced go block, got %q", i, c.Text)
+		}
+	}
+	if !strings.Contains(chunks[1].Text, "// Start listens on addr.\nfunc (s *Server) Start") {
+		t.Errorf("Expected doc comment kept with its function, got %q", chunks[1].Text)
+	}
+}
+
+func TestSplitCode_PacksSmallDeclarations(t *testing.T) {
+	chunks := ingestion.NewMarkdownChunker("", 0).SplitCode("go", goSource)
+	if len(chunks) != 1 || chunks[0].SectionPath != "Server" {
+		t.Errorf("Expected one chunk named after its first declaration, got %+v", chunks)
+	}
+}
+
+func TestCodeLanguage(t *testing.T) {
+	if ingestion.CodeLanguage("src/App.TSX") != "typescript" || ingestion.CodeLanguage("README.md") != "" {
+		t.Error("Unexpected language detection")
+	}
+}
+
+func TestRSTToMarkdown(t *testing.T) {
+	rst := `=====
+Guide
+=====
+
+Install
+-------
+
+Run::
+
+    make install
+
+.. code-block:: python
+
+   print("hi")
+`
+	md := ingestion.RSTToMarkdown(rst)
+	for _, want := range []string{"# Guide", "## Install", "Run:\n\n```\nmake install\n```", "```python\nprint(\"hi\")\n```"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected %q in:\n%s", want, md)
+		}
+	}
+}
+
+func TestMDXToMarkdown(t *testing.T) {
+	mdx := "import Tabs from '@theme/Tabs'\n\n# Setup\n\n```js\nimport x from 'y'\n```\n"
+	md := ingestion.MDXToMarkdown(mdx)
+	if strings.Contains(md, "@theme/Tabs") || !strings.Contains(md, "import x from 'y'") {
+		t.Errorf("Expected only top-level imports removed, got %q", md)
+	}
+}
@@ -0,0 +1,243 @@
+package ingestion
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxDOCXPartSize bounds the decompressed size of a part read from a DOCX
+// archive, so a small file cannot expand without limit.
+const maxDOCXPartSize = 64 << 20
+
+// ExtractDOCX converts a Word document to Markdown. Heading and Title
+// paragraph styles become headings, numbered and bulleted paragraphs become
+// list items, code-styled paragraphs become fenced code and tables become
+// pipe tables. The title is read from the document properties.
+func ExtractDOCX(ctx context.Context, data []byte) (*Extracted, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open docx: %w", err)
+	}
+	doc, err := readZipPart(zr, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, errors.New("not a docx file: word/document.xml missing")
+	}
+	text, err := docxToMarkdown(ctx, bytes.NewReader(doc))
+	if err != nil {
+		return nil, err
+	}
+
+	title := ""
+	if core, err := readZipPart(zr, "docProps/core.xml"); err == nil && core != nil {
+		var props struct {
+			Title string `xml:"title"`
+		}
+		if xml.Unmarshal(core, &props) == nil {
+			title = strings.TrimSpace(props.Title)
+		}
+	}
+	return &Extracted{Title: title, Text: text}, nil
+}
+
+// readZipPart returns the content of the named archive entry, or nil when
+// the archive has no such entry.
+func readZipPart(zr *zip.Reader, name string) ([]byte, error) {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(io.LimitReader(rc, maxDOCXPartSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if len(data) > maxDOCXPartSize {
+			return nil, fmt.Errorf("%s larger than %d bytes", name, maxDOCXPartSize)
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+// docxToMarkdown walks the WordprocessingML body paragraph by paragraph.
+func docxToMarkdown(ctx context.Context, r io.Reader) (string, error) {
+	dec := xml.NewDecoder(r)
+	var (
+		blocks    []string
+		code      []string // pending code-styled paragraphs
+		para      strings.Builder
+		style     string
+		listLevel = -1
+		inText    bool
+		lastList  bool // the last block is a list, so items append to it
+
+		tableDepth int
+		rows       [][]string
+		cells      []string
+		cell       []string
+	)
+	flushCode := func() {
+		if len(code) > 0 {
+			blocks = append(blocks, "```\n"+strings.Join(code, "\n")+"\n```")
+			code = nil
+			lastList = false
+		}
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to parse docx: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				para.Reset()
+				style, listLevel = "", -1
+			case "pStyle":
+				style = xmlAttr(t, "val")
+			case "numPr":
+				listLevel = max(listLevel, 0)
+			case "ilvl":
+				if n, err := strconv.Atoi(xmlAttr(t, "val")); err == nil {
+					listLevel = n
+				}
+			case "t":
+				inText = true
+			case "tab":
+				para.WriteString("\t")
+			case "br", "cr":
+				para.WriteString("\n")
+			case "tbl":
+				tableDepth++
+				if tableDepth == 1 {
+					flushCode()
+					rows = nil
+				}
+			case "tr":
+				if tableDepth == 1 {
+					cells = nil
+				}
+			case "tc":
+				if tableDepth == 1 {
+					cell = nil
+				}
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				raw := para.String()
+				text := strings.Join(strings.Fields(raw), " ")
+				switch {
+				case tableDepth > 0:
+					if text != "" {
+						cell = append(cell, text)
+					}
+				case isDOCXCodeStyle(style):
+					code = append(code, strings.TrimRight(raw, " \t"))
+				case text == "":
+				default:
+					flushCode()
+					if level := docxHeadingLevel(style); level > 0 {
+						blocks = append(blocks, heading(level, text))
+						lastList = false
+						continue
+					}
+					if listLevel < 0 && strings.Contains(strings.ToLower(style), "list") {
+						listLevel = 0
+					}
+					if listLevel < 0 {
+						blocks = append(blocks, text)
+						lastList = false
+						continue
+					}
+					item := strings.Repeat("  ", min(listLevel, 8)) + "- " + text
+					if lastList {
+						blocks[len(blocks)-1] += "\n" + item
+					} else {
+						blocks = append(blocks, item)
+					}
+					lastList = true
+				}
+			case "tc":
+				if tableDepth == 1 {
+					cells = append(cells, strings.ReplaceAll(strings.Join(cell, " "), "|", `\|`))
+				}
+			case "tr":
+				if tableDepth == 1 && len(cells) > 0 {
+					rows = append(rows, cells)
+				}
+			case "tbl":
+				tableDepth--
+				if tableDepth == 0 {
+					if table := pipeTable(rows); table != "" {
+						blocks = append(blocks, table)
+						lastList = false
+					}
+				}
+			}
+		}
+	}
+	flushCode()
+	return strings.Join(blocks, "\n\n"), nil
+}
+
+// docxHeadingLevel maps the Title and HeadingN paragraph styles to a heading
+// level, or returns 0 for other styles.
+func docxHeadingLevel(style string) int {
+	lower := strings.ToLower(strings.ReplaceAll(style, " ", ""))
+	if lower == "title" {
+		return 1
+	}
+	rest, ok := strings.CutPrefix(lower, "heading")
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(rest)
+	if err != nil || n < 1 {
+		return 0
+	}
+	return min(n, 6)
+}
+
+func isDOCXCodeStyle(style string) bool {
+	lower := strings.ToLower(style)
+	return strings.Contains(lower, "code") || strings.Contains(lower, "preformatted")
+}
+
+func xmlAttr(e xml.StartElement, local string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
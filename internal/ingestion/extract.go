@@ -0,0 +1,179 @@
+package ingestion
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Extractor names, usable as FileSpec.Format or FileSpec.Extract.
+const (
+	ExtractorHTML     = "html"
+	ExtractorPDF      = "pdf"
+	ExtractorDOCX     = "docx"
+	ExtractorMarkdown = "markdown"
+	ExtractorText     = "text"
+)
+
+// Extracted is the searchable content of a fetched file.
+type Extracted struct {
+	Title     string // from the file's metadata or <title>; may be empty
+	Text      string // Markdown
+	Extractor string // name of the extractor that produced it
+}
+
+// Extractor converts the raw bytes of one file format to Markdown.
+type Extractor interface {
+	Extract(ctx context.Context, data []byte) (*Extracted, error)
+}
+
+// ExtractorFunc adapts a function to Extractor.
+type ExtractorFunc func(ctx context.Context, data []byte) (*Extracted, error)
+
+func (f ExtractorFunc) Extract(ctx context.Context, data []byte) (*Extracted, error) {
+	return f(ctx, data)
+}
+
+// defaultExtractors backs ValidFileFormat.
+var defaultExtractors = NewExtractorRegistry()
+
+// ValidFileFormat reports whether format names a built-in extractor ("" and
+// "auto" detect the format).
+func ValidFileFormat(format string) bool {
+	return defaultExtractors.Has(format)
+}
+
+// extractorAliases maps alternative format names to registered names.
+var extractorAliases = map[string]string{
+	"md":    ExtractorMarkdown,
+	"txt":   ExtractorText,
+	"plain": ExtractorText,
+	"htm":   ExtractorHTML,
+	// PDF backends accepted by files.extract before the built-in extractors
+	"pdfium": ExtractorPDF,
+	"tika":   ExtractorPDF,
+}
+
+// ExtractorRegistry selects an Extractor for a file by explicit format,
+// magic bytes, file extension or content type, in that order.
+type ExtractorRegistry struct {
+	byName        map[string]Extractor
+	byExtension   map[string]string // ".pdf" -> name
+	byContentType map[string]string // "application/pdf" -> name
+}
+
+// NewExtractorRegistry returns a registry with the built-in extractors for
+// HTML, PDF, DOCX, Markdown and plain text.
+func NewExtractorRegistry() *ExtractorRegistry {
+	r := &ExtractorRegistry{
+		byName:        map[string]Extractor{},
+		byExtension:   map[string]string{},
+		byContentType: map[string]string{},
+	}
+	r.Register(ExtractorHTML, ExtractorFunc(extractHTML), []string{".html", ".htm", ".xhtml"}, []string{"text/html", "application/xhtml+xml"})
+	r.Register(ExtractorPDF, ExtractorFunc(ExtractPDF), []string{".pdf"}, []string{"application/pdf"})
+	r.Register(ExtractorDOCX, ExtractorFunc(ExtractDOCX), []string{".docx"}, []string{"application/vnd.openxmlformats-officedocument.wordprocessingml.document"})
+	r.Register(ExtractorMarkdown, ExtractorFunc(extractPlain), []string{".md", ".markdown", ".mdx"}, []string{"text/markdown", "text/x-markdown"})
+	r.Register(ExtractorText, ExtractorFunc(extractPlain), []string{".txt", ".text"}, []string{"text/plain"})
+	return r
+}
+
+// Register adds or replaces the extractor called name and routes the given
+// file extensions (with leading dot) and content types to it.
+func (r *ExtractorRegistry) Register(name string, e Extractor, extensions, contentTypes []string) {
+	r.byName[name] = e
+	for _, ext := range extensions {
+		r.byExtension[strings.ToLower(ext)] = name
+	}
+	for _, ct := range contentTypes {
+		r.byContentType[strings.ToLower(ct)] = name
+	}
+}
+
+// Has reports whether format names a registered extractor (or an alias of
+// one). "" and "auto" mean detection and are always valid.
+func (r *ExtractorRegistry) Has(format string) bool {
+	if format == "" || format == "auto" {
+		return true
+	}
+	_, ok := r.byName[canonicalExtractor(format)]
+	return ok
+}
+
+// ForPath returns the extractor registered for the extension of the
+// slash-separated path p.
+func (r *ExtractorRegistry) ForPath(p string) (string, bool) {
+	name, ok := r.byExtension[strings.ToLower(path.Ext(p))]
+	return name, ok
+}
+
+// Detect returns the name of the extractor for a file. format forces one
+// ("" or "auto" detects); uri and contentType are the fetched URL and the
+// Content-Type header.
+func (r *ExtractorRegistry) Detect(format, uri, contentType string, data []byte) string {
+	if name := canonicalExtractor(format); name != "" && name != "auto" {
+		return name
+	}
+	// Magic bytes win over headers: object stores often serve application/octet-stream.
+	switch {
+	case bytes.HasPrefix(data, []byte("%PDF-")):
+		return ExtractorPDF
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")) && bytes.Contains(data[:min(len(data), 4096)], []byte("word/")):
+		return ExtractorDOCX
+	}
+	if u, err := url.Parse(uri); err == nil {
+		if name, ok := r.byExtension[strings.ToLower(path.Ext(u.Path))]; ok {
+			return name
+		}
+	}
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		if name, ok := r.byContentType[mt]; ok {
+			return name
+		}
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		return ExtractorHTML
+	}
+	return ExtractorText
+}
+
+// Extract detects the format of a fetched file and extracts its content.
+func (r *ExtractorRegistry) Extract(ctx context.Context, format, uri, contentType string, data []byte) (*Extracted, error) {
+	name := r.Detect(format, uri, contentType, data)
+	e, ok := r.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("no extractor for format %q", name)
+	}
+	out, err := e.Extract(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract %s: %w", name, err)
+	}
+	out.Extractor = name
+	return out, nil
+}
+
+func canonicalExtractor(format string) string {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if name, ok := extractorAliases[format]; ok {
+		return name
+	}
+	return format
+}
+
+// extractHTML keeps the main content of a page and its <title>.
+func extractHTML(_ context.Context, data []byte) (*Extracted, error) {
+	title, text, err := ExtractHTML(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return &Extracted{Title: title, Text: text}, nil
+}
+
+// extractPlain passes Markdown and text through unchanged.
+func extractPlain(_ context.Context, data []byte) (*Extracted, error) {
+	return &Extracted{Text: string(data)}, nil
+}
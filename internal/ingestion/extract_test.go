@@ -0,0 +1,169 @@
+package ingestion_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"cgap/internal/ingestion"
+)
+
+func TestExtractorRegistry_Detect(t *testing.T) {
+	r := ingestion.NewExtractorRegistry()
+	for _, tc := range []struct {
+		format, uri, contentType, body string
+		want                           string
+	}{
+		{"", "https://x.test/guide", "text/html; charset=utf-8", "<html></html>", ingestion.ExtractorHTML},
+		{"", "https://x.test/files/a", "application/octet-stream", "%PDF-1.4 ...", ingestion.ExtractorPDF},
+		{"", "https://x.test/README.md", "text/plain", "# Title", ingestion.ExtractorMarkdown},
+		{"", "https://x.test/spec.docx?sig=1", "", "", ingestion.ExtractorDOCX},
+		{"", "https://x.test/notes", "", "just text", ingestion.ExtractorText},
+		{"md", "https://x.test/page.html", "text/html", "<p>x</p>", ingestion.ExtractorMarkdown},
+		{"tika", "https://x.test/files/a", "", "%PDF-1.4 ...", ingestion.ExtractorPDF},
+	} {
+		if got := r.Detect(tc.format, tc.uri, tc.contentType, []byte(tc.body)); got != tc.want {
+			t.Errorf("Detect(%q, %q, %q) = %q, want %q", tc.format, tc.uri, tc.contentType, got, tc.want)
+		}
+	}
+	if ingestion.ValidFileFormat("docx2") || !ingestion.ValidFileFormat("txt") || !ingestion.ValidFileFormat("auto") {
+		t.Error("ValidFileFormat accepted or rejected the wrong formats")
+	}
+}
+
+func TestExtractHTML_MainContent(t *testing.T) {
+	page := `<html><head><title>Install | Acme Docs</title></head><body>
+<div class="sidebar"><a href="/a">Intro</a><a href="/b">Install</a><a href="/c">FAQ</a></div>
+<main>
+<div class="breadcrumbs">Docs / Install</div>
+<h1>Install</h1>
+<ol><li>Download<ul><li>macOS</li><li>Linux</li></ul></li><li>Run the installer</li></ol>
+<div class="share-buttons">Tweet this</div>
+</main>
+<div id="cookie-banner">We use cookies</div>
+</body></html>`
+
+	title, md, err := ingestion.ExtractHTML(page)
+	if err != nil {
+		t.Fatalf("ExtractHTML failed: %v", err)
+	}
+	if title != "Install | Acme Docs" {
+		t.Errorf("title = %q", title)
+	}
+	if want := "# Install\n\n1. Download\n  - macOS\n  - Linux\n2. Run the installer"; md != want {
+		t.Errorf("markdown = %q, want %q", md, want)
+	}
+}
+
+func TestExtractHTML_LinkListsWithoutMain(t *testing.T) {
+	page := `<body>
+<div><a href="/1">One</a> <a href="/2">Two</a> <a href="/3">Three</a></div>
+<h1>Guide</h1><p>See <a href="/ref">the reference</a> for all options of the command line tool.</p>
+</body>`
+	title, md, err := ingestion.ExtractHTML(page)
+	if err != nil {
+		t.Fatalf("ExtractHTML failed: %v", err)
+	}
+	if title != "Guide" || strings.Contains(md, "Three") || !strings.Contains(md, "See the reference for all options") {
+		t.Errorf("title %q, markdown:\n%s", title, md)
+	}
+}
+
+func TestExtractDOCX(t *testing.T) {
+	const body = `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Setup</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Install the </w:t></w:r><w:r><w:t>agent.</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>Step one</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="1"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>Detail</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="SourceCode"/></w:pPr><w:r><w:t xml:space="preserve">  agent --start</w:t></w:r></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Flag</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Default</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>--port</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>8080</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+</w:body></w:document>`
+	const core = `<?xml version="1.0"?><cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Agent Guide</dc:title></cp:coreProperties>`
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{"word/document.xml": body, "docProps/core.xml": core} {
+		w, _ := zw.Create(name)
+		_, _ = w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := ingestion.NewExtractorRegistry().Extract(context.Background(), "", "https://x.test/guide.docx", "", buf.Bytes())
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	want := "# Setup\n\nInstall the agent.\n\n- Step one\n  - Detail\n\n```\n  agent --start\n```\n\n| Flag | Default |\n| --- | --- |\n| --port | 8080 |"
+	if out.Title != "Agent Guide" || out.Text != want || out.Extractor != ingestion.ExtractorDOCX {
+		t.Errorf("got title %q, extractor %q, text:\n%s", out.Title, out.Extractor, out.Text)
+	}
+}
+
+func TestExtractPDF_Pages(t *testing.T) {
+	data := minimalPDF("Release Notes", "Hello from page one.", "Second page text.")
+	out, err := ingestion.NewExtractorRegistry().Extract(context.Background(), "", "https://x.test/notes", "application/octet-stream", data)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if out.Title != "Release Notes" || out.Extractor != ingestion.ExtractorPDF {
+		t.Errorf("title %q, extractor %q", out.Title, out.Extractor)
+	}
+	want := "# Page 1\n\nHello from page one.\n\n# Page 2\n\nSecond page text."
+	if out.Text != want {
+		t.Errorf("text = %q, want %q", out.Text, want)
+	}
+
+	chunks := ingestion.NewMarkdownChunker("", 0).Split(out.Text)
+	if len(chunks) != 2 || chunks[1].SectionPath != "Page 2" {
+		t.Errorf("chunks = %+v", chunks)
+	}
+}
+
+func TestExtractPDF_Malformed(t *testing.T) {
+	if _, err := ingestion.ExtractPDF(context.Background(), []byte("%PDF-1.4 garbage")); err == nil {
+		t.Error("expected error for malformed pdf")
+	}
+}
+
+// minimalPDF builds a PDF with one line of Helvetica text per page.
+func minimalPDF(title string, pages ...string) []byte {
+	n := len(pages)
+	objs := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // pages, filled below
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< /Title (%s) >>", title),
+	}
+	kids := make([]string, n)
+	for i, text := range pages {
+		pageObj, contentObj := len(objs)+1, len(objs)+2
+		kids[i] = fmt.Sprintf("%d 0 R", pageObj)
+		stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		objs = append(objs,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", contentObj),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		)
+	}
+	objs[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), n)
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objs))
+	for i, o := range objs {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objs)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R /Info 4 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objs)+1, xref)
+	return b.Bytes()
+}
@@ -0,0 +1,318 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// DefaultGitHubAPIURL is the REST API base of github.com.
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// maxCompareFiles is the number of files the compare API lists; a comparison
+// at the limit may be incomplete.
+const maxCompareFiles = 300
+
+// ErrCompareIncomplete is returned by Compare when the change list cannot be
+// used for an incremental sync (history rewritten or too many files changed).
+var ErrCompareIncomplete = errors.New("github comparison incomplete")
+
+// GitHubClient reads repository trees and files through the GitHub REST API.
+// The base URL is configurable for GitHub Enterprise or a local stub.
+type GitHubClient struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewGitHubClient creates a client for the API at baseURL (DefaultGitHubAPIURL
+// when empty). token is optional for public repositories.
+func NewGitHubClient(baseURL, token string) *GitHubClient {
+	if baseURL == "" {
+		baseURL = DefaultGitHubAPIURL
+	}
+	return &GitHubClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// GitHubFile is a file (blob) in a repository tree.
+type GitHubFile struct {
+	Path string
+	SHA  string // blob SHA; changes whenever the content does
+	Size int
+}
+
+// GitHubChange is a file changed between two commits.
+type GitHubChange struct {
+	Path         string
+	SHA          string
+	Status       string // added|modified|removed|renamed|copied|changed
+	PreviousPath string // set for renamed files
+}
+
+// Removed reports whether the change leaves no file at Path.
+func (c GitHubChange) Removed() bool { return c.Status == "removed" }
+
+// WebURL returns the base URL of the web UI matching the API base, used to
+// build links to files: api.github.com maps to github.com and an Enterprise
+// "https://host/api/v3" to "https://host".
+func (c *GitHubClient) WebURL() string {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return c.baseURL
+	}
+	if u.Host == "api.github.com" {
+		return "https://github.com"
+	}
+	u.Path = strings.TrimSuffix(strings.TrimRight(u.Path, "/"), "/api/v3")
+	return strings.TrimRight(u.String(), "/")
+}
+
+// DefaultBranch returns the repository's default branch.
+func (c *GitHubClient) DefaultBranch(ctx context.Context, owner, repo string) (string, error) {
+	var out struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := c.get(ctx, repoPath(owner, repo), &out); err != nil {
+		return "", fmt.Errorf("failed to get repository: %w", err)
+	}
+	if out.DefaultBranch == "" {
+		return "", errors.New("repository has no default branch")
+	}
+	return out.DefaultBranch, nil
+}
+
+// ResolveRef returns the commit SHA a branch, tag or SHA points to.
+func (c *GitHubClient) ResolveRef(ctx context.Context, owner, repo, ref string) (string, error) {
+	var out struct {
+		SHA string `json:"sha"`
+	}
+	if err := c.get(ctx, repoPath(owner, repo)+"/commits/"+url.PathEscape(ref), &out); err != nil {
+		return "", fmt.Errorf("failed to resolve ref %s: %w", ref, err)
+	}
+	return out.SHA, nil
+}
+
+// Tree lists the files of the tree at a commit. truncated is set when GitHub
+// could not return the whole tree.
+func (c *GitHubClient) Tree(ctx context.Context, owner, repo, commit string) (files []GitHubFile, truncated bool, err error) {
+	var out struct {
+		Tree []struct {
+			Path string `json:"path"`
+			Type string `json:"type"`
+			SHA  string `json:"sha"`
+			Size int    `json:"size"`
+		} `json:"tree"`
+		Truncated bool `json:"truncated"`
+	}
+	if err := c.get(ctx, repoPath(owner, repo)+"/git/trees/"+url.PathEscape(commit)+"?recursive=1", &out); err != nil {
+		return nil, false, fmt.Errorf("failed to list tree: %w", err)
+	}
+	for _, e := range out.Tree {
+		if e.Type == "blob" {
+			files = append(files, GitHubFile{Path: e.Path, SHA: e.SHA, Size: e.Size})
+		}
+	}
+	return files, out.Truncated, nil
+}
+
+// Compare lists the files changed from base to head. It returns
+// ErrCompareIncomplete when base is no longer an ancestor of head or the list
+// may be cut off, in which case the caller should list the whole tree.
+func (c *GitHubClient) Compare(ctx context.Context, owner, repo, base, head string) ([]GitHubChange, error) {
+	var out struct {
+		Status string `json:"status"` // ahead|behind|identical|diverged
+		Files  []struct {
+			Filename         string `json:"filename"`
+			PreviousFilename string `json:"previous_filename"`
+			SHA              string `json:"sha"`
+			Status           string `json:"status"`
+		} `json:"files"`
+	}
+	err := c.get(ctx, repoPath(owner, repo)+"/compare/"+url.PathEscape(base)+"..."+url.PathEscape(head), &out)
+	var apiErr *GitHubError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusUnprocessableEntity) {
+		return nil, ErrCompareIncomplete
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare commits: %w", err)
+	}
+	if (out.Status != "ahead" && out.Status != "identical") || len(out.Files) >= maxCompareFiles {
+		return nil, ErrCompareIncomplete
+	}
+	changes := make([]GitHubChange, 0, len(out.Files))
+	for _, f := range out.Files {
+		changes = append(changes, GitHubChange{Path: f.Filename, SHA: f.SHA, Status: f.Status, PreviousPath: f.PreviousFilename})
+	}
+	return changes, nil
+}
+
+// Blob returns the content of a blob.
+func (c *GitHubClient) Blob(ctx context.Context, owner, repo, sha string) ([]byte, error) {
+	var out struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}
+	if err := c.get(ctx, repoPath(owner, repo)+"/git/blobs/"+url.PathEscape(sha), &out); err != nil {
+		return nil, fmt.Errorf("failed to get blob: %w", err)
+	}
+	if out.Encoding != "base64" {
+		return []byte(out.Content), nil
+	}
+	// GitHub wraps base64 content at 60 characters.
+	data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(out.Content, "\n", ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode blob: %w", err)
+	}
+	return data, nil
+}
+
+// GitHubError is a non-2xx response from the API.
+type GitHubError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *GitHubError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("github api: %d %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("github api: %d", e.StatusCode)
+}
+
+func (c *GitHubClient) get(ctx context.Context, p string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+p, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var body struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+		return &GitHubError{StatusCode: resp.StatusCode, Message: body.Message}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func repoPath(owner, repo string) string {
+	return "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+}
+
+// DefaultDocExtensions are the documentation formats ingested from a repository.
+var DefaultDocExtensions = []string{".md", ".mdx", ".markdown", ".rst", ".txt"}
+
+// GitHubFilter selects the repository files to ingest.
+type GitHubFilter struct {
+	Paths       []string // globs such as "docs/**" or "*.md"; empty matches every path
+	Extensions  []string // e.g. ".md"; empty uses DefaultDocExtensions
+	IncludeCode bool     // also ingest source files of known languages
+}
+
+// Match reports whether the file at p should be ingested.
+func (f GitHubFilter) Match(p string) bool {
+	if len(f.Paths) > 0 {
+		matched := false
+		for _, g := range f.Paths {
+			if MatchGlob(g, p) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return f.IsDoc(p) || (f.IncludeCode && CodeLanguage(p) != "")
+}
+
+// IsDoc reports whether p has one of the documentation extensions.
+func (f GitHubFilter) IsDoc(p string) bool {
+	exts := f.Extensions
+	if len(exts) == 0 {
+		exts = DefaultDocExtensions
+	}
+	ext := path.Ext(p)
+	for _, e := range exts {
+		if !strings.HasPrefix(e, ".") {
+			e = "." + e
+		}
+		if strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchGlob matches a slash-separated path against a glob where "*" and "?"
+// stay within one path segment and "**" matches any number of segments. A
+// pattern without "/" matches the file name in any directory.
+func MatchGlob(pattern, p string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(p))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(p, "/"))
+}
+
+func matchSegments(pat, segs []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			pat = pat[1:]
+			if len(pat) == 0 {
+				return true
+			}
+			for i := range segs {
+				if matchSegments(pat, segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], segs[0]); !ok {
+			return false
+		}
+		pat, segs = pat[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
+
+// RepoDocToMarkdown converts a documentation file to Markdown by extension.
+// Files of other types are returned unchanged.
+func RepoDocToMarkdown(p, content string) string {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".mdx":
+		return MDXToMarkdown(content)
+	case ".rst":
+		return RSTToMarkdown(content)
+	}
+	return content
+}
@@ -0,0 +1,141 @@
+package ingestion_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cgap/internal/ingestion"
+)
+
+func TestMatchGlob(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"docs/**", "docs/guide/install.md", true},
+		{"docs/**", "src/docs/a.md", false},
+		{"**/*.md", "README.md", true},
+		{"**/*.md", "a/b/c.md", true},
+		{"docs/*.md", "docs/guide/install.md", false},
+		{"*.rst", "docs/index.rst", true},
+	} {
+		if got := ingestion.MatchGlob(tc.pattern, tc.path); got != tc.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tc.pattern, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestGitHubFilter(t *testing.T) {
+	docs := ingestion.GitHubFilter{Paths: []string{"docs/**"}}
+	if !docs.Match("docs/a.mdx") || docs.Match("docs/main.go") || docs.Match("README.md") {
+		t.Error("Expected only documentation under docs/ to match")
+	}
+	code := ingestion.GitHubFilter{IncludeCode: true}
+	if !code.Match("cmd/main.go") || code.Match("logo.png") {
+		t.Error("Expected code files to match with include_code")
+	}
+}
+
+// githubStub serves a repository with one commit range through the REST paths the client uses.
+func githubStub(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	write := func(w http.ResponseWriter, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	mux.HandleFunc("GET /repos/acme/docs", func(w http.ResponseWriter, r *http.Request) {
+		write(w, map[string]any{"default_branch": "main"})
+	})
+	mux.HandleFunc("GET /repos/acme/docs/commits/main", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		write(w, map[string]any{"sha": "c2"})
+	})
+	mux.HandleFunc("GET /repos/acme/docs/git/trees/c2", func(w http.ResponseWriter, r *http.Request) {
+		write(w, map[string]any{"tree": []map[string]any{
+			{"path": "docs", "type": "tree", "sha": "t1"},
+			{"path": "docs/intro.md", "type": "blob", "sha": "b1", "size": 12},
+		}})
+	})
+	mux.HandleFunc("GET /repos/acme/docs/git/blobs/b1", func(w http.ResponseWriter, r *http.Request) {
+		write(w, map[string]any{"encoding": "base64", "content": base64.StdEncoding.EncodeToString([]byte("# Intro\n\nHi."))})
+	})
+	mux.HandleFunc("GET /repos/acme/docs/compare/{range}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("range") {
+		case "c1...c2":
+			write(w, map[string]any{"status": "ahead", "files": []map[string]any{
+				{"filename": "docs/intro.md", "status": "modified", "sha": "b1"},
+				{"filename": "docs/old.md", "status": "removed", "sha": "b0"},
+			}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			write(w, map[string]any{"message": "No common ancestor"})
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGitHubClient(t *testing.T) {
+	srv := githubStub(t)
+	client := ingestion.NewGitHubClient(srv.URL, "secret")
+	ctx := context.Background()
+
+	branch, err := client.DefaultBranch(ctx, "acme", "docs")
+	if err != nil || branch != "main" {
+		t.Fatalf("DefaultBranch = %q, %v", branch, err)
+	}
+	head, err := client.ResolveRef(ctx, "acme", "docs", branch)
+	if err != nil || head != "c2" {
+		t.Fatalf("ResolveRef = %q, %v", head, err)
+	}
+
+	files, truncated, err := client.Tree(ctx, "acme", "docs", head)
+	if err != nil || truncated {
+		t.Fatalf("Tree failed: %v (truncated %v)", err, truncated)
+	}
+	if len(files) != 1 || files[0].Path != "docs/intro.md" || files[0].SHA != "b1" {
+		t.Fatalf("Expected only the blob, got %+v", files)
+	}
+	data, err := client.Blob(ctx, "acme", "docs", "b1")
+	if err != nil || string(data) != "# Intro\n\nHi." {
+		t.Fatalf("Blob = %q, %v", data, err)
+	}
+
+	changes, err := client.Compare(ctx, "acme", "docs", "c1", head)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if len(changes) != 2 || changes[0].Removed() || !changes[1].Removed() {
+		t.Errorf("Unexpected changes %+v", changes)
+	}
+	if _, err := client.Compare(ctx, "acme", "docs", "rewritten", head); !errors.Is(err, ingestion.ErrCompareIncomplete) {
+		t.Errorf("Expected ErrCompareIncomplete for unknown base, got %v", err)
+	}
+
+	if _, err := ingestion.NewGitHubClient(srv.URL, "").ResolveRef(ctx, "acme", "docs", "main"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected 401 error without token, got %v", err)
+	}
+}
+
+func TestGitHubClient_WebURL(t *testing.T) {
+	for api, want := range map[string]string{
+		"":                                "https://github.com",
+		"https://ghe.example.com/api/v3/": "https://ghe.example.com",
+		"http://127.0.0.1:9000":           "http://127.0.0.1:9000",
+	} {
+		if got := ingestion.NewGitHubClient(api, "").WebURL(); got != want {
+			t.Errorf("WebURL(%q) = %q, want %q", api, got, want)
+		}
+	}
+}
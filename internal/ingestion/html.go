@@ -0,0 +1,406 @@
+package ingestion
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// skippedElements carry page chrome or non-text content and are dropped entirely.
+var skippedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Svg:      true,
+	atom.Nav:      true,
+	atom.Header:   true,
+	atom.Footer:   true,
+	atom.Template: true,
+	atom.Iframe:   true,
+}
+
+// HTMLToMarkdown converts the main content of an HTML page to Markdown so
+// MarkdownChunker can follow its heading structure. Headings become ATX
+// headings, <pre> becomes fenced code, lists keep their nesting and tables
+// become pipe tables; navigation, scripts, styles and other page chrome are
+// dropped.
+func HTMLToMarkdown(s string) (string, error) {
+	_, md, err := ExtractHTML(s)
+	return md, err
+}
+
+// ExtractHTML returns the <title> of an HTML page (the first <h1> when it has
+// none) and its main content as Markdown. Like readability, it keeps the
+// <main> element, or the largest <article>, when the page has one, and drops
+// blocks whose class, id or role marks them as menus, sidebars, banners or
+// similar. Without a main element, link-heavy blocks are dropped as well.
+func ExtractHTML(s string) (title, markdown string, err error) {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse html: %w", err)
+	}
+	if t := findElement(doc, atom.Title); t != nil {
+		title = inlineText(t)
+	}
+	if title == "" {
+		if h1 := findElement(doc, atom.H1); h1 != nil {
+			title = inlineText(h1)
+		}
+	}
+
+	root := doc
+	if body := findElement(doc, atom.Body); body != nil {
+		root = body
+	}
+	w := &mdWriter{}
+	if main := mainContent(root); main != nil {
+		root = main
+	} else {
+		w.pruneLinks = true
+	}
+	w.blocks(root)
+	w.endBlock()
+	return title, strings.TrimSpace(w.out.String()), nil
+}
+
+// mainContent returns the <main> (or role="main") element of a page, else
+// its article with the most text, else nil.
+func mainContent(body *html.Node) *html.Node {
+	var main, article *html.Node
+	articleLen := 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if main != nil {
+			return
+		}
+		if n.Type == html.ElementNode {
+			if n.DataAtom == atom.Main || attr(n, "role") == "main" {
+				main = n
+				return
+			}
+			if n.DataAtom == atom.Article {
+				if l := len(inlineText(n)); l > articleLen {
+					article, articleLen = n, l
+				}
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(body)
+	if main != nil {
+		return main
+	}
+	return article
+}
+
+// chromePattern matches class or id values of page chrome; contentPattern
+// matches those of content containers, which are kept regardless.
+var (
+	chromePattern  = regexp.MustCompile(`(?i)(^|[\s_-])(sidebar|menu|breadcrumbs?|comments?|footer|masthead|navbar|navigation|cookie|banner|share|social|related|advert|ads|promo|popup|modal|pagination|skip)([\s_-]|$)`)
+	contentPattern = regexp.MustCompile(`(?i)(^|[\s_-])(content|article|main|post|entry|markdown|prose|body)([\s_-]|$)`)
+)
+
+// chromeRoles are ARIA landmark roles of page chrome.
+var chromeRoles = map[string]bool{
+	"navigation":    true,
+	"banner":        true,
+	"contentinfo":   true,
+	"complementary": true,
+	"search":        true,
+	"dialog":        true,
+}
+
+// isChrome reports whether a container element is page chrome by its tag,
+// role, visibility or class and id.
+func isChrome(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Aside, atom.Form, atom.Button, atom.Dialog, atom.Select:
+		return true
+	}
+	if chromeRoles[attr(n, "role")] || attr(n, "aria-hidden") == "true" || hasAttr(n, "hidden") {
+		return true
+	}
+	switch n.DataAtom {
+	case atom.Div, atom.Section, atom.Ul, atom.Ol, atom.Span, atom.Table:
+	default:
+		return false
+	}
+	names := attr(n, "class") + " " + attr(n, "id")
+	return chromePattern.MatchString(names) && !contentPattern.MatchString(names)
+}
+
+// isLinkList reports whether most of a block's text is link text, as in
+// menus and footers of pages without a main element.
+func isLinkList(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Div, atom.Section, atom.Ul, atom.Ol:
+	default:
+		return false
+	}
+	text := len(inlineText(n))
+	if text == 0 || text > 2000 {
+		return false
+	}
+	links, linkText := 0, 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			links++
+			linkText += len(inlineText(n))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return links >= 3 && float64(linkText) > 0.7*float64(text)
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+type mdWriter struct {
+	out        strings.Builder
+	inline     strings.Builder
+	pruneLinks bool // drop link-heavy blocks (no main element was found)
+}
+
+// endBlock writes the pending inline text as a paragraph.
+func (w *mdWriter) endBlock() {
+	text := strings.Join(strings.Fields(w.inline.String()), " ")
+	w.inline.Reset()
+	if text != "" {
+		w.write(text)
+	}
+}
+
+func (w *mdWriter) write(block string) {
+	if w.out.Len() > 0 {
+		w.out.WriteString("\n\n")
+	}
+	w.out.WriteString(block)
+}
+
+func (w *mdWriter) blocks(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.node(c)
+	}
+}
+
+func (w *mdWriter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.inline.WriteString(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	if skippedElements[n.DataAtom] || isChrome(n) || (w.pruneLinks && isLinkList(n)) {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		w.endBlock()
+		level := int(n.Data[1] - '0')
+		if title := inlineText(n); title != "" {
+			w.write(strings.Repeat("#", level) + " " + title)
+		}
+	case atom.Pre:
+		w.endBlock()
+		code := strings.Trim(textContent(n), "\n")
+		if code != "" {
+			w.write("```" + codeLanguage(n) + "\n" + code + "\n```")
+		}
+	case atom.Table:
+		w.endBlock()
+		if table := markdownTable(n); table != "" {
+			w.write(table)
+		}
+	case atom.Ul, atom.Ol:
+		w.endBlock()
+		if list := markdownList(n, 0); list != "" {
+			w.write(list)
+		}
+	case atom.Li:
+		w.endBlock()
+		if item := inlineText(n); item != "" {
+			w.write("- " + item)
+		}
+	case atom.Br:
+		w.inline.WriteString("\n")
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Main,
+		atom.Blockquote, atom.Dl, atom.Dt, atom.Dd, atom.Figure, atom.Hr:
+		w.endBlock()
+		w.blocks(n)
+		w.endBlock()
+	case atom.Code:
+		w.inline.WriteString("`" + textContent(n) + "`")
+	default:
+		w.blocks(n)
+	}
+}
+
+// markdownList renders a <ul> or <ol> as list items, indenting nested lists
+// under their item.
+func markdownList(list *html.Node, depth int) string {
+	var lines []string
+	num := 0
+	for li := list.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+		num++
+		marker := "- "
+		if list.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", num)
+		}
+
+		// The item's own text, then its nested lists.
+		var text strings.Builder
+		var nested []string
+		for c := li.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (c.DataAtom == atom.Ul || c.DataAtom == atom.Ol) {
+				if sub := markdownList(c, depth+1); sub != "" {
+					nested = append(nested, sub)
+				}
+				continue
+			}
+			text.WriteString(textContent(c))
+			text.WriteString(" ")
+		}
+		item := strings.Join(strings.Fields(text.String()), " ")
+		if item == "" && len(nested) == 0 {
+			continue
+		}
+		lines = append(lines, strings.Repeat("  ", depth)+marker+item)
+		lines = append(lines, nested...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inlineText is the whitespace-collapsed text of n, excluding skipped elements.
+func inlineText(n *html.Node) string {
+	return strings.Join(strings.Fields(textContent(n)), " ")
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			return
+		}
+		if n.Type == html.ElementNode && skippedElements[n.DataAtom] {
+			return
+		}
+		if n.Type == html.ElementNode && n.DataAtom == atom.Br {
+			b.WriteString("\n")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// codeLanguage reads a "language-xxx" class from <pre> or its <code> child.
+func codeLanguage(pre *html.Node) string {
+	for _, n := range []*html.Node{pre, findElement(pre, atom.Code)} {
+		if n == nil {
+			continue
+		}
+		for _, a := range n.Attr {
+			if a.Key != "class" {
+				continue
+			}
+			for _, cls := range strings.Fields(a.Val) {
+				if lang, ok := strings.CutPrefix(cls, "language-"); ok {
+					return lang
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// markdownTable renders a table as pipe rows with a separator after the first row.
+func markdownTable(table *html.Node) string {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Tr {
+			var cells []string
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && (c.DataAtom == atom.Td || c.DataAtom == atom.Th) {
+					cells = append(cells, strings.ReplaceAll(inlineText(c), "|", `\|`))
+				}
+			}
+			if len(cells) > 0 {
+				rows = append(rows, cells)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(table)
+	return pipeTable(rows)
+}
+
+// pipeTable renders rows as a Markdown pipe table with the first row as header.
+func pipeTable(rows [][]string) string {
+	if len(rows) == 0 {
+		return ""
+	}
+	lines := make([]string, 0, len(rows)+1)
+	for i, cells := range rows {
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+		if i == 0 {
+			sep := make([]string, len(cells))
+			for j := range sep {
+				sep[j] = "---"
+			}
+			lines = append(lines, "| "+strings.Join(sep, " | ")+" |")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
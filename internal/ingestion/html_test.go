@@ -0,0 +1,51 @@
+package ingestion_test
+
+import (
+	"strings"
+	"testing"
+
+	"cgap/internal/ingestion"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	page := `<html><head><title>Docs</title><style>body{}</style></head><body>
+<nav><a href="/">Home</a></nav>
+<h1>API</h1>
+<p>Use the <code>token</code> header.</p>
+<h2>Limits</h2>
+<ul><li>100 requests</li><li>per minute</li></ul>
+<pre><code class="language-bash">curl -H "token: x"
+  https://example.com</code></pre>
+<table><tr><th>Plan</th><th>Limit</th></tr><tr><td>Free</td><td>100</td></tr></table>
+<script>alert(1)</script>
+<footer>Copyright</footer>
+</body></html>`
+
+	md, err := ingestion.HTMLToMarkdown(page)
+	if err != nil {
+		t.Fatalf("HTMLToMarkdown failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"# API",
+		"Use the `token` header.",
+		"## Limits",
+		"- 100 requests",
+		"```bash\ncurl -H \"token: x\"\n  https://example.com\n```",
+		"| Plan | Limit |\n| --- | --- |\n| Free | 100 |",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected %q in output:\n%s", want, md)
+		}
+	}
+	for _, unwanted := range []string{"Home", "alert", "Copyright", "body{}"} {
+		if strings.Contains(md, unwanted) {
+			t.Errorf("Expected %q to be stripped:\n%s", unwanted, md)
+		}
+	}
+
+	chunks := ingestion.NewMarkdownChunker("", 0).Split(md)
+	if len(chunks) != 2 || chunks[1].SectionPath != "API > Limits" {
+		t.Errorf("Expected chunks to follow HTML headings, got %+v", chunks)
+	}
+}
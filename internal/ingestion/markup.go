@@ -0,0 +1,143 @@
+package ingestion
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mdxStatement matches top-level MDX import/export lines, which carry no text.
+var mdxStatement = regexp.MustCompile(`^(import|export)\s`)
+
+// MDXToMarkdown drops the ESM import/export statements of an MDX file. JSX
+// elements are left in place as text; fenced code is kept as-is.
+func MDXToMarkdown(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	out := make([]string, 0, len(lines))
+	inFence := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if !inFence && mdxStatement.MatchString(line) {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// rstDirective matches a reStructuredText code directive and captures its language.
+var rstDirective = regexp.MustCompile(`^\.\.\s+(?:code-block|code|sourcecode)::\s*(\S*)`)
+
+// RSTToMarkdown converts the reStructuredText structure MarkdownChunker relies
+// on: section titles become ATX headings (levels follow the order adornment
+// styles first appear in, as in RST) and code directives and literal blocks
+// become fenced code. Inline markup is kept as-is.
+func RSTToMarkdown(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	var out []string
+	var styles []string // adornment styles in order of first use
+
+	level := func(style string) int {
+		for i, st := range styles {
+			if st == style {
+				return i + 1
+			}
+		}
+		styles = append(styles, style)
+		return len(styles)
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		// Overlined title: adornment, title, adornment.
+		if isAdornment(trimmed) && i+2 < len(lines) && strings.TrimSpace(lines[i+1]) != "" && strings.TrimSpace(lines[i+2]) == trimmed {
+			title := strings.TrimSpace(lines[i+1])
+			out = append(out, heading(min(level("over"+trimmed[:1]), 6), title))
+			i += 2
+			continue
+		}
+		// Underlined title: title, adornment at least as long.
+		if trimmed != "" && !isAdornment(trimmed) && i+1 < len(lines) {
+			next := strings.TrimSpace(lines[i+1])
+			if isAdornment(next) && len(next) >= len(trimmed) && !strings.HasPrefix(line, " ") {
+				out = append(out, heading(min(level(next[:1]), 6), trimmed))
+				i++
+				continue
+			}
+		}
+
+		// Code directive or "::" literal block: the following indented lines are code.
+		lang, isCode := "", false
+		if m := rstDirective.FindStringSubmatch(trimmed); m != nil {
+			lang, isCode = m[1], true
+		} else if strings.HasSuffix(trimmed, "::") && !strings.HasPrefix(trimmed, "..") {
+			if text := strings.TrimSpace(strings.TrimSuffix(trimmed, "::")); text != "" {
+				out = append(out, text+":")
+			}
+			isCode = true
+		}
+		if isCode {
+			j := i + 1
+			// Skip directive options and the blank line before the body.
+			for j < len(lines) && (strings.TrimSpace(lines[j]) == "" || strings.HasPrefix(strings.TrimSpace(lines[j]), ":")) {
+				j++
+			}
+			var code []string
+			for j < len(lines) && (strings.TrimSpace(lines[j]) == "" || strings.HasPrefix(lines[j], " ") || strings.HasPrefix(lines[j], "\t")) {
+				code = append(code, lines[j])
+				j++
+			}
+			if len(code) > 0 {
+				out = append(out, "", "```"+lang, dedent(strings.TrimRight(strings.Join(code, "\n"), "\n ")), "```", "")
+				i = j - 1
+				continue
+			}
+		}
+
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+func heading(level int, title string) string {
+	return strings.Repeat("#", level) + " " + title
+}
+
+// isAdornment reports whether line is an RST section adornment: one repeated
+// punctuation character, at least 3 long.
+func isAdornment(line string) bool {
+	if len(line) < 3 || !strings.ContainsRune("=-~^\"'`#*+_:.", rune(line[0])) {
+		return false
+	}
+	return strings.Count(line, line[:1]) == len(line)
+}
+
+// dedent removes the indentation common to all non-blank lines.
+func dedent(s string) string {
+	lines := strings.Split(s, "\n")
+	common := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		n := len(l) - len(strings.TrimLeft(l, " \t"))
+		if common < 0 || n < common {
+			common = n
+		}
+	}
+	if common <= 0 {
+		return s
+	}
+	for i, l := range lines {
+		if len(l) >= common {
+			lines[i] = l[common:]
+		} else {
+			lines[i] = strings.TrimLeft(l, " \t")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
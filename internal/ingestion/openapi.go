@@ -0,0 +1,569 @@
+package ingestion
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIMethods are the operation keys of a path item, in display order.
+var openAPIMethods = []string{"get", "put", "post", "patch", "delete", "options", "head", "trace"}
+
+// Rendering limits keep operation documents readable for recursive or very
+// large schemas.
+const (
+	maxSchemaDepth   = 6
+	maxExampleLength = 2000
+)
+
+// APISpec is a parsed OpenAPI 3.x or Swagger 2.0 document.
+type APISpec struct {
+	Title   string
+	Version string // info.version
+	root    map[string]any
+}
+
+// APIOperation is one operation of an API rendered as a Markdown document.
+// The document starts with "# <tag>" and "## <operationId>" headings, so its
+// chunks get section paths like "orders > createOrder".
+type APIOperation struct {
+	Method      string // upper case, e.g. POST
+	Path        string
+	OperationID string
+	Tag         string // first tag, "default" without one
+	Summary     string
+	Text        string
+}
+
+// Anchor identifies the operation within its spec, for document URIs.
+func (op APIOperation) Anchor() string {
+	if op.OperationID != "" {
+		return "operation/" + url.PathEscape(op.OperationID)
+	}
+	return "operation/" + strings.ToLower(op.Method) + url.PathEscape(op.Path)
+}
+
+// Title is a short title for the operation's document.
+func (op APIOperation) Title() string {
+	if op.Summary != "" {
+		return op.Method + " " + op.Path + " - " + op.Summary
+	}
+	return op.Method + " " + op.Path
+}
+
+// ParseOpenAPI parses an OpenAPI 3.x or Swagger 2.0 document in JSON or YAML.
+func ParseOpenAPI(data []byte) (*APISpec, error) {
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse openapi document: %w", err)
+	}
+	root, ok := normalizeYAML(raw).(map[string]any)
+	if !ok {
+		return nil, errors.New("openapi document is not an object")
+	}
+	if root["openapi"] == nil && root["swagger"] == nil {
+		return nil, errors.New("not an openapi or swagger document")
+	}
+	if _, ok := root["paths"].(map[string]any); !ok {
+		return nil, errors.New("openapi document has no paths")
+	}
+	info, _ := root["info"].(map[string]any)
+	return &APISpec{
+		Title:   str(info["title"]),
+		Version: str(info["version"]),
+		root:    root,
+	}, nil
+}
+
+// normalizeYAML converts maps with non-string keys (e.g. unquoted response
+// codes) to map[string]any so the document can be walked like JSON.
+func normalizeYAML(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, e := range t {
+			t[k] = normalizeYAML(e)
+		}
+		return t
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for k, e := range t {
+			m[fmt.Sprint(k)] = normalizeYAML(e)
+		}
+		return m
+	case []any:
+		for i, e := range t {
+			t[i] = normalizeYAML(e)
+		}
+		return t
+	}
+	return v
+}
+
+// Operations renders every operation of the spec, ordered by path and method.
+func (s *APISpec) Operations() []APIOperation {
+	paths, _ := s.root["paths"].(map[string]any)
+	keys := make([]string, 0, len(paths))
+	for p := range paths {
+		keys = append(keys, p)
+	}
+	sort.Strings(keys)
+
+	var ops []APIOperation
+	for _, p := range keys {
+		item, _ := s.resolve(paths[p]).(map[string]any)
+		if item == nil {
+			continue
+		}
+		for _, method := range openAPIMethods {
+			op, ok := item[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			ops = append(ops, s.operation(strings.ToUpper(method), p, item, op))
+		}
+	}
+	return ops
+}
+
+func (s *APISpec) operation(method, path string, item, op map[string]any) APIOperation {
+	out := APIOperation{
+		Method:      method,
+		Path:        path,
+		OperationID: str(op["operationId"]),
+		Tag:         "default",
+		Summary:     strings.TrimSpace(str(op["summary"])),
+	}
+	if tags, ok := op["tags"].([]any); ok && len(tags) > 0 && str(tags[0]) != "" {
+		out.Tag = str(tags[0])
+	}
+	name := out.OperationID
+	if name == "" {
+		name = method + " " + path
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n## %s\n\n", out.Tag, name)
+	fmt.Fprintf(&b, "`%s %s`", method, path)
+	if s.Title != "" {
+		fmt.Fprintf(&b, " (%s)", s.Title)
+	}
+	b.WriteString("\n\n")
+	if out.Summary != "" {
+		b.WriteString(out.Summary + "\n\n")
+	}
+	if d := strings.TrimSpace(str(op["description"])); d != "" {
+		b.WriteString(d + "\n\n")
+	}
+	if op["deprecated"] == true {
+		b.WriteString("Deprecated.\n\n")
+	}
+
+	// Swagger 2 describes the request body as an "in: body" parameter.
+	params, body := s.parameters(item, op)
+	if len(params) > 0 {
+		b.WriteString("**Parameters**\n\n")
+		for _, p := range params {
+			s.writeParameter(&b, p)
+		}
+		b.WriteString("\n")
+	}
+	if body != nil {
+		s.writeRequestBody(&b, body, op)
+	}
+	s.writeResponses(&b, op)
+
+	out.Text = strings.TrimSpace(b.String())
+	return out
+}
+
+// parameters merges path-level and operation-level parameters (the latter
+// win) and splits off a Swagger 2 body parameter.
+func (s *APISpec) parameters(item, op map[string]any) (params []map[string]any, body map[string]any) {
+	index := map[string]int{}
+	for _, list := range []any{item["parameters"], op["parameters"]} {
+		items, _ := list.([]any)
+		for _, raw := range items {
+			p, ok := s.resolve(raw).(map[string]any)
+			if !ok {
+				continue
+			}
+			if str(p["in"]) == "body" {
+				body = p
+				continue
+			}
+			key := str(p["in"]) + ":" + str(p["name"])
+			if i, ok := index[key]; ok {
+				params[i] = p
+				continue
+			}
+			index[key] = len(params)
+			params = append(params, p)
+		}
+	}
+	if rb, ok := s.resolve(op["requestBody"]).(map[string]any); ok {
+		body = rb
+	}
+	return params, body
+}
+
+func (s *APISpec) writeParameter(b *strings.Builder, p map[string]any) {
+	schema := p["schema"]
+	if schema == nil {
+		// Swagger 2 non-body parameters carry the type inline.
+		schema = p
+	}
+	fmt.Fprintf(b, "- `%s` (%s, %s", str(p["name"]), str(p["in"]), s.typeName(schema, nil))
+	if p["required"] == true {
+		b.WriteString(", required")
+	}
+	b.WriteString(")")
+	if d := oneLine(str(p["description"])); d != "" {
+		b.WriteString(": " + d)
+	}
+	writeConstraints(b, asMap(s.resolve(schema)))
+	b.WriteString("\n")
+}
+
+func (s *APISpec) writeRequestBody(b *strings.Builder, body, op map[string]any) {
+	b.WriteString("**Request body**")
+	if body["required"] == true {
+		b.WriteString(" (required)")
+	}
+	b.WriteString("\n\n")
+	if d := strings.TrimSpace(str(body["description"])); d != "" {
+		b.WriteString(d + "\n\n")
+	}
+	if schema, ok := body["schema"]; ok {
+		// Swagger 2 body parameter.
+		s.writeMedia(b, str(first(op["consumes"], s.root["consumes"])), map[string]any{"schema": schema})
+		return
+	}
+	s.writeContent(b, body)
+}
+
+func (s *APISpec) writeResponses(b *strings.Builder, op map[string]any) {
+	responses, _ := op["responses"].(map[string]any)
+	if len(responses) == 0 {
+		return
+	}
+	codes := make([]string, 0, len(responses))
+	for code := range responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	b.WriteString("**Responses**\n\n")
+	for _, code := range codes {
+		r := asMap(s.resolve(responses[code]))
+		fmt.Fprintf(b, "Response %s", code)
+		if d := oneLine(str(r["description"])); d != "" {
+			b.WriteString(": " + d)
+		}
+		b.WriteString("\n\n")
+		if schema, ok := r["schema"]; ok {
+			s.writeMedia(b, str(first(op["produces"], s.root["produces"])), map[string]any{"schema": schema, "examples": r["examples"]})
+			continue
+		}
+		s.writeContent(b, r)
+	}
+}
+
+// writeContent renders the media types of an OpenAPI 3 request body or response.
+func (s *APISpec) writeContent(b *strings.Builder, owner map[string]any) {
+	content, _ := owner["content"].(map[string]any)
+	types := make([]string, 0, len(content))
+	for t := range content {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		s.writeMedia(b, t, asMap(content[t]))
+	}
+}
+
+func (s *APISpec) writeMedia(b *strings.Builder, mediaType string, media map[string]any) {
+	schema := media["schema"]
+	if mediaType != "" {
+		fmt.Fprintf(b, "Content type `%s`: %s\n\n", mediaType, s.typeName(schema, nil))
+	} else if schema != nil {
+		fmt.Fprintf(b, "Type: %s\n\n", s.typeName(schema, nil))
+	}
+	if fields := s.fields(schema, 0, map[string]bool{}); fields != "" {
+		b.WriteString(fields + "\n")
+	}
+	if ex := s.example(media, schema); ex != "" {
+		b.WriteString("Example:\n\n```json\n" + ex + "\n```\n\n")
+	}
+}
+
+// example returns the first example of a media object or its schema as JSON.
+func (s *APISpec) example(media map[string]any, schema any) string {
+	var v any
+	switch {
+	case media["example"] != nil:
+		v = media["example"]
+	case media["examples"] != nil:
+		// OpenAPI 3: name -> Example Object; Swagger 2: mime type -> value.
+		ex, _ := media["examples"].(map[string]any)
+		keys := make([]string, 0, len(ex))
+		for k := range ex {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if len(keys) > 0 {
+			v = s.resolve(ex[keys[0]])
+			if m, ok := v.(map[string]any); ok && m["value"] != nil {
+				v = m["value"]
+			}
+		}
+	default:
+		v = asMap(s.resolve(schema))["example"]
+	}
+	if v == nil {
+		return ""
+	}
+	if sv, ok := v.(string); ok {
+		return truncate(sv, maxExampleLength)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return truncate(string(data), maxExampleLength)
+}
+
+// fields renders the properties of an object schema (or of the items of an
+// array schema) as a nested list. seen holds the $refs being expanded, so
+// recursive schemas stop at their first repetition.
+func (s *APISpec) fields(schema any, depth int, seen map[string]bool) string {
+	m := asMap(schema)
+	if ref := str(m["$ref"]); ref != "" {
+		if seen[ref] {
+			return ""
+		}
+		seen[ref] = true
+		defer delete(seen, ref)
+		m = asMap(s.resolve(m))
+	}
+	if depth >= maxSchemaDepth || m == nil {
+		return ""
+	}
+	if items, ok := m["items"]; ok && str(m["type"]) == "array" {
+		return s.fields(items, depth, seen)
+	}
+
+	props, required := s.properties(m, seen)
+	if len(props) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(props))
+	for n := range props {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	indent := strings.Repeat("  ", depth)
+	for _, n := range names {
+		prop := props[n]
+		fmt.Fprintf(&b, "%s- `%s` (%s", indent, n, s.typeName(prop, seen))
+		if required[n] {
+			b.WriteString(", required")
+		}
+		b.WriteString(")")
+		pm := asMap(s.resolve(prop))
+		if d := oneLine(str(pm["description"])); d != "" {
+			b.WriteString(": " + d)
+		}
+		writeConstraints(&b, pm)
+		b.WriteString("\n")
+		b.WriteString(s.fields(prop, depth+1, seen))
+	}
+	return b.String()
+}
+
+// properties collects an object schema's properties, merging allOf parts.
+func (s *APISpec) properties(m map[string]any, seen map[string]bool) (map[string]any, map[string]bool) {
+	props := map[string]any{}
+	required := map[string]bool{}
+	for k, v := range asMap(m["properties"]) {
+		props[k] = v
+	}
+	for _, r := range asSlice(m["required"]) {
+		required[str(r)] = true
+	}
+	for _, part := range asSlice(m["allOf"]) {
+		ref := str(asMap(part)["$ref"])
+		if ref != "" && seen[ref] {
+			continue
+		}
+		p, r := s.properties(asMap(s.resolve(part)), seen)
+		for k, v := range p {
+			props[k] = v
+		}
+		for k := range r {
+			required[k] = true
+		}
+	}
+	return props, required
+}
+
+// typeName describes a schema in a few words, e.g. "array of Order" or
+// "string (date-time)".
+func (s *APISpec) typeName(schema any, seen map[string]bool) string {
+	m := asMap(schema)
+	if ref := str(m["$ref"]); ref != "" {
+		name := ref[strings.LastIndex(ref, "/")+1:]
+		if seen[ref] {
+			return name + " (recursive)"
+		}
+		m = asMap(s.resolve(m))
+		if t := str(m["type"]); t != "" && t != "object" {
+			return name + " (" + s.typeName(m, seen) + ")"
+		}
+		return name
+	}
+	if m == nil {
+		return "any"
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if variants := asSlice(m[key]); len(variants) > 0 {
+			names := make([]string, len(variants))
+			for i, v := range variants {
+				names[i] = s.typeName(v, seen)
+			}
+			return "one of " + strings.Join(names, ", ")
+		}
+	}
+
+	t := str(m["type"])
+	if list := asSlice(m["type"]); len(list) > 0 {
+		// OpenAPI 3.1 type arrays, e.g. [string, "null"].
+		parts := make([]string, len(list))
+		for i, v := range list {
+			parts[i] = str(v)
+		}
+		t = strings.Join(parts, " or ")
+	}
+	switch {
+	case t == "array":
+		return "array of " + s.typeName(m["items"], seen)
+	case t == "" && (m["properties"] != nil || m["allOf"] != nil):
+		t = "object"
+	case t == "":
+		t = "any"
+	}
+	if f := str(m["format"]); f != "" {
+		t += " (" + f + ")"
+	}
+	if m["nullable"] == true {
+		t += ", nullable"
+	}
+	return t
+}
+
+// writeConstraints appends enum values, defaults and examples of a schema.
+func writeConstraints(b *strings.Builder, m map[string]any) {
+	if enum := asSlice(m["enum"]); len(enum) > 0 {
+		vals := make([]string, len(enum))
+		for i, v := range enum {
+			vals[i] = fmt.Sprint(v)
+		}
+		b.WriteString(". One of: " + strings.Join(vals, ", "))
+	}
+	if d, ok := m["default"]; ok && d != nil {
+		fmt.Fprintf(b, ". Default: %v", d)
+	}
+	if ex, ok := m["example"]; ok && ex != nil {
+		if _, isMap := ex.(map[string]any); !isMap {
+			fmt.Fprintf(b, ". Example: %v", ex)
+		}
+	}
+}
+
+// resolve follows local $refs ("#/components/schemas/Order") to the object
+// they point to. External or broken refs are returned unresolved.
+func (s *APISpec) resolve(v any) any {
+	for range maxSchemaDepth {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return v
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return v
+		}
+		target := s.pointer(ref[2:])
+		if target == nil {
+			return v
+		}
+		v = target
+	}
+	return v
+}
+
+// pointer evaluates a JSON pointer against the document root.
+func (s *APISpec) pointer(p string) any {
+	var cur any = s.root
+	for _, tok := range strings.Split(p, "/") {
+		tok = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+		if unescaped, err := url.PathUnescape(tok); err == nil {
+			tok = unescaped
+		}
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		if cur, ok = m[tok]; !ok {
+			return nil
+		}
+	}
+	return cur
+}
+
+func str(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+func asMap(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+func asSlice(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
+
+// first returns the first element of a list value, or the second list's.
+func first(lists ...any) any {
+	for _, l := range lists {
+		if items := asSlice(l); len(items) > 0 {
+			return items[0]
+		}
+	}
+	return nil
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "\n..."
+}
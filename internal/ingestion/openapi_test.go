@@ -0,0 +1,176 @@
+package ingestion_test
+
+import (
+	"strings"
+	"testing"
+
+	"cgap/internal/ingestion"
+)
+
+const petstoreYAML = `
+openapi: 3.0.3
+info:
+  title: Petstore
+  version: 1.2.0
+paths:
+  /pets/{petId}:
+    parameters:
+      - $ref: '#/components/parameters/PetID'
+    get:
+      tags: [pets]
+      operationId: getPet
+      summary: Get a pet
+      responses:
+        200:
+          description: The pet
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+              example:
+                id: 7
+                name: Rex
+        404:
+          $ref: '#/components/responses/NotFound'
+  /pets:
+    post:
+      tags: [pets]
+      operationId: createPet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+      responses:
+        '201':
+          description: Created
+components:
+  parameters:
+    PetID:
+      name: petId
+      in: path
+      required: true
+      description: ID of the pet.
+      schema:
+        type: integer
+        format: int64
+  responses:
+    NotFound:
+      description: Pet not found
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        id:
+          type: integer
+        name:
+          type: string
+          description: Name of the pet.
+        status:
+          type: string
+          enum: [available, sold]
+        parent:
+          $ref: '#/components/schemas/Pet'
+`
+
+func TestParseOpenAPI_Operations(t *testing.T) {
+	spec, err := ingestion.ParseOpenAPI([]byte(petstoreYAML))
+	if err != nil {
+		t.Fatalf("ParseOpenAPI: %v", err)
+	}
+	if spec.Title != "Petstore" || spec.Version != "1.2.0" {
+		t.Errorf("info = %q %q", spec.Title, spec.Version)
+	}
+	ops := spec.Operations()
+	if len(ops) != 2 {
+		t.Fatalf("got %d operations, want 2", len(ops))
+	}
+
+	create, get := ops[0], ops[1]
+	if create.Method != "POST" || create.Path != "/pets" || get.OperationID != "getPet" {
+		t.Fatalf("unexpected operations order: %+v %+v", create, get)
+	}
+	if get.Anchor() != "operation/getPet" || get.Title() != "GET /pets/{petId} - Get a pet" {
+		t.Errorf("anchor %q, title %q", get.Anchor(), get.Title())
+	}
+	for _, want := range []string{
+		"`GET /pets/{petId}`",
+		"- `petId` (path, integer (int64), required): ID of the pet.",
+		"Response 200: The pet",
+		"- `name` (string, required): Name of the pet.",
+		"- `status` (string). One of: available, sold",
+		"- `parent` (Pet (recursive))",
+		"\"name\": \"Rex\"",
+		"Response 404: Pet not found",
+	} {
+		if !strings.Contains(get.Text, want) {
+			t.Errorf("getPet text missing %q:\n%s", want, get.Text)
+		}
+	}
+	if !strings.Contains(create.Text, "**Request body** (required)") {
+		t.Errorf("createPet text missing request body:\n%s", create.Text)
+	}
+
+	chunks := ingestion.NewMarkdownChunker("", 0).Split(get.Text)
+	if len(chunks) == 0 || chunks[0].SectionPath != "pets > getPet" {
+		t.Errorf("section path = %+v, want pets > getPet", chunks)
+	}
+}
+
+func TestParseOpenAPI_Swagger2JSON(t *testing.T) {
+	spec, err := ingestion.ParseOpenAPI([]byte(`{
+		"swagger": "2.0",
+		"info": {"title": "Orders", "version": "v1"},
+		"consumes": ["application/json"],
+		"paths": {
+			"/orders": {
+				"post": {
+					"parameters": [
+						{"name": "dry_run", "in": "query", "type": "boolean"},
+						{"name": "body", "in": "body", "required": true, "schema": {"$ref": "#/definitions/Order"}}
+					],
+					"responses": {"200": {"description": "OK", "schema": {"type": "array", "items": {"$ref": "#/definitions/Order"}}}}
+				}
+			}
+		},
+		"definitions": {
+			"Order": {"properties": {"sku": {"type": "string", "example": "A-1"}}}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseOpenAPI: %v", err)
+	}
+	ops := spec.Operations()
+	if len(ops) != 1 {
+		t.Fatalf("got %d operations, want 1", len(ops))
+	}
+	op := ops[0]
+	if op.Tag != "default" || op.Anchor() != "operation/post%2Forders" {
+		t.Errorf("tag %q, anchor %q", op.Tag, op.Anchor())
+	}
+	for _, want := range []string{
+		"## POST /orders",
+		"- `dry_run` (query, boolean)",
+		"Content type `application/json`: Order",
+		"- `sku` (string). Example: A-1",
+		"Type: array of Order",
+	} {
+		if !strings.Contains(op.Text, want) {
+			t.Errorf("text missing %q:\n%s", want, op.Text)
+		}
+	}
+}
+
+func TestParseOpenAPI_Invalid(t *testing.T) {
+	for name, doc := range map[string]string{
+		"not a spec": `{"name": "x"}`,
+		"no paths":   `openapi: 3.1.0`,
+		"malformed":  `{"openapi": `,
+	} {
+		if _, err := ingestion.ParseOpenAPI([]byte(doc)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package ingestion
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// ExtractPDF extracts the text of a PDF page by page. Each page becomes a
+// "# Page N" section, so chunks never span pages and their section path
+// gives the page number. Rows of text are kept as lines in reading order.
+func ExtractPDF(ctx context.Context, data []byte) (out *Extracted, err error) {
+	// The PDF reader panics on some malformed files.
+	defer func() {
+		if r := recover(); r != nil {
+			out, err = nil, fmt.Errorf("malformed pdf: %v", r)
+		}
+	}()
+
+	r, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pdf: %w", err)
+	}
+
+	var b strings.Builder
+	for i := 1; i <= r.NumPage(); i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page := r.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		lines := pdfLines(page.Content().Text)
+		if len(lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "# Page %d\n\n%s\n\n", i, joinPDFLines(lines))
+	}
+
+	text := strings.TrimSpace(b.String())
+	if text == "" {
+		return nil, errors.New("pdf has no extractable text (scanned pages need OCR)")
+	}
+	title := ""
+	if info := r.Trailer().Key("Info"); !info.IsNull() {
+		title = strings.TrimSpace(info.Key("Title").Text())
+	}
+	return &Extracted{Title: title, Text: text}, nil
+}
+
+// pdfLines groups the glyphs of a page into lines, top to bottom, adding
+// spaces where glyphs of a line are visibly apart.
+func pdfLines(glyphs []pdf.Text) []string {
+	type row struct {
+		y      float64
+		glyphs []pdf.Text
+	}
+	var rows []*row
+	for _, g := range glyphs {
+		var r *row
+		for _, cand := range rows {
+			if math.Abs(cand.y-g.Y) <= max(g.FontSize, 1)/2 {
+				r = cand
+				break
+			}
+		}
+		if r == nil {
+			r = &row{y: g.Y}
+			rows = append(rows, r)
+		}
+		r.glyphs = append(r.glyphs, g)
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].y > rows[j].y })
+
+	lines := make([]string, 0, len(rows))
+	for _, r := range rows {
+		sort.SliceStable(r.glyphs, func(i, j int) bool { return r.glyphs[i].X < r.glyphs[j].X })
+		var b strings.Builder
+		end := r.glyphs[0].X
+		for _, g := range r.glyphs {
+			if g.X-end > g.FontSize*0.2 {
+				b.WriteString(" ")
+			}
+			b.WriteString(g.S)
+			end = g.X + g.W
+		}
+		if line := strings.Join(strings.Fields(b.String()), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// joinPDFLines rebuilds paragraphs from visual lines: a line that ends a
+// sentence and is clearly shorter than the page's longest line closes its
+// paragraph; other lines continue it.
+func joinPDFLines(lines []string) string {
+	longest := 0
+	for _, l := range lines {
+		longest = max(longest, len(l))
+	}
+	var b strings.Builder
+	for i, l := range lines {
+		b.WriteString(l)
+		if i == len(lines)-1 {
+			break
+		}
+		if len(l) < longest*3/4 && strings.ContainsAny(l[len(l)-1:], ".:!?") {
+			b.WriteString("\n\n")
+		} else {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"strings"
 
 	"cgap/internal/model"
 )
@@ -77,35 +76,9 @@ type Chunk struct {
 	TokenCount  int
 	SectionPath string
 	ScoreRaw    float32
-}
-
-// SimpleChunker uses fixed token size + overlap.
-type SimpleChunker struct {
-	chunkSize int
-	overlap   int
-}
-
-func NewSimpleChunker(chunkSize, overlap int) *SimpleChunker {
-	return &SimpleChunker{
-		chunkSize: chunkSize,
-		overlap:   overlap,
-	}
-}
-
-func (c *SimpleChunker) Chunk(ctx context.Context, doc *model.Document, content string) ([]Chunk, error) {
-	lines := strings.Split(content, "\n")
-	var chunks []Chunk
-	for i, line := range lines {
-		if strings.TrimSpace(line) != "" {
-			chunks = append(chunks, Chunk{
-				Ord:         i,
-				Text:        line,
-				TokenCount:  len(strings.Fields(line)),
-				SectionPath: "root",
-			})
-		}
-	}
-	return chunks, nil
+	// Position of a transcript chunk in its video; nil for text.
+	StartSeconds *int
+	EndSeconds   *int
 }
 
 // IngestionPipeline orchestrates crawl -> chunk -> embed -> index.
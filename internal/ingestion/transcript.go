@@ -0,0 +1,83 @@
+package ingestion
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TranscriptSegment is a timed piece of a video transcript.
+type TranscriptSegment struct {
+	Text         string
+	StartSeconds int
+	EndSeconds   int
+}
+
+// SplitTranscript chunks a transcript along segment boundaries. Segments are
+// packed to the target size and never split, so each chunk records the start
+// of its first and the end of its last segment; SectionPath is that time
+// range, e.g. "1:05-2:30". Consecutive chunks overlap by the trailing segments
+// that fit the overlap size.
+func (c *MarkdownChunker) SplitTranscript(segments []TranscriptSegment) []Chunk {
+	var chunks []Chunk
+	var cur []TranscriptSegment
+	curTokens := 0
+	carried := 0 // leading segments of cur repeated from the previous chunk
+	emit := func() {
+		if len(cur) == carried {
+			return
+		}
+		texts := make([]string, len(cur))
+		for i, s := range cur {
+			texts[i] = s.Text
+		}
+		text := strings.Join(texts, " ")
+		start, end := cur[0].StartSeconds, cur[len(cur)-1].EndSeconds
+		chunks = append(chunks, Chunk{
+			Ord:          len(chunks),
+			Text:         text,
+			TokenCount:   EstimateTokens(text),
+			SectionPath:  formatTimestamp(start) + "-" + formatTimestamp(end),
+			StartSeconds: &start,
+			EndSeconds:   &end,
+		})
+
+		keep, used := 0, 0
+		for i := len(cur) - 1; i > 0; i-- {
+			t := EstimateTokens(cur[i].Text)
+			if used+t > c.overlap {
+				break
+			}
+			used += t
+			keep++
+		}
+		cur = append([]TranscriptSegment(nil), cur[len(cur)-keep:]...)
+		curTokens, carried = used, keep
+	}
+
+	for _, seg := range segments {
+		seg.Text = strings.TrimSpace(seg.Text)
+		if seg.Text == "" {
+			continue
+		}
+		tokens := EstimateTokens(seg.Text)
+		if len(cur) > carried && curTokens+tokens > c.target {
+			emit()
+		}
+		cur = append(cur, seg)
+		curTokens += tokens
+	}
+	emit()
+	return chunks
+}
+
+// formatTimestamp renders seconds as m:ss, or h:mm:ss from one hour on.
+func formatTimestamp(seconds int) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	h, m, s := seconds/3600, seconds/60%60, seconds%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
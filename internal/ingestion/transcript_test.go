@@ -0,0 +1,64 @@
+package ingestion_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"cgap/internal/ingestion"
+)
+
+func TestSplitTranscript_SegmentBoundaries(t *testing.T) {
+	// 5-token segments of 30 seconds each; 64-token chunks hold twelve and
+	// the 6-token overlap repeats one.
+	var segments []ingestion.TranscriptSegment
+	for i := range 40 {
+		segments = append(segments, ingestion.TranscriptSegment{
+			Text:         fmt.Sprintf("Segment %02d %s", i, strings.Repeat("x", 9)),
+			StartSeconds: 30 * i,
+			EndSeconds:   30*i + 30,
+		})
+	}
+	segments = append(segments, ingestion.TranscriptSegment{Text: "  ", StartSeconds: 1200, EndSeconds: 1201})
+
+	chunks := ingestion.NewMarkdownChunker("", ingestion.MinChunkTokens).SplitTranscript(segments)
+	if len(chunks) < 2 {
+		t.Fatalf("Expected several chunks, got %+v", chunks)
+	}
+	for i, c := range chunks {
+		if c.Ord != i || c.StartSeconds == nil || c.EndSeconds == nil {
+			t.Fatalf("chunk %d: expected ord and timestamps, got %+v", i, c)
+		}
+		first := strings.Index(c.Text, "Segment ")
+		n := 0
+		fmt.Sscanf(c.Text[first:], "Segment %d", &n)
+		if first != 0 || *c.StartSeconds != 30*n {
+			t.Errorf("chunk %d: expected to start at segment %d (%ds), got %ds: %q", i, n, 30*n, *c.StartSeconds, c.Text)
+		}
+		if want := ingestion.EstimateTokens(c.Text); c.TokenCount != want || c.TokenCount > ingestion.MinChunkTokens {
+			t.Errorf("chunk %d: unexpected token count %d", i, c.TokenCount)
+		}
+	}
+	if got := chunks[0].SectionPath; got != "0:00-6:00" {
+		t.Errorf("Expected time range as section path, got %q", got)
+	}
+	last := chunks[len(chunks)-1]
+	if *last.EndSeconds != 1200 || !strings.Contains(last.Text, "Segment 39") {
+		t.Errorf("Expected last chunk to end with the last segment, got %+v", last)
+	}
+	if *chunks[1].StartSeconds >= *chunks[0].EndSeconds {
+		t.Errorf("Expected overlapping chunks, got %d after %d", *chunks[1].StartSeconds, *chunks[0].EndSeconds)
+	}
+}
+
+func TestSplitTranscript_LongVideo(t *testing.T) {
+	chunks := ingestion.NewMarkdownChunker("", 0).SplitTranscript([]ingestion.TranscriptSegment{
+		{Text: "Welcome back.", StartSeconds: 3725, EndSeconds: 3731},
+	})
+	if len(chunks) != 1 || chunks[0].SectionPath != "1:02:05-1:02:11" {
+		t.Errorf("Unexpected chunks %+v", chunks)
+	}
+	if len(ingestion.NewMarkdownChunker("", 0).SplitTranscript(nil)) != 0 {
+		t.Error("Expected no chunks for an empty transcript")
+	}
+}
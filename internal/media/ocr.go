@@ -1,49 +1,137 @@
 package media
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
+// DefaultGoogleVisionEndpoint is the Cloud Vision images:annotate REST method.
+const DefaultGoogleVisionEndpoint = "https://vision.googleapis.com/v1/images:annotate"
+
+// maxImageBytes bounds images sent to OCR; base64 encoded, they must fit
+// the 10 MB request limit of Vision.
+const maxImageBytes = 7 << 20
+
+// GoogleVisionConfig configures GoogleVisionOCR.
+type GoogleVisionConfig struct {
+	// APIKey authenticates requests; without one, OCR returns mock text.
+	APIKey string
+	// Endpoint overrides DefaultGoogleVisionEndpoint, e.g. for a local stub.
+	Endpoint string
+	// HTTPClient is used for Vision requests and image downloads.
+	HTTPClient *http.Client
+}
+
 // GoogleVisionOCR implements OCRHandler using Google Cloud Vision API
 type GoogleVisionOCR struct {
-	apiKey string
-	logger *slog.Logger
+	apiKey   string
+	endpoint string
+	client   *http.Client
+	logger   *slog.Logger
 }
 
-// NewGoogleVisionOCR creates a new Google Vision OCR handler
+// NewGoogleVisionOCR creates a Google Vision OCR handler configured from
+// GOOGLE_CLOUD_VISION_API_KEY (or GOOGLE_API_KEY) and GOOGLE_VISION_ENDPOINT.
 func NewGoogleVisionOCR(_ context.Context, logger *slog.Logger) (*GoogleVisionOCR, error) {
-	if logger == nil {
-		logger = slog.Default()
-	}
+	return NewGoogleVisionOCRWithConfig(googleVisionConfigFromEnv(), logger), nil
+}
 
-	// Get API key from environment
+func googleVisionConfigFromEnv() GoogleVisionConfig {
 	apiKey := os.Getenv("GOOGLE_CLOUD_VISION_API_KEY")
 	if apiKey == "" {
 		apiKey = os.Getenv("GOOGLE_API_KEY")
 	}
+	return GoogleVisionConfig{
+		APIKey:   apiKey,
+		Endpoint: os.Getenv("GOOGLE_VISION_ENDPOINT"),
+	}
+}
 
-	if apiKey == "" {
-		logger.Warn("GOOGLE_CLOUD_VISION_API_KEY or GOOGLE_API_KEY not set, OCR will use mock data for testing")
+// ConfigFromEnv reads the media configuration: OCR_BACKEND selects the OCR
+// backend, which is then configured from its own variables, as are video
+// transcription and YouTube captions.
+func ConfigFromEnv() Config {
+	return Config{
+		OCRBackend:   strings.ToLower(strings.TrimSpace(os.Getenv("OCR_BACKEND"))),
+		GoogleVision: googleVisionConfigFromEnv(),
+		Tesseract:    tesseractConfigFromEnv(),
+		VisionLLM:    visionLLMConfigFromEnv(),
+		Whisper:      whisperConfigFromEnv(),
+		YouTube:      youTubeConfigFromEnv(),
 	}
+}
+
+// NewOCRHandler creates the OCR handler selected by cfg.OCRBackend.
+func NewOCRHandler(cfg Config, logger *slog.Logger) (OCRHandler, error) {
+	switch cfg.OCRBackend {
+	case "", OCRBackendGoogle:
+		return NewGoogleVisionOCRWithConfig(cfg.GoogleVision, logger), nil
+	case OCRBackendTesseract:
+		return NewTesseractOCR(cfg.Tesseract, logger)
+	case OCRBackendVisionLLM:
+		return NewVisionLLMOCR(cfg.VisionLLM, logger)
+	}
+	return nil, fmt.Errorf("unknown OCR backend %q", cfg.OCRBackend)
+}
 
+// NewGoogleVisionOCRWithConfig creates a Google Vision OCR handler from cfg.
+func NewGoogleVisionOCRWithConfig(cfg GoogleVisionConfig, logger *slog.Logger) *GoogleVisionOCR {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.APIKey == "" {
+		logger.Warn("GOOGLE_CLOUD_VISION_API_KEY or GOOGLE_API_KEY not set, OCR will use mock data for testing")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = DefaultGoogleVisionEndpoint
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 60 * time.Second}
+	}
 	return &GoogleVisionOCR{
-		apiKey: apiKey,
-		logger: logger,
-	}, nil
+		apiKey:   cfg.APIKey,
+		endpoint: cfg.Endpoint,
+		client:   cfg.HTTPClient,
+		logger:   logger,
+	}
 }
 
 // ExtractFromURL extracts text from an image URL using Google Vision
 func (g *GoogleVisionOCR) ExtractFromURL(ctx context.Context, imageURL string) (*OCRResult, error) {
 	g.logger.Debug("Extracting text from URL", "url", imageURL)
 
-	// Validate URL
+	u, err := parseMediaURL(imageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Images are downloaded and sent inline, so Vision needs no access to
+	// the URL (it may be private).
+	data, err := downloadImage(ctx, g.client, imageURL)
+	if err != nil {
+		return nil, err
+	}
+	if g.apiKey != "" {
+		return g.extractWithGoogleVisionAPIBytes(ctx, data)
+	}
+	return g.mockResult(path.Base(u.Path)), nil
+}
+
+// parseMediaURL validates the URL of an image or video to download.
+func parseMediaURL(imageURL string) (*url.URL, error) {
 	u, err := url.Parse(imageURL)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
@@ -51,24 +139,15 @@ func (g *GoogleVisionOCR) ExtractFromURL(ctx context.Context, imageURL string) (
 	if u.Scheme != "http" && u.Scheme != "https" {
 		return nil, fmt.Errorf("%w: unsupported scheme %s", ErrInvalidURL, u.Scheme)
 	}
-
-	// If we have API key, use real Google Vision API
-	if g.apiKey != "" {
-		return g.extractWithGoogleVisionAPI(ctx, imageURL)
-	}
-
-	// Otherwise, download and process locally
-	return g.extractFromURLLocal(ctx, imageURL)
+	return u, nil
 }
 
-// extractFromURLLocal downloads image and processes locally
-func (g *GoogleVisionOCR) extractFromURLLocal(ctx context.Context, imageURL string) (*OCRResult, error) {
-	// Download the image (validated URL)
+// downloadImage fetches an image of at most maxImageBytes.
+func downloadImage(ctx context.Context, client *http.Client, imageURL string) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download image: %w", err)
 	}
-	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download image: %w", err)
@@ -78,22 +157,14 @@ func (g *GoogleVisionOCR) extractFromURLLocal(ctx context.Context, imageURL stri
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("%w: HTTP %d", ErrExtractionFailed, resp.StatusCode)
 	}
-
-	// Save to temp file
-	tempFile, err := os.CreateTemp("", "ocr_*.jpg")
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file: %w", err)
+		return nil, fmt.Errorf("failed to download image: %w", err)
 	}
-	defer func() { _ = os.Remove(tempFile.Name()) }()
-
-	if _, err := io.Copy(tempFile, resp.Body); err != nil {
-		_ = tempFile.Close()
-		return nil, fmt.Errorf("failed to write image: %w", err)
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("%w: image larger than %d bytes", ErrExtractionFailed, maxImageBytes)
 	}
-	_ = tempFile.Close()
-
-	// Extract from file
-	return g.ExtractFromFile(ctx, tempFile.Name())
+	return data, nil
 }
 
 // ExtractFromFile extracts text from a local image file
@@ -102,12 +173,16 @@ func (g *GoogleVisionOCR) ExtractFromFile(ctx context.Context, filePath string)
 
 	// Sanitize and check file path
 	cleanPath := filepath.Clean(filePath)
-	if _, err := os.Stat(cleanPath); err != nil {
+	info, err := os.Stat(cleanPath)
+	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
 	// If we have API key, use Google Vision API
 	if g.apiKey != "" {
+		if info.Size() > maxImageBytes {
+			return nil, fmt.Errorf("%w: image larger than %d bytes", ErrExtractionFailed, maxImageBytes)
+		}
 		data, err := os.ReadFile(cleanPath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read file: %w", err)
@@ -115,55 +190,296 @@ func (g *GoogleVisionOCR) ExtractFromFile(ctx context.Context, filePath string)
 		return g.extractWithGoogleVisionAPIBytes(ctx, data)
 	}
 
-	// For now, return mock data for local processing (placeholder for future OCR engine)
-	g.logger.Debug("Processing file locally with mock OCR", "path", filePath)
+	return g.mockResult(filepath.Base(filePath)), nil
+}
 
+// mockResult stands in for OCR when no API key is configured.
+func (g *GoogleVisionOCR) mockResult(name string) *OCRResult {
+	g.logger.Debug("Processing image locally with mock OCR", "name", name)
 	return &OCRResult{
-		Text:            fmt.Sprintf("[Mock OCR] Text extracted from: %s\n\nNote: For production use, set GOOGLE_CLOUD_VISION_API_KEY environment variable.", filepath.Base(filePath)),
+		Text:            fmt.Sprintf("[Mock OCR] Text extracted from: %s\n\nNote: For production use, set GOOGLE_CLOUD_VISION_API_KEY environment variable.", name),
 		ConfidenceScore: 0.75,
 		Language:        "en",
 		BoundingBoxes:   []TextBoundingBox{},
 		RawResponse:     nil,
-	}, nil
+	}
 }
 
-// extractWithGoogleVisionAPI calls Google Cloud Vision API with image URL
-func (g *GoogleVisionOCR) extractWithGoogleVisionAPI(_ context.Context, imageURL string) (*OCRResult, error) {
-	g.logger.Debug("Google Vision placeholder: using image URL", "url", imageURL)
-	// Build request to Google Vision API
-	// This would use the actual Google Vision client library
-	// For now, placeholder for future implementation
-	g.logger.Warn("Google Vision API integration not yet implemented")
+// Vision images:annotate request and response, limited to the fields used.
+type visionRequest struct {
+	Requests []visionImageRequest `json:"requests"`
+}
 
-	// Return mock response
-	return &OCRResult{
-		Text:            "[Google Vision API] Text extraction not yet implemented. Please configure and implement.",
-		ConfidenceScore: 0.0,
-		Language:        "unknown",
-		BoundingBoxes:   []TextBoundingBox{},
-		RawResponse:     nil,
-	}, nil
+type visionImageRequest struct {
+	Image        visionImage         `json:"image"`
+	Features     []visionFeature     `json:"features"`
+	ImageContext *visionImageContext `json:"imageContext,omitempty"`
 }
 
-// extractWithGoogleVisionAPIBytes calls Google Cloud Vision API with image bytes
-func (g *GoogleVisionOCR) extractWithGoogleVisionAPIBytes(_ context.Context, imageData []byte) (*OCRResult, error) {
-	g.logger.Debug("Google Vision placeholder: using image bytes", "size", len(imageData))
-	// Build request to Google Vision API
-	// This would use the actual Google Vision client library
-	// For now, placeholder for future implementation
-	g.logger.Warn("Google Vision API integration not yet implemented")
+type visionImage struct {
+	Content string `json:"content"` // base64
+}
 
-	// Return mock response
-	return &OCRResult{
-		Text:            "[Google Vision API] Text extraction not yet implemented. Please configure and implement.",
-		ConfidenceScore: 0.0,
-		Language:        "unknown",
-		BoundingBoxes:   []TextBoundingBox{},
-		RawResponse:     nil,
-	}, nil
+type visionFeature struct {
+	Type string `json:"type"`
+}
+
+type visionImageContext struct {
+	LanguageHints []string `json:"languageHints,omitempty"`
+}
+
+type visionResponse struct {
+	Responses []visionAnnotateResponse `json:"responses"`
+}
+
+type visionAnnotateResponse struct {
+	TextAnnotations []struct {
+		Locale      string `json:"locale"`
+		Description string `json:"description"`
+	} `json:"textAnnotations"`
+	FullTextAnnotation *visionTextAnnotation `json:"fullTextAnnotation"`
+	Error              *visionStatus         `json:"error"`
+}
+
+type visionStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type visionTextAnnotation struct {
+	Text  string       `json:"text"`
+	Pages []visionPage `json:"pages"`
+}
+
+type visionPage struct {
+	Property   *visionTextProperty `json:"property"`
+	Width      int                 `json:"width"`
+	Height     int                 `json:"height"`
+	Blocks     []visionBlock       `json:"blocks"`
+	Confidence float64             `json:"confidence"`
+}
+
+type visionTextProperty struct {
+	DetectedLanguages []struct {
+		LanguageCode string  `json:"languageCode"`
+		Confidence   float64 `json:"confidence"`
+	} `json:"detectedLanguages"`
+	DetectedBreak *struct {
+		Type string `json:"type"`
+	} `json:"detectedBreak"`
+}
+
+type visionBlock struct {
+	BoundingBox *visionPoly `json:"boundingBox"`
+	Paragraphs  []struct {
+		Words []struct {
+			Symbols []struct {
+				Text     string              `json:"text"`
+				Property *visionTextProperty `json:"property"`
+			} `json:"symbols"`
+		} `json:"words"`
+	} `json:"paragraphs"`
+	Confidence float64 `json:"confidence"`
+}
+
+type visionPoly struct {
+	Vertices []struct {
+		X float64 `json:"x"`
+		Y float64 `json:"y"`
+	} `json:"vertices"`
+	NormalizedVertices []struct {
+		X float64 `json:"x"`
+		Y float64 `json:"y"`
+	} `json:"normalizedVertices"`
+}
+
+// extractWithGoogleVisionAPIBytes runs DOCUMENT_TEXT_DETECTION on image
+// bytes, with the language hints of ctx (see WithOCRLanguages).
+func (g *GoogleVisionOCR) extractWithGoogleVisionAPIBytes(ctx context.Context, imageData []byte) (*OCRResult, error) {
+	g.logger.Debug("Calling Google Vision API", "size", len(imageData))
+
+	areq := visionImageRequest{
+		Image:    visionImage{Content: base64.StdEncoding.EncodeToString(imageData)},
+		Features: []visionFeature{{Type: "DOCUMENT_TEXT_DETECTION"}},
+	}
+	if langs := OCRLanguages(ctx); len(langs) > 0 {
+		areq.ImageContext = &visionImageContext{LanguageHints: langs}
+	}
+	body, err := json.Marshal(visionRequest{Requests: []visionImageRequest{areq}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode vision request: %w", err)
+	}
+
+	endpoint, err := url.Parse(g.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vision endpoint: %w", err)
+	}
+	q := endpoint.Query()
+	q.Set("key", g.apiKey)
+	endpoint.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vision request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vision request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("%w: vision returned HTTP %d", ErrUnauthorized, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error visionStatus `json:"error"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&e)
+		return nil, fmt.Errorf("%w: vision returned HTTP %d: %s", ErrExtractionFailed, resp.StatusCode, e.Error.Message)
+	}
+
+	var vr visionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&vr); err != nil {
+		return nil, fmt.Errorf("failed to decode vision response: %w", err)
+	}
+	if len(vr.Responses) == 0 {
+		return nil, fmt.Errorf("%w: empty vision response", ErrExtractionFailed)
+	}
+	ar := vr.Responses[0]
+	if ar.Error != nil && ar.Error.Message != "" {
+		return nil, fmt.Errorf("%w: vision: %s", ErrExtractionFailed, ar.Error.Message)
+	}
+	return visionResult(&ar), nil
+}
+
+// visionResult maps an annotate response to an OCRResult: one bounding box
+// per text block, with coordinates normalized to 0-1 of the page size. The
+// overall confidence is the mean block confidence weighted by text length.
+func visionResult(ar *visionAnnotateResponse) *OCRResult {
+	result := &OCRResult{BoundingBoxes: []TextBoundingBox{}, RawResponse: ar}
+	if len(ar.TextAnnotations) > 0 {
+		result.Text = ar.TextAnnotations[0].Description
+		result.Language = ar.TextAnnotations[0].Locale
+	}
+	fta := ar.FullTextAnnotation
+	if fta == nil {
+		return result
+	}
+	if fta.Text != "" {
+		result.Text = fta.Text
+	}
+	result.Text = strings.TrimSpace(result.Text)
+
+	var weighted, weight float64
+	for _, page := range fta.Pages {
+		if result.Language == "" && page.Property != nil && len(page.Property.DetectedLanguages) > 0 {
+			result.Language = page.Property.DetectedLanguages[0].LanguageCode
+		}
+		for _, block := range page.Blocks {
+			text := blockText(&block)
+			if text == "" {
+				continue
+			}
+			box := TextBoundingBox{Text: text, Confidence: block.Confidence}
+			box.X1, box.Y1, box.X2, box.Y2 = normalizedBounds(block.BoundingBox, page.Width, page.Height)
+			result.BoundingBoxes = append(result.BoundingBoxes, box)
+			n := float64(len(text))
+			weighted += block.Confidence * n
+			weight += n
+		}
+	}
+	if weight > 0 {
+		result.ConfidenceScore = weighted / weight
+	} else if len(fta.Pages) > 0 {
+		result.ConfidenceScore = fta.Pages[0].Confidence
+	}
+	return result
+}
+
+// blockText joins the symbols of a block, following the detected breaks.
+func blockText(b *visionBlock) string {
+	var sb strings.Builder
+	for _, p := range b.Paragraphs {
+		for _, w := range p.Words {
+			for _, s := range w.Symbols {
+				sb.WriteString(s.Text)
+				if s.Property == nil || s.Property.DetectedBreak == nil {
+					continue
+				}
+				switch s.Property.DetectedBreak.Type {
+				case "SPACE", "SURE_SPACE":
+					sb.WriteByte(' ')
+				case "EOL_SURE_SPACE", "LINE_BREAK":
+					sb.WriteByte('\n')
+				case "HYPHEN":
+					sb.WriteString("-\n")
+				}
+			}
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// normalizedBounds returns the bounding rectangle of poly as fractions of
+// the page size. Vision omits zero coordinates, which decode as 0.
+func normalizedBounds(poly *visionPoly, width, height int) (x1, y1, x2, y2 float32) {
+	if poly == nil {
+		return 0, 0, 0, 0
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	add := func(x, y float64) {
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+	switch {
+	case len(poly.NormalizedVertices) > 0:
+		for _, v := range poly.NormalizedVertices {
+			add(v.X, v.Y)
+		}
+	case len(poly.Vertices) > 0 && width > 0 && height > 0:
+		for _, v := range poly.Vertices {
+			add(v.X/float64(width), v.Y/float64(height))
+		}
+	default:
+		return 0, 0, 0, 0
+	}
+	clamp := func(v float64) float32 { return float32(math.Max(0, math.Min(1, v))) }
+	return clamp(minX), clamp(minY), clamp(maxX), clamp(maxY)
 }
 
 // Close closes any resources
 func (g *GoogleVisionOCR) Close() error {
 	return nil
 }
+
+type ocrLanguagesKey struct{}
+
+// WithOCRLanguages returns a context carrying language hints (BCP-47 codes
+// such as "en" or "de") for OCR calls made with it.
+func WithOCRLanguages(ctx context.Context, langs ...string) context.Context {
+	if len(langs) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, ocrLanguagesKey{}, langs)
+}
+
+// OCRLanguages returns the language hints of ctx.
+func OCRLanguages(ctx context.Context) []string {
+	langs, _ := ctx.Value(ocrLanguagesKey{}).([]string)
+	return langs
+}
+
+// ParseLanguageHints splits a comma-separated hint list such as
+// MediaSpec.OCRLang ("en, de") into language codes.
+func ParseLanguageHints(s string) []string {
+	var langs []string
+	for _, l := range strings.Split(s, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			langs = append(langs, l)
+		}
+	}
+	return langs
+}
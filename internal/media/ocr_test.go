@@ -2,20 +2,40 @@ package media_test
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"cgap/internal/media"
 )
 
+// pngBytes stands in for an image; OCR handlers pass it through unparsed.
+var pngBytes = []byte("\x89PNG\r\n\x1a\nfake image")
+
+func imageServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/logo.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(pngBytes)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
 func TestGoogleVisionOCR_ExtractFromURL(t *testing.T) {
-	logger := slog.Default()
 	ctx := context.Background()
-
-	ocr, err := media.NewGoogleVisionOCR(ctx, logger)
-	if err != nil {
-		t.Fatalf("Failed to create GoogleVisionOCR: %v", err)
-	}
+	images := imageServer(t)
+	ocr := media.NewGoogleVisionOCRWithConfig(media.GoogleVisionConfig{}, slog.Default())
 
 	testCases := []struct {
 		name        string
@@ -24,9 +44,14 @@ func TestGoogleVisionOCR_ExtractFromURL(t *testing.T) {
 	}{
 		{
 			name:        "valid image URL",
-			imageURL:    "https://www.google.com/images/branding/googlelogo/1x/googlelogo_color_272x92dp.png",
+			imageURL:    images.URL + "/logo.png",
 			expectError: false,
 		},
+		{
+			name:        "missing image",
+			imageURL:    images.URL + "/missing.png",
+			expectError: true,
+		},
 		{
 			name:        "invalid URL",
 			imageURL:    "not a valid url",
@@ -57,6 +82,113 @@ func TestGoogleVisionOCR_ExtractFromURL(t *testing.T) {
 	}
 }
 
+// visionStubResponse annotates a two-block document on a 200x100 page.
+const visionStubResponse = `{"responses":[{
+  "textAnnotations":[{"locale":"de","description":"Benutzer anlegen\nSpeichern"}],
+  "fullTextAnnotation":{
+    "text":"Benutzer anlegen\nSpeichern\n",
+    "pages":[{"width":200,"height":100,"blocks":[
+      {"confidence":0.9,"boundingBox":{"vertices":[{"x":20,"y":10},{"x":180,"y":10},{"x":180,"y":30},{"x":20,"y":30}]},
+       "paragraphs":[{"words":[
+         {"symbols":[{"text":"Benutzer","property":{"detectedBreak":{"type":"SPACE"}}}]},
+         {"symbols":[{"text":"anlegen","property":{"detectedBreak":{"type":"LINE_BREAK"}}}]}]}]},
+      {"confidence":0.6,"boundingBox":{"vertices":[{"x":150,"y":80},{"x":190,"y":80},{"x":190,"y":95},{"x":150,"y":95}]},
+       "paragraphs":[{"words":[{"symbols":[{"text":"Speichern"}]}]}]}
+    ]}]
+  }
+}]}`
+
+func TestGoogleVisionOCR_DocumentTextDetection(t *testing.T) {
+	var got struct {
+		Requests []struct {
+			Image struct {
+				Content string `json:"content"`
+			} `json:"image"`
+			Features []struct {
+				Type string `json:"type"`
+			} `json:"features"`
+			ImageContext struct {
+				LanguageHints []string `json:"languageHints"`
+			} `json:"imageContext"`
+		} `json:"requests"`
+	}
+	vision := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Query().Get("key") != "test-key" {
+			http.Error(w, `{"error":{"code":403,"message":"bad key"}}`, http.StatusForbidden)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(visionStubResponse))
+	}))
+	defer vision.Close()
+	images := imageServer(t)
+
+	ocr := media.NewGoogleVisionOCRWithConfig(media.GoogleVisionConfig{APIKey: "test-key", Endpoint: vision.URL}, nil)
+	ctx := media.WithOCRLanguages(context.Background(), media.ParseLanguageHints("de, en")...)
+	result, err := ocr.ExtractFromURL(ctx, images.URL+"/logo.png")
+	if err != nil {
+		t.Fatalf("ExtractFromURL failed: %v", err)
+	}
+
+	if len(got.Requests) != 1 || len(got.Requests[0].Features) != 1 || got.Requests[0].Features[0].Type != "DOCUMENT_TEXT_DETECTION" {
+		t.Fatalf("Unexpected request %+v", got)
+	}
+	if content, _ := base64.StdEncoding.DecodeString(got.Requests[0].Image.Content); string(content) != string(pngBytes) {
+		t.Errorf("Image content not sent inline: %q", content)
+	}
+	if hints := got.Requests[0].ImageContext.LanguageHints; len(hints) != 2 || hints[0] != "de" || hints[1] != "en" {
+		t.Errorf("Language hints = %v", hints)
+	}
+
+	if result.Text != "Benutzer anlegen\nSpeichern" || result.Language != "de" {
+		t.Errorf("Text %q, language %q", result.Text, result.Language)
+	}
+	if len(result.BoundingBoxes) != 2 {
+		t.Fatalf("Expected 2 bounding boxes, got %+v", result.BoundingBoxes)
+	}
+	want := media.TextBoundingBox{Text: "Benutzer anlegen", Confidence: 0.9, X1: 0.1, Y1: 0.1, X2: 0.9, Y2: 0.3}
+	if box := result.BoundingBoxes[0]; box != want {
+		t.Errorf("First box = %+v, want %+v", box, want)
+	}
+	// Weighted by text length: (0.9*16 + 0.6*9) / 25
+	if c := result.ConfidenceScore; c < 0.791 || c > 0.793 {
+		t.Errorf("Confidence = %f, want 0.792", c)
+	}
+
+	// Local files go through the same call
+	path := filepath.Join(t.TempDir(), "screen.png")
+	if err := os.WriteFile(path, pngBytes, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := ocr.ExtractFromFile(context.Background(), path); err != nil || len(result.BoundingBoxes) != 2 {
+		t.Errorf("ExtractFromFile: %v, %+v", err, result)
+	}
+
+	bad := media.NewGoogleVisionOCRWithConfig(media.GoogleVisionConfig{APIKey: "wrong", Endpoint: vision.URL}, nil)
+	if _, err := bad.ExtractFromFile(context.Background(), path); !errors.Is(err, media.ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestGoogleVisionOCR_AnnotateError(t *testing.T) {
+	vision := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"responses":[{"error":{"code":3,"message":"Bad image data."}}]}`))
+	}))
+	defer vision.Close()
+
+	path := filepath.Join(t.TempDir(), "broken.png")
+	if err := os.WriteFile(path, []byte("not an image"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ocr := media.NewGoogleVisionOCRWithConfig(media.GoogleVisionConfig{APIKey: "k", Endpoint: vision.URL}, nil)
+	if _, err := ocr.ExtractFromFile(context.Background(), path); !errors.Is(err, media.ErrExtractionFailed) {
+		t.Errorf("Expected ErrExtractionFailed, got %v", err)
+	}
+}
+
 func TestNewGoogleVisionOCR(t *testing.T) {
 	ctx := context.Background()
 	logger := slog.Default()
@@ -70,3 +202,29 @@ func TestNewGoogleVisionOCR(t *testing.T) {
 		t.Error("Expected non-nil OCR handler")
 	}
 }
+
+func TestNewOCRHandler(t *testing.T) {
+	bin, _ := fakeTesseract(t)
+	testCases := []struct {
+		name        string
+		cfg         media.Config
+		expectError bool
+	}{
+		{name: "default is google", cfg: media.Config{}},
+		{name: "tesseract", cfg: media.Config{OCRBackend: media.OCRBackendTesseract, Tesseract: media.TesseractConfig{Path: bin}}},
+		{name: "tesseract missing", cfg: media.Config{OCRBackend: media.OCRBackendTesseract, Tesseract: media.TesseractConfig{Path: filepath.Join(t.TempDir(), "none")}}, expectError: true},
+		{name: "vision llm", cfg: media.Config{OCRBackend: media.OCRBackendVisionLLM, VisionLLM: media.VisionLLMConfig{APIKey: "k"}}},
+		{name: "unknown", cfg: media.Config{OCRBackend: "abbyy"}, expectError: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ocr, err := media.NewOCRHandler(tc.cfg, nil)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+			if !tc.expectError && ocr == nil {
+				t.Error("Expected non-nil OCR handler")
+			}
+		})
+	}
+}
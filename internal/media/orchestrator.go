@@ -14,27 +14,34 @@ const (
 
 // MediaOrchestrator routes media items to the appropriate handler based on type
 type MediaOrchestrator struct {
-	ocrHandler     *GoogleVisionOCR
+	ocrHandler     OCRHandler
 	youtubeHandler *YouTubeTranscriptFetcher
 	videoHandler   *VideoTranscriber
 	logger         *slog.Logger
 }
 
-// NewMediaOrchestrator creates a new orchestrator with all media handlers
+// NewMediaOrchestrator creates a new orchestrator with all media handlers,
+// configured from the environment (see ConfigFromEnv)
 func NewMediaOrchestrator(logger *slog.Logger) (*MediaOrchestrator, error) {
+	return NewMediaOrchestratorWithConfig(ConfigFromEnv(), logger)
+}
+
+// NewMediaOrchestratorWithConfig creates an orchestrator using the OCR
+// backend selected by cfg and its transcription and caption settings.
+func NewMediaOrchestratorWithConfig(cfg Config, logger *slog.Logger) (*MediaOrchestrator, error) {
 	if logger == nil {
 		logger = slog.Default()
 	}
 
-	ocrHandler, err := NewGoogleVisionOCR(context.Background(), logger)
+	ocrHandler, err := NewOCRHandler(cfg, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OCR handler: %w", err)
 	}
 
 	return &MediaOrchestrator{
 		ocrHandler:     ocrHandler,
-		youtubeHandler: NewYouTubeTranscriptFetcher(logger),
-		videoHandler:   NewVideoTranscriber(logger),
+		youtubeHandler: NewYouTubeTranscriptFetcherWithConfig(cfg.YouTube, logger),
+		videoHandler:   NewVideoTranscriberWithConfig(cfg.Whisper, logger),
 		logger:         logger,
 	}, nil
 }
@@ -59,7 +66,13 @@ func (o *MediaOrchestrator) ProcessMediaItem(ctx context.Context, item *MediaIte
 func (o *MediaOrchestrator) processImage(ctx context.Context, item *MediaItem) (*ExtractedContent, error) {
 	o.logger.Debug("Processing image with OCR", "url", item.URL)
 
-	result, err := o.ocrHandler.ExtractFromURL(ctx, item.URL)
+	var result *OCRResult
+	var err error
+	if item.FilePath != "" {
+		result, err = o.ocrHandler.ExtractFromFile(ctx, item.FilePath)
+	} else {
+		result, err = o.ocrHandler.ExtractFromURL(ctx, item.URL)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("OCR extraction failed: %w", err)
 	}
@@ -104,6 +117,8 @@ func (o *MediaOrchestrator) processYouTube(ctx context.Context, item *MediaItem)
 	return &ExtractedContent{
 		MediaItemID: item.ID,
 		Text:        result.Transcript,
+		Title:       result.Title,
+		Segments:    result.Segments,
 		Language:    result.Language,
 		Confidence:  1.0, // YouTube transcripts typically high confidence
 		ExtractedAt: item.CreatedAt,
@@ -123,7 +138,13 @@ func (o *MediaOrchestrator) processYouTube(ctx context.Context, item *MediaItem)
 func (o *MediaOrchestrator) processVideo(ctx context.Context, item *MediaItem) (*ExtractedContent, error) {
 	o.logger.Debug("Processing video file", "url", item.URL)
 
-	result, err := o.videoHandler.TranscribeFromURL(ctx, item.URL)
+	var result *TranscriptResult
+	var err error
+	if item.FilePath != "" {
+		result, err = o.videoHandler.TranscribeFromFile(ctx, item.FilePath)
+	} else {
+		result, err = o.videoHandler.TranscribeFromURL(ctx, item.URL)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("video transcription failed: %w", err)
 	}
@@ -141,6 +162,7 @@ func (o *MediaOrchestrator) processVideo(ctx context.Context, item *MediaItem) (
 	return &ExtractedContent{
 		MediaItemID: item.ID,
 		Text:        result.Transcript,
+		Segments:    result.Segments,
 		Language:    result.Language,
 		Confidence:  0.95, // Speech-to-text typically high confidence
 		ExtractedAt: item.CreatedAt,
@@ -200,13 +222,19 @@ func (o *MediaOrchestrator) Close() error {
 	return nil
 }
 
+// IsPlaceholder reports whether text is the mock output of a handler running
+// without credentials, which must not be indexed as content.
+func IsPlaceholder(text string) bool {
+	return strings.HasPrefix(text, "[Mock")
+}
+
 // Helper functions
 
 func determineStatus(text string, confidence float64) string {
 	if text == "" {
 		return "failed"
 	}
-	if confidence < 0.5 {
+	if confidence < 0.5 || IsPlaceholder(text) {
 		return "partial"
 	}
 	return "success"
@@ -216,7 +244,7 @@ func determineStatusFromText(text string) string {
 	if text == "" {
 		return "failed"
 	}
-	if strings.Contains(text, "[Mock]") || strings.Contains(text, "Placeholder") {
+	if IsPlaceholder(text) || strings.Contains(text, "[Mock]") || strings.Contains(text, "Placeholder") {
 		return "partial"
 	}
 	return "success"
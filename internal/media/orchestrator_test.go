@@ -19,7 +19,10 @@ func TestMediaOrchestrator_ProcessImage(t *testing.T) {
 }
 
 func TestMediaOrchestrator_ProcessYouTube(t *testing.T) {
-	orchestrator, err := media.NewMediaOrchestrator(slog.Default())
+	stub := youTubeStub(t)
+	orchestrator, err := media.NewMediaOrchestratorWithConfig(media.Config{
+		YouTube: media.YouTubeConfig{BaseURL: stub.URL, TimedTextURL: stub.URL},
+	}, slog.Default())
 	if err != nil {
 		t.Fatalf("Failed to create orchestrator: %v", err)
 	}
@@ -30,7 +33,7 @@ func TestMediaOrchestrator_ProcessYouTube(t *testing.T) {
 		ProjectID: "proj_123",
 		SourceID:  "src_456",
 		Type:      "youtube",
-		URL:       "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		URL:       "https://www.youtube.com/watch?v=manual",
 		CreatedAt: time.Now().UTC().Format(time.RFC3339),
 	}
 
@@ -50,6 +53,9 @@ func TestMediaOrchestrator_ProcessYouTube(t *testing.T) {
 	if result.ContentType != "transcript" {
 		t.Errorf("Expected ContentType=transcript, got %s", result.ContentType)
 	}
+	if result.Title != "Getting started" || len(result.Segments) != 2 || result.Segments[1].StartSeconds != 4 {
+		t.Errorf("Expected title and timed segments, got %q %+v", result.Title, result.Segments)
+	}
 
 	// Verify metadata contains YouTube-specific fields
 	if result.Metadata == nil {
@@ -95,6 +101,9 @@ func TestMediaOrchestrator_ProcessVideo(t *testing.T) {
 	if result.ContentType != "transcript" {
 		t.Errorf("Expected ContentType=transcript, got %s", result.ContentType)
 	}
+	if !media.IsPlaceholder(result.Text) || result.Status != "partial" {
+		t.Errorf("Expected mock transcript marked partial, got %q (%s)", result.Text, result.Status)
+	}
 
 	// Verify metadata contains video-specific fields
 	if result.Metadata == nil {
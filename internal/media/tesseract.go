@@ -0,0 +1,260 @@
+package media
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TesseractConfig configures TesseractOCR.
+type TesseractConfig struct {
+	// Path is the tesseract binary; default "tesseract" from PATH.
+	Path string
+	// Languages are used when a call carries no language hints; default
+	// "eng". Both tesseract codes ("deu") and BCP-47 codes ("de") work.
+	Languages []string
+	// HTTPClient is used for image downloads.
+	HTTPClient *http.Client
+}
+
+// TesseractOCR implements OCRHandler by running the tesseract CLI locally,
+// so images never leave the host.
+type TesseractOCR struct {
+	path      string
+	languages []string
+	client    *http.Client
+	logger    *slog.Logger
+}
+
+func tesseractConfigFromEnv() TesseractConfig {
+	return TesseractConfig{
+		Path:      os.Getenv("TESSERACT_PATH"),
+		Languages: ParseLanguageHints(strings.ReplaceAll(os.Getenv("TESSERACT_LANG"), "+", ",")),
+	}
+}
+
+// NewTesseractOCR creates a Tesseract OCR handler. It fails when the binary
+// cannot be found.
+func NewTesseractOCR(cfg TesseractConfig, logger *slog.Logger) (*TesseractOCR, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.Path == "" {
+		cfg.Path = "tesseract"
+	}
+	bin, err := exec.LookPath(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("tesseract not available: %w", err)
+	}
+	if len(cfg.Languages) == 0 {
+		cfg.Languages = []string{"eng"}
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 60 * time.Second}
+	}
+	return &TesseractOCR{
+		path:      bin,
+		languages: cfg.Languages,
+		client:    cfg.HTTPClient,
+		logger:    logger,
+	}, nil
+}
+
+// ExtractFromURL downloads an image to a temporary file and runs tesseract on it
+func (t *TesseractOCR) ExtractFromURL(ctx context.Context, imageURL string) (*OCRResult, error) {
+	t.logger.Debug("Extracting text from URL", "url", imageURL)
+
+	u, err := parseMediaURL(imageURL)
+	if err != nil {
+		return nil, err
+	}
+	data, err := downloadImage(ctx, t.client, imageURL)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp("", "ocr-*"+path.Ext(u.Path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	return t.ExtractFromFile(ctx, f.Name())
+}
+
+// ExtractFromFile runs tesseract on a local image file, with the language
+// hints of ctx (see WithOCRLanguages) or the configured languages.
+func (t *TesseractOCR) ExtractFromFile(ctx context.Context, filePath string) (*OCRResult, error) {
+	t.logger.Debug("Extracting text from file", "path", filePath)
+
+	cleanPath := filepath.Clean(filePath)
+	if _, err := os.Stat(cleanPath); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	langs := OCRLanguages(ctx)
+	if len(langs) == 0 {
+		langs = t.languages
+	}
+	codes := make([]string, len(langs))
+	for i, l := range langs {
+		codes[i] = tesseractLanguage(l)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.path, cleanPath, "stdout", "-l", strings.Join(codes, "+"), "tsv")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: tesseract: %v: %s", ErrExtractionFailed, err, strings.TrimSpace(stderr.String()))
+	}
+
+	result := tesseractResult(stdout.Bytes())
+	result.Language = bcp47Language(langs[0])
+	return result, nil
+}
+
+// tesseractBlock collects the words of one block of tesseract TSV output.
+type tesseractBlock struct {
+	left, top, width, height int
+	text                     strings.Builder
+	line                     string // "<par>.<line>" of the last word
+	weighted, weight         float64
+}
+
+// tesseractResult maps tesseract TSV output to an OCRResult: one bounding
+// box per block, normalized to 0-1 of the page size, with the word
+// confidences averaged by text length as for Google Vision.
+func tesseractResult(tsv []byte) *OCRResult {
+	var (
+		blocks                []*tesseractBlock
+		pageWidth, pageHeight int
+	)
+	sc := bufio.NewScanner(bytes.NewReader(tsv))
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		// level page block par line word left top width height conf text
+		f := strings.Split(sc.Text(), "\t")
+		if len(f) < 11 {
+			continue
+		}
+		level, err := strconv.Atoi(f[0])
+		if err != nil {
+			continue // header
+		}
+		n := make([]int, 10)
+		for i := range n {
+			n[i], _ = strconv.Atoi(f[i])
+		}
+		switch level {
+		case 1:
+			pageWidth, pageHeight = n[8], n[9]
+		case 2:
+			blocks = append(blocks, &tesseractBlock{left: n[6], top: n[7], width: n[8], height: n[9]})
+		case 5:
+			word := ""
+			if len(f) > 11 {
+				word = strings.TrimSpace(f[11])
+			}
+			conf, err := strconv.ParseFloat(f[10], 64)
+			if word == "" || err != nil || conf < 0 || len(blocks) == 0 {
+				continue
+			}
+			b := blocks[len(blocks)-1]
+			line := f[3] + "." + f[4]
+			switch {
+			case b.text.Len() == 0:
+			case line != b.line:
+				b.text.WriteByte('\n')
+			default:
+				b.text.WriteByte(' ')
+			}
+			b.text.WriteString(word)
+			b.line = line
+			b.weighted += conf / 100 * float64(len(word))
+			b.weight += float64(len(word))
+		}
+	}
+
+	result := &OCRResult{BoundingBoxes: []TextBoundingBox{}}
+	var texts []string
+	var weighted, weight float64
+	for _, b := range blocks {
+		text := b.text.String()
+		if text == "" {
+			continue
+		}
+		box := TextBoundingBox{Text: text, Confidence: b.weighted / b.weight}
+		if pageWidth > 0 && pageHeight > 0 {
+			box.X1 = float32(b.left) / float32(pageWidth)
+			box.Y1 = float32(b.top) / float32(pageHeight)
+			box.X2 = float32(b.left+b.width) / float32(pageWidth)
+			box.Y2 = float32(b.top+b.height) / float32(pageHeight)
+		}
+		result.BoundingBoxes = append(result.BoundingBoxes, box)
+		texts = append(texts, text)
+		weighted += b.weighted
+		weight += b.weight
+	}
+	result.Text = strings.Join(texts, "\n\n")
+	if weight > 0 {
+		result.ConfidenceScore = weighted / weight
+	}
+	return result
+}
+
+// tesseractLanguages maps BCP-47 language codes to tesseract's traineddata
+// names for the languages most often installed.
+var tesseractLanguages = map[string]string{
+	"ar": "ara", "cs": "ces", "da": "dan", "de": "deu", "el": "ell",
+	"en": "eng", "es": "spa", "fi": "fin", "fr": "fra", "he": "heb",
+	"hi": "hin", "hu": "hun", "it": "ita", "ja": "jpn", "ko": "kor",
+	"nl": "nld", "no": "nor", "pl": "pol", "pt": "por", "ro": "ron",
+	"ru": "rus", "sv": "swe", "tr": "tur", "uk": "ukr", "zh": "chi_sim",
+	"zh-hans": "chi_sim", "zh-hant": "chi_tra", "zh-tw": "chi_tra",
+}
+
+// tesseractLanguage returns the tesseract code of a language hint; codes it
+// does not know are passed through, so "deu" or "frk" work as well.
+func tesseractLanguage(lang string) string {
+	l := strings.ToLower(lang)
+	if code, ok := tesseractLanguages[l]; ok {
+		return code
+	}
+	if base, _, ok := strings.Cut(l, "-"); ok {
+		if code, ok := tesseractLanguages[base]; ok {
+			return code
+		}
+	}
+	return lang
+}
+
+// bcp47Language is the inverse of tesseractLanguage.
+func bcp47Language(lang string) string {
+	for bcp, code := range tesseractLanguages {
+		if code == lang && !strings.Contains(bcp, "-") {
+			return bcp
+		}
+	}
+	return lang
+}
+
+// Close closes any resources
+func (t *TesseractOCR) Close() error {
+	return nil
+}
@@ -0,0 +1,98 @@
+package media_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"cgap/internal/media"
+)
+
+// tesseractTSV is the TSV output for two blocks on a 200x100 page.
+const tesseractTSV = "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n" +
+	"1\t1\t0\t0\t0\t0\t0\t0\t200\t100\t-1\t\n" +
+	"2\t1\t1\t0\t0\t0\t20\t10\t160\t20\t-1\t\n" +
+	"4\t1\t1\t1\t1\t0\t20\t10\t160\t10\t-1\t\n" +
+	"5\t1\t1\t1\t1\t1\t20\t10\t80\t10\t90\tBenutzer\n" +
+	"5\t1\t1\t1\t1\t2\t110\t10\t70\t10\t90\tanlegen\n" +
+	"5\t1\t1\t1\t2\t1\t20\t20\t50\t10\t90\tjetzt\n" +
+	"2\t1\t2\t0\t0\t0\t150\t80\t40\t15\t-1\t\n" +
+	"5\t1\t2\t1\t1\t1\t150\t80\t40\t15\t60\tSpeichern\n" +
+	"5\t1\t2\t1\t1\t2\t150\t80\t40\t15\t95\t \n"
+
+// fakeTesseract writes a script that records its arguments and prints
+// tesseractTSV.
+func fakeTesseract(t *testing.T) (bin, argsFile string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tesseract is a shell script")
+	}
+	dir := t.TempDir()
+	bin = filepath.Join(dir, "tesseract")
+	argsFile = filepath.Join(dir, "args")
+	if err := os.WriteFile(filepath.Join(dir, "out.tsv"), []byte(tesseractTSV), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\ncat " + filepath.Join(dir, "out.tsv") + "\n"
+	if err := os.WriteFile(bin, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return bin, argsFile
+}
+
+func TestTesseractOCR(t *testing.T) {
+	bin, argsFile := fakeTesseract(t)
+	ocr, err := media.NewTesseractOCR(media.TesseractConfig{Path: bin}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	images := imageServer(t)
+
+	ctx := media.WithOCRLanguages(context.Background(), "de", "en-US")
+	result, err := ocr.ExtractFromURL(ctx, images.URL+"/logo.png")
+	if err != nil {
+		t.Fatalf("ExtractFromURL failed: %v", err)
+	}
+	args, _ := os.ReadFile(argsFile)
+	if fields := strings.Fields(string(args)); len(fields) != 5 || !strings.HasSuffix(fields[0], ".png") ||
+		strings.Join(fields[1:], " ") != "stdout -l deu+eng tsv" {
+		t.Errorf("tesseract called with %q", args)
+	}
+
+	if result.Text != "Benutzer anlegen\njetzt\n\nSpeichern" || result.Language != "de" {
+		t.Errorf("Text %q, language %q", result.Text, result.Language)
+	}
+	if len(result.BoundingBoxes) != 2 {
+		t.Fatalf("Expected 2 bounding boxes, got %+v", result.BoundingBoxes)
+	}
+	want := media.TextBoundingBox{Text: "Benutzer anlegen\njetzt", Confidence: 0.9, X1: 0.1, Y1: 0.1, X2: 0.9, Y2: 0.3}
+	if box := result.BoundingBoxes[0]; box != want {
+		t.Errorf("First box = %+v, want %+v", box, want)
+	}
+	// Weighted by word length: (0.9*20 + 0.6*9) / 29
+	if c := result.ConfidenceScore; c < 0.806 || c > 0.808 {
+		t.Errorf("Confidence = %f, want 0.807", c)
+	}
+
+	// Without hints, the configured languages are used
+	path := filepath.Join(t.TempDir(), "screen.png")
+	if err := os.WriteFile(path, pngBytes, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := ocr.ExtractFromFile(context.Background(), path); err != nil || result.Language != "en" {
+		t.Errorf("ExtractFromFile: %v, %+v", err, result)
+	}
+	if args, _ := os.ReadFile(argsFile); !strings.Contains(string(args), "-l eng tsv") {
+		t.Errorf("tesseract called with %q", args)
+	}
+}
+
+func TestNewTesseractOCR_MissingBinary(t *testing.T) {
+	_, err := media.NewTesseractOCR(media.TesseractConfig{Path: filepath.Join(t.TempDir(), "tesseract")}, nil)
+	if err == nil {
+		t.Error("Expected error for missing tesseract binary")
+	}
+}
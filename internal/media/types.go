@@ -49,6 +49,8 @@ type YouTubeHandler interface {
 
 // TranscriptResult contains the output of transcript extraction
 type TranscriptResult struct {
+	// Title of the video, when the source provides one
+	Title string
 	// Full transcript text
 	Transcript string
 	// Language of the transcript
@@ -84,6 +86,7 @@ type MediaItem struct {
 	SourceID      string
 	Type          string // "image", "video", "youtube", "pdf", "audio"
 	URL           string
+	FilePath      string // Local copy of an uploaded file; processed instead of URL when set
 	ExternalID    string // YouTube video ID, etc.
 	FileSizeBytes int
 	CreatedAt     string // RFC3339 timestamp
@@ -103,26 +106,36 @@ type ExtractedContent struct {
 	Language string
 	// When extraction was performed
 	ExtractedAt string
+	// Title of the media, e.g. of a YouTube video; may be empty
+	Title string
+	// Timed segments of a transcript; nil for images
+	Segments []TranscriptSegment
 	// Additional metadata (segments, video_id, etc.)
 	Metadata map[string]interface{}
 	// Status: "success", "partial", "failed"
 	Status string
 }
 
+// OCR backends selectable with Config.OCRBackend.
+const (
+	OCRBackendGoogle    = "google"
+	OCRBackendTesseract = "tesseract"
+	OCRBackendVisionLLM = "vision_llm"
+)
+
 // Config holds configuration for media handlers
 type Config struct {
-	// Google Cloud credentials (for OCR)
-	GoogleCredentialsPath string
-	// YouTube API key (if using authenticated API)
-	GoogleAPIKey string
-	// YouTube API key (if using authenticated API)
-	YouTubeAPIKey string
-	// Default language for OCR
-	DefaultLanguage string
-	// Retry configuration
-	MaxRetries int
-	// milliseconds
-	RetryDelay int
+	// OCRBackend selects the OCR handler: OCRBackendGoogle (default),
+	// OCRBackendTesseract or OCRBackendVisionLLM.
+	OCRBackend string
+	// Settings of each OCR backend; only the selected one is used.
+	GoogleVision GoogleVisionConfig
+	Tesseract    TesseractConfig
+	VisionLLM    VisionLLMConfig
+	// Whisper configures video transcription.
+	Whisper WhisperConfig
+	// YouTube configures caption fetching.
+	YouTube YouTubeConfig
 }
 
 // Errors
@@ -131,4 +144,5 @@ var (
 	ErrInvalidURL       = fmt.Errorf("invalid URL")
 	ErrExtractionFailed = fmt.Errorf("extraction failed")
 	ErrUnauthorized     = fmt.Errorf("unauthorized (check API credentials)")
+	ErrNoTranscript     = fmt.Errorf("no transcript available")
 )
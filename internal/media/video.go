@@ -1,54 +1,171 @@
 package media
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
+	"math"
+	"mime/multipart"
+	"net/http"
 	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Defaults of WhisperConfig.
+const (
+	DefaultWhisperBaseURL = "https://api.openai.com/v1"
+	DefaultWhisperModel   = "whisper-1"
+	// DefaultWhisperMaxUploadBytes is the OpenAI file size limit.
+	DefaultWhisperMaxUploadBytes = 25 << 20
+	// DefaultVideoMaxBytes bounds downloaded and local media files.
+	DefaultVideoMaxBytes = 1 << 30
+	// DefaultSegmentSeconds is the length of the audio chunks cut by ffmpeg;
+	// at 64 kbit/s mono, ten minutes are about 5 MB.
+	DefaultSegmentSeconds = 600
+)
+
+// WhisperConfig configures VideoTranscriber.
+type WhisperConfig struct {
+	// BaseURL of an OpenAI-compatible API with /audio/transcriptions, e.g. a
+	// local faster-whisper server; default DefaultWhisperBaseURL.
+	BaseURL string
+	// APIKey is sent as a bearer token. Without a key and BaseURL, the
+	// transcriber returns mock transcripts.
+	APIKey string
+	// Model defaults to DefaultWhisperModel.
+	Model string
+	// Language is an optional ISO-639-1 hint ("en") for the spoken language.
+	Language string
+	// MaxBytes bounds media files; default DefaultVideoMaxBytes.
+	MaxBytes int64
+	// MaxUploadBytes bounds one upload; larger files are cut into audio
+	// chunks with ffmpeg. Default DefaultWhisperMaxUploadBytes.
+	MaxUploadBytes int64
+	// FFmpegPath is the ffmpeg binary; default "ffmpeg" from PATH. Without
+	// it, files larger than MaxUploadBytes cannot be transcribed.
+	FFmpegPath string
+	// SegmentSeconds is the chunk length; default DefaultSegmentSeconds.
+	SegmentSeconds int
+	// HTTPClient is used for API requests and media downloads.
+	HTTPClient *http.Client
+}
+
 // VideoTranscriber handles transcription of direct video files (non-YouTube)
-// Uses speech-to-text services like Whisper, AssemblyAI, or similar
+// with the OpenAI Whisper API or an OpenAI-compatible server
 type VideoTranscriber struct {
-	logger *slog.Logger
-	apiKey string
+	logger         *slog.Logger
+	baseURL        string
+	apiKey         string
+	model          string
+	language       string
+	maxBytes       int64
+	maxUploadBytes int64
+	ffmpeg         string // "" when ffmpeg is not available
+	segmentSeconds int
+	client         *http.Client
+	mock           bool
 }
 
-// NewVideoTranscriber creates a new video transcriber
+// NewVideoTranscriber creates a new video transcriber configured from
+// WHISPER_API_KEY (or OPENAI_API_KEY), WHISPER_BASE_URL, WHISPER_MODEL,
+// WHISPER_LANGUAGE, VIDEO_MAX_BYTES and FFMPEG_PATH
 func NewVideoTranscriber(logger *slog.Logger) *VideoTranscriber {
-	if logger == nil {
-		logger = slog.Default()
-	}
+	return NewVideoTranscriberWithConfig(whisperConfigFromEnv(), logger)
+}
 
-	// Check for API keys in environment
+func whisperConfigFromEnv() WhisperConfig {
 	apiKey := os.Getenv("WHISPER_API_KEY")
 	if apiKey == "" {
-		apiKey = os.Getenv("ASSEMBLYAI_API_KEY")
+		apiKey = os.Getenv("OPENAI_API_KEY")
 	}
-	if apiKey == "" {
-		apiKey = os.Getenv("OPENAI_API_KEY") // OpenAI Whisper
+	maxBytes, _ := strconv.ParseInt(os.Getenv("VIDEO_MAX_BYTES"), 10, 64)
+	return WhisperConfig{
+		BaseURL:    os.Getenv("WHISPER_BASE_URL"),
+		APIKey:     apiKey,
+		Model:      os.Getenv("WHISPER_MODEL"),
+		Language:   os.Getenv("WHISPER_LANGUAGE"),
+		MaxBytes:   maxBytes,
+		FFmpegPath: os.Getenv("FFMPEG_PATH"),
 	}
+}
 
-	if apiKey == "" {
-		logger.Warn("No transcription API key found (WHISPER_API_KEY, ASSEMBLYAI_API_KEY, or OPENAI_API_KEY), will use mock mode")
+// NewVideoTranscriberWithConfig creates a video transcriber from cfg.
+func NewVideoTranscriberWithConfig(cfg WhisperConfig, logger *slog.Logger) *VideoTranscriber {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	mock := cfg.APIKey == "" && cfg.BaseURL == ""
+	if mock {
+		logger.Warn("No transcription API key found (WHISPER_API_KEY or OPENAI_API_KEY), will use mock mode")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultWhisperBaseURL
+	}
+	if cfg.Model == "" {
+		cfg.Model = DefaultWhisperModel
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultVideoMaxBytes
+	}
+	if cfg.MaxUploadBytes <= 0 {
+		cfg.MaxUploadBytes = DefaultWhisperMaxUploadBytes
+	}
+	if cfg.SegmentSeconds <= 0 {
+		cfg.SegmentSeconds = DefaultSegmentSeconds
+	}
+	if cfg.FFmpegPath == "" {
+		cfg.FFmpegPath = "ffmpeg"
+	}
+	ffmpeg, err := exec.LookPath(cfg.FFmpegPath)
+	if err != nil {
+		ffmpeg = ""
+		if !mock {
+			logger.Warn("ffmpeg not found, media files larger than the upload limit cannot be transcribed", "max_upload_bytes", cfg.MaxUploadBytes)
+		}
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Minute}
 	}
 
 	return &VideoTranscriber{
-		logger: logger,
-		apiKey: apiKey,
+		logger:         logger,
+		baseURL:        strings.TrimSuffix(cfg.BaseURL, "/"),
+		apiKey:         cfg.APIKey,
+		model:          cfg.Model,
+		language:       cfg.Language,
+		maxBytes:       cfg.MaxBytes,
+		maxUploadBytes: cfg.MaxUploadBytes,
+		ffmpeg:         ffmpeg,
+		segmentSeconds: cfg.SegmentSeconds,
+		client:         cfg.HTTPClient,
+		mock:           mock,
 	}
 }
 
-// TranscribeFromURL transcribes a video from a URL
+// TranscribeFromURL downloads a video to a temporary file and transcribes it
 func (v *VideoTranscriber) TranscribeFromURL(ctx context.Context, videoURL string) (*TranscriptResult, error) {
 	v.logger.Info("Transcribing video from URL", "url", videoURL)
 
-	if v.apiKey != "" {
-		return v.transcribeWithAPI(ctx, videoURL)
+	if v.mock {
+		// Mock mode - return sample transcript
+		return v.getMockTranscript(videoURL), nil
 	}
 
-	// Mock mode - return sample transcript
-	return v.getMockTranscript(videoURL), nil
+	filePath, err := v.download(ctx, videoURL)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(filePath)
+	return v.transcribeFile(ctx, filePath)
 }
 
 // TranscribeFromFile transcribes a local video file
@@ -60,41 +177,248 @@ func (v *VideoTranscriber) TranscribeFromFile(ctx context.Context, filePath stri
 		return nil, fmt.Errorf("failed to read video file: %w", err)
 	}
 
-	if v.apiKey != "" {
-		return v.transcribeFileWithAPI(ctx, filePath)
+	if v.mock {
+		// Mock mode - return sample transcript
+		return v.getMockTranscript(filePath), nil
+	}
+	return v.transcribeFile(ctx, filePath)
+}
+
+// download copies a video of at most maxBytes to a temporary file, keeping
+// the extension, which the API uses to detect the format.
+func (v *VideoTranscriber) download(ctx context.Context, videoURL string) (string, error) {
+	u, err := parseMediaURL(videoURL)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, videoURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to download video: %w", err)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download video: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: HTTP %d", ErrExtractionFailed, resp.StatusCode)
 	}
 
-	// Mock mode - return sample transcript
-	return v.getMockTranscript(filePath), nil
+	f, err := os.CreateTemp("", "video-*"+path.Ext(u.Path))
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	n, err := io.Copy(f, io.LimitReader(resp.Body, v.maxBytes+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > v.maxBytes {
+		err = fmt.Errorf("%w: video larger than %d bytes", ErrExtractionFailed, v.maxBytes)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to download video: %w", err)
+	}
+	return f.Name(), nil
 }
 
-// transcribeWithAPI transcribes video using API service
-func (v *VideoTranscriber) transcribeWithAPI(_ context.Context, videoURL string) (*TranscriptResult, error) {
-	// Placeholder for real API integration
-	// This would call Whisper, AssemblyAI, etc.
-	v.logger.Warn("Video transcription API not yet implemented", "url", videoURL)
+// transcribeFile uploads a file in one request, or, when it is larger than
+// maxUploadBytes, as audio chunks cut by ffmpeg. Chunk timestamps are
+// shifted by the durations of the chunks before them.
+func (v *VideoTranscriber) transcribeFile(ctx context.Context, filePath string) (*TranscriptResult, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read video file: %w", err)
+	}
+	if info.Size() > v.maxBytes {
+		return nil, fmt.Errorf("%w: video larger than %d bytes", ErrExtractionFailed, v.maxBytes)
+	}
 
-	return &TranscriptResult{
-		Transcript:      "[API Placeholder] Video transcription not yet implemented. Configure WHISPER_API_KEY or ASSEMBLYAI_API_KEY.",
-		Language:        "en",
-		Segments:        []TranscriptSegment{},
-		IsAutoGenerated: true,
-		Duration:        0,
-	}, nil
+	files := []string{filePath}
+	if info.Size() > v.maxUploadBytes {
+		if v.ffmpeg == "" {
+			return nil, fmt.Errorf("%w: file larger than %d bytes and ffmpeg not available to split it", ErrExtractionFailed, v.maxUploadBytes)
+		}
+		dir, err := os.MkdirTemp("", "segments-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp dir: %w", err)
+		}
+		defer os.RemoveAll(dir)
+		if files, err = v.segment(ctx, filePath, dir); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &TranscriptResult{Segments: []TranscriptSegment{}, IsAutoGenerated: true}
+	var texts []string
+	var offset float64
+	for i, f := range files {
+		tr, err := v.upload(ctx, f)
+		if err != nil {
+			if len(files) > 1 {
+				return nil, fmt.Errorf("chunk %d of %d: %w", i+1, len(files), err)
+			}
+			return nil, err
+		}
+		if result.Language == "" {
+			result.Language = whisperLanguage(tr.Language)
+		}
+		if text := strings.TrimSpace(tr.Text); text != "" {
+			texts = append(texts, text)
+		}
+		for _, s := range tr.Segments {
+			text := strings.TrimSpace(s.Text)
+			if text == "" {
+				continue
+			}
+			result.Segments = append(result.Segments, TranscriptSegment{
+				Text:         text,
+				StartSeconds: int(math.Floor(offset + s.Start)),
+				EndSeconds:   int(math.Ceil(offset + s.End)),
+			})
+		}
+		duration := tr.Duration
+		if duration == 0 && len(tr.Segments) > 0 {
+			duration = tr.Segments[len(tr.Segments)-1].End
+		}
+		offset += duration
+	}
+	result.Transcript = strings.Join(texts, "\n")
+	result.Duration = int(math.Round(offset))
+	return result, nil
 }
 
-// transcribeFileWithAPI transcribes local file using API service
-func (v *VideoTranscriber) transcribeFileWithAPI(_ context.Context, filePath string) (*TranscriptResult, error) {
-	// Placeholder for real API integration
-	v.logger.Warn("Video file transcription API not yet implemented", "path", filePath)
+// segment cuts the audio track of filePath into mono 64 kbit/s MP3 chunks
+// of segmentSeconds in dir and returns them in order.
+func (v *VideoTranscriber) segment(ctx context.Context, filePath, dir string) ([]string, error) {
+	v.logger.Debug("Splitting audio with ffmpeg", "path", filePath, "segment_seconds", v.segmentSeconds)
 
-	return &TranscriptResult{
-		Transcript:      "[API Placeholder] Video file transcription not yet implemented. Configure WHISPER_API_KEY or ASSEMBLYAI_API_KEY.",
-		Language:        "en",
-		Segments:        []TranscriptSegment{},
-		IsAutoGenerated: true,
-		Duration:        0,
-	}, nil
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, v.ffmpeg, "-nostdin", "-loglevel", "error", "-i", filePath,
+		"-vn", "-ac", "1", "-ar", "16000", "-c:a", "libmp3lame", "-b:a", "64k",
+		"-f", "segment", "-segment_time", strconv.Itoa(v.segmentSeconds), "-reset_timestamps", "1",
+		filepath.Join(dir, "chunk-%04d.mp3"))
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: ffmpeg: %v: %s", ErrExtractionFailed, err, strings.TrimSpace(stderr.String()))
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "chunk-*.mp3"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: ffmpeg produced no audio", ErrExtractionFailed)
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil && info.Size() > v.maxUploadBytes {
+			return nil, fmt.Errorf("%w: audio chunk larger than %d bytes", ErrExtractionFailed, v.maxUploadBytes)
+		}
+	}
+	return files, nil
+}
+
+// whisperTranscription is the verbose_json response, limited to the fields used.
+type whisperTranscription struct {
+	Language string  `json:"language"`
+	Duration float64 `json:"duration"`
+	Text     string  `json:"text"`
+	Segments []struct {
+		Start float64 `json:"start"`
+		End   float64 `json:"end"`
+		Text  string  `json:"text"`
+	} `json:"segments"`
+}
+
+// upload sends one file to /audio/transcriptions.
+func (v *VideoTranscriber) upload(ctx context.Context, filePath string) (*whisperTranscription, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read video file: %w", err)
+	}
+	defer f.Close()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", filepath.Base(filePath))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return nil, fmt.Errorf("failed to read video file: %w", err)
+	}
+	fields := [][2]string{
+		{"model", v.model},
+		{"response_format", "verbose_json"},
+		{"timestamp_granularities[]", "segment"},
+	}
+	if v.language != "" {
+		fields = append(fields, [2]string{"language", v.language})
+	}
+	for _, kv := range fields {
+		if err := mw.WriteField(kv[0], kv[1]); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	v.logger.Debug("Calling transcription API", "model", v.model, "size", body.Len())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.baseURL+"/audio/transcriptions", &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transcription request: %w", err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if v.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+v.apiKey)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("transcription request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("%w: transcription API returned HTTP %d", ErrUnauthorized, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&e)
+		return nil, fmt.Errorf("%w: transcription API returned HTTP %d: %s", ErrExtractionFailed, resp.StatusCode, e.Error.Message)
+	}
+
+	var tr whisperTranscription
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return nil, fmt.Errorf("failed to decode transcription response: %w", err)
+	}
+	return &tr, nil
+}
+
+// whisperLanguages maps the language names in Whisper responses to
+// ISO-639-1 codes for the languages most often seen.
+var whisperLanguages = map[string]string{
+	"arabic": "ar", "chinese": "zh", "czech": "cs", "danish": "da",
+	"dutch": "nl", "english": "en", "finnish": "fi", "french": "fr",
+	"german": "de", "greek": "el", "hebrew": "he", "hindi": "hi",
+	"hungarian": "hu", "italian": "it", "japanese": "ja", "korean": "ko",
+	"norwegian": "no", "polish": "pl", "portuguese": "pt", "romanian": "ro",
+	"russian": "ru", "spanish": "es", "swedish": "sv", "turkish": "tr",
+	"ukrainian": "uk",
+}
+
+// whisperLanguage returns the code of a Whisper language name. Servers
+// that already return codes, and unknown names, are passed through.
+func whisperLanguage(lang string) string {
+	if code, ok := whisperLanguages[strings.ToLower(lang)]; ok {
+		return code
+	}
+	return lang
 }
 
 // getMockTranscript returns mock transcript for testing
@@ -102,7 +426,7 @@ func (v *VideoTranscriber) getMockTranscript(identifier string) *TranscriptResul
 	v.logger.Debug("Returning mock video transcript", "identifier", identifier)
 
 	return &TranscriptResult{
-		Transcript: fmt.Sprintf("[Mock Video Transcript] This is a mock transcript for video: %s\n\nNote: For production use, implement video transcription with:\n1. OpenAI Whisper API (set OPENAI_API_KEY)\n2. AssemblyAI (set ASSEMBLYAI_API_KEY)\n3. Or local Whisper model\n\nSupported formats: MP4, AVI, MOV, MKV, WebM", identifier),
+		Transcript: fmt.Sprintf("[Mock Video Transcript] This is a mock transcript for video: %s\n\nNote: For production use, set WHISPER_API_KEY or OPENAI_API_KEY, or WHISPER_BASE_URL for a local Whisper server.\n\nSupported formats: MP4, AVI, MOV, MKV, WebM", identifier),
 		Language:   "en",
 		Segments: []TranscriptSegment{
 			{
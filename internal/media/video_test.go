@@ -2,7 +2,17 @@ package media_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 
 	"cgap/internal/media"
@@ -118,3 +128,146 @@ func TestNewVideoTranscriber_NilLogger(t *testing.T) {
 		t.Error("Expected non-nil transcriber with nil logger")
 	}
 }
+
+// whisperStub serves /v1/audio/transcriptions, answering the n-th upload
+// with responses[n], and records the uploaded file names and form fields.
+func whisperStub(t *testing.T, responses ...string) (*httptest.Server, *[]map[string]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var uploads []map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			http.Error(w, `{"error":{"message":"invalid key"}}`, http.StatusUnauthorized)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f, hdr, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, `{"error":{"message":"file required"}}`, http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(f)
+		fields := map[string]string{"filename": hdr.Filename, "content": string(data)}
+		for k, v := range r.MultipartForm.Value {
+			fields[k] = v[0]
+		}
+		mu.Lock()
+		n := len(uploads)
+		uploads = append(uploads, fields)
+		mu.Unlock()
+		if n >= len(responses) {
+			http.Error(w, `{"error":{"message":"unexpected upload"}}`, http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(responses[n]))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &uploads
+}
+
+func TestVideoTranscriber_Whisper(t *testing.T) {
+	stub, uploads := whisperStub(t, `{"task":"transcribe","language":"german","duration":12.4,"text":" Hallo. Wir legen einen Benutzer an.",
+		"segments":[{"id":0,"start":0.0,"end":4.2,"text":" Hallo."},{"id":1,"start":4.2,"end":12.4,"text":" Wir legen einen Benutzer an."}]}`)
+	videos := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("fake mp4"))
+	}))
+	defer videos.Close()
+
+	transcriber := media.NewVideoTranscriberWithConfig(media.WhisperConfig{
+		BaseURL: stub.URL + "/v1", APIKey: "test-key", Language: "de",
+	}, nil)
+	result, err := transcriber.TranscribeFromURL(context.Background(), videos.URL+"/walkthrough.mp4")
+	if err != nil {
+		t.Fatalf("TranscribeFromURL failed: %v", err)
+	}
+
+	if len(*uploads) != 1 {
+		t.Fatalf("Expected 1 upload, got %d", len(*uploads))
+	}
+	up := (*uploads)[0]
+	if !strings.HasSuffix(up["filename"], ".mp4") || up["content"] != "fake mp4" || up["model"] != "whisper-1" ||
+		up["response_format"] != "verbose_json" || up["language"] != "de" {
+		t.Errorf("Unexpected upload %v", up)
+	}
+
+	want := []media.TranscriptSegment{
+		{Text: "Hallo.", StartSeconds: 0, EndSeconds: 5},
+		{Text: "Wir legen einen Benutzer an.", StartSeconds: 4, EndSeconds: 13},
+	}
+	if fmt.Sprint(result.Segments) != fmt.Sprint(want) {
+		t.Errorf("Segments = %+v, want %+v", result.Segments, want)
+	}
+	if result.Transcript != "Hallo. Wir legen einen Benutzer an." || result.Language != "de" || result.Duration != 12 || !result.IsAutoGenerated {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	bad := media.NewVideoTranscriberWithConfig(media.WhisperConfig{BaseURL: stub.URL + "/v1", APIKey: "wrong"}, nil)
+	if _, err := bad.TranscribeFromURL(context.Background(), videos.URL+"/walkthrough.mp4"); !errors.Is(err, media.ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+}
+
+// fakeFFmpeg writes a script that "splits" its input into two chunks in the
+// directory of the output pattern, its last argument.
+func fakeFFmpeg(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg is a shell script")
+	}
+	bin := filepath.Join(t.TempDir(), "ffmpeg")
+	script := "#!/bin/sh\nfor out; do :; done\ndir=$(dirname \"$out\")\nprintf one > \"$dir/chunk-0000.mp3\"\nprintf two > \"$dir/chunk-0001.mp3\"\n"
+	if err := os.WriteFile(bin, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return bin
+}
+
+func TestVideoTranscriber_SegmentsLargeFiles(t *testing.T) {
+	stub, uploads := whisperStub(t,
+		`{"language":"english","duration":600.5,"text":"First part.","segments":[{"start":0,"end":598.2,"text":"First part."}]}`,
+		`{"language":"english","duration":30,"text":"Second part.","segments":[{"start":1.5,"end":10.5,"text":"Second part."}]}`)
+	path := filepath.Join(t.TempDir(), "long.mp4")
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", 100)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	transcriber := media.NewVideoTranscriberWithConfig(media.WhisperConfig{
+		BaseURL: stub.URL + "/v1", APIKey: "test-key", MaxUploadBytes: 50, FFmpegPath: fakeFFmpeg(t),
+	}, nil)
+	result, err := transcriber.TranscribeFromFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("TranscribeFromFile failed: %v", err)
+	}
+	if len(*uploads) != 2 || (*uploads)[0]["content"] != "one" || (*uploads)[1]["content"] != "two" {
+		t.Fatalf("Expected the two chunks in order, got %v", *uploads)
+	}
+	// The second chunk is shifted by the duration of the first
+	want := []media.TranscriptSegment{
+		{Text: "First part.", StartSeconds: 0, EndSeconds: 599},
+		{Text: "Second part.", StartSeconds: 602, EndSeconds: 611},
+	}
+	if fmt.Sprint(result.Segments) != fmt.Sprint(want) {
+		t.Errorf("Segments = %+v, want %+v", result.Segments, want)
+	}
+	if result.Transcript != "First part.\nSecond part." || result.Language != "en" || result.Duration != 631 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	// Without ffmpeg, large files are rejected instead of sent
+	noFFmpeg := media.NewVideoTranscriberWithConfig(media.WhisperConfig{
+		BaseURL: stub.URL + "/v1", APIKey: "test-key", MaxUploadBytes: 50, FFmpegPath: filepath.Join(t.TempDir(), "none"),
+	}, nil)
+	if _, err := noFFmpeg.TranscribeFromFile(context.Background(), path); !errors.Is(err, media.ErrExtractionFailed) {
+		t.Errorf("Expected ErrExtractionFailed, got %v", err)
+	}
+	if len(*uploads) != 2 {
+		t.Errorf("Unexpected upload without ffmpeg")
+	}
+}
@@ -0,0 +1,246 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Defaults of VisionLLMConfig.
+const (
+	DefaultVisionLLMBaseURL = "https://api.openai.com/v1"
+	DefaultVisionLLMModel   = "gpt-4o-mini"
+	DefaultVisionLLMPrompt  = "Describe and transcribe this image. First transcribe all visible text exactly, " +
+		"keeping its reading order and line breaks. Then, after a blank line, describe in a few sentences what " +
+		"the image shows, such as the screen, dialog or diagram and its important elements. Reply with plain text only."
+)
+
+// visionLLMConfidence is reported for LLM transcriptions, which come
+// without a confidence score.
+const visionLLMConfidence = 0.8
+
+// VisionLLMConfig configures VisionLLMOCR.
+type VisionLLMConfig struct {
+	// BaseURL of an OpenAI-compatible API, e.g. a local vLLM or Ollama
+	// server ("http://localhost:11434/v1"); default DefaultVisionLLMBaseURL.
+	BaseURL string
+	// APIKey is sent as a bearer token. It is required for the default
+	// BaseURL only, since local servers often need none.
+	APIKey string
+	// Model must accept image input; default DefaultVisionLLMModel.
+	Model string
+	// Prompt overrides DefaultVisionLLMPrompt.
+	Prompt string
+	// HTTPClient is used for API requests and image downloads.
+	HTTPClient *http.Client
+}
+
+// VisionLLMOCR implements OCRHandler by asking a vision-capable chat model
+// to describe and transcribe the image. Unlike the other backends, the
+// text includes a description of what is shown and has no bounding boxes.
+type VisionLLMOCR struct {
+	baseURL string
+	apiKey  string
+	model   string
+	prompt  string
+	client  *http.Client
+	logger  *slog.Logger
+}
+
+func visionLLMConfigFromEnv() VisionLLMConfig {
+	apiKey := os.Getenv("VISION_LLM_API_KEY")
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	return VisionLLMConfig{
+		BaseURL: os.Getenv("VISION_LLM_BASE_URL"),
+		APIKey:  apiKey,
+		Model:   os.Getenv("VISION_LLM_MODEL"),
+	}
+}
+
+// NewVisionLLMOCR creates a vision LLM OCR handler from cfg.
+func NewVisionLLMOCR(cfg VisionLLMConfig, logger *slog.Logger) (*VisionLLMOCR, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.BaseURL == "" {
+		if cfg.APIKey == "" {
+			return nil, errors.New("vision LLM API key required (VISION_LLM_API_KEY or OPENAI_API_KEY)")
+		}
+		cfg.BaseURL = DefaultVisionLLMBaseURL
+	}
+	if cfg.Model == "" {
+		cfg.Model = DefaultVisionLLMModel
+	}
+	if cfg.Prompt == "" {
+		cfg.Prompt = DefaultVisionLLMPrompt
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 120 * time.Second}
+	}
+	return &VisionLLMOCR{
+		baseURL: strings.TrimSuffix(cfg.BaseURL, "/"),
+		apiKey:  cfg.APIKey,
+		model:   cfg.Model,
+		prompt:  cfg.Prompt,
+		client:  cfg.HTTPClient,
+		logger:  logger,
+	}, nil
+}
+
+// ExtractFromURL downloads an image and sends it inline to the model
+func (v *VisionLLMOCR) ExtractFromURL(ctx context.Context, imageURL string) (*OCRResult, error) {
+	v.logger.Debug("Extracting text from URL", "url", imageURL)
+
+	if _, err := parseMediaURL(imageURL); err != nil {
+		return nil, err
+	}
+	data, err := downloadImage(ctx, v.client, imageURL)
+	if err != nil {
+		return nil, err
+	}
+	return v.transcribe(ctx, data)
+}
+
+// ExtractFromFile sends a local image file to the model
+func (v *VisionLLMOCR) ExtractFromFile(ctx context.Context, filePath string) (*OCRResult, error) {
+	v.logger.Debug("Extracting text from file", "path", filePath)
+
+	cleanPath := filepath.Clean(filePath)
+	info, err := os.Stat(cleanPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if info.Size() > maxImageBytes {
+		return nil, fmt.Errorf("%w: image larger than %d bytes", ErrExtractionFailed, maxImageBytes)
+	}
+	data, err := os.ReadFile(cleanPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return v.transcribe(ctx, data)
+}
+
+// Chat completion request and response, limited to the fields used.
+type visionLLMRequest struct {
+	Model       string             `json:"model"`
+	Messages    []visionLLMMessage `json:"messages"`
+	Temperature float64            `json:"temperature"`
+}
+
+type visionLLMMessage struct {
+	Role    string             `json:"role"`
+	Content []visionLLMContent `json:"content"`
+}
+
+type visionLLMContent struct {
+	Type     string             `json:"type"`
+	Text     string             `json:"text,omitempty"`
+	ImageURL *visionLLMImageURL `json:"image_url,omitempty"`
+}
+
+type visionLLMImageURL struct {
+	URL string `json:"url"`
+}
+
+type visionLLMResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// transcribe sends image bytes as a data URL, with the language hints of
+// ctx (see WithOCRLanguages) added to the prompt.
+func (v *VisionLLMOCR) transcribe(ctx context.Context, imageData []byte) (*OCRResult, error) {
+	mimeType := http.DetectContentType(imageData)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return nil, fmt.Errorf("%w: not an image (%s)", ErrExtractionFailed, mimeType)
+	}
+	v.logger.Debug("Calling vision LLM", "model", v.model, "size", len(imageData))
+
+	prompt := v.prompt
+	langs := OCRLanguages(ctx)
+	if len(langs) > 0 {
+		prompt += "\nThe text is in " + strings.Join(langs, ", ") + "."
+	}
+	body, err := json.Marshal(visionLLMRequest{
+		Model: v.model,
+		Messages: []visionLLMMessage{{
+			Role: "user",
+			Content: []visionLLMContent{
+				{Type: "text", Text: prompt},
+				{Type: "image_url", ImageURL: &visionLLMImageURL{
+					URL: "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(imageData),
+				}},
+			},
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode vision LLM request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vision LLM request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if v.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+v.apiKey)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vision LLM request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("%w: vision LLM returned HTTP %d", ErrUnauthorized, resp.StatusCode)
+	}
+	var out visionLLMResponse
+	if resp.StatusCode != http.StatusOK {
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&out)
+		msg := ""
+		if out.Error != nil {
+			msg = out.Error.Message
+		}
+		return nil, fmt.Errorf("%w: vision LLM returned HTTP %d: %s", ErrExtractionFailed, resp.StatusCode, msg)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode vision LLM response: %w", err)
+	}
+	if len(out.Choices) == 0 {
+		return nil, fmt.Errorf("%w: empty vision LLM response", ErrExtractionFailed)
+	}
+
+	result := &OCRResult{
+		Text:            strings.TrimSpace(out.Choices[0].Message.Content),
+		ConfidenceScore: visionLLMConfidence,
+		BoundingBoxes:   []TextBoundingBox{},
+	}
+	if len(langs) > 0 {
+		result.Language = langs[0]
+	}
+	return result, nil
+}
+
+// Close closes any resources
+func (v *VisionLLMOCR) Close() error {
+	return nil
+}
@@ -0,0 +1,84 @@
+package media_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cgap/internal/media"
+)
+
+func TestVisionLLMOCR(t *testing.T) {
+	var got struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content []struct {
+				Type     string `json:"type"`
+				Text     string `json:"text"`
+				ImageURL struct {
+					URL string `json:"url"`
+				} `json:"image_url"`
+			} `json:"content"`
+		} `json:"messages"`
+	}
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			http.Error(w, `{"error":{"message":"invalid key"}}`, http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Benutzer anlegen\nSpeichern\n\nA dialog for creating a user.\n"}}]}`))
+	}))
+	defer llm.Close()
+	images := imageServer(t)
+
+	ocr, err := media.NewVisionLLMOCR(media.VisionLLMConfig{BaseURL: llm.URL + "/v1/", APIKey: "test-key", Model: "llava"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := media.WithOCRLanguages(context.Background(), "de")
+	result, err := ocr.ExtractFromURL(ctx, images.URL+"/logo.png")
+	if err != nil {
+		t.Fatalf("ExtractFromURL failed: %v", err)
+	}
+
+	if got.Model != "llava" || len(got.Messages) != 1 || len(got.Messages[0].Content) != 2 {
+		t.Fatalf("Unexpected request %+v", got)
+	}
+	content := got.Messages[0].Content
+	if !strings.HasPrefix(content[0].Text, "Describe and transcribe this image.") || !strings.HasSuffix(content[0].Text, "The text is in de.") {
+		t.Errorf("Prompt = %q", content[0].Text)
+	}
+	if content[1].Type != "image_url" || !strings.HasPrefix(content[1].ImageURL.URL, "data:image/png;base64,") {
+		t.Errorf("Image not sent as data URL: %.40q", content[1].ImageURL.URL)
+	}
+	if result.Text != "Benutzer anlegen\nSpeichern\n\nA dialog for creating a user." || result.Language != "de" {
+		t.Errorf("Text %q, language %q", result.Text, result.Language)
+	}
+
+	bad, _ := media.NewVisionLLMOCR(media.VisionLLMConfig{BaseURL: llm.URL + "/v1", APIKey: "wrong"}, nil)
+	if _, err := bad.ExtractFromURL(context.Background(), images.URL+"/logo.png"); !errors.Is(err, media.ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestNewVisionLLMOCR_RequiresKeyForOpenAI(t *testing.T) {
+	if _, err := media.NewVisionLLMOCR(media.VisionLLMConfig{}, nil); err == nil {
+		t.Error("Expected error without API key")
+	}
+	if _, err := media.NewVisionLLMOCR(media.VisionLLMConfig{BaseURL: "http://localhost:11434/v1"}, nil); err != nil {
+		t.Errorf("Local server without key: %v", err)
+	}
+}
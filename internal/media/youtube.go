@@ -1,29 +1,99 @@
 package media
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
 )
 
-// YouTubeTranscriptFetcher fetches transcripts from YouTube videos using public APIs
+// DefaultYouTubeBaseURL serves the watch pages captions are looked up on.
+const DefaultYouTubeBaseURL = "https://www.youtube.com"
+
+// maxWatchPageBytes bounds the watch page and caption downloads.
+const maxWatchPageBytes = 16 << 20
+
+// YouTubeConfig configures YouTubeTranscriptFetcher.
+type YouTubeConfig struct {
+	// BaseURL overrides DefaultYouTubeBaseURL, e.g. for a local stub.
+	BaseURL string
+	// TimedTextURL, when set, replaces the scheme and host of the caption
+	// track URLs listed on the watch page.
+	TimedTextURL string
+	// Languages are the preferred caption languages in order, e.g.
+	// ["en", "de"]; without them, the first track listed is used.
+	Languages []string
+	// HTTPClient is used for all requests.
+	HTTPClient *http.Client
+}
+
+// YouTubeTranscriptFetcher fetches the captions of YouTube videos from their
+// watch page
 type YouTubeTranscriptFetcher struct {
-	logger *slog.Logger
+	baseURL   string
+	timedText *url.URL
+	languages []string
+	client    *http.Client
+	logger    *slog.Logger
 }
 
 // NewYouTubeTranscriptFetcher creates a new YouTube transcript fetcher
+// configured from YOUTUBE_BASE_URL, YOUTUBE_TIMEDTEXT_URL and
+// YOUTUBE_LANGUAGES
 func NewYouTubeTranscriptFetcher(logger *slog.Logger) *YouTubeTranscriptFetcher {
+	return NewYouTubeTranscriptFetcherWithConfig(youTubeConfigFromEnv(), logger)
+}
+
+func youTubeConfigFromEnv() YouTubeConfig {
+	return YouTubeConfig{
+		BaseURL:      os.Getenv("YOUTUBE_BASE_URL"),
+		TimedTextURL: os.Getenv("YOUTUBE_TIMEDTEXT_URL"),
+		Languages:    ParseLanguageHints(os.Getenv("YOUTUBE_LANGUAGES")),
+	}
+}
+
+// NewYouTubeTranscriptFetcherWithConfig creates a YouTube transcript fetcher from cfg.
+func NewYouTubeTranscriptFetcherWithConfig(cfg YouTubeConfig, logger *slog.Logger) *YouTubeTranscriptFetcher {
 	if logger == nil {
 		logger = slog.Default()
 	}
-
-	return &YouTubeTranscriptFetcher{
-		logger: logger,
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultYouTubeBaseURL
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
 	}
+	y := &YouTubeTranscriptFetcher{
+		baseURL:   strings.TrimSuffix(cfg.BaseURL, "/"),
+		languages: cfg.Languages,
+		client:    cfg.HTTPClient,
+		logger:    logger,
+	}
+	if cfg.TimedTextURL != "" {
+		u, err := url.Parse(cfg.TimedTextURL)
+		if err != nil || u.Host == "" {
+			logger.Warn("Ignoring invalid YouTube timed text URL", "url", cfg.TimedTextURL)
+		} else {
+			y.timedText = u
+		}
+	}
+	return y
 }
 
-// GetTranscript fetches the transcript for a YouTube video
+// GetTranscript fetches the captions of a YouTube video, preferring manual
+// captions over auto-generated ones. It fails with ErrNoTranscript when the
+// video has no captions.
 func (y *YouTubeTranscriptFetcher) GetTranscript(ctx context.Context, videoID string) (*TranscriptResult, error) {
 	if videoID == "" {
 		return nil, fmt.Errorf("%w: video ID is empty", ErrInvalidURL)
@@ -31,73 +101,290 @@ func (y *YouTubeTranscriptFetcher) GetTranscript(ctx context.Context, videoID st
 
 	y.logger.Info("Fetching YouTube transcript", "videoID", videoID)
 
-	// Try to fetch transcript from YouTube's public transcript endpoint
-	transcript, language, segments, err := y.fetchTranscriptFromAPI(ctx, videoID)
+	player, err := y.fetchPlayerResponse(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+	if s := player.PlayabilityStatus; s.Status != "" && s.Status != "OK" {
+		return nil, fmt.Errorf("%w: video %s is not playable: %s %s", ErrExtractionFailed, videoID, s.Status, s.Reason)
+	}
+	track := selectCaptionTrack(player.Captions.Renderer.CaptionTracks, y.languages)
+	if track == nil {
+		return nil, fmt.Errorf("%w: video %s has no captions", ErrNoTranscript, videoID)
+	}
+
+	segments, err := y.fetchCaptions(ctx, track.BaseURL)
 	if err != nil {
-		y.logger.Warn("Failed to fetch transcript from API", "error", err, "videoID", videoID)
-		// Return mock for testing
-		return y.getMockTranscript(videoID), nil
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("%w: caption track %s of video %s is empty", ErrNoTranscript, track.LanguageCode, videoID)
 	}
 
-	if transcript == "" {
-		y.logger.Debug("No transcript content returned")
-		return y.getMockTranscript(videoID), nil
+	texts := make([]string, len(segments))
+	for i, s := range segments {
+		texts[i] = s.Text
+	}
+	duration, _ := strconv.Atoi(player.VideoDetails.LengthSeconds)
+	if duration == 0 {
+		duration = segments[len(segments)-1].EndSeconds
+	}
+	result := &TranscriptResult{
+		Title:           player.VideoDetails.Title,
+		Transcript:      strings.Join(texts, " "),
+		Language:        track.LanguageCode,
+		Segments:        segments,
+		IsAutoGenerated: track.Kind == "asr",
+		Duration:        duration,
 	}
 
 	y.logger.Info("Transcript fetched successfully",
 		"videoID", videoID,
-		"textLength", len(transcript),
-		"language", language,
+		"textLength", len(result.Transcript),
+		"language", result.Language,
+		"autoGenerated", result.IsAutoGenerated,
 		"segmentCount", len(segments))
+	return result, nil
+}
 
-	return &TranscriptResult{
-		Transcript:      transcript,
-		Language:        language,
-		Segments:        segments,
-		IsAutoGenerated: false,
-		Duration:        0, // Not provided by transcript API
-	}, nil
-}
-
-// fetchTranscriptFromAPI attempts to fetch transcript from YouTube's API
-func (y *YouTubeTranscriptFetcher) fetchTranscriptFromAPI(ctx context.Context, videoID string) (string, string, []TranscriptSegment, error) {
-	// mark context as used for linting; future implementation will propagate it
-	_ = ctx
-	// Construct the YouTube video page URL to extract captions
-	// This is a simplified approach - in production, would use proper API
-	y.logger.Debug("Attempting to fetch transcript via API", "videoID", videoID)
-
-	// Return empty for now - placeholder for real implementation
-	return "", "en", []TranscriptSegment{}, fmt.Errorf("transcript API not yet implemented")
-}
-
-// getMockTranscript returns mock transcript data for testing
-func (y *YouTubeTranscriptFetcher) getMockTranscript(videoID string) *TranscriptResult {
-	y.logger.Debug("Returning mock transcript", "videoID", videoID)
-
-	return &TranscriptResult{
-		Transcript: fmt.Sprintf("[Mock Transcript] This is a mock transcript for YouTube video: %s\n\nNote: For production use, implement proper YouTube Transcript API integration.\n\nTo get real transcripts:\n1. Use youtube-transcript-api Python package\n2. Or call YouTube's official API with API key\n3. Or implement web scraping of captions", videoID),
-		Language:   "en",
-		Segments: []TranscriptSegment{
-			{
-				Text:         "[Mock] Segment 1: Introduction",
-				StartSeconds: 0,
-				EndSeconds:   30,
-			},
-			{
-				Text:         "[Mock] Segment 2: Main content",
-				StartSeconds: 30,
-				EndSeconds:   60,
-			},
-			{
-				Text:         "[Mock] Segment 3: Conclusion",
-				StartSeconds: 60,
-				EndSeconds:   90,
-			},
-		},
-		IsAutoGenerated: true,
-		Duration:        0,
+// youTubePlayerResponse is ytInitialPlayerResponse, limited to the fields used.
+type youTubePlayerResponse struct {
+	PlayabilityStatus struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	} `json:"playabilityStatus"`
+	VideoDetails struct {
+		Title         string `json:"title"`
+		LengthSeconds string `json:"lengthSeconds"`
+	} `json:"videoDetails"`
+	Captions struct {
+		Renderer struct {
+			CaptionTracks []captionTrack `json:"captionTracks"`
+		} `json:"playerCaptionsTracklistRenderer"`
+	} `json:"captions"`
+}
+
+type captionTrack struct {
+	BaseURL      string `json:"baseUrl"`
+	LanguageCode string `json:"languageCode"`
+	Kind         string `json:"kind"` // "asr" for auto-generated captions
+}
+
+// fetchPlayerResponse reads the player response embedded in the watch page.
+func (y *YouTubeTranscriptFetcher) fetchPlayerResponse(ctx context.Context, videoID string) (*youTubePlayerResponse, error) {
+	page, err := y.get(ctx, y.baseURL+"/watch?v="+url.QueryEscape(videoID)+"&hl=en")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch watch page: %w", err)
+	}
+	const marker = "ytInitialPlayerResponse"
+	i := bytes.Index(page, []byte(marker))
+	if i < 0 {
+		return nil, fmt.Errorf("%w: no player response on watch page of %s", ErrExtractionFailed, videoID)
+	}
+	rest := page[i+len(marker):]
+	if j := bytes.IndexByte(rest, '{'); j >= 0 {
+		rest = rest[j:]
+	}
+	// The decoder stops after the object, ignoring the script that follows.
+	var player youTubePlayerResponse
+	if err := json.NewDecoder(bytes.NewReader(rest)).Decode(&player); err != nil {
+		return nil, fmt.Errorf("%w: invalid player response: %v", ErrExtractionFailed, err)
+	}
+	return &player, nil
+}
+
+// selectCaptionTrack picks manual captions in the first preferred language
+// that has them, then auto-generated ones, then any manual and any
+// auto-generated track. Languages match by prefix, so "en" matches "en-GB".
+func selectCaptionTrack(tracks []captionTrack, languages []string) *captionTrack {
+	find := func(lang string, asr bool) *captionTrack {
+		for i, t := range tracks {
+			if (t.Kind == "asr") != asr || t.BaseURL == "" {
+				continue
+			}
+			if lang == "" || strings.EqualFold(t.LanguageCode, lang) || strings.HasPrefix(strings.ToLower(t.LanguageCode), strings.ToLower(lang)+"-") {
+				return &tracks[i]
+			}
+		}
+		return nil
+	}
+	for _, asr := range []bool{false, true} {
+		for _, lang := range languages {
+			if t := find(lang, asr); t != nil {
+				return t
+			}
+		}
+	}
+	for _, asr := range []bool{false, true} {
+		if t := find("", asr); t != nil {
+			return t
+		}
+	}
+	return nil
+}
+
+// fetchCaptions downloads a caption track as json3, falling back to WebVTT
+// for servers that return it.
+func (y *YouTubeTranscriptFetcher) fetchCaptions(ctx context.Context, trackURL string) ([]TranscriptSegment, error) {
+	u, err := url.Parse(trackURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid caption URL: %v", ErrExtractionFailed, err)
+	}
+	if !u.IsAbs() {
+		base, _ := url.Parse(y.baseURL + "/")
+		u = base.ResolveReference(u)
+	}
+	if y.timedText != nil {
+		u.Scheme, u.Host = y.timedText.Scheme, y.timedText.Host
+	}
+	q := u.Query()
+	q.Set("fmt", "json3")
+	u.RawQuery = q.Encode()
+
+	body, err := y.get(ctx, u.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch captions: %w", err)
+	}
+	body = bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+	if bytes.HasPrefix(body, []byte("WEBVTT")) {
+		return parseVTT(body), nil
+	}
+	return parseJSON3(body)
+}
+
+func (y *YouTubeTranscriptFetcher) get(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; cgap/1.0)")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	// Skips the cookie consent page served in the EU
+	req.AddCookie(&http.Cookie{Name: "CONSENT", Value: "YES+1"})
+	resp, err := y.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: HTTP %d", ErrExtractionFailed, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxWatchPageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxWatchPageBytes {
+		return nil, fmt.Errorf("%w: response larger than %d bytes", ErrExtractionFailed, maxWatchPageBytes)
+	}
+	return data, nil
+}
+
+// parseJSON3 maps the events of a json3 caption track to segments.
+func parseJSON3(data []byte) ([]TranscriptSegment, error) {
+	var track struct {
+		Events []struct {
+			StartMs    int64 `json:"tStartMs"`
+			DurationMs int64 `json:"dDurationMs"`
+			Segs       []struct {
+				UTF8 string `json:"utf8"`
+			} `json:"segs"`
+		} `json:"events"`
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	if err := json.Unmarshal(data, &track); err != nil {
+		return nil, fmt.Errorf("%w: invalid json3 captions: %v", ErrExtractionFailed, err)
+	}
+	var segments []TranscriptSegment
+	for _, e := range track.Events {
+		var sb strings.Builder
+		for _, s := range e.Segs {
+			sb.WriteString(s.UTF8)
+		}
+		text := strings.Join(strings.Fields(sb.String()), " ")
+		if text == "" {
+			continue
+		}
+		segments = append(segments, TranscriptSegment{
+			Text:         text,
+			StartSeconds: int(e.StartMs / 1000),
+			EndSeconds:   int(math.Ceil(float64(e.StartMs+e.DurationMs) / 1000)),
+		})
+	}
+	return segments, nil
+}
+
+var vttTag = regexp.MustCompile(`<[^>]*>`)
+
+// parseVTT maps WebVTT cues to segments. Auto-generated captions repeat the
+// previous line in each cue ("roll-up"); lines already seen in the previous
+// cue are dropped.
+func parseVTT(data []byte) []TranscriptSegment {
+	var segments []TranscriptSegment
+	var prev map[string]bool
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		start, end, ok := parseVTTTiming(sc.Text())
+		if !ok {
+			continue
+		}
+		var lines []string
+		seen := map[string]bool{}
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if line == "" {
+				break
+			}
+			line = strings.Join(strings.Fields(vttTag.ReplaceAllString(line, "")), " ")
+			if line == "" {
+				continue
+			}
+			seen[line] = true
+			if !prev[line] {
+				lines = append(lines, line)
+			}
+		}
+		prev = seen
+		if len(lines) == 0 {
+			continue
+		}
+		segments = append(segments, TranscriptSegment{
+			Text:         strings.Join(lines, " "),
+			StartSeconds: int(start),
+			EndSeconds:   int(math.Ceil(end)),
+		})
+	}
+	return segments
+}
+
+// parseVTTTiming parses a cue timing line such as
+// "00:01:02.500 --> 00:01:05.000 align:start" into seconds.
+func parseVTTTiming(line string) (start, end float64, ok bool) {
+	from, rest, found := strings.Cut(line, "-->")
+	if !found {
+		return 0, 0, false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return 0, 0, false
+	}
+	start, ok1 := parseVTTTime(strings.TrimSpace(from))
+	end, ok2 := parseVTTTime(fields[0])
+	return start, end, ok1 && ok2
+}
+
+func parseVTTTime(s string) (float64, bool) {
+	var seconds float64
+	for _, part := range strings.Split(s, ":") {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, false
+		}
+		seconds = seconds*60 + v
 	}
+	return seconds, true
 }
 
 // ExtractVideoIDFromURL extracts the video ID from a YouTube URL
@@ -2,60 +2,122 @@ package media_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"cgap/internal/media"
 )
 
+// youTubeStub serves watch pages and caption tracks. Video "manual" has
+// manual English and German captions plus auto-generated English ones,
+// "auto" only auto-generated WebVTT captions, and "none" no captions.
+func youTubeStub(t *testing.T) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/watch":
+			var tracks string
+			switch r.URL.Query().Get("v") {
+			case "manual":
+				tracks = `[{"baseUrl":"https://www.youtube.com/api/timedtext?v=manual&lang=en&kind=asr","languageCode":"en","kind":"asr"},
+					{"baseUrl":"https://www.youtube.com/api/timedtext?v=manual&lang=en-GB","languageCode":"en-GB"},
+					{"baseUrl":"/api/timedtext?v=manual&lang=de","languageCode":"de"}]`
+			case "auto":
+				tracks = `[{"baseUrl":"/api/timedtext?v=auto&lang=en&kind=asr","languageCode":"en","kind":"asr"}]`
+			case "none":
+				tracks = `[]`
+			default:
+				_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"playabilityStatus":{"status":"ERROR","reason":"Video unavailable"}};</script>`))
+				return
+			}
+			_, _ = fmt.Fprintf(w, `<html><script>var ytInitialPlayerResponse = {"playabilityStatus":{"status":"OK"},
+				"videoDetails":{"title":"Getting started","lengthSeconds":"95"},
+				"captions":{"playerCaptionsTracklistRenderer":{"captionTracks":%s}}};var meta = {};</script></html>`, tracks)
+		case "/api/timedtext":
+			q := r.URL.Query()
+			if q.Get("fmt") != "json3" {
+				http.Error(w, "fmt=json3 expected", http.StatusBadRequest)
+				return
+			}
+			switch q.Get("v") + "/" + q.Get("lang") + "/" + q.Get("kind") {
+			case "manual/en-GB/":
+				_, _ = w.Write([]byte(`{"events":[{"tStartMs":0,"dDurationMs":1000},
+					{"tStartMs":1200,"dDurationMs":3300,"segs":[{"utf8":"Open the"},{"utf8":"\nadmin panel."}]},
+					{"tStartMs":4500,"dDurationMs":2000,"segs":[{"utf8":"Click Users."}]}]}`))
+			case "manual/de/":
+				_, _ = w.Write([]byte(`{"events":[{"tStartMs":1200,"dDurationMs":3300,"segs":[{"utf8":"Öffnen Sie die Verwaltung."}]}]}`))
+			case "auto/en/asr":
+				_, _ = w.Write([]byte("WEBVTT\nKind: captions\nLanguage: en\n\n" +
+					"00:00:01.000 --> 00:00:03.500 align:start position:0%\nopen<00:00:01.500><c> the</c>\n\n" +
+					"00:00:03.500 --> 00:01:05.000 align:start position:0%\nopen the\nadmin panel\n"))
+			default:
+				http.NotFound(w, r)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
 func TestYouTubeTranscriptFetcher_GetTranscript(t *testing.T) {
-	logger := slog.Default()
 	ctx := context.Background()
+	stub := youTubeStub(t)
+	fetcher := func(langs ...string) *media.YouTubeTranscriptFetcher {
+		return media.NewYouTubeTranscriptFetcherWithConfig(media.YouTubeConfig{
+			BaseURL: stub.URL, TimedTextURL: stub.URL, Languages: langs,
+		}, nil)
+	}
 
-	fetcher := media.NewYouTubeTranscriptFetcher(logger)
+	result, err := fetcher().GetTranscript(ctx, "manual")
+	if err != nil {
+		t.Fatalf("GetTranscript failed: %v", err)
+	}
+	want := []media.TranscriptSegment{
+		{Text: "Open the admin panel.", StartSeconds: 1, EndSeconds: 5},
+		{Text: "Click Users.", StartSeconds: 4, EndSeconds: 7},
+	}
+	if fmt.Sprint(result.Segments) != fmt.Sprint(want) {
+		t.Errorf("Segments = %+v, want %+v", result.Segments, want)
+	}
+	if result.Transcript != "Open the admin panel. Click Users." || result.Title != "Getting started" || result.Language != "en-GB" || result.IsAutoGenerated || result.Duration != 95 {
+		t.Errorf("Unexpected result %+v", result)
+	}
 
-	testCases := []struct {
-		name        string
-		videoID     string
-		expectError bool
-		validate    func(*media.TranscriptResult) error
-	}{
-		{
-			name:        "valid video ID",
-			videoID:     "dQw4w9WgXcQ",
-			expectError: false,
-			validate: func(r *media.TranscriptResult) error {
-				if r.Transcript == "" {
-					t.Error("Expected non-empty transcript")
-				}
-				if r.Language == "" {
-					t.Error("Expected language to be set")
-				}
-				if len(r.Segments) == 0 {
-					t.Error("Expected at least one segment")
-				}
-				return nil
-			},
-		},
-		{
-			name:        "empty video ID",
-			videoID:     "",
-			expectError: true,
-		},
+	// Preferred languages come first; "en" matches "en-GB" before auto-generated "en"
+	if result, err := fetcher("fr", "de", "en").GetTranscript(ctx, "manual"); err != nil || result.Language != "de" {
+		t.Errorf("Preferred language: %v, %+v", err, result)
+	}
+	if result, err := fetcher("en").GetTranscript(ctx, "manual"); err != nil || result.Language != "en-GB" || result.IsAutoGenerated {
+		t.Errorf("Manual captions not preferred: %v, %+v", err, result)
 	}
 
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			result, err := fetcher.GetTranscript(ctx, tc.videoID)
-			if (err != nil) != tc.expectError {
-				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
-			}
-			if !tc.expectError && result != nil {
-				if err := tc.validate(result); err != nil {
-					t.Errorf("Validation failed: %v", err)
-				}
-			}
-		})
+	result, err = fetcher("en").GetTranscript(ctx, "auto")
+	if err != nil {
+		t.Fatalf("GetTranscript of auto captions failed: %v", err)
+	}
+	want = []media.TranscriptSegment{
+		{Text: "open the", StartSeconds: 1, EndSeconds: 4},
+		{Text: "admin panel", StartSeconds: 3, EndSeconds: 65},
+	}
+	if fmt.Sprint(result.Segments) != fmt.Sprint(want) || !result.IsAutoGenerated {
+		t.Errorf("Auto-generated result %+v, want segments %+v", result, want)
+	}
+
+	if _, err := fetcher().GetTranscript(ctx, "none"); !errors.Is(err, media.ErrNoTranscript) {
+		t.Errorf("Expected ErrNoTranscript, got %v", err)
+	}
+	if _, err := fetcher().GetTranscript(ctx, "private"); !errors.Is(err, media.ErrExtractionFailed) {
+		t.Errorf("Expected ErrExtractionFailed for unplayable video, got %v", err)
+	}
+	if _, err := fetcher().GetTranscript(ctx, ""); !errors.Is(err, media.ErrInvalidURL) {
+		t.Errorf("Expected ErrInvalidURL for empty video ID, got %v", err)
 	}
 }
 
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 
+	"cgap/api"
 	"cgap/internal/service"
 )
 
@@ -28,10 +29,11 @@ func New(baseURL, apiKey string) *Client {
 
 // searchRequest matches Meilisearch search API request format
 type searchRequest struct {
-	Q       string   `json:"q"`
-	Limit   int      `json:"limit,omitempty"`
-	Filter  []string `json:"filter,omitempty"`
-	Ranking []string `json:"rankingRules,omitempty"`
+	Q                string   `json:"q"`
+	Limit            int      `json:"limit,omitempty"`
+	Filter           []string `json:"filter,omitempty"`
+	Ranking          []string `json:"rankingRules,omitempty"`
+	ShowRankingScore bool     `json:"showRankingScore,omitempty"`
 }
 
 // searchResponse matches Meilisearch search API response format
@@ -45,12 +47,15 @@ type searchResponse struct {
 }
 
 type searchHit struct {
-	ID           string  `json:"id"`
-	ChunkID      string  `json:"chunk_id"`
+	ID           string  `json:"id"` // chunk id
 	DocumentID   string  `json:"document_id"`
+	DocumentURI  string  `json:"document_uri,omitempty"`
+	Title        string  `json:"title,omitempty"`
 	Text         string  `json:"text"`
 	SectionPath  string  `json:"section_path,omitempty"`
 	RankingScore float32 `json:"_rankingScore,omitempty"`
+	StartSeconds *int    `json:"start_seconds,omitempty"`
+	EndSeconds   *int    `json:"end_seconds,omitempty"`
 }
 
 // Search queries Meilisearch and returns results.
@@ -62,15 +67,26 @@ func (c *Client) Search(ctx context.Context, index, query string, topK int, filt
 	// Build filter array from filters map
 	var filterArray []string
 	for key, val := range filters {
-		if val != nil {
-			filterArray = append(filterArray, fmt.Sprintf("%s = %v", key, val))
+		if key == api.FusionFilterKey || val == nil {
+			continue
 		}
+		v := fmt.Sprintf("%v", val)
+		if s, ok := val.(string); ok {
+			v = FilterValue(s)
+		}
+		if key == "project_id" {
+			// Callers may pass the project slug instead of its UUID.
+			filterArray = append(filterArray, fmt.Sprintf("(project_id = %s OR project_slug = %s)", v, v))
+			continue
+		}
+		filterArray = append(filterArray, fmt.Sprintf("%s = %s", key, v))
 	}
 
 	req := searchRequest{
-		Q:      query,
-		Limit:  topK,
-		Filter: filterArray,
+		Q:                query,
+		Limit:            topK,
+		Filter:           filterArray,
+		ShowRankingScore: true,
 	}
 
 	body, err := json.Marshal(req)
@@ -78,7 +94,7 @@ func (c *Client) Search(ctx context.Context, index, query string, topK int, filt
 		return nil, fmt.Errorf("failed to marshal search request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/indexes/%s/search", c.baseURL, index), bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/indexes/%s/search", c.baseURL, IndexUID(index)), bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -109,15 +125,22 @@ func (c *Client) Search(ctx context.Context, index, query string, topK int, filt
 	// Convert to service.SearchResult
 	var results []service.SearchResult
 	for _, hit := range searchResp.Hits {
+		meta := map[string]any{
+			"document_id":  hit.DocumentID,
+			"section_path": hit.SectionPath,
+			"chunk_id":     hit.ID,
+			"title":        hit.Title,
+			"document_uri": hit.DocumentURI,
+		}
+		if hit.StartSeconds != nil && hit.EndSeconds != nil {
+			meta["start_seconds"] = *hit.StartSeconds
+			meta["end_seconds"] = *hit.EndSeconds
+		}
 		results = append(results, service.SearchResult{
-			ID:   hit.ChunkID,
-			Text: hit.Text,
-			Metadata: map[string]any{
-				"document_id":  hit.DocumentID,
-				"section_path": hit.SectionPath,
-				"chunk_id":     hit.ChunkID,
-			},
-			Score: hit.RankingScore,
+			ID:       hit.ID,
+			Text:     hit.Text,
+			Metadata: meta,
+			Score:    hit.RankingScore,
 		})
 	}
 
@@ -0,0 +1,241 @@
+package meilisearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ChunksIndex is the logical index searched for documentation chunks. All
+// projects share it; records carry project_id for filtering.
+const ChunksIndex = "chunks"
+
+// indexPrefix namespaces cgap indexes on a shared Meilisearch instance, so the
+// logical "chunks" index is stored as "cgap_chunks".
+const indexPrefix = "cgap_"
+
+// IndexUID returns the Meilisearch index uid for a logical index name.
+func IndexUID(name string) string {
+	return indexPrefix + name
+}
+
+// Settings is the subset of Meilisearch index settings cgap manages.
+type Settings struct {
+	SearchableAttributes []string `json:"searchableAttributes,omitempty"`
+	FilterableAttributes []string `json:"filterableAttributes,omitempty"`
+	SortableAttributes   []string `json:"sortableAttributes,omitempty"`
+	RankingRules         []string `json:"rankingRules,omitempty"`
+}
+
+// ChunkSettings are applied to the chunks index; keep scripts/meili_bootstrap.sh in sync.
+var ChunkSettings = Settings{
+	SearchableAttributes: []string{"title", "text", "section_path"},
+	FilterableAttributes: []string{"project_id", "project_slug", "document_id", "document_uri", "source_type"},
+	SortableAttributes:   []string{"score_raw", "ord"},
+	RankingRules:         []string{"typo", "words", "proximity", "attribute", "sort", "exactness"},
+}
+
+// Task is an asynchronous Meilisearch task as returned by write endpoints and /tasks.
+type Task struct {
+	TaskUID int64      `json:"taskUid"`
+	UID     int64      `json:"uid"`
+	Status  string     `json:"status"` // enqueued|processing|succeeded|failed|canceled
+	Type    string     `json:"type"`
+	Error   *TaskError `json:"error,omitempty"`
+}
+
+// TaskError describes why a task (or request) failed.
+type TaskError struct {
+	Message string `json:"message"`
+	Code    string `json:"code"`
+	Type    string `json:"type"`
+}
+
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("meilisearch %s: %s", e.Code, e.Message)
+}
+
+// id returns the task uid; enqueue responses use taskUid, /tasks uses uid.
+func (t Task) id() int64 {
+	if t.TaskUID != 0 {
+		return t.TaskUID
+	}
+	return t.UID
+}
+
+// Task polling defaults.
+const (
+	taskPollInterval = 100 * time.Millisecond
+	taskTimeout      = 30 * time.Second
+)
+
+// EnsureIndex creates the index (primary key "id") if it does not exist and
+// applies settings, waiting for both tasks to finish.
+func (c *Client) EnsureIndex(ctx context.Context, index string, settings Settings) error {
+	uid := IndexUID(index)
+
+	task, err := c.enqueue(ctx, http.MethodPost, "/indexes", map[string]string{"uid": uid, "primaryKey": "id"})
+	if err != nil {
+		return fmt.Errorf("failed to create index %s: %w", uid, err)
+	}
+	if err := c.WaitForTask(ctx, task); err != nil {
+		var te *TaskError
+		if !errors.As(err, &te) || te.Code != "index_already_exists" {
+			return fmt.Errorf("failed to create index %s: %w", uid, err)
+		}
+	}
+
+	task, err = c.enqueue(ctx, http.MethodPatch, "/indexes/"+uid+"/settings", settings)
+	if err != nil {
+		return fmt.Errorf("failed to update settings of %s: %w", uid, err)
+	}
+	if err := c.WaitForTask(ctx, task); err != nil {
+		return fmt.Errorf("failed to update settings of %s: %w", uid, err)
+	}
+	return nil
+}
+
+// AddDocuments adds or replaces documents by primary key.
+func (c *Client) AddDocuments(ctx context.Context, index string, docs any) (Task, error) {
+	task, err := c.enqueue(ctx, http.MethodPost, "/indexes/"+IndexUID(index)+"/documents", docs)
+	if err != nil {
+		return task, fmt.Errorf("failed to add documents: %w", err)
+	}
+	return task, nil
+}
+
+// UpdateDocuments merges the given fields into existing documents (or adds them).
+func (c *Client) UpdateDocuments(ctx context.Context, index string, docs any) (Task, error) {
+	task, err := c.enqueue(ctx, http.MethodPut, "/indexes/"+IndexUID(index)+"/documents", docs)
+	if err != nil {
+		return task, fmt.Errorf("failed to update documents: %w", err)
+	}
+	return task, nil
+}
+
+// DeleteDocuments deletes documents by primary key.
+func (c *Client) DeleteDocuments(ctx context.Context, index string, ids []string) (Task, error) {
+	task, err := c.enqueue(ctx, http.MethodPost, "/indexes/"+IndexUID(index)+"/documents/delete-batch", ids)
+	if err != nil {
+		return task, fmt.Errorf("failed to delete documents: %w", err)
+	}
+	return task, nil
+}
+
+// DeleteDocumentsByFilter deletes every document matching a filter expression,
+// e.g. `document_id = "…"`. The attribute must be filterable.
+func (c *Client) DeleteDocumentsByFilter(ctx context.Context, index, filter string) (Task, error) {
+	task, err := c.enqueue(ctx, http.MethodPost, "/indexes/"+IndexUID(index)+"/documents/delete", map[string]string{"filter": filter})
+	if err != nil {
+		return task, fmt.Errorf("failed to delete documents by filter: %w", err)
+	}
+	return task, nil
+}
+
+// WaitForTask polls a task until it finishes. A failed or canceled task is
+// returned as an error (*TaskError when Meilisearch reports one).
+func (c *Client) WaitForTask(ctx context.Context, task Task) error {
+	ctx, cancel := context.WithTimeout(ctx, taskTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(taskPollInterval)
+	defer ticker.Stop()
+
+	for {
+		switch task.Status {
+		case "succeeded":
+			return nil
+		case "failed":
+			if task.Error != nil {
+				return task.Error
+			}
+			return fmt.Errorf("meilisearch task %d failed", task.id())
+		case "canceled":
+			return fmt.Errorf("meilisearch task %d was canceled", task.id())
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for meilisearch task %d: %w", task.id(), ctx.Err())
+		case <-ticker.C:
+		}
+
+		next, err := c.getTask(ctx, task.id())
+		if err != nil {
+			return err
+		}
+		task = next
+	}
+}
+
+func (c *Client) getTask(ctx context.Context, uid int64) (Task, error) {
+	var task Task
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/tasks/%d", uid), nil, &task); err != nil {
+		return task, fmt.Errorf("failed to get task %d: %w", uid, err)
+	}
+	return task, nil
+}
+
+// enqueue sends a write request and decodes the summarized task it returns.
+func (c *Client) enqueue(ctx context.Context, method, path string, body any) (Task, error) {
+	var task Task
+	err := c.do(ctx, method, path, body, &task)
+	return task, err
+}
+
+// do sends a JSON request and decodes a 2xx response into out. Error responses
+// are returned as *TaskError when they carry Meilisearch's error body.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("meilisearch request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var te TaskError
+		if json.Unmarshal(respBody, &te) == nil && te.Code != "" {
+			return &te
+		}
+		return fmt.Errorf("meilisearch error: status %d, body: %s", resp.StatusCode, string(respBody))
+	}
+	if out != nil {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+	}
+	return nil
+}
+
+// FilterValue quotes a string for use in a filter expression.
+func FilterValue(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,126 @@
+package meilisearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"cgap/internal/meilisearch"
+)
+
+// fakeMeili records request bodies by "METHOD path". Every write enqueues a new
+// task that is reported as processing on its first poll and finished on the next.
+// The first task fails with code fail when set.
+type fakeMeili struct {
+	mu     sync.Mutex
+	bodies map[string]string
+	tasks  int
+	polled map[string]bool
+	fail   string
+}
+
+func (f *fakeMeili) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	f.bodies[r.Method+" "+r.URL.Path] = string(body)
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/tasks/"):
+		uid := strings.TrimPrefix(r.URL.Path, "/tasks/")
+		switch {
+		case !f.polled[uid]:
+			f.polled[uid] = true
+			_, _ = w.Write([]byte(`{"uid":` + uid + `,"status":"processing"}`))
+		case uid == "1" && f.fail != "":
+			_, _ = w.Write([]byte(`{"uid":1,"status":"failed","error":{"code":"` + f.fail + `","message":"boom"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"uid":` + uid + `,"status":"succeeded"}`))
+		}
+	case r.URL.Path == "/indexes/cgap_chunks/search":
+		_, _ = w.Write([]byte(`{"hits":[{"id":"c1","document_id":"d1","text":"hello","_rankingScore":0.8},` +
+			`{"id":"c2","document_id":"d2","text":"hello video","start_seconds":65,"end_seconds":130}]}`))
+	default:
+		f.tasks++
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = fmt.Fprintf(w, `{"taskUid":%d,"status":"enqueued"}`, f.tasks)
+	}
+}
+
+func newFake(t *testing.T, fail string) (*fakeMeili, *meilisearch.Client) {
+	f := &fakeMeili{bodies: map[string]string{}, polled: map[string]bool{}, fail: fail}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, meilisearch.New(srv.URL, "key")
+}
+
+func TestEnsureIndex_CreatesIndexAndAppliesSettings(t *testing.T) {
+	f, client := newFake(t, "index_already_exists")
+
+	if err := client.EnsureIndex(context.Background(), meilisearch.ChunksIndex, meilisearch.ChunkSettings); err != nil {
+		t.Fatalf("EnsureIndex failed: %v", err)
+	}
+
+	if got := f.bodies["POST /indexes"]; !strings.Contains(got, `"uid":"cgap_chunks"`) || !strings.Contains(got, `"primaryKey":"id"`) {
+		t.Errorf("Unexpected create index body: %s", got)
+	}
+	var settings meilisearch.Settings
+	if err := json.Unmarshal([]byte(f.bodies["PATCH /indexes/cgap_chunks/settings"]), &settings); err != nil {
+		t.Fatalf("settings not sent: %v", err)
+	}
+	if len(settings.FilterableAttributes) == 0 || settings.RankingRules[0] != "typo" {
+		t.Errorf("Unexpected settings: %+v", settings)
+	}
+}
+
+func TestWaitForTask_ReturnsTaskError(t *testing.T) {
+	_, client := newFake(t, "invalid_document_id")
+
+	task, err := client.AddDocuments(context.Background(), meilisearch.ChunksIndex, []map[string]string{{"id": "bad id"}})
+	if err != nil {
+		t.Fatalf("AddDocuments failed: %v", err)
+	}
+	err = client.WaitForTask(context.Background(), task)
+	var te *meilisearch.TaskError
+	if !errors.As(err, &te) || te.Code != "invalid_document_id" {
+		t.Fatalf("Expected task error, got %v", err)
+	}
+}
+
+func TestDeleteDocumentsByFilter(t *testing.T) {
+	f, client := newFake(t, "")
+
+	filter := "document_id = " + meilisearch.FilterValue(`d"1`)
+	if _, err := client.DeleteDocumentsByFilter(context.Background(), meilisearch.ChunksIndex, filter); err != nil {
+		t.Fatalf("DeleteDocumentsByFilter failed: %v", err)
+	}
+	if got := f.bodies["POST /indexes/cgap_chunks/documents/delete"]; got != `{"filter":"document_id = \"d\\\"1\""}` {
+		t.Errorf("Unexpected delete body: %s", got)
+	}
+}
+
+func TestSearch_UsesPrefixedIndexAndProjectFilter(t *testing.T) {
+	f, client := newFake(t, "")
+
+	results, err := client.Search(context.Background(), meilisearch.ChunksIndex, "hello", 5, map[string]any{"project_id": "docs"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 || results[0].ID != "c1" || results[0].Metadata["document_id"] != "d1" {
+		t.Fatalf("Unexpected results: %+v", results)
+	}
+	if _, ok := results[0].Metadata["start_seconds"]; ok || results[1].Metadata["start_seconds"] != 65 || results[1].Metadata["end_seconds"] != 130 {
+		t.Errorf("Expected timestamps on transcript chunks only, got %+v", results)
+	}
+	if got := f.bodies["POST /indexes/cgap_chunks/search"]; !strings.Contains(got, `(project_id = \"docs\" OR project_slug = \"docs\")`) {
+		t.Errorf("Expected project filter to match id or slug, got %s", got)
+	}
+}
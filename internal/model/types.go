@@ -1,11 +1,18 @@
 package model
 
-import "time"
+import (
+	"net/url"
+	"time"
+)
 
 // Common string constants used across the domain
 const (
 	// Source types
-	SourceTypeCrawl = "crawl"
+	SourceTypeCrawl   = "crawl"
+	SourceTypeGitHub  = "github"
+	SourceTypeOpenAPI = "openapi"
+	SourceTypeUpload  = "upload"
+	SourceTypeArchive = "archive"
 
 	// Extraction statuses
 	ExtractionSuccess = "success"
@@ -51,16 +58,36 @@ type APIKey struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// Source is an ingest source; Config holds the ingest SourceSpec. Sources with
+// a sync interval are re-synced by the worker's scheduler while active.
 type Source struct {
-	ID        string         `json:"id"`
-	ProjectID string         `json:"project_id"`
-	Type      string         `json:"type"`
-	Config    map[string]any `json:"config"`
-	Status    string         `json:"status"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
+	ID             string         `json:"id"`
+	ProjectID      string         `json:"project_id"`
+	Name           string         `json:"name,omitempty"`
+	Type           string         `json:"type"`
+	Config         map[string]any `json:"config"`
+	Status         string         `json:"status"`             // active|paused
+	Schedule       string         `json:"schedule,omitempty"` // @hourly|@daily|@weekly or a duration such as "6h"
+	SyncInterval   time.Duration  `json:"-"`                  // parsed Schedule; 0 syncs on demand only
+	ChunkStrategy  string         `json:"chunk_strategy,omitempty"`
+	ChunkSizeToken int            `json:"chunk_size_token,omitempty"`
+	FullSync       bool           `json:"full_sync,omitempty"`
+	NextSyncAt     *time.Time     `json:"next_sync_at,omitempty"`
+	LastSyncedAt   *time.Time     `json:"last_synced_at,omitempty"`
+	LastJobID      string         `json:"last_job_id,omitempty"`
+	LastJobStatus  string         `json:"last_job_status,omitempty"` // status of LastJobID in job history
+	LastError      string         `json:"last_error,omitempty"`      // error of LastJobID, if any
+	SyncCursor     string         `json:"sync_cursor,omitempty"`     // where the last sync stopped, e.g. a commit SHA
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
 }
 
+// Source statuses.
+const (
+	SourceActive = "active"
+	SourcePaused = "paused"
+)
+
 type Document struct {
 	ID          string    `json:"id"`
 	ProjectID   string    `json:"project_id"`
@@ -83,6 +110,9 @@ type Chunk struct {
 	SectionPath string    `json:"section_path"`
 	ScoreRaw    float32   `json:"score_raw"`
 	CreatedAt   time.Time `json:"created_at"`
+	// Position of a transcript chunk in its video, in seconds
+	StartSeconds *int `json:"start_seconds,omitempty"`
+	EndSeconds   *int `json:"end_seconds,omitempty"`
 }
 
 type Thread struct {
@@ -181,3 +211,78 @@ type GapClusterExample struct {
 	Citations           []string `json:"citations"`
 	RepresentativeScore float32  `json:"representative_score"`
 }
+
+// Job statuses; completed, failed and cancelled are terminal.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobRetrying  = "retrying"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job is the durable record of an ingest job; Redis holds only live progress.
+type Job struct {
+	ID                string         `json:"id"`
+	ProjectID         string         `json:"project_id"`
+	SourceID          string         `json:"source_id,omitempty"`
+	Type              string         `json:"type"`
+	Status            string         `json:"status"`
+	Payload           map[string]any `json:"payload,omitempty"`
+	Processed         int            `json:"processed"`
+	Total             int            `json:"total"`
+	Unchanged         int            `json:"unchanged"`
+	Deleted           int            `json:"deleted"`
+	Failed            int            `json:"failed"`
+	Retries           int            `json:"retries"`
+	Error             string         `json:"error,omitempty"`
+	CancelRequestedAt *time.Time     `json:"cancel_requested_at,omitempty"`
+	CreatedAt         time.Time      `json:"created_at"`
+	StartedAt         *time.Time     `json:"started_at,omitempty"`
+	FinishedAt        *time.Time     `json:"finished_at,omitempty"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+}
+
+// IsTerminalJobStatus reports whether a job in this status can no longer change.
+func IsTerminalJobStatus(status string) bool {
+	switch status {
+	case JobCompleted, JobFailed, JobCancelled:
+		return true
+	}
+	return false
+}
+
+// JobCounters are increments applied to a job's progress counters.
+type JobCounters struct {
+	Processed int
+	Unchanged int
+	Deleted   int
+	Failed    int
+	Retries   int
+}
+
+// JobURLError records why a single URL of a job failed.
+type JobURLError struct {
+	URL        string    `json:"url"`
+	Error      string    `json:"error"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Upload is a file uploaded for ingestion or media processing; its content
+// is kept in the object store under StorageKey.
+type Upload struct {
+	ID          string    `json:"id"`
+	ProjectID   string    `json:"project_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	SHA256      string    `json:"sha256"`
+	StorageKey  string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// URI identifies the upload as a document or media URL: upload://<id>/<filename>.
+func (u *Upload) URI() string {
+	return "upload://" + u.ID + "/" + url.PathEscape(u.Filename)
+}
@@ -0,0 +1,118 @@
+package objectstore_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"cgap/internal/objectstore"
+)
+
+// roundTrip exercises Put, Get and Delete against any store.
+func roundTrip(t *testing.T, s objectstore.Store) {
+	t.Helper()
+	ctx := context.Background()
+	key := "proj/123/release notes.pdf"
+
+	if err := s.Put(ctx, key, strings.NewReader("%PDF-1.4 body"), 13, "application/pdf"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	rc, err := s.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "%PDF-1.4 body" {
+		t.Errorf("Get = %q", data)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get(ctx, key); !errors.Is(err, objectstore.ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of missing object: %v", err)
+	}
+
+	for _, bad := range []string{"../etc/passwd", "/abs", "a//b", "a/./b", ""} {
+		if err := s.Put(ctx, bad, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("Put(%q) accepted an invalid key", bad)
+		}
+	}
+}
+
+func TestFSStore(t *testing.T) {
+	s, err := objectstore.NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	roundTrip(t, s)
+
+	if err := s.Put(context.Background(), "short", strings.NewReader("abc"), 5, ""); err == nil {
+		t.Error("Put accepted a body shorter than size")
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") ||
+			!strings.Contains(auth, "/eu-west-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=") ||
+			r.Header.Get("X-Amz-Date") == "" {
+			http.Error(w, "bad signature", http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		key := r.URL.EscapedPath()
+		switch r.Method {
+		case http.MethodPut:
+			if r.ContentLength < 0 || r.Header.Get("Content-Type") != "application/pdf" {
+				http.Error(w, "missing length or type", http.StatusBadRequest)
+				return
+			}
+			objects[key], _ = io.ReadAll(r.Body)
+		case http.MethodGet:
+			data, ok := objects[key]
+			if !ok {
+				http.Error(w, "NoSuchKey", http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(data)
+		case http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	s, err := objectstore.NewS3Store(objectstore.S3Config{
+		Endpoint: srv.URL, Region: "eu-west-1", Bucket: "docs",
+		AccessKeyID: "AKID", SecretAccessKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roundTrip(t, s)
+
+	if err := s.Put(context.Background(), "a.pdf", strings.NewReader("x"), 1, "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := objects["/docs/a.pdf"]; !ok {
+		t.Errorf("objects not stored path-style: %v", objects)
+	}
+
+	if _, err := objectstore.NewS3Store(objectstore.S3Config{Bucket: "docs"}); err == nil {
+		t.Error("expected error without credentials")
+	}
+}
@@ -0,0 +1,69 @@
+// Package objectstore stores uploaded files as blobs addressed by key, on the
+// local filesystem or in an S3-compatible bucket.
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrNotFound is returned by Get when no object has the key.
+var ErrNotFound = errors.New("object not found")
+
+// Store is a blob store. Keys are slash-separated relative paths.
+type Store interface {
+	// Put stores size bytes read from r under key, replacing any object there.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object at key; it returns ErrNotFound if there is none.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// FromEnv builds the store selected by OBJECT_STORE: "fs" (default) keeps
+// objects under UPLOAD_DIR (default ./data/uploads); "s3" uses S3_ENDPOINT,
+// S3_REGION, S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY.
+func FromEnv() (Store, error) {
+	switch kind := os.Getenv("OBJECT_STORE"); kind {
+	case "", "fs":
+		dir := os.Getenv("UPLOAD_DIR")
+		if dir == "" {
+			dir = "./data/uploads"
+		}
+		s, err := NewFSStore(dir)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case "s3":
+		s, err := NewS3Store(S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unsupported OBJECT_STORE %q (want fs or s3)", kind)
+	}
+}
+
+// validKey rejects keys that could escape the store's root.
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid object key %q", key)
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return fmt.Errorf("invalid object key %q", key)
+		}
+	}
+	return nil
+}
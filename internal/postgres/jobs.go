@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"cgap/internal/model"
+	"cgap/internal/storage"
+)
+
+// JobRepo implementation.
+type JobRepo struct {
+	pool *pgxpool.Pool
+}
+
+const jobColumns = `
+	id, project_id, COALESCE(source_id::text, ''), type, status, payload, processed, total, unchanged, deleted, failed, retries,
+	COALESCE(error, ''), cancel_requested_at, created_at, started_at, finished_at, updated_at
+`
+
+func scanJob(row pgx.Row) (*model.Job, error) {
+	j := &model.Job{}
+	err := row.Scan(
+		&j.ID, &j.ProjectID, &j.SourceID, &j.Type, &j.Status, &j.Payload, &j.Processed, &j.Total, &j.Unchanged, &j.Deleted, &j.Failed, &j.Retries,
+		&j.Error, &j.CancelRequestedAt, &j.CreatedAt, &j.StartedAt, &j.FinishedAt, &j.UpdatedAt,
+	)
+	return j, err
+}
+
+func (r *JobRepo) Create(ctx context.Context, j *model.Job) error {
+	const query = `
+		INSERT INTO jobs (id, project_id, source_id, type, status, payload)
+		SELECT $1, id, NULLIF($6, '')::uuid, $3, $4, $5 FROM projects WHERE id::text = $2 OR slug = $2
+		LIMIT 1
+		RETURNING project_id, created_at, updated_at
+	`
+	if j.Type == "" {
+		j.Type = "ingest"
+	}
+	if j.Status == "" {
+		j.Status = model.JobQueued
+	}
+	err := r.pool.QueryRow(ctx, query, j.ID, j.ProjectID, j.Type, j.Status, j.Payload, j.SourceID).Scan(&j.ProjectID, &j.CreatedAt, &j.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to create job: project %s: %w", j.ProjectID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+	return nil
+}
+
+func (r *JobRepo) GetByID(ctx context.Context, id string) (*model.Job, error) {
+	j, err := scanJob(r.pool.QueryRow(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get job: %w", storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return j, nil
+}
+
+func (r *JobRepo) ListByProject(ctx context.Context, projectID, status string, limit, offset int) ([]*model.Job, error) {
+	query := `SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE project_id IN (SELECT id FROM projects WHERE id::text = $1 OR slug = $1)
+		  AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.pool.Query(ctx, query, projectID, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*model.Job
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, j)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return jobs, nil
+}
+
+func (r *JobRepo) Start(ctx context.Context, id string, total int) error {
+	const query = `
+		UPDATE jobs
+		SET status = 'running', total = $2, processed = 0, unchanged = 0, deleted = 0, failed = 0,
+		    error = NULL, started_at = now(), finished_at = NULL, updated_at = now()
+		WHERE id = $1
+	`
+	if _, err := r.pool.Exec(ctx, query, id, total); err != nil {
+		return fmt.Errorf("failed to start job: %w", err)
+	}
+	// A new attempt starts with a clean error list.
+	if _, err := r.pool.Exec(ctx, `DELETE FROM job_url_errors WHERE job_id = $1`, id); err != nil {
+		return fmt.Errorf("failed to reset job errors: %w", err)
+	}
+	return nil
+}
+
+func (r *JobRepo) SetStatus(ctx context.Context, id, status, errMsg string) error {
+	const query = `
+		UPDATE jobs
+		SET status = $2, error = NULLIF($3, ''), updated_at = now(),
+		    finished_at = CASE WHEN $2 IN ('completed', 'failed', 'cancelled') THEN now() ELSE NULL END
+		WHERE id = $1
+	`
+	if _, err := r.pool.Exec(ctx, query, id, status, errMsg); err != nil {
+		return fmt.Errorf("failed to set job status: %w", err)
+	}
+	return nil
+}
+
+func (r *JobRepo) AddCounters(ctx context.Context, id string, delta model.JobCounters) error {
+	const query = `
+		UPDATE jobs
+		SET processed = processed + $2, unchanged = unchanged + $3, deleted = deleted + $4,
+		    failed = failed + $5, retries = retries + $6, updated_at = now()
+		WHERE id = $1
+	`
+	_, err := r.pool.Exec(ctx, query, id, delta.Processed, delta.Unchanged, delta.Deleted, delta.Failed, delta.Retries)
+	if err != nil {
+		return fmt.Errorf("failed to update job counters: %w", err)
+	}
+	return nil
+}
+
+func (r *JobRepo) AddURLError(ctx context.Context, id, url, errMsg string) error {
+	const query = `INSERT INTO job_url_errors (job_id, url, error) VALUES ($1, $2, $3)`
+	if _, err := r.pool.Exec(ctx, query, id, url, errMsg); err != nil {
+		return fmt.Errorf("failed to record job url error: %w", err)
+	}
+	return nil
+}
+
+func (r *JobRepo) ListURLErrors(ctx context.Context, id string, limit int) ([]model.JobURLError, error) {
+	const query = `
+		SELECT url, error, occurred_at FROM job_url_errors
+		WHERE job_id = $1
+		ORDER BY occurred_at, id
+		LIMIT $2
+	`
+	rows, err := r.pool.Query(ctx, query, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list job url errors: %w", err)
+	}
+	defer rows.Close()
+
+	var errs []model.JobURLError
+	for rows.Next() {
+		var e model.JobURLError
+		if err := rows.Scan(&e.URL, &e.Error, &e.OccurredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan job url error: %w", err)
+		}
+		errs = append(errs, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return errs, nil
+}
+
+func (r *JobRepo) RequestCancel(ctx context.Context, id string) (*model.Job, error) {
+	query := `
+		UPDATE jobs
+		SET cancel_requested_at = COALESCE(cancel_requested_at, now()), updated_at = now()
+		WHERE id = $1 AND status NOT IN ('completed', 'failed', 'cancelled')
+		RETURNING ` + jobColumns
+	j, err := scanJob(r.pool.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Either unknown or already finished; let the caller tell them apart.
+		return r.GetByID(ctx, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel job: %w", err)
+	}
+	return j, nil
+}
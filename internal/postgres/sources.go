@@ -0,0 +1,212 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"cgap/internal/model"
+	"cgap/internal/storage"
+)
+
+// SourceRepo implementation.
+type SourceRepo struct {
+	pool *pgxpool.Pool
+}
+
+// sourceSelect reads sources (alias s) with the status of their last job.
+const sourceSelect = `
+	SELECT s.id, s.project_id, COALESCE(s.name, ''), s.type, s.config, COALESCE(s.status, 'active'),
+	       COALESCE(s.schedule, ''), s.sync_interval_seconds, COALESCE(s.chunk_strategy, ''), s.chunk_size_token, s.full_sync,
+	       s.next_sync_at, s.last_synced_at, COALESCE(s.last_job_id, ''), COALESCE(j.status, ''), COALESCE(j.error, ''),
+	       COALESCE(s.sync_cursor, ''), s.created_at, s.updated_at
+`
+
+func scanSource(row pgx.Row) (*model.Source, error) {
+	s := &model.Source{}
+	var intervalSeconds int
+	err := row.Scan(
+		&s.ID, &s.ProjectID, &s.Name, &s.Type, &s.Config, &s.Status,
+		&s.Schedule, &intervalSeconds, &s.ChunkStrategy, &s.ChunkSizeToken, &s.FullSync,
+		&s.NextSyncAt, &s.LastSyncedAt, &s.LastJobID, &s.LastJobStatus, &s.LastError,
+		&s.SyncCursor, &s.CreatedAt, &s.UpdatedAt,
+	)
+	s.SyncInterval = time.Duration(intervalSeconds) * time.Second
+	return s, err
+}
+
+func scanSources(rows pgx.Rows) ([]*model.Source, error) {
+	defer rows.Close()
+
+	var sources []*model.Source
+	for rows.Next() {
+		s, err := scanSource(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan source: %w", err)
+		}
+		sources = append(sources, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return sources, nil
+}
+
+func (r *SourceRepo) Create(ctx context.Context, s *model.Source) error {
+	const query = `
+		INSERT INTO sources (project_id, name, type, config, status, schedule, sync_interval_seconds,
+		                     chunk_strategy, chunk_size_token, full_sync, next_sync_at)
+		SELECT id, NULLIF($2, ''), $3, $4, $5, NULLIF($6, ''), $7, NULLIF($8, ''), $9, $10,
+		       CASE WHEN $7 > 0 THEN now() END
+		FROM projects WHERE id::text = $1 OR slug = $1
+		LIMIT 1
+		RETURNING id, project_id, next_sync_at, created_at, updated_at
+	`
+	if s.Status == "" {
+		s.Status = model.SourceActive
+	}
+	err := r.pool.QueryRow(ctx, query,
+		s.ProjectID, s.Name, s.Type, s.Config, s.Status, s.Schedule, int(s.SyncInterval/time.Second),
+		s.ChunkStrategy, s.ChunkSizeToken, s.FullSync,
+	).Scan(&s.ID, &s.ProjectID, &s.NextSyncAt, &s.CreatedAt, &s.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to create source: project %s: %w", s.ProjectID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create source: %w", err)
+	}
+	return nil
+}
+
+func (r *SourceRepo) GetByID(ctx context.Context, id string) (*model.Source, error) {
+	query := sourceSelect + `
+		FROM sources s LEFT JOIN jobs j ON j.id = s.last_job_id
+		WHERE s.id::text = $1
+	`
+	s, err := scanSource(r.pool.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get source: %w", storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source: %w", err)
+	}
+	return s, nil
+}
+
+func (r *SourceRepo) List(ctx context.Context, projectID string, limit, offset int) ([]*model.Source, error) {
+	query := sourceSelect + `
+		FROM sources s LEFT JOIN jobs j ON j.id = s.last_job_id
+		WHERE s.project_id IN (SELECT id FROM projects WHERE id::text = $1 OR slug = $1)
+		ORDER BY s.created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.pool.Query(ctx, query, projectID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sources: %w", err)
+	}
+	return scanSources(rows)
+}
+
+func (r *SourceRepo) Update(ctx context.Context, s *model.Source) error {
+	const query = `
+		UPDATE sources
+		SET name = NULLIF($2, ''), type = $3, config = $4, status = $5, schedule = NULLIF($6, ''),
+		    sync_interval_seconds = $7, chunk_strategy = NULLIF($8, ''), chunk_size_token = $9, full_sync = $10,
+		    next_sync_at = CASE WHEN $7 > 0 THEN COALESCE(last_synced_at + make_interval(secs => $7), now()) END,
+		    -- A changed config (e.g. other paths) needs a full sync
+		    sync_cursor = CASE WHEN config IS DISTINCT FROM $4 THEN NULL ELSE sync_cursor END,
+		    updated_at = now()
+		WHERE id::text = $1
+		RETURNING next_sync_at, COALESCE(sync_cursor, ''), updated_at
+	`
+	err := r.pool.QueryRow(ctx, query,
+		s.ID, s.Name, s.Type, s.Config, s.Status, s.Schedule, int(s.SyncInterval/time.Second),
+		s.ChunkStrategy, s.ChunkSizeToken, s.FullSync,
+	).Scan(&s.NextSyncAt, &s.SyncCursor, &s.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to update source: %w", storage.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update source: %w", err)
+	}
+	return nil
+}
+
+func (r *SourceRepo) Delete(ctx context.Context, id string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM sources WHERE id::text = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete source: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("failed to delete source: %w", storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *SourceRepo) FindOrCreate(ctx context.Context, s *model.Source) error {
+	query := sourceSelect + `
+		FROM sources s LEFT JOIN jobs j ON j.id = s.last_job_id
+		WHERE s.project_id IN (SELECT id FROM projects WHERE id::text = $1 OR slug = $1)
+		  AND s.type = $2 AND s.config = $3
+		ORDER BY s.created_at
+		LIMIT 1
+	`
+	found, err := scanSource(r.pool.QueryRow(ctx, query, s.ProjectID, s.Type, s.Config))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r.Create(ctx, s)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find source: %w", err)
+	}
+	*s = *found
+	return nil
+}
+
+func (r *SourceRepo) ClaimDue(ctx context.Context, limit int) ([]*model.Source, error) {
+	query := `
+		WITH due AS (
+			SELECT id FROM sources
+			WHERE status = 'active' AND sync_interval_seconds > 0 AND next_sync_at <= now()
+			ORDER BY next_sync_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE sources
+			SET next_sync_at = now() + make_interval(secs => sync_interval_seconds), updated_at = now()
+			WHERE id IN (SELECT id FROM due)
+			RETURNING *
+		)
+	` + sourceSelect + `
+		FROM claimed s LEFT JOIN jobs j ON j.id = s.last_job_id
+	`
+	rows, err := r.pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due sources: %w", err)
+	}
+	return scanSources(rows)
+}
+
+func (r *SourceRepo) RecordSync(ctx context.Context, id, jobID string) error {
+	const query = `
+		UPDATE sources SET last_job_id = $2, last_synced_at = now(), updated_at = now()
+		WHERE id::text = $1
+	`
+	if _, err := r.pool.Exec(ctx, query, id, jobID); err != nil {
+		return fmt.Errorf("failed to record source sync: %w", err)
+	}
+	return nil
+}
+
+func (r *SourceRepo) SaveCursor(ctx context.Context, id, cursor string) error {
+	const query = `UPDATE sources SET sync_cursor = NULLIF($2, ''), updated_at = now() WHERE id::text = $1`
+	if _, err := r.pool.Exec(ctx, query, id, cursor); err != nil {
+		return fmt.Errorf("failed to save source cursor: %w", err)
+	}
+	return nil
+}
@@ -2,11 +2,14 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pgvector/pgvector-go"
 	"github.com/pressly/goose/v3"
 
 	"cgap/internal/model"
@@ -98,6 +101,21 @@ func (s *Store) Gaps() storage.GapRepo {
 	return &GapRepo{pool: s.pool}
 }
 
+// Sources returns the ingest source repository implementation.
+func (s *Store) Sources() storage.SourceRepo {
+	return &SourceRepo{pool: s.pool}
+}
+
+// Jobs returns the ingest job repository implementation.
+func (s *Store) Jobs() storage.JobRepo {
+	return &JobRepo{pool: s.pool}
+}
+
+// Uploads returns the upload repository implementation.
+func (s *Store) Uploads() storage.UploadRepo {
+	return &UploadRepo{pool: s.pool}
+}
+
 func (s *Store) Close() error {
 	s.pool.Close()
 	return nil
@@ -125,6 +143,9 @@ func (r *ProjectRepo) GetByID(ctx context.Context, id string) (*model.Project, e
 	err := row.Scan(
 		&p.ID, &p.Name, &p.Slug, &p.DefaultModel, &p.Settings, &p.UsagePlan, &p.CreatedAt, &p.UpdatedAt,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get project: %w", storage.ErrNotFound)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get project: %w", err)
 	}
@@ -141,6 +162,9 @@ func (r *ProjectRepo) GetBySlug(ctx context.Context, slug string) (*model.Projec
 	err := row.Scan(
 		&p.ID, &p.Name, &p.Slug, &p.DefaultModel, &p.Settings, &p.UsagePlan, &p.CreatedAt, &p.UpdatedAt,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get project by slug: %w", storage.ErrNotFound)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get project by slug: %w", err)
 	}
@@ -260,13 +284,13 @@ type ChunkRepo struct {
 
 func (r *ChunkRepo) GetByID(ctx context.Context, id string) (*model.Chunk, error) {
 	const query = `
-		SELECT id, document_id, ord, text, token_count, section_path, score_raw, created_at
+		SELECT id, document_id, ord, text, token_count, section_path, score_raw, start_seconds, end_seconds, created_at
 		FROM chunks WHERE id = $1
 	`
 	row := r.pool.QueryRow(ctx, query, id)
 	c := &model.Chunk{}
 	err := row.Scan(
-		&c.ID, &c.DocumentID, &c.Ord, &c.Text, &c.TokenCount, &c.SectionPath, &c.ScoreRaw, &c.CreatedAt,
+		&c.ID, &c.DocumentID, &c.Ord, &c.Text, &c.TokenCount, &c.SectionPath, &c.ScoreRaw, &c.StartSeconds, &c.EndSeconds, &c.CreatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chunk: %w", err)
@@ -282,8 +306,8 @@ func (r *ChunkRepo) CreateBatch(ctx context.Context, chunks []*model.Chunk) erro
 	batch := &pgx.Batch{}
 	for _, c := range chunks {
 		batch.Queue(
-			"INSERT INTO chunks (id, document_id, ord, text, token_count, section_path, score_raw, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
-			c.ID, c.DocumentID, c.Ord, c.Text, c.TokenCount, c.SectionPath, c.ScoreRaw, c.CreatedAt,
+			"INSERT INTO chunks (id, document_id, ord, text, token_count, section_path, score_raw, start_seconds, end_seconds, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+			c.ID, c.DocumentID, c.Ord, c.Text, c.TokenCount, c.SectionPath, c.ScoreRaw, c.StartSeconds, c.EndSeconds, c.CreatedAt,
 		)
 	}
 
@@ -302,7 +326,7 @@ func (r *ChunkRepo) CreateBatch(ctx context.Context, chunks []*model.Chunk) erro
 
 func (r *ChunkRepo) ListByDocument(ctx context.Context, documentID string) ([]*model.Chunk, error) {
 	const query = `
-		SELECT id, document_id, ord, text, token_count, section_path, score_raw, created_at
+		SELECT id, document_id, ord, text, token_count, section_path, score_raw, start_seconds, end_seconds, created_at
 		FROM chunks WHERE document_id = $1
 		ORDER BY ord ASC
 	`
@@ -316,7 +340,7 @@ func (r *ChunkRepo) ListByDocument(ctx context.Context, documentID string) ([]*m
 	for rows.Next() {
 		c := &model.Chunk{}
 		err := rows.Scan(
-			&c.ID, &c.DocumentID, &c.Ord, &c.Text, &c.TokenCount, &c.SectionPath, &c.ScoreRaw, &c.CreatedAt,
+			&c.ID, &c.DocumentID, &c.Ord, &c.Text, &c.TokenCount, &c.SectionPath, &c.ScoreRaw, &c.StartSeconds, &c.EndSeconds, &c.CreatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan chunk: %w", err)
@@ -344,6 +368,9 @@ func (r *ThreadRepo) GetByID(ctx context.Context, id string) (*model.Thread, err
 	row := r.pool.QueryRow(ctx, query, id)
 	t := &model.Thread{}
 	err := row.Scan(&t.ID, &t.ProjectID, &t.Integration, &t.ExternalRef, &t.Status, &t.CreatedAt, &t.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get thread: %w", storage.ErrNotFound)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get thread: %w", err)
 	}
@@ -353,12 +380,15 @@ func (r *ThreadRepo) GetByID(ctx context.Context, id string) (*model.Thread, err
 func (r *ThreadRepo) Create(ctx context.Context, t *model.Thread) error {
 	const query = `
 		INSERT INTO threads (id, project_id, integration, external_ref, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		SELECT $1, id, $3, $4, $5, $6, $7 FROM projects WHERE id::text = $2
 	`
-	_, err := r.pool.Exec(ctx, query, t.ID, t.ProjectID, t.Integration, t.ExternalRef, t.Status, t.CreatedAt, t.UpdatedAt)
+	tag, err := r.pool.Exec(ctx, query, t.ID, t.ProjectID, t.Integration, t.ExternalRef, t.Status, t.CreatedAt, t.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create thread: %w", err)
 	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("failed to create thread: project %s: %w", t.ProjectID, storage.ErrNotFound)
+	}
 	return nil
 }
 
@@ -417,6 +447,29 @@ func (r *MessageRepo) ListByThread(ctx context.Context, threadID string, limit,
 	if err != nil {
 		return nil, fmt.Errorf("failed to list messages: %w", err)
 	}
+	return scanMessages(rows)
+}
+
+func (r *MessageRepo) ListRecentByThread(ctx context.Context, threadID string, limit int) ([]*model.Message, error) {
+	const query = `
+		SELECT id, thread_id, role, content, meta, latency_ms, created_at
+		FROM messages WHERE thread_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.pool.Query(ctx, query, threadID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(messages)
+	return messages, nil
+}
+
+func scanMessages(rows pgx.Rows) ([]*model.Message, error) {
 	defer rows.Close()
 
 	var messages []*model.Message
@@ -429,7 +482,7 @@ func (r *MessageRepo) ListByThread(ctx context.Context, threadID string, limit,
 		messages = append(messages, m)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("row iteration error: %w", err)
 	}
 
@@ -589,7 +642,12 @@ func (r *GapRepo) CreateCandidate(ctx context.Context, gc *model.GapCandidate) e
 		INSERT INTO gap_candidates (answer_id, question_embedding, uncertainty_reason)
 		VALUES ($1, $2, $3)
 	`
-	_, err := r.pool.Exec(ctx, query, gc.AnswerID, gc.QuestionEmbedding, gc.UncertaintyReason)
+	var embedding *pgvector.Vector
+	if len(gc.QuestionEmbedding) > 0 {
+		v := pgvector.NewVector(gc.QuestionEmbedding)
+		embedding = &v
+	}
+	_, err := r.pool.Exec(ctx, query, gc.AnswerID, embedding, gc.UncertaintyReason)
 	if err != nil {
 		return fmt.Errorf("failed to create gap candidate: %w", err)
 	}
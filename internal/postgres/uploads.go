@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"cgap/internal/model"
+	"cgap/internal/storage"
+)
+
+// UploadRepo implementation.
+type UploadRepo struct {
+	pool *pgxpool.Pool
+}
+
+// Create inserts u, using u.ID when set, and resolves u.ProjectID to the project UUID.
+func (r *UploadRepo) Create(ctx context.Context, u *model.Upload) error {
+	const query = `
+		INSERT INTO uploads (id, project_id, filename, content_type, size_bytes, sha256, storage_key)
+		SELECT COALESCE(NULLIF($1, '')::uuid, uuid_generate_v4()), id, $3, $4, $5, $6, $7
+		FROM projects WHERE id::text = $2 OR slug = $2
+		LIMIT 1
+		RETURNING id, project_id, created_at
+	`
+	err := r.pool.QueryRow(ctx, query,
+		u.ID, u.ProjectID, u.Filename, u.ContentType, u.SizeBytes, u.SHA256, u.StorageKey,
+	).Scan(&u.ID, &u.ProjectID, &u.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to create upload: project %s: %w", u.ProjectID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create upload: %w", err)
+	}
+	return nil
+}
+
+func (r *UploadRepo) GetByID(ctx context.Context, id string) (*model.Upload, error) {
+	const query = `
+		SELECT id, project_id, filename, content_type, size_bytes, sha256, storage_key, created_at
+		FROM uploads WHERE id::text = $1
+	`
+	u := &model.Upload{}
+	err := r.pool.QueryRow(ctx, query, id).Scan(
+		&u.ID, &u.ProjectID, &u.Filename, &u.ContentType, &u.SizeBytes, &u.SHA256, &u.StorageKey, &u.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get upload: %w", storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+	return u, nil
+}
@@ -3,18 +3,78 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// Redis keys. Pending tasks are pushed on the left and consumed from the right
+// (FIFO). A consumed task is moved atomically to the processing list and leased
+// until its visibility deadline; tasks whose lease expires (e.g. the worker
+// crashed) are redelivered. Failed tasks wait in the delayed set until their
+// backoff elapses and end up in the dead-letter list after MaxRetries.
+const (
+	pendingKey    = "cgap:tasks"
+	processingKey = "cgap:tasks:processing"
+	leasesKey     = "cgap:tasks:leases"
+	delayedKey    = "cgap:tasks:delayed"
+	deadKey       = "cgap:tasks:dead"
+
+	// cancelChannel broadcasts IDs of cancelled tasks to running consumers.
+	cancelChannel = "cgap:tasks:cancel"
+)
+
+// ErrTaskNotFound is returned when a dead-letter task does not exist.
+var ErrTaskNotFound = errors.New("task not found")
+
 // Task represents a job to be processed.
 type Task struct {
-	Type    string `json:"type"` // "ingest", "gap_cluster", etc.
-	Payload any    `json:"payload"`
-	ID      string `json:"id,omitempty"`
-	Retries int    `json:"retries,omitempty"`
+	Type      string `json:"type"` // "ingest", "gap_cluster", etc.
+	Payload   any    `json:"payload"`
+	ID        string `json:"id,omitempty"`
+	Retries   int    `json:"retries,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+	FailedAt  string `json:"failed_at,omitempty"` // RFC3339, set when dead-lettered
+
+	// raw is the encoded task as stored in the processing list; it identifies
+	// the delivery for Ack, Fail and Extend.
+	raw string
+}
+
+// Options configures delivery guarantees.
+type Options struct {
+	// Visibility is how long a consumed task stays leased before it is
+	// redelivered. Long-running handlers keep the lease with Heartbeat.
+	Visibility time.Duration
+	// MaxRetries is how many times a failed task is retried before it is
+	// moved to the dead-letter list.
+	MaxRetries int
+	// BaseBackoff is the delay before the first retry; it doubles per retry up to MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// DefaultOptions returns a 5 minute visibility timeout and 5 retries backing off from 10s to 10m.
+func DefaultOptions() Options {
+	return Options{
+		Visibility:  5 * time.Minute,
+		MaxRetries:  5,
+		BaseBackoff: 10 * time.Second,
+		MaxBackoff:  10 * time.Minute,
+	}
+}
+
+// Backoff returns the delay before the given retry (1-based).
+func (o Options) Backoff(retry int) time.Duration {
+	d := o.BaseBackoff
+	for i := 1; i < retry && d < o.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, o.MaxBackoff)
 }
 
 // Producer enqueues tasks.
@@ -27,11 +87,11 @@ type Producer struct {
 func NewProducer(redisClient *redis.Client) *Producer {
 	return &Producer{
 		client: redisClient,
-		key:    "cgap:tasks",
+		key:    pendingKey,
 	}
 }
 
-// Enqueue adds a task to the queue.
+// Enqueue adds a task to the back of the queue.
 func (p *Producer) Enqueue(ctx context.Context, task Task) error {
 	if task.ID == "" {
 		task.ID = fmt.Sprintf("%d", time.Now().UnixNano())
@@ -49,27 +109,169 @@ func (p *Producer) Enqueue(ctx context.Context, task Task) error {
 	return nil
 }
 
+// DeadTasks returns dead-lettered tasks, most recent first, and the total count.
+func (p *Producer) DeadTasks(ctx context.Context, offset, limit int) ([]Task, int64, error) {
+	total, err := p.client.LLen(ctx, deadKey).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count dead tasks: %w", err)
+	}
+	raws, err := p.client.LRange(ctx, deadKey, int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list dead tasks: %w", err)
+	}
+
+	tasks := make([]Task, 0, len(raws))
+	for _, raw := range raws {
+		var t Task
+		if err := json.Unmarshal([]byte(raw), &t); err != nil {
+			return nil, 0, fmt.Errorf("failed to unmarshal dead task: %w", err)
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, total, nil
+}
+
+// RequeueDead moves a dead-lettered task back to the queue with its retry count reset.
+func (p *Producer) RequeueDead(ctx context.Context, id string) (*Task, error) {
+	raws, err := p.client.LRange(ctx, deadKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead tasks: %w", err)
+	}
+	for _, raw := range raws {
+		var t Task
+		if err := json.Unmarshal([]byte(raw), &t); err != nil || t.ID != id {
+			continue
+		}
+		t.Retries, t.LastError, t.FailedAt = 0, "", ""
+		data, err := json.Marshal(t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal task: %w", err)
+		}
+		moved, err := moveScript.Run(ctx, p.client, []string{deadKey, p.key}, raw, string(data), "left").Int()
+		if err != nil {
+			return nil, fmt.Errorf("failed to requeue task: %w", err)
+		}
+		if moved == 0 {
+			break // requeued concurrently
+		}
+		return &t, nil
+	}
+	return nil, ErrTaskNotFound
+}
+
+// Cancel removes a task that has not started yet (pending or waiting for a
+// retry) and reports whether it did. Otherwise the task may be running, and
+// consumers are notified through WatchCancels so they can stop it.
+func (p *Producer) Cancel(ctx context.Context, id string) (bool, error) {
+	pending, err := p.client.LRange(ctx, p.key, 0, -1).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	for _, raw := range pending {
+		if taskID(raw) != id {
+			continue
+		}
+		n, err := p.client.LRem(ctx, p.key, 1, raw).Result()
+		if err != nil {
+			return false, fmt.Errorf("failed to remove task: %w", err)
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+
+	delayed, err := p.client.ZRange(ctx, delayedKey, 0, -1).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to list delayed tasks: %w", err)
+	}
+	for _, raw := range delayed {
+		if taskID(raw) != id {
+			continue
+		}
+		n, err := p.client.ZRem(ctx, delayedKey, raw).Result()
+		if err != nil {
+			return false, fmt.Errorf("failed to remove task: %w", err)
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+
+	if err := p.client.Publish(ctx, cancelChannel, id).Err(); err != nil {
+		return false, fmt.Errorf("failed to publish cancellation: %w", err)
+	}
+	return false, nil
+}
+
+// taskID decodes the ID of an encoded task, or returns "" if it is malformed.
+func taskID(raw string) string {
+	var t struct {
+		ID string `json:"id"`
+	}
+	if json.Unmarshal([]byte(raw), &t) != nil {
+		return ""
+	}
+	return t.ID
+}
+
 // Consumer dequeues and processes tasks.
 type Consumer struct {
 	client  *redis.Client
 	key     string
 	timeout time.Duration
+	opts    Options
+
+	mu          sync.Mutex
+	lastRecover time.Time
 }
 
-// NewConsumer creates a new task queue consumer.
+// NewConsumer creates a new task queue consumer with DefaultOptions.
 func NewConsumer(redisClient *redis.Client) *Consumer {
+	return NewConsumerWithOptions(redisClient, DefaultOptions())
+}
+
+// NewConsumerWithOptions creates a consumer with custom delivery options.
+// Zero durations fall back to DefaultOptions; MaxRetries 0 dead-letters on the first failure.
+func NewConsumerWithOptions(redisClient *redis.Client, opts Options) *Consumer {
+	def := DefaultOptions()
+	if opts.Visibility <= 0 {
+		opts.Visibility = def.Visibility
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = def.BaseBackoff
+	}
+	if opts.MaxBackoff < opts.BaseBackoff {
+		opts.MaxBackoff = max(def.MaxBackoff, opts.BaseBackoff)
+	}
 	return &Consumer{
 		client:  redisClient,
-		key:     "cgap:tasks",
+		key:     pendingKey,
 		timeout: 30 * time.Second,
+		opts:    opts,
 	}
 }
 
-// Process retrieves and processes the next task from the queue.
+// recoverInterval bounds how often Process scans for due retries and expired leases.
+const recoverInterval = 5 * time.Second
+
+// Process retrieves the next task from the queue and leases it. The caller must
+// Ack or Fail it; otherwise it is redelivered after the visibility timeout.
 // Returns nil if timeout occurs without a task.
 func (c *Consumer) Process(ctx context.Context) (*Task, error) {
-	// Use BLPOP with timeout to get next task
-	result, err := c.client.BLPop(ctx, c.timeout, c.key).Result()
+	c.mu.Lock()
+	due := time.Since(c.lastRecover) >= recoverInterval
+	if due {
+		c.lastRecover = time.Now()
+	}
+	c.mu.Unlock()
+	if due {
+		if err := c.Recover(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// Wait at most until the next recovery pass so due retries are not delayed.
+	raw, err := c.client.BLMove(ctx, c.key, processingKey, "RIGHT", "LEFT", min(c.timeout, recoverInterval)).Result()
 	if err != nil {
 		if err == redis.Nil {
 			// Timeout - no task available
@@ -78,14 +280,243 @@ func (c *Consumer) Process(ctx context.Context) (*Task, error) {
 		return nil, fmt.Errorf("failed to pop task: %w", err)
 	}
 
-	if len(result) < 2 {
-		return nil, fmt.Errorf("unexpected BLPOP result format")
+	if err := c.lease(ctx, raw); err != nil {
+		return nil, err
 	}
 
 	var task Task
-	if err := json.Unmarshal([]byte(result[1]), &task); err != nil {
+	if err := json.Unmarshal([]byte(raw), &task); err != nil {
+		// A task that cannot be decoded can never succeed.
+		_ = c.deadLetter(ctx, raw, Task{ID: "undecodable", Payload: raw, LastError: err.Error()})
 		return nil, fmt.Errorf("failed to unmarshal task: %w", err)
 	}
+	task.raw = raw
 
 	return &task, nil
 }
+
+// Ack removes a successfully processed task.
+func (c *Consumer) Ack(ctx context.Context, task *Task) error {
+	pipe := c.client.TxPipeline()
+	pipe.LRem(ctx, processingKey, 1, task.raw)
+	pipe.ZRem(ctx, leasesKey, task.raw)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to ack task: %w", err)
+	}
+	return nil
+}
+
+// ErrLeaseLost is returned by Extend when the task was already redelivered.
+var ErrLeaseLost = errors.New("task lease lost")
+
+// Extend renews the lease on a task that is still being processed.
+func (c *Consumer) Extend(ctx context.Context, task *Task) error {
+	n, err := c.client.ZAddArgs(ctx, leasesKey, redis.ZAddArgs{
+		XX:      true,
+		Ch:      true,
+		Members: []redis.Z{{Score: float64(c.deadline()), Member: task.raw}},
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to extend lease: %w", err)
+	}
+	if n == 0 {
+		// CH does not count a renewal to the same millisecond; only a missing
+		// lease means the task was redelivered.
+		if err := c.client.ZScore(ctx, leasesKey, task.raw).Err(); err == redis.Nil {
+			return ErrLeaseLost
+		} else if err != nil {
+			return fmt.Errorf("failed to extend lease: %w", err)
+		}
+	}
+	return nil
+}
+
+// Heartbeat extends the task's lease every third of the visibility timeout
+// until the returned stop function is called.
+func (c *Consumer) Heartbeat(ctx context.Context, task *Task) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(c.opts.Visibility / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.Extend(ctx, task); err != nil {
+					slog.Warn("failed to extend task lease", "task_id", task.ID, "error", err)
+					if errors.Is(err, ErrLeaseLost) {
+						return
+					}
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// Fail records a failed attempt. The task is retried after an exponential
+// backoff, or dead-lettered once MaxRetries is exceeded; dead reports which.
+func (c *Consumer) Fail(ctx context.Context, task *Task, cause error) (dead bool, err error) {
+	next := *task
+	next.Retries++
+	next.LastError = cause.Error()
+
+	if next.Retries > c.opts.MaxRetries {
+		return true, c.deadLetter(ctx, task.raw, next)
+	}
+
+	data, err := json.Marshal(next)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal task: %w", err)
+	}
+	readyAt := time.Now().Add(c.opts.Backoff(next.Retries)).UnixMilli()
+	if _, err := retryScript.Run(ctx, c.client, []string{processingKey, leasesKey, delayedKey}, task.raw, string(data), readyAt).Result(); err != nil {
+		return false, fmt.Errorf("failed to schedule retry: %w", err)
+	}
+	return false, nil
+}
+
+// WatchCancels calls fn with the ID of every task cancelled through
+// Producer.Cancel until ctx is done. Notifications are not persisted, so
+// callers should also check a durable flag when a task starts.
+func (c *Consumer) WatchCancels(ctx context.Context, fn func(id string)) error {
+	sub := c.client.Subscribe(ctx, cancelChannel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			fn(msg.Payload)
+		}
+	}
+}
+
+// Requeue returns an unfinished task to the front of the queue without counting
+// a failed attempt, e.g. when a worker shuts down before the task completed.
+func (c *Consumer) Requeue(ctx context.Context, task *Task) error {
+	if err := moveScript.Run(ctx, c.client, []string{processingKey, c.key}, task.raw, task.raw, "right").Err(); err != nil {
+		return fmt.Errorf("failed to requeue task: %w", err)
+	}
+	if err := c.client.ZRem(ctx, leasesKey, task.raw).Err(); err != nil {
+		return fmt.Errorf("failed to release task: %w", err)
+	}
+	return nil
+}
+
+// Recover moves retries whose backoff elapsed back to the queue and redelivers
+// tasks whose lease expired. Redelivery counts as a failed attempt so a task
+// that keeps crashing the worker is eventually dead-lettered.
+func (c *Consumer) Recover(ctx context.Context) error {
+	now := time.Now().UnixMilli()
+	if err := promoteScript.Run(ctx, c.client, []string{delayedKey, c.key}, now).Err(); err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to promote retries: %w", err)
+	}
+
+	raws, err := c.client.LRange(ctx, processingKey, 0, -1).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list processing tasks: %w", err)
+	}
+	for _, raw := range raws {
+		deadline, err := c.client.ZScore(ctx, leasesKey, raw).Result()
+		if err == redis.Nil {
+			// Consumed but not yet leased (or leased by a consumer that died
+			// in between): start the clock now.
+			if err := c.client.ZAddNX(ctx, leasesKey, redis.Z{Score: float64(c.deadline()), Member: raw}).Err(); err != nil {
+				return fmt.Errorf("failed to lease task: %w", err)
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read lease: %w", err)
+		}
+		if int64(deadline) > now {
+			continue
+		}
+
+		var task Task
+		if err := json.Unmarshal([]byte(raw), &task); err != nil {
+			task = Task{ID: "undecodable", Payload: raw}
+		}
+		task.raw = raw
+		if _, err := c.Fail(ctx, &task, errors.New("visibility timeout expired")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Consumer) deadline() int64 {
+	return time.Now().Add(c.opts.Visibility).UnixMilli()
+}
+
+func (c *Consumer) lease(ctx context.Context, raw string) error {
+	if err := c.client.ZAdd(ctx, leasesKey, redis.Z{Score: float64(c.deadline()), Member: raw}).Err(); err != nil {
+		return fmt.Errorf("failed to lease task: %w", err)
+	}
+	return nil
+}
+
+// deadLetter moves a delivery from the processing list to the dead-letter list.
+func (c *Consumer) deadLetter(ctx context.Context, raw string, task Task) error {
+	task.FailedAt = time.Now().UTC().Format(time.RFC3339)
+	data, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+	if err := c.client.ZRem(ctx, leasesKey, raw).Err(); err != nil {
+		return fmt.Errorf("failed to release task: %w", err)
+	}
+	if err := moveScript.Run(ctx, c.client, []string{processingKey, deadKey}, raw, string(data), "left").Err(); err != nil {
+		return fmt.Errorf("failed to dead-letter task: %w", err)
+	}
+	return nil
+}
+
+// moveScript replaces ARGV[1] in list KEYS[1] with ARGV[2] pushed onto list
+// KEYS[2] (ARGV[3] = "left" or "right"). It returns 0 without pushing when the
+// source item is gone, so concurrent movers cannot duplicate a task.
+var moveScript = redis.NewScript(`
+if redis.call("LREM", KEYS[1], 1, ARGV[1]) == 0 then
+	return 0
+end
+if ARGV[3] == "left" then
+	redis.call("LPUSH", KEYS[2], ARGV[2])
+else
+	redis.call("RPUSH", KEYS[2], ARGV[2])
+end
+return 1
+`)
+
+// retryScript moves a delivery from processing (KEYS[1]) to the delayed set
+// (KEYS[3]) scored by its ready time, dropping its lease (KEYS[2]).
+var retryScript = redis.NewScript(`
+if redis.call("LREM", KEYS[1], 1, ARGV[1]) == 0 then
+	return 0
+end
+redis.call("ZREM", KEYS[2], ARGV[1])
+redis.call("ZADD", KEYS[3], ARGV[3], ARGV[2])
+return 1
+`)
+
+// promoteScript moves delayed tasks (KEYS[1]) that are ready by ARGV[1] to the
+// consuming end of the queue (KEYS[2]) so retries run before newer tasks.
+var promoteScript = redis.NewScript(`
+local ready = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, 100)
+for _, raw in ipairs(ready) do
+	redis.call("ZREM", KEYS[1], raw)
+	redis.call("RPUSH", KEYS[2], raw)
+end
+return #ready
+`)
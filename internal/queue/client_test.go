@@ -0,0 +1,222 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"cgap/internal/queue"
+)
+
+// newTestQueue returns a producer and a consumer sharing an in-memory Redis.
+func newTestQueue(t *testing.T, opts queue.Options) (*queue.Producer, *queue.Consumer, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return queue.NewProducer(client), queue.NewConsumerWithOptions(client, opts), client
+}
+
+// testOptions leases for 50ms and retries after 10ms.
+func testOptions(maxRetries int) queue.Options {
+	return queue.Options{
+		Visibility:  50 * time.Millisecond,
+		MaxRetries:  maxRetries,
+		BaseBackoff: 10 * time.Millisecond,
+		MaxBackoff:  10 * time.Millisecond,
+	}
+}
+
+// mustProcess consumes the next task and fails the test if there is none.
+func mustProcess(t *testing.T, c *queue.Consumer) *queue.Task {
+	t.Helper()
+	task, err := c.Process(context.Background())
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if task == nil {
+		t.Fatal("Expected a task, got none")
+	}
+	return task
+}
+
+func TestOptions_Backoff(t *testing.T) {
+	opts := queue.Options{BaseBackoff: 10 * time.Second, MaxBackoff: time.Minute}
+
+	for retry, want := range map[int]time.Duration{
+		1: 10 * time.Second,
+		2: 20 * time.Second,
+		3: 40 * time.Second,
+		4: time.Minute,
+		9: time.Minute,
+	} {
+		if got := opts.Backoff(retry); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", retry, got, want)
+		}
+	}
+}
+
+func TestConsumer_ProcessesInFIFOOrder(t *testing.T) {
+	ctx := context.Background()
+	p, c, client := newTestQueue(t, testOptions(3))
+
+	for _, id := range []string{"a", "b", "c"} {
+		if err := p.Enqueue(ctx, queue.Task{ID: id, Type: "ingest"}); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+	for _, want := range []string{"a", "b", "c"} {
+		task := mustProcess(t, c)
+		if task.ID != want {
+			t.Errorf("Expected task %s, got %s", want, task.ID)
+		}
+		if err := c.Ack(ctx, task); err != nil {
+			t.Fatalf("Ack failed: %v", err)
+		}
+	}
+	if n := client.LLen(ctx, "cgap:tasks:processing").Val(); n != 0 {
+		t.Errorf("Expected acked tasks to leave the processing list, got %d", n)
+	}
+}
+
+func TestConsumer_RecoverRedeliversExpiredLease(t *testing.T) {
+	ctx := context.Background()
+	p, c, _ := newTestQueue(t, testOptions(3))
+
+	if err := p.Enqueue(ctx, queue.Task{ID: "a", Type: "ingest"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	first := mustProcess(t, c)
+
+	// An unexpired lease is left alone.
+	if err := c.Recover(ctx); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if err := c.Extend(ctx, first); err != nil {
+		t.Errorf("Expected the lease to be held, got %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := c.Recover(ctx); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if err := c.Extend(ctx, first); !errors.Is(err, queue.ErrLeaseLost) {
+		t.Errorf("Expected ErrLeaseLost after the lease expired, got %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if err := c.Recover(ctx); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	again := mustProcess(t, c)
+	if again.ID != "a" || again.Retries != 1 || again.LastError != "visibility timeout expired" {
+		t.Errorf("Expected redelivery counted as a failed attempt, got %+v", again)
+	}
+}
+
+func TestConsumer_FailDelaysRetry(t *testing.T) {
+	ctx := context.Background()
+	p, c, client := newTestQueue(t, queue.Options{MaxRetries: 3, BaseBackoff: 100 * time.Millisecond, MaxBackoff: 100 * time.Millisecond})
+
+	for _, id := range []string{"a", "b"} {
+		if err := p.Enqueue(ctx, queue.Task{ID: id, Type: "ingest"}); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+	task := mustProcess(t, c)
+	if dead, err := c.Fail(ctx, task, errors.New("boom")); err != nil || dead {
+		t.Fatalf("Expected a retry, got dead=%v err=%v", dead, err)
+	}
+
+	// Not due yet: the retry stays delayed.
+	if err := c.Recover(ctx); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if n := client.ZCard(ctx, "cgap:tasks:delayed").Val(); n != 1 {
+		t.Fatalf("Expected one delayed retry, got %d", n)
+	}
+	if n := client.LLen(ctx, "cgap:tasks").Val(); n != 1 {
+		t.Fatalf("Expected only b pending, got %d", n)
+	}
+
+	time.Sleep(120 * time.Millisecond)
+	if err := c.Recover(ctx); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	// Promoted retries run before newer tasks.
+	retry := mustProcess(t, c)
+	if retry.ID != "a" || retry.Retries != 1 || retry.LastError != "boom" {
+		t.Errorf("Expected the promoted retry of a, got %+v", retry)
+	}
+	if next := mustProcess(t, c); next.ID != "b" {
+		t.Errorf("Expected b after the retry, got %s", next.ID)
+	}
+}
+
+func TestConsumer_DeadLettersAfterMaxRetries(t *testing.T) {
+	ctx := context.Background()
+	p, c, client := newTestQueue(t, testOptions(1))
+
+	if err := p.Enqueue(ctx, queue.Task{ID: "a", Type: "ingest"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	task := mustProcess(t, c)
+	if dead, err := c.Fail(ctx, task, errors.New("first")); err != nil || dead {
+		t.Fatalf("Expected a retry, got dead=%v err=%v", dead, err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if err := c.Recover(ctx); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	task = mustProcess(t, c)
+	if dead, err := c.Fail(ctx, task, errors.New("second")); err != nil || !dead {
+		t.Fatalf("Expected the task to be dead-lettered, got dead=%v err=%v", dead, err)
+	}
+
+	tasks, total, err := p.DeadTasks(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("DeadTasks failed: %v", err)
+	}
+	if total != 1 || len(tasks) != 1 {
+		t.Fatalf("Expected one dead task, got %d", total)
+	}
+	if d := tasks[0]; d.ID != "a" || d.Retries != 2 || d.LastError != "second" || d.FailedAt == "" {
+		t.Errorf("Unexpected dead task %+v", d)
+	}
+	if n := client.LLen(ctx, "cgap:tasks:processing").Val() + client.ZCard(ctx, "cgap:tasks:leases").Val(); n != 0 {
+		t.Errorf("Expected the dead task to leave processing and leases, got %d entries", n)
+	}
+}
+
+func TestProducer_RequeueDead(t *testing.T) {
+	ctx := context.Background()
+	p, c, _ := newTestQueue(t, testOptions(0))
+
+	if err := p.Enqueue(ctx, queue.Task{ID: "a", Type: "ingest"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if dead, err := c.Fail(ctx, mustProcess(t, c), errors.New("boom")); err != nil || !dead {
+		t.Fatalf("Expected the task to be dead-lettered, got dead=%v err=%v", dead, err)
+	}
+
+	if _, err := p.RequeueDead(ctx, "missing"); !errors.Is(err, queue.ErrTaskNotFound) {
+		t.Errorf("Expected ErrTaskNotFound, got %v", err)
+	}
+	requeued, err := p.RequeueDead(ctx, "a")
+	if err != nil {
+		t.Fatalf("RequeueDead failed: %v", err)
+	}
+	if requeued.Retries != 0 || requeued.LastError != "" || requeued.FailedAt != "" {
+		t.Errorf("Expected retry state reset, got %+v", requeued)
+	}
+	if _, total, _ := p.DeadTasks(ctx, 0, 10); total != 0 {
+		t.Errorf("Expected the dead-letter list to be empty, got %d", total)
+	}
+	if task := mustProcess(t, c); task.ID != "a" || task.Retries != 0 {
+		t.Errorf("Expected the requeued task, got %+v", task)
+	}
+}
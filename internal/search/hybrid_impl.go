@@ -2,60 +2,296 @@ package search
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
 
+	"cgap/api"
 	"cgap/internal/service"
 )
 
-// Hybrid blends two search providers (primary preferred, secondary for recall).
+// FusionStrategy selects how Hybrid merges the two ranked lists.
+type FusionStrategy string
+
+const (
+	// FusionRRF is Reciprocal Rank Fusion: score = Σ w / (k + rank).
+	FusionRRF FusionStrategy = "rrf"
+	// FusionWeighted is a weighted mean of min-max normalized backend scores.
+	FusionWeighted FusionStrategy = "weighted"
+)
+
+// DefaultRRFK is the conventional RRF rank constant.
+const DefaultRRFK = 60
+
+// FusionConfig configures Hybrid. Zero weights default to 1.
+type FusionConfig struct {
+	Strategy        FusionStrategy
+	RRFK            float64
+	PrimaryWeight   float64 // semantic (pgvector)
+	SecondaryWeight float64 // lexical (Meilisearch)
+}
+
+// DefaultFusionConfig is RRF with k=60 and equal weights.
+func DefaultFusionConfig() FusionConfig {
+	return FusionConfig{Strategy: FusionRRF, RRFK: DefaultRRFK, PrimaryWeight: 1, SecondaryWeight: 1}
+}
+
+// Hybrid blends two search providers (primary semantic, secondary lexical).
 type Hybrid struct {
 	primary   service.Search
 	secondary service.Search
+	fusion    FusionConfig
 }
 
 func NewHybrid(primary, secondary service.Search) *Hybrid {
-	return &Hybrid{primary: primary, secondary: secondary}
+	return NewHybridWithFusion(primary, secondary, DefaultFusionConfig())
 }
 
-// Search queries primary then secondary and merges results up to topK.
+// NewHybridWithFusion creates a Hybrid using the given default fusion settings.
+func NewHybridWithFusion(primary, secondary service.Search, cfg FusionConfig) *Hybrid {
+	return &Hybrid{primary: primary, secondary: secondary, fusion: cfg.withDefaults()}
+}
+
+// Search queries both providers in parallel and fuses their rankings up to topK.
+// A per-request api.FusionOptions may be passed under filters[api.FusionFilterKey].
+// The fused score (0-1) is returned in SearchResult.Score; Metadata["relevance"]
+// keeps the best raw backend score for absolute relevance checks.
 func (h *Hybrid) Search(ctx context.Context, index, query string, topK int, filters map[string]any) ([]service.SearchResult, error) {
 	if topK <= 0 {
 		topK = 10
 	}
 
-	// Try primary
-	pRes, pErr := h.primary.Search(ctx, index, query, topK, filters)
+	cfg, backendFilters, err := h.requestFusion(filters)
+	if err != nil {
+		return nil, err
+	}
 
-	// Always try secondary to improve recall
-	sRes, sErr := h.secondary.Search(ctx, index, query, topK, filters)
+	var (
+		wg         sync.WaitGroup
+		pRes, sRes []service.SearchResult
+		pErr, sErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		pRes, pErr = h.primary.Search(ctx, index, query, topK, backendFilters)
+	}()
+	go func() {
+		defer wg.Done()
+		sRes, sErr = h.secondary.Search(ctx, index, query, topK, backendFilters)
+	}()
+	wg.Wait()
 
 	// If both failed, return one of the errors
 	if pErr != nil && sErr != nil {
 		return nil, pErr
 	}
+	// A nil list marks a failed backend so it does not count towards the fused maximum.
+	if pErr != nil {
+		slog.Warn("hybrid search: primary backend failed, using secondary only", "error", pErr)
+		pRes = nil
+	} else if pRes == nil {
+		pRes = []service.SearchResult{}
+	}
+	if sErr != nil {
+		slog.Warn("hybrid search: secondary backend failed, using primary only", "error", sErr)
+		sRes = nil
+	} else if sRes == nil {
+		sRes = []service.SearchResult{}
+	}
+
+	out := fuse(cfg, pRes, sRes)
+	if len(out) > topK {
+		out = out[:topK]
+	}
+	return out, nil
+}
+
+// fused accumulates one document's contribution from each list.
+type fused struct {
+	result    service.SearchResult
+	score     float64
+	bestRank  int
+	fromList  int
+	relevance float32
+}
 
-	// Merge results: prefer primary ordering, then fill with secondary (dedupe by ID)
-	seen := make(map[string]bool)
-	out := make([]service.SearchResult, 0, topK)
+// fuse merges the ranked lists (deduplicated by ID) according to cfg. Nil lists
+// (failed backends) are skipped.
+func fuse(cfg FusionConfig, lists ...[]service.SearchResult) []service.SearchResult {
+	weights := []float64{cfg.PrimaryWeight, cfg.SecondaryWeight}
 
-	for _, r := range pRes {
-		if len(out) >= topK {
-			break
+	byID := make(map[string]*fused)
+	var order []*fused
+	var maxScore float64
+	for li, list := range lists {
+		if list == nil {
+			continue
 		}
-		if !seen[r.ID] {
-			seen[r.ID] = true
-			out = append(out, r)
+		w := weights[li]
+		norm := minMax(list)
+		if cfg.Strategy == FusionRRF {
+			maxScore += w / (cfg.RRFK + 1)
+		} else {
+			maxScore += w
+		}
+
+		for rank, r := range list {
+			f, ok := byID[r.ID]
+			if !ok {
+				f = &fused{result: r, bestRank: rank + 1, fromList: li}
+				f.result.Metadata = copyMeta(r.Metadata)
+				byID[r.ID] = f
+				order = append(order, f)
+			} else {
+				for k, v := range r.Metadata {
+					if _, exists := f.result.Metadata[k]; !exists {
+						f.result.Metadata[k] = v
+					}
+				}
+				f.bestRank = min(f.bestRank, rank+1)
+			}
+			f.relevance = max(f.relevance, min(max(r.Score, 0), 1))
+
+			if cfg.Strategy == FusionRRF {
+				f.score += w / (cfg.RRFK + float64(rank+1))
+			} else {
+				f.score += w * norm[rank]
+			}
 		}
 	}
 
-	for _, r := range sRes {
-		if len(out) >= topK {
-			break
+	// Stable ordering: fused score, then best rank, then primary before secondary.
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if a.score != b.score {
+			return a.score > b.score
 		}
-		if !seen[r.ID] {
-			seen[r.ID] = true
-			out = append(out, r)
+		if a.bestRank != b.bestRank {
+			return a.bestRank < b.bestRank
 		}
+		return a.fromList < b.fromList
+	})
+
+	out := make([]service.SearchResult, 0, len(order))
+	for _, f := range order {
+		r := f.result
+		if maxScore > 0 {
+			r.Score = float32(f.score / maxScore)
+		}
+		r.Metadata["relevance"] = f.relevance
+		out = append(out, r)
 	}
+	return out
+}
 
-	return out, nil
+// minMax scales a list's scores to [0, 1]; a list with a single distinct score maps to 1.
+func minMax(list []service.SearchResult) []float64 {
+	out := make([]float64, len(list))
+	if len(list) == 0 {
+		return out
+	}
+	lo, hi := list[0].Score, list[0].Score
+	for _, r := range list[1:] {
+		lo, hi = min(lo, r.Score), max(hi, r.Score)
+	}
+	for i, r := range list {
+		if hi == lo {
+			out[i] = 1
+			continue
+		}
+		out[i] = float64(r.Score-lo) / float64(hi-lo)
+	}
+	return out
+}
+
+// requestFusion applies per-request overrides and strips them from the filters
+// forwarded to the backends.
+func (h *Hybrid) requestFusion(filters map[string]any) (FusionConfig, map[string]any, error) {
+	cfg := h.fusion
+	raw, ok := filters[api.FusionFilterKey]
+	if !ok || raw == nil {
+		return cfg, filters, nil
+	}
+
+	backendFilters := make(map[string]any, len(filters))
+	for k, v := range filters {
+		if k != api.FusionFilterKey {
+			backendFilters[k] = v
+		}
+	}
+
+	opts, err := fusionOptions(raw)
+	if err != nil {
+		return cfg, nil, err
+	}
+	if opts.Strategy != "" {
+		cfg.Strategy = FusionStrategy(opts.Strategy)
+	}
+	if opts.K > 0 {
+		cfg.RRFK = opts.K
+	}
+	if opts.SemanticWeight != nil {
+		cfg.PrimaryWeight = *opts.SemanticWeight
+	}
+	if opts.LexicalWeight != nil {
+		cfg.SecondaryWeight = *opts.LexicalWeight
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, nil, fmt.Errorf("%w: %w", api.ErrInvalidFusion, err)
+	}
+	return cfg, backendFilters, nil
+}
+
+// fusionOptions accepts api.FusionOptions or the equivalent decoded JSON object.
+func fusionOptions(raw any) (api.FusionOptions, error) {
+	switch v := raw.(type) {
+	case api.FusionOptions:
+		return v, nil
+	case *api.FusionOptions:
+		return *v, nil
+	}
+	var opts api.FusionOptions
+	b, err := json.Marshal(raw)
+	if err == nil {
+		err = json.Unmarshal(b, &opts)
+	}
+	if err != nil {
+		return opts, fmt.Errorf("%w: %w", api.ErrInvalidFusion, err)
+	}
+	return opts, nil
+}
+
+func (c FusionConfig) withDefaults() FusionConfig {
+	if c.Strategy == "" {
+		c.Strategy = FusionRRF
+	}
+	if c.RRFK <= 0 {
+		c.RRFK = DefaultRRFK
+	}
+	if c.PrimaryWeight == 0 && c.SecondaryWeight == 0 {
+		c.PrimaryWeight, c.SecondaryWeight = 1, 1
+	}
+	return c
+}
+
+// Validate reports unknown strategies and unusable weights.
+func (c FusionConfig) Validate() error {
+	if c.Strategy != FusionRRF && c.Strategy != FusionWeighted {
+		return fmt.Errorf("unknown fusion strategy %q", c.Strategy)
+	}
+	if c.PrimaryWeight < 0 || c.SecondaryWeight < 0 || c.PrimaryWeight+c.SecondaryWeight == 0 {
+		return fmt.Errorf("fusion weights must be non-negative and not both zero")
+	}
+	return nil
+}
+
+func copyMeta(m map[string]any) map[string]any {
+	out := make(map[string]any, len(m)+1)
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
 }
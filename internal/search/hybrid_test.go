@@ -2,8 +2,11 @@ package search_test
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"cgap/api"
 	"cgap/internal/search"
 	"cgap/internal/service"
 	"cgap/internal/testutil"
@@ -13,9 +16,22 @@ import (
 type MockSearch struct {
 	Results     []service.SearchResult
 	SearchError error
+	Filters     map[string]any
+	// Started, when set, is closed on entry and Wait is awaited before returning.
+	Started chan struct{}
+	Wait    chan struct{}
 }
 
 func (m *MockSearch) Search(ctx context.Context, index, query string, topK int, filters map[string]any) ([]service.SearchResult, error) {
+	m.Filters = filters
+	if m.Started != nil {
+		close(m.Started)
+		select {
+		case <-m.Wait:
+		case <-time.After(time.Second):
+			return nil, errors.New("backends were not queried in parallel")
+		}
+	}
 	if m.SearchError != nil {
 		return nil, m.SearchError
 	}
@@ -207,3 +223,112 @@ func TestNewHybrid(t *testing.T) {
 		t.Error("Expected non-nil hybrid search instance")
 	}
 }
+
+func TestHybrid_Search_RRFPromotesLexicalMatches(t *testing.T) {
+	primary := &MockSearch{
+		Results: []service.SearchResult{
+			{ID: "s1", Score: 0.62},
+			{ID: "s2", Score: 0.60},
+			{ID: "s3", Score: 0.58},
+		},
+	}
+	secondary := &MockSearch{
+		Results: []service.SearchResult{
+			{ID: "err-E1042", Score: 0.99},
+			{ID: "s1", Score: 0.40},
+		},
+	}
+
+	results, err := search.NewHybrid(primary, secondary).Search(context.Background(), "chunks", "E1042", 10, map[string]any{})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	got := ids(results)
+	want := []string{"s1", "err-E1042", "s2", "s3"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected %v, got %v", want, got)
+		}
+	}
+
+	if results[0].Score <= results[1].Score || results[0].Score > 1 {
+		t.Errorf("Expected normalized fused scores in descending order, got %v then %v", results[0].Score, results[1].Score)
+	}
+	if rel, _ := results[1].Metadata["relevance"].(float32); rel != 0.99 {
+		t.Errorf("Expected raw relevance 0.99 kept in metadata, got %v", results[1].Metadata["relevance"])
+	}
+}
+
+func TestHybrid_Search_WeightedPerRequest(t *testing.T) {
+	primary := &MockSearch{
+		Results: []service.SearchResult{
+			{ID: "a", Score: 0.9},
+			{ID: "b", Score: 0.5},
+		},
+	}
+	secondary := &MockSearch{
+		Results: []service.SearchResult{
+			{ID: "b", Score: 0.8},
+			{ID: "c", Score: 0.2},
+		},
+	}
+
+	filters := map[string]any{
+		"project_id": "p1",
+		"fusion":     map[string]any{"strategy": "weighted", "semantic_weight": 1.0, "lexical_weight": 3.0},
+	}
+	results, err := search.NewHybrid(primary, secondary).Search(context.Background(), "chunks", "q", 10, filters)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	// b: (1*0 + 3*1)/4 = 0.75, a: (1*1)/4 = 0.25, c: 0
+	if got := ids(results); got[0] != "b" || got[1] != "a" || got[2] != "c" {
+		t.Errorf("Expected lexical-weighted order [b a c], got %v", got)
+	}
+	if results[0].Score != 0.75 {
+		t.Errorf("Expected fused score 0.75, got %v", results[0].Score)
+	}
+
+	if _, ok := primary.Filters["fusion"]; ok {
+		t.Error("Expected fusion options to be stripped from backend filters")
+	}
+	if secondary.Filters["project_id"] != "p1" {
+		t.Errorf("Expected project filter forwarded, got %v", secondary.Filters)
+	}
+}
+
+func TestHybrid_Search_InvalidFusion(t *testing.T) {
+	hybrid := search.NewHybrid(&MockSearch{}, &MockSearch{})
+
+	_, err := hybrid.Search(context.Background(), "chunks", "q", 10, map[string]any{"fusion": map[string]any{"strategy": "magic"}})
+	if !errors.Is(err, api.ErrInvalidFusion) {
+		t.Errorf("Expected ErrInvalidFusion for unknown fusion strategy, got %v", err)
+	}
+}
+
+func TestHybrid_Search_QueriesBackendsInParallel(t *testing.T) {
+	primaryStarted, secondaryStarted := make(chan struct{}), make(chan struct{})
+	primary := &MockSearch{Results: []service.SearchResult{{ID: "1", Score: 0.9}}, Started: primaryStarted, Wait: secondaryStarted}
+	secondary := &MockSearch{Results: []service.SearchResult{{ID: "2", Score: 0.9}}, Started: secondaryStarted, Wait: primaryStarted}
+
+	results, err := search.NewHybrid(primary, secondary).Search(context.Background(), "chunks", "q", 10, map[string]any{})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected results from both backends, got %d", len(results))
+	}
+}
+
+func ids(results []service.SearchResult) []string {
+	out := make([]string, len(results))
+	for i, r := range results {
+		out[i] = r.ID
+	}
+	return out
+}
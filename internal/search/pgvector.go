@@ -74,6 +74,11 @@ func (p *PGVector) Search(ctx context.Context, index, query string, topK int, fi
 			c.id,
 			c.text,
 			d.id AS document_id,
+			COALESCE(d.title, ''),
+			COALESCE(d.uri, ''),
+			COALESCE(c.section_path, ''),
+			c.start_seconds,
+			c.end_seconds,
 			1.0 - (ce.embedding <=> $1) AS score
 		FROM chunk_embeddings ce
 		JOIN chunks c ON c.id = ce.chunk_id
@@ -95,18 +100,30 @@ func (p *PGVector) Search(ctx context.Context, index, query string, topK int, fi
 			chunkID    string
 			text       string
 			documentID string
+			title      string
+			uri        string
+			section    string
+			start, end *int
 			score      float32
 		)
-		if err := rows.Scan(&chunkID, &text, &documentID, &score); err != nil {
+		if err := rows.Scan(&chunkID, &text, &documentID, &title, &uri, &section, &start, &end, &score); err != nil {
 			return nil, fmt.Errorf("scan row failed: %w", err)
 		}
+		meta := map[string]any{
+			"document_id":  documentID,
+			"title":        title,
+			"document_uri": uri,
+			"section_path": section,
+		}
+		if start != nil && end != nil {
+			meta["start_seconds"] = *start
+			meta["end_seconds"] = *end
+		}
 		out = append(out, service.SearchResult{
-			ID:   chunkID,
-			Text: text,
-			Metadata: map[string]any{
-				"document_id": documentID,
-			},
-			Score: score,
+			ID:       chunkID,
+			Text:     text,
+			Metadata: meta,
+			Score:    score,
 		})
 	}
 	if err := rows.Err(); err != nil {
@@ -0,0 +1,286 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cgap/internal/llm"
+	"cgap/internal/service"
+)
+
+// Reranker reorders retrieved candidates by relevance to the query and keeps the top K.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, candidates []service.SearchResult, topK int) ([]service.SearchResult, error)
+}
+
+// DefaultRerankCandidates is how many candidates are fetched for reranking when unset.
+const DefaultRerankCandidates = 20
+
+// nearDuplicateJaccard is the word-set similarity above which a lower-ranked chunk is dropped.
+const nearDuplicateJaccard = 0.9
+
+// Reranked wraps a search backend: it over-fetches candidates, reranks them and
+// returns the top K, dropping near-duplicate chunks.
+type Reranked struct {
+	base       service.Search
+	reranker   Reranker
+	candidates int
+}
+
+// NewReranked creates a reranking search. candidates is the over-fetch size N.
+func NewReranked(base service.Search, reranker Reranker, candidates int) *Reranked {
+	if candidates <= 0 {
+		candidates = DefaultRerankCandidates
+	}
+	return &Reranked{base: base, reranker: reranker, candidates: candidates}
+}
+
+// Search fetches max(N, topK) candidates from the base backend and reranks them.
+// If reranking fails the base ordering is used so retrieval never hard-fails on it.
+func (r *Reranked) Search(ctx context.Context, index, query string, topK int, filters map[string]any) ([]service.SearchResult, error) {
+	if topK <= 0 {
+		topK = 10
+	}
+
+	candidates, err := r.base.Search(ctx, index, query, max(r.candidates, topK), filters)
+	if err != nil {
+		return nil, err
+	}
+	candidates = dropNearDuplicates(candidates)
+	if len(candidates) <= 1 {
+		return candidates, nil
+	}
+
+	ranked, err := r.reranker.Rerank(ctx, query, candidates, topK)
+	if err != nil {
+		slog.Warn("rerank failed, using retrieval order", "error", err)
+		ranked = candidates
+	}
+	if len(ranked) > topK {
+		ranked = ranked[:topK]
+	}
+	return ranked, nil
+}
+
+// dropNearDuplicates keeps the first of any chunks whose word sets are nearly identical.
+func dropNearDuplicates(results []service.SearchResult) []service.SearchResult {
+	out := make([]service.SearchResult, 0, len(results))
+	kept := make([]map[string]bool, 0, len(results))
+	for _, r := range results {
+		words := wordSet(r.Text)
+		dup := false
+		for _, k := range kept {
+			if jaccard(words, k) >= nearDuplicateJaccard {
+				dup = true
+				break
+			}
+		}
+		if dup {
+			continue
+		}
+		kept = append(kept, words)
+		out = append(out, r)
+	}
+	return out
+}
+
+func wordSet(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(strings.ToLower(text)) {
+		set[w] = true
+	}
+	return set
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0 // empty texts are not treated as duplicates
+	}
+	inter := 0
+	for w := range a {
+		if b[w] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
+
+// HTTPReranker calls a Cohere/Jina-compatible rerank endpoint:
+// POST {model, query, documents, top_n} -> {results: [{index, relevance_score}]}.
+type HTTPReranker struct {
+	url    string
+	apiKey string
+	model  string
+	client *http.Client
+}
+
+func NewHTTPReranker(url, apiKey, model string) *HTTPReranker {
+	return &HTTPReranker{
+		url:    url,
+		apiKey: apiKey,
+		model:  model,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+type httpRerankRequest struct {
+	Model           string   `json:"model,omitempty"`
+	Query           string   `json:"query"`
+	Documents       []string `json:"documents"`
+	TopN            int      `json:"top_n"`
+	ReturnDocuments bool     `json:"return_documents"`
+}
+
+type httpRerankResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float32 `json:"relevance_score"`
+	} `json:"results"`
+}
+
+// Rerank scores candidates with the remote model; the score is kept in Metadata["rerank_score"].
+func (h *HTTPReranker) Rerank(ctx context.Context, query string, candidates []service.SearchResult, topK int) ([]service.SearchResult, error) {
+	if h.url == "" {
+		return nil, fmt.Errorf("HTTPReranker: RERANK_URL not set")
+	}
+
+	docs := make([]string, len(candidates))
+	for i, c := range candidates {
+		docs[i] = c.Text
+	}
+	body, err := json.Marshal(httpRerankRequest{
+		Model:     h.model,
+		Query:     query,
+		Documents: docs,
+		TopN:      min(topK, len(candidates)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal rerank request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rerank request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.apiKey)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("rerank request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("rerank error: status %d, body: %s", resp.StatusCode, string(b))
+	}
+
+	var out httpRerankResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode rerank response: %w", err)
+	}
+
+	// Endpoints normally sort by score already; sort anyway so ordering is guaranteed.
+	sort.SliceStable(out.Results, func(i, j int) bool {
+		return out.Results[i].RelevanceScore > out.Results[j].RelevanceScore
+	})
+
+	ranked := make([]service.SearchResult, 0, len(out.Results))
+	seen := make(map[int]bool, len(out.Results))
+	for _, res := range out.Results {
+		if res.Index < 0 || res.Index >= len(candidates) || seen[res.Index] {
+			continue
+		}
+		seen[res.Index] = true
+		c := candidates[res.Index]
+		c.Metadata = copyMeta(c.Metadata)
+		c.Metadata["rerank_score"] = res.RelevanceScore
+		ranked = append(ranked, c)
+	}
+	if len(ranked) == 0 {
+		return nil, fmt.Errorf("rerank response contained no valid results")
+	}
+	return ranked, nil
+}
+
+// llmRerankPassageChars bounds each passage shown to the LLM reranker.
+const llmRerankPassageChars = 600
+
+const llmRerankPrompt = "You rank documentation passages by how well they answer a search query. " +
+	"Reply with only a JSON array of passage numbers, most relevant first, e.g. [3, 1, 2]. " +
+	"Leave out passages that are irrelevant or repeat an earlier passage."
+
+var passageNumberRe = regexp.MustCompile(`\d+`)
+
+// LLMReranker asks an LLM to order the candidates listwise.
+type LLMReranker struct {
+	llm llm.Provider
+}
+
+func NewLLMReranker(provider llm.Provider) *LLMReranker {
+	return &LLMReranker{llm: provider}
+}
+
+// Rerank sends all candidates in one prompt and reads back the ranked passage numbers.
+// Candidates the model leaves out are dropped.
+func (l *LLMReranker) Rerank(ctx context.Context, query string, candidates []service.SearchResult, topK int) ([]service.SearchResult, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Query: %s\n\nPassages:\n", query)
+	for i, c := range candidates {
+		text := c.Text
+		if r := []rune(text); len(r) > llmRerankPassageChars {
+			text = string(r[:llmRerankPassageChars]) + "…"
+		}
+		fmt.Fprintf(&b, "[%d] %s\n\n", i+1, strings.TrimSpace(text))
+	}
+
+	reply, err := l.llm.Chat(ctx, []service.Message{
+		{Role: "system", Content: llmRerankPrompt},
+		{Role: "user", Content: b.String()},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("llm rerank failed: %w", err)
+	}
+
+	order := parsePassageOrder(reply, len(candidates))
+	if len(order) == 0 {
+		return nil, fmt.Errorf("llm rerank returned no passage numbers")
+	}
+
+	ranked := make([]service.SearchResult, 0, min(len(order), topK))
+	for _, i := range order {
+		if len(ranked) >= topK {
+			break
+		}
+		ranked = append(ranked, candidates[i])
+	}
+	return ranked, nil
+}
+
+// parsePassageOrder extracts unique, in-range 1-based passage numbers as 0-based indexes.
+func parsePassageOrder(reply string, n int) []int {
+	var order []int
+	seen := make(map[int]bool)
+	for _, m := range passageNumberRe.FindAllString(reply, -1) {
+		i, err := strconv.Atoi(m)
+		if err != nil || i < 1 || i > n || seen[i-1] {
+			continue
+		}
+		seen[i-1] = true
+		order = append(order, i-1)
+	}
+	return order
+}
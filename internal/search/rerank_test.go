@@ -0,0 +1,174 @@
+package search_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cgap/internal/search"
+	"cgap/internal/service"
+)
+
+// stubReranker reverses candidates, or fails when Err is set.
+type stubReranker struct {
+	Err error
+	Got []service.SearchResult
+}
+
+func (s *stubReranker) Rerank(ctx context.Context, query string, candidates []service.SearchResult, topK int) ([]service.SearchResult, error) {
+	s.Got = candidates
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	out := make([]service.SearchResult, 0, len(candidates))
+	for i := len(candidates) - 1; i >= 0; i-- {
+		out = append(out, candidates[i])
+	}
+	return out, nil
+}
+
+// stubProvider implements llm.Provider with a canned reply.
+type stubProvider struct {
+	Reply    string
+	Messages []service.Message
+}
+
+func (p *stubProvider) Chat(ctx context.Context, messages []service.Message) (string, error) {
+	p.Messages = messages
+	return p.Reply, nil
+}
+
+func (p *stubProvider) Stream(ctx context.Context, messages []service.Message) (<-chan string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *stubProvider) Name() string { return "stub" }
+
+// topKSearch records the topK it was asked for.
+type topKSearch struct {
+	MockSearch
+	TopK int
+}
+
+func (m *topKSearch) Search(ctx context.Context, index, query string, topK int, filters map[string]any) ([]service.SearchResult, error) {
+	m.TopK = topK
+	return m.MockSearch.Search(ctx, index, query, topK, filters)
+}
+
+func TestReranked_OverFetchesAndKeepsTopK(t *testing.T) {
+	base := &topKSearch{MockSearch: MockSearch{Results: []service.SearchResult{
+		{ID: "1", Text: "alpha"}, {ID: "2", Text: "beta"}, {ID: "3", Text: "gamma"}, {ID: "4", Text: "delta"},
+	}}}
+	reranker := &stubReranker{}
+
+	results, err := search.NewReranked(base, reranker, 20).Search(context.Background(), "chunks", "q", 2, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if base.TopK != 20 {
+		t.Errorf("Expected over-fetch of 20 candidates, got %d", base.TopK)
+	}
+	if len(reranker.Got) != 4 {
+		t.Errorf("Expected all 4 candidates reranked, got %d", len(reranker.Got))
+	}
+	if len(results) != 2 || results[0].ID != "4" || results[1].ID != "3" {
+		t.Errorf("Expected reranked top 2 [4 3], got %v", ids(results))
+	}
+}
+
+func TestReranked_DropsNearDuplicates(t *testing.T) {
+	base := &MockSearch{Results: []service.SearchResult{
+		{ID: "1", Text: "Click Settings then API keys to rotate a key"},
+		{ID: "2", Text: "click settings then api keys to rotate a key"},
+		{ID: "3", Text: "Billing is monthly"},
+	}}
+	reranker := &stubReranker{}
+
+	if _, err := search.NewReranked(base, reranker, 10).Search(context.Background(), "chunks", "q", 5, nil); err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if got := ids(reranker.Got); len(got) != 2 || got[0] != "1" || got[1] != "3" {
+		t.Errorf("Expected near-duplicate chunk dropped, got %v", got)
+	}
+}
+
+func TestReranked_FallsBackOnError(t *testing.T) {
+	base := &MockSearch{Results: []service.SearchResult{{ID: "1", Text: "one"}, {ID: "2", Text: "two"}, {ID: "3", Text: "three"}}}
+
+	results, err := search.NewReranked(base, &stubReranker{Err: errors.New("boom")}, 10).Search(context.Background(), "chunks", "q", 2, nil)
+	if err != nil {
+		t.Fatalf("Search should not fail when reranking fails: %v", err)
+	}
+	if got := ids(results); len(got) != 2 || got[0] != "1" || got[1] != "2" {
+		t.Errorf("Expected retrieval order, got %v", got)
+	}
+}
+
+func TestHTTPReranker_Rerank(t *testing.T) {
+	var got struct {
+		Model     string   `json:"model"`
+		Query     string   `json:"query"`
+		Documents []string `json:"documents"`
+		TopN      int      `json:"top_n"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"results":[{"index":2,"relevance_score":0.93},{"index":0,"relevance_score":0.41}]}`))
+	}))
+	defer srv.Close()
+
+	candidates := []service.SearchResult{
+		{ID: "a", Text: "first"}, {ID: "b", Text: "second"}, {ID: "c", Text: "third"},
+	}
+	results, err := search.NewHTTPReranker(srv.URL, "secret", "rerank-v3").Rerank(context.Background(), "which?", candidates, 2)
+	if err != nil {
+		t.Fatalf("Rerank failed: %v", err)
+	}
+
+	if got.Model != "rerank-v3" || got.Query != "which?" || got.TopN != 2 || len(got.Documents) != 3 || got.Documents[2] != "third" {
+		t.Errorf("Unexpected rerank request: %+v", got)
+	}
+	if len(results) != 2 || results[0].ID != "c" || results[1].ID != "a" {
+		t.Fatalf("Expected [c a], got %v", ids(results))
+	}
+	if score, _ := results[0].Metadata["rerank_score"].(float32); score != 0.93 {
+		t.Errorf("Expected rerank score in metadata, got %v", results[0].Metadata["rerank_score"])
+	}
+}
+
+func TestHTTPReranker_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	_, err := search.NewHTTPReranker(srv.URL, "", "").Rerank(context.Background(), "q", []service.SearchResult{{ID: "a"}, {ID: "b"}}, 2)
+	if err == nil {
+		t.Error("Expected error for non-200 rerank response")
+	}
+}
+
+func TestLLMReranker_Rerank(t *testing.T) {
+	provider := &stubProvider{Reply: "[3, 1, 9, 3]"}
+	candidates := []service.SearchResult{
+		{ID: "a", Text: "first"}, {ID: "b", Text: "second"}, {ID: "c", Text: "third"},
+	}
+
+	results, err := search.NewLLMReranker(provider).Rerank(context.Background(), "which?", candidates, 5)
+	if err != nil {
+		t.Fatalf("Rerank failed: %v", err)
+	}
+	if got := ids(results); len(got) != 2 || got[0] != "c" || got[1] != "a" {
+		t.Errorf("Expected [c a] ignoring out-of-range and repeated numbers, got %v", got)
+	}
+	if len(provider.Messages) != 2 || provider.Messages[1].Content == "" {
+		t.Errorf("Expected system and user prompt, got %+v", provider.Messages)
+	}
+}
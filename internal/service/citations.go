@@ -0,0 +1,226 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"cgap/api"
+)
+
+// maxQuoteChars bounds the quoted span returned for a citation.
+const maxQuoteChars = 300
+
+// citationMarkerRe matches [1], [2, 3] and [1][2] style source references.
+var citationMarkerRe = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// numberSources drops duplicate chunks while keeping retrieval order; the
+// position in the returned slice (1-based) is the source number shown to the LLM.
+func numberSources(results []SearchResult) []SearchResult {
+	seen := make(map[string]bool, len(results))
+	out := make([]SearchResult, 0, len(results))
+	for _, r := range results {
+		if r.ID != "" {
+			if seen[r.ID] {
+				continue
+			}
+			seen[r.ID] = true
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// buildContext renders numbered sources for the prompt, e.g. "[1] Title › Section".
+func buildContext(sources []SearchResult) string {
+	var b strings.Builder
+	for i, src := range sources {
+		header := metaString(src.Metadata, "title")
+		if section := metaString(src.Metadata, "section_path"); section != "" {
+			if header != "" {
+				header += " › "
+			}
+			header += section
+		}
+		fmt.Fprintf(&b, "[%d] %s\n%s\n\n", i+1, header, src.Text)
+	}
+	return b.String()
+}
+
+// buildCitations resolves the answer's [n] markers against the numbered sources.
+// Sources are returned in order of first reference; when the model cited nothing
+// every source is returned so the answer is still traceable.
+func buildCitations(answer string, sources []SearchResult) []api.AnswerCitation {
+	claims := make(map[int][]string)
+	var order []int
+	for _, loc := range citationMarkerRe.FindAllStringSubmatchIndex(answer, -1) {
+		claim := enclosingSentence(answer, loc[0], loc[1])
+		for _, part := range strings.Split(answer[loc[2]:loc[3]], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || n < 1 || n > len(sources) {
+				continue
+			}
+			if _, ok := claims[n]; !ok {
+				order = append(order, n)
+			}
+			claims[n] = append(claims[n], claim)
+		}
+	}
+
+	if len(order) == 0 {
+		for i := range sources {
+			order = append(order, i+1)
+			claims[i+1] = []string{answer}
+		}
+	}
+
+	citations := make([]api.AnswerCitation, 0, len(order))
+	for _, n := range order {
+		src := sources[n-1]
+		quote, start, end := quoteSpan(src.Text, strings.Join(claims[n], " "))
+		uri, startSec, endSec := sourceLink(src.Metadata)
+		citations = append(citations, api.AnswerCitation{
+			Index:        n,
+			ChunkID:      src.ID,
+			DocumentID:   metaString(src.Metadata, "document_id"),
+			Title:        metaString(src.Metadata, "title"),
+			URI:          uri,
+			SectionPath:  metaString(src.Metadata, "section_path"),
+			Quote:        quote,
+			StartChar:    start,
+			EndChar:      end,
+			Score:        src.Score,
+			StartSeconds: startSec,
+			EndSeconds:   endSec,
+		})
+	}
+	return citations
+}
+
+// enclosingSentence returns the answer sentence containing the marker at [start, end),
+// with citation markers removed.
+func enclosingSentence(text string, start, end int) string {
+	from := strings.LastIndexAny(text[:start], ".!?\n") + 1
+	to := len(text)
+	if i := strings.IndexAny(text[end:], ".!?\n"); i >= 0 {
+		to = end + i
+	}
+	return citationMarkerRe.ReplaceAllString(text[from:to], "")
+}
+
+// quoteSpan picks the sentence of text that best supports claim, by word overlap.
+// Offsets are character (rune) offsets into text.
+func quoteSpan(text, claim string) (string, int, int) {
+	if text == "" {
+		return "", 0, 0
+	}
+
+	claimWords := make(map[string]bool)
+	for _, w := range significantWords(claim) {
+		claimWords[w] = true
+	}
+
+	bestStart, bestEnd, bestScore := 0, len(text), -1
+	for _, span := range sentenceSpans(text) {
+		score := 0
+		for _, w := range significantWords(text[span[0]:span[1]]) {
+			if claimWords[w] {
+				score++
+			}
+		}
+		if score > bestScore {
+			bestStart, bestEnd, bestScore = span[0], span[1], score
+		}
+	}
+
+	quote := text[bestStart:bestEnd]
+	if utf8.RuneCountInString(quote) > maxQuoteChars {
+		quote = string([]rune(quote)[:maxQuoteChars])
+	}
+
+	start := utf8.RuneCountInString(text[:bestStart])
+	return quote, start, start + utf8.RuneCountInString(quote)
+}
+
+// sentenceSpans returns byte ranges of the trimmed sentences in text.
+func sentenceSpans(text string) [][2]int {
+	var spans [][2]int
+	begin := 0
+	add := func(end int) {
+		s, e := begin, end
+		for s < e && unicode.IsSpace(rune(text[s])) {
+			s++
+		}
+		for e > s && unicode.IsSpace(rune(text[e-1])) {
+			e--
+		}
+		if e > s {
+			spans = append(spans, [2]int{s, e})
+		}
+	}
+	for i, r := range text {
+		switch r {
+		case '.', '!', '?', '\n':
+			add(i + 1)
+			begin = i + 1
+		}
+	}
+	add(len(text))
+	return spans
+}
+
+// significantWords lowercases text and keeps words long enough to carry meaning.
+func significantWords(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := words[:0]
+	for _, w := range words {
+		if len(w) > 3 {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// metaString reads a string metadata value, returning "" when absent.
+func metaString(meta map[string]any, key string) string {
+	v, _ := meta[key].(string)
+	return v
+}
+
+// sourceLink returns the document URI of a search result. Transcript chunks
+// carry their position in the video, and their URI links to its start.
+func sourceLink(meta map[string]any) (uri string, start, end *int) {
+	uri = metaString(meta, "document_uri")
+	s, ok1 := meta["start_seconds"].(int)
+	e, ok2 := meta["end_seconds"].(int)
+	if !ok1 || !ok2 {
+		return uri, nil, nil
+	}
+	return timestampLink(uri, s), &s, &e
+}
+
+// timestampLink points a video URI at the given second: YouTube links get a
+// t query parameter ("watch?v=ID&t=123"), other videos a media fragment
+// ("talk.mp4#t=123") as understood by browsers.
+func timestampLink(uri string, seconds int) string {
+	u, err := url.Parse(uri)
+	if err != nil || uri == "" {
+		return uri
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "m.")
+	if host == "youtube.com" || host == "youtu.be" {
+		q := u.Query()
+		q.Set("t", strconv.Itoa(seconds))
+		u.RawQuery = q.Encode()
+	} else {
+		u.Fragment = "t=" + strconv.Itoa(seconds)
+	}
+	return u.String()
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"cgap/internal/model"
+)
+
+const (
+	// historyTokenBudget caps how many (estimated) tokens of prior turns are sent to the LLM.
+	historyTokenBudget = 2000
+	// maxHistoryMessages bounds how many of a thread's latest messages are considered.
+	maxHistoryMessages = 500
+)
+
+const rewritePrompt = "Rewrite the user's latest question as a standalone search query, " +
+	"resolving pronouns and references using the conversation. " +
+	"Reply with the query only, no explanation or quotes."
+
+// loadHistory returns the latest user/assistant turns stored on a thread in
+// chronological order.
+func (s *ChatServiceImpl) loadHistory(ctx context.Context, threadID string) ([]Message, error) {
+	stored, err := s.store.Messages().ListRecentByThread(ctx, threadID, maxHistoryMessages)
+	if err != nil {
+		return nil, fmt.Errorf("load thread history: %w", err)
+	}
+	return historyMessages(stored), nil
+}
+
+// fitHistory keeps the most recent turns whose estimated size fits within budget tokens.
+func fitHistory(history []Message, budget int) []Message {
+	used := 0
+	start := len(history)
+	for i := len(history) - 1; i >= 0; i-- {
+		cost := estimateTokens(history[i].Content)
+		if used+cost > budget {
+			break
+		}
+		used += cost
+		start = i
+	}
+	// Never open the window on a dangling assistant reply.
+	for start < len(history) && history[start].Role != "user" {
+		start++
+	}
+	return history[start:]
+}
+
+// estimateTokens approximates token count using the ~4 characters per token heuristic.
+func estimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// standaloneQuery rewrites a follow-up question into a self-contained search query,
+// given the turns of history that fit the history token budget. It falls back to the original question when there is no history or the rewrite fails.
+func (s *ChatServiceImpl) standaloneQuery(ctx context.Context, history []Message, query string) string {
+	if len(history) == 0 {
+		return query
+	}
+
+	var convo strings.Builder
+	for _, m := range fitHistory(history, historyTokenBudget) {
+		convo.WriteString(m.Role + ": " + m.Content + "\n")
+	}
+
+	rewritten, err := s.llm.Chat(ctx, []Message{
+		{Role: "system", Content: rewritePrompt},
+		{Role: "user", Content: "Conversation:\n" + convo.String() + "\nLatest question: " + query},
+	})
+	if err != nil {
+		slog.Warn("query rewrite failed, using original question", "error", err)
+		return query
+	}
+
+	rewritten = strings.Trim(strings.TrimSpace(rewritten), "\"'")
+	if rewritten == "" {
+		return query
+	}
+	return rewritten
+}
+
+// historyMessages converts stored messages to LLM messages, dropping non-chat roles.
+func historyMessages(msgs []*model.Message) []Message {
+	out := make([]Message, 0, len(msgs))
+	for _, m := range msgs {
+		if m.Role != "user" && m.Role != "assistant" {
+			continue
+		}
+		out = append(out, Message{Role: m.Role, Content: m.Content})
+	}
+	return out
+}
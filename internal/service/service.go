@@ -2,17 +2,32 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+
 	"cgap/api"
+	"cgap/internal/model"
 	"cgap/internal/storage"
 )
 
+// chatPromptVersion identifies the prompt template recorded on each answer.
+const chatPromptVersion = "chat-v4"
+
+const chatSystemPrompt = "You are a helpful assistant. Use the provided context and the conversation so far to answer the question. " +
+	"The context is a list of numbered sources; cite the sources supporting each statement inline with their numbers, e.g. [1] or [1, 3]. " +
+	"If the sources do not contain the answer, say \"I don't know\" rather than guessing."
+
 // ChatService implementation.
 type ChatServiceImpl struct {
-	store  storage.Store
-	llm    LLM
-	search Search
+	store    storage.Store
+	llm      LLM
+	search   Search
+	embedder Embedder
 }
 
 func NewChatService(store storage.Store, llm LLM, search Search) *ChatServiceImpl {
@@ -23,97 +38,344 @@ func NewChatService(store storage.Store, llm LLM, search Search) *ChatServiceImp
 	}
 }
 
+// WithEmbedder sets the embedder used to store question embeddings on gap candidates.
+func (s *ChatServiceImpl) WithEmbedder(e Embedder) *ChatServiceImpl {
+	s.embedder = e
+	return s
+}
+
 func (s *ChatServiceImpl) Chat(ctx context.Context, req api.ChatRequest) (api.ChatResponse, error) {
-	// 1. Search hybrid (meili + pgvector)
-	searchResults, err := s.search.Search(ctx, "chunks", req.Query, 5, map[string]any{
-		"project_id": req.ProjectID,
-	})
+	start := time.Now()
+
+	// 1. Resolve thread, load prior turns and record the user turn
+	thread, history, err := s.startTurn(ctx, req)
 	if err != nil {
 		return api.ChatResponse{}, err
 	}
 
-	// 2. Build context from search results
-	var context string
-	var citations []string
-	for _, result := range searchResults {
-		context += result.Text + "\n"
-		if docID, ok := result.Metadata["document_id"].(string); ok {
-			citations = append(citations, docID)
-		}
+	// 2. Rewrite follow-ups into a standalone query, then search hybrid (meili + pgvector)
+	searchQuery := s.standaloneQuery(ctx, history, req.Query)
+	searchResults, err := s.search.Search(ctx, "chunks", searchQuery, 5, map[string]any{
+		"project_id": thread.ProjectID,
+	})
+	if err != nil {
+		return api.ChatResponse{}, err
 	}
 
-	// 3. Call LLM with context
-	messages := []Message{
-		{Role: "system", Content: "You are a helpful assistant. Use the provided context to answer the question."},
-		{Role: "user", Content: "Context:\n" + context + "\n\nQuestion: " + req.Query},
+	// 3. Call LLM with numbered sources and conversation history
+	sources := numberSources(searchResults)
+	llmResponse, err := s.llm.Chat(ctx, buildChatMessages(history, buildContext(sources), req.Query, req.Instructions))
+	if err != nil {
+		return api.ChatResponse{}, err
 	}
 
-	llmResponse, err := s.llm.Chat(ctx, messages)
-	if err != nil {
+	// 4. Resolve [n] markers and assess grounding, then store assistant message + answer + citations
+	citations := buildCitations(llmResponse, sources)
+	unc := assessUncertainty(llmResponse, sources)
+	if err := s.recordAnswer(ctx, thread, turn{
+		Question:    req.Query,
+		Answer:      llmResponse,
+		SearchQuery: searchQuery,
+		Retrieved:   len(searchResults),
+		Citations:   citations,
+		Uncertainty: unc,
+		Latency:     time.Since(start),
+	}); err != nil {
 		return api.ChatResponse{}, err
 	}
 
-	// 4. Return response (TODO: Store thread + message + answer)
 	return api.ChatResponse{
-		Answer:     llmResponse,
-		Citations:  citations,
-		Confidence: 0.8,
+		ThreadID:    thread.ID,
+		Answer:      llmResponse,
+		IsUncertain: unc.Uncertain,
+		Citations:   citations,
+		Confidence:  unc.Confidence,
 	}, nil
 }
 
 func (s *ChatServiceImpl) ChatStream(ctx context.Context, req api.ChatRequest) (<-chan api.StreamFrame, error) {
+	start := time.Now()
+
+	// Resolve the thread up front so callers can reject unknown threads before streaming.
+	thread, history, err := s.startTurn(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
 	ch := make(chan api.StreamFrame)
 
+	// send delivers a frame unless the caller has gone away.
+	send := func(frame api.StreamFrame) bool {
+		select {
+		case ch <- frame:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
 	go func() {
 		defer close(ch)
 
 		// Search for context
-		searchResults, err := s.search.Search(ctx, "chunks", req.Query, 5, map[string]any{
-			"project_id": req.ProjectID,
+		searchQuery := s.standaloneQuery(ctx, history, req.Query)
+		searchResults, err := s.search.Search(ctx, "chunks", searchQuery, 5, map[string]any{
+			"project_id": thread.ProjectID,
 		})
 		if err != nil {
-			ch <- api.StreamFrame{Type: "error", Data: map[string]any{"error": err.Error()}}
+			send(api.StreamFrame{Type: "error", Data: map[string]any{"error": err.Error()}})
 			return
 		}
 
-		// Build context
-		var context string
-		var citations []string
-		for _, result := range searchResults {
-			context += result.Text + "\n"
-			if docID, ok := result.Metadata["document_id"].(string); ok {
-				citations = append(citations, docID)
-			}
-		}
-
 		// Stream from LLM
-		messages := []Message{
-			{Role: "system", Content: "You are a helpful assistant. Use the provided context to answer the question."},
-			{Role: "user", Content: "Context:\n" + context + "\n\nQuestion: " + req.Query},
-		}
-
-		tokenChan, err := s.llm.Stream(ctx, messages)
+		sources := numberSources(searchResults)
+		tokenChan, err := s.llm.Stream(ctx, buildChatMessages(history, buildContext(sources), req.Query, req.Instructions))
 		if err != nil {
-			ch <- api.StreamFrame{Type: "error", Data: map[string]any{"error": err.Error()}}
+			send(api.StreamFrame{Type: "error", Data: map[string]any{"error": err.Error()}})
 			return
 		}
 
+		var answer strings.Builder
 		for token := range tokenChan {
-			ch <- api.StreamFrame{
+			answer.WriteString(token)
+			// Keep draining after a disconnect so the provider goroutine can exit.
+			send(api.StreamFrame{
 				Type: "token",
 				Data: map[string]any{"token": token},
-			}
+			})
 		}
 
-		ch <- api.StreamFrame{
-			Type: "done",
-			Data: map[string]any{"citations": citations},
+		if ctx.Err() != nil {
+			slog.Info("chat stream cancelled by client", "thread_id", thread.ID)
+			return
 		}
+
+		citations := buildCitations(answer.String(), sources)
+		unc := assessUncertainty(answer.String(), sources)
+		if err := s.recordAnswer(ctx, thread, turn{
+			Question:    req.Query,
+			Answer:      answer.String(),
+			SearchQuery: searchQuery,
+			Retrieved:   len(searchResults),
+			Citations:   citations,
+			Uncertainty: unc,
+			Latency:     time.Since(start),
+		}); err != nil {
+			send(api.StreamFrame{Type: "error", Data: map[string]any{"error": err.Error()}})
+			return
+		}
+
+		send(api.StreamFrame{
+			Type: "done",
+			Data: map[string]any{
+				"citations":    citations,
+				"thread_id":    thread.ID,
+				"is_uncertain": unc.Uncertain,
+				"confidence":   unc.Confidence,
+			},
+		})
 	}()
 
 	return ch, nil
 }
 
+// CreateThread opens an empty conversation thread for a project.
+func (s *ChatServiceImpl) CreateThread(ctx context.Context, req api.ThreadCreateRequest) (api.Thread, error) {
+	projectID, err := resolveProjectID(ctx, s.store, req.ProjectID)
+	if err != nil {
+		return api.Thread{}, err
+	}
+
+	integration := req.Integration
+	if integration == "" {
+		integration = "api"
+	}
+
+	now := time.Now().UTC()
+	thread := model.Thread{
+		ID:          uuid.New().String(),
+		ProjectID:   projectID,
+		Integration: integration,
+		ExternalRef: req.ExternalRef,
+		Status:      "open",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.store.Threads().Create(ctx, &thread); err != nil {
+		return api.Thread{}, fmt.Errorf("create thread: %w", err)
+	}
+	return thread, nil
+}
+
+// buildChatMessages assembles the system prompt, caller instructions, budgeted
+// history and the grounded question.
+func buildChatMessages(history []Message, context, query, instructions string) []Message {
+	messages := []Message{{Role: "system", Content: chatSystemPrompt}}
+	if instructions != "" {
+		messages = append(messages, Message{Role: "system", Content: instructions})
+	}
+	messages = append(messages, fitHistory(history, historyTokenBudget)...)
+	return append(messages, Message{Role: "user", Content: "Context:\n" + context + "\n\nQuestion: " + query})
+}
+
+// startTurn resolves (or creates) the thread for req, loads its prior turns and
+// stores the new user message.
+func (s *ChatServiceImpl) startTurn(ctx context.Context, req api.ChatRequest) (*model.Thread, []Message, error) {
+	projectID, err := resolveProjectID(ctx, s.store, req.ProjectID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().UTC()
+	var thread *model.Thread
+	var history []Message
+	if req.ThreadID != "" {
+		if _, perr := uuid.Parse(req.ThreadID); perr != nil {
+			return nil, nil, fmt.Errorf("%w: %s", api.ErrThreadNotFound, req.ThreadID)
+		}
+		thread, err = s.store.Threads().GetByID(ctx, req.ThreadID)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, fmt.Errorf("load thread: %w", err)
+		}
+		if thread == nil || thread.ProjectID != projectID {
+			return nil, nil, fmt.Errorf("%w: %s", api.ErrThreadNotFound, req.ThreadID)
+		}
+		if history, err = s.loadHistory(ctx, thread.ID); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		thread = &model.Thread{
+			ID:          uuid.New().String(),
+			ProjectID:   projectID,
+			Integration: "api",
+			Status:      "open",
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if err := s.store.Threads().Create(ctx, thread); err != nil {
+			return nil, nil, fmt.Errorf("create thread: %w", err)
+		}
+	}
+
+	userMsg := &model.Message{
+		ID:        uuid.New().String(),
+		ThreadID:  thread.ID,
+		Role:      "user",
+		Content:   req.Query,
+		Meta:      map[string]any{},
+		CreatedAt: now,
+	}
+	if req.UserID != "" {
+		userMsg.Meta["user_id"] = req.UserID
+	}
+	if err := s.store.Messages().Create(ctx, userMsg); err != nil {
+		return nil, nil, fmt.Errorf("create user message: %w", err)
+	}
+
+	return thread, history, nil
+}
+
+// turn is a completed question/answer exchange ready to be persisted.
+type turn struct {
+	Question    string
+	Answer      string
+	SearchQuery string
+	Retrieved   int
+	Citations   []api.AnswerCitation
+	Uncertainty uncertainty
+	Latency     time.Duration
+}
+
+// recordAnswer stores the assistant message, its answer row, the citations it
+// references and, for uncertain answers, a gap candidate.
+func (s *ChatServiceImpl) recordAnswer(ctx context.Context, thread *model.Thread, t turn) error {
+	now := time.Now().UTC()
+	assistantMsg := &model.Message{
+		ID:        uuid.New().String(),
+		ThreadID:  thread.ID,
+		Role:      "assistant",
+		Content:   t.Answer,
+		Meta:      map[string]any{"retrieved": t.Retrieved, "search_query": t.SearchQuery},
+		LatencyMS: int(t.Latency.Milliseconds()),
+		CreatedAt: now,
+	}
+	if err := s.store.Messages().Create(ctx, assistantMsg); err != nil {
+		return fmt.Errorf("create assistant message: %w", err)
+	}
+
+	trace := map[string]any{
+		"retrieved":  t.Retrieved,
+		"cited":      len(t.Citations),
+		"confidence": t.Uncertainty.Confidence,
+	}
+	if t.Uncertainty.Uncertain {
+		trace["uncertainty_reason"] = t.Uncertainty.Reason()
+	}
+	if err := s.store.Answers().Create(ctx, &model.Answer{
+		MessageID:      assistantMsg.ID,
+		Model:          s.modelName(),
+		IsUncertain:    t.Uncertainty.Uncertain,
+		ReasoningTrace: trace,
+		PromptVersion:  chatPromptVersion,
+	}); err != nil {
+		return fmt.Errorf("create answer: %w", err)
+	}
+
+	citations := make([]*model.Citation, 0, len(t.Citations))
+	for _, c := range t.Citations {
+		if c.ChunkID == "" {
+			continue
+		}
+		citations = append(citations, &model.Citation{
+			ID:        uuid.New().String(),
+			AnswerID:  assistantMsg.ID,
+			ChunkID:   c.ChunkID,
+			Score:     c.Score,
+			Quote:     c.Quote,
+			StartChar: c.StartChar,
+			EndChar:   c.EndChar,
+		})
+	}
+	if err := s.store.Citations().CreateBatch(ctx, citations); err != nil {
+		return fmt.Errorf("create citations: %w", err)
+	}
+
+	if t.Uncertainty.Uncertain {
+		if err := s.recordGapCandidate(ctx, assistantMsg.ID, t.Question, t.Uncertainty.Reason()); err != nil {
+			return err
+		}
+	}
+
+	thread.UpdatedAt = now
+	if err := s.store.Threads().Update(ctx, thread); err != nil {
+		return fmt.Errorf("update thread: %w", err)
+	}
+	return nil
+}
+
+// modelName reports the LLM identifier when the client exposes one.
+func (s *ChatServiceImpl) modelName() string {
+	if n, ok := s.llm.(interface{ Name() string }); ok {
+		return n.Name()
+	}
+	return "unknown"
+}
+
+// resolveProjectID maps a project slug to its UUID; UUIDs are returned unchanged.
+func resolveProjectID(ctx context.Context, store storage.Store, projectID string) (string, error) {
+	if _, err := uuid.Parse(projectID); err == nil {
+		return projectID, nil
+	}
+	p, err := store.Projects().GetBySlug(ctx, projectID)
+	if err != nil {
+		return "", fmt.Errorf("resolve project %q: %w", projectID, err)
+	}
+	if p == nil {
+		return "", fmt.Errorf("resolve project %q: %w", projectID, storage.ErrNotFound)
+	}
+	return p.ID, nil
+}
+
 // SearchService implementation.
 type SearchServiceImpl struct {
 	store  storage.Store
@@ -128,10 +390,12 @@ func NewSearchService(store storage.Store, search Search) *SearchServiceImpl {
 }
 
 func (s *SearchServiceImpl) Search(ctx context.Context, projectID, query string, topK int, filters map[string]any) ([]api.SearchHit, error) {
-	// 1. Query Meilisearch
-	results, err := s.search.Search(ctx, "chunks", query, topK, map[string]any{
-		"project_id": projectID,
-	})
+	// 1. Query the configured backend (hybrid fuses pgvector + Meilisearch)
+	searchFilters := map[string]any{"project_id": projectID}
+	if fusion, ok := filters[api.FusionFilterKey]; ok {
+		searchFilters[api.FusionFilterKey] = fusion
+	}
+	results, err := s.search.Search(ctx, "chunks", query, topK, searchFilters)
 	if err != nil {
 		return nil, err
 	}
@@ -140,11 +404,15 @@ func (s *SearchServiceImpl) Search(ctx context.Context, projectID, query string,
 	var hits []api.SearchHit
 	for _, result := range results {
 		docID, _ := result.Metadata["document_id"].(string)
+		uri, start, end := sourceLink(result.Metadata)
 		hits = append(hits, api.SearchHit{
-			ChunkID:    result.ID,
-			Text:       result.Text,
-			DocumentID: docID,
-			Confidence: result.Score,
+			ChunkID:      result.ID,
+			Text:         result.Text,
+			DocumentID:   docID,
+			Confidence:   result.Score,
+			DocumentURI:  uri,
+			StartSeconds: start,
+			EndSeconds:   end,
 		})
 	}
 
@@ -267,6 +535,11 @@ type Search interface {
 	Search(ctx context.Context, index, query string, topK int, filters map[string]any) ([]SearchResult, error)
 }
 
+// Embedder generates an embedding vector for input text.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
 // SearchResult represents a search hit.
 type SearchResult struct {
 	ID       string
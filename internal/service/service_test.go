@@ -2,7 +2,11 @@ package service_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"cgap/api"
 	"cgap/internal/model"
@@ -13,6 +17,9 @@ import (
 	"github.com/google/uuid"
 )
 
+// testProjectUUID is the ID every project slug resolves to in these tests.
+const testProjectUUID = "00000000-0000-4000-8000-000000000001"
+
 // MockProjectRepo implements storage.ProjectRepo for testing
 type MockProjectRepo struct{}
 
@@ -20,7 +27,7 @@ func (m *MockProjectRepo) GetByID(ctx context.Context, id string) (*model.Projec
 	return nil, nil
 }
 func (m *MockProjectRepo) GetBySlug(ctx context.Context, slug string) (*model.Project, error) {
-	return nil, nil
+	return &model.Project{ID: testProjectUUID, Slug: slug}, nil
 }
 func (m *MockProjectRepo) Create(ctx context.Context, p *model.Project) error { return nil }
 func (m *MockProjectRepo) Update(ctx context.Context, p *model.Project) error { return nil }
@@ -51,37 +58,78 @@ func (m *MockChunkRepo) ListByDocument(ctx context.Context, documentID string) (
 }
 
 // MockThreadRepo implements storage.ThreadRepo for testing
-type MockThreadRepo struct{}
+type MockThreadRepo struct {
+	Threads map[string]*model.Thread
+}
 
 func (m *MockThreadRepo) GetByID(ctx context.Context, id string) (*model.Thread, error) {
-	return nil, nil
+	return m.Threads[id], nil
+}
+func (m *MockThreadRepo) Create(ctx context.Context, t *model.Thread) error {
+	if m.Threads == nil {
+		m.Threads = map[string]*model.Thread{}
+	}
+	m.Threads[t.ID] = t
+	return nil
 }
-func (m *MockThreadRepo) Create(ctx context.Context, t *model.Thread) error { return nil }
 func (m *MockThreadRepo) Update(ctx context.Context, t *model.Thread) error { return nil }
 
 // MockMessageRepo implements storage.MessageRepo for testing
-type MockMessageRepo struct{}
+type MockMessageRepo struct {
+	Messages []*model.Message
+}
 
 func (m *MockMessageRepo) GetByID(ctx context.Context, id string) (*model.Message, error) {
 	return nil, nil
 }
-func (m *MockMessageRepo) Create(ctx context.Context, msg *model.Message) error { return nil }
+func (m *MockMessageRepo) Create(ctx context.Context, msg *model.Message) error {
+	m.Messages = append(m.Messages, msg)
+	return nil
+}
 func (m *MockMessageRepo) ListByThread(ctx context.Context, threadID string, limit, offset int) ([]*model.Message, error) {
-	return nil, nil
+	var out []*model.Message
+	for _, msg := range m.Messages {
+		if msg.ThreadID == threadID {
+			out = append(out, msg)
+		}
+	}
+	if offset >= len(out) {
+		return nil, nil
+	}
+	out = out[offset:]
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+func (m *MockMessageRepo) ListRecentByThread(ctx context.Context, threadID string, limit int) ([]*model.Message, error) {
+	out, _ := m.ListByThread(ctx, threadID, 0, 0)
+	if len(out) > limit {
+		out = out[len(out)-limit:]
+	}
+	return out, nil
 }
 
 // MockAnswerRepo implements storage.AnswerRepo for testing
-type MockAnswerRepo struct{}
+type MockAnswerRepo struct {
+	Answers []*model.Answer
+}
 
-func (m *MockAnswerRepo) Create(ctx context.Context, a *model.Answer) error { return nil }
+func (m *MockAnswerRepo) Create(ctx context.Context, a *model.Answer) error {
+	m.Answers = append(m.Answers, a)
+	return nil
+}
 func (m *MockAnswerRepo) GetByMessageID(ctx context.Context, messageID string) (*model.Answer, error) {
 	return nil, nil
 }
 
 // MockCitationRepo implements storage.CitationRepo for testing
-type MockCitationRepo struct{}
+type MockCitationRepo struct {
+	Citations []*model.Citation
+}
 
 func (m *MockCitationRepo) CreateBatch(ctx context.Context, citations []*model.Citation) error {
+	m.Citations = append(m.Citations, citations...)
 	return nil
 }
 func (m *MockCitationRepo) ListByAnswer(ctx context.Context, answerID string) ([]*model.Citation, error) {
@@ -102,10 +150,15 @@ func (m *MockAnalyticsRepo) CountUncertain(ctx context.Context, projectID string
 }
 
 // MockGapRepo implements storage.GapRepo for testing
-type MockGapRepo struct{}
+type MockGapRepo struct {
+	Candidates []*model.GapCandidate
+}
 
-func (m *MockGapRepo) CreateCandidate(ctx context.Context, gc *model.GapCandidate) error { return nil }
-func (m *MockGapRepo) CreateCluster(ctx context.Context, gc *model.GapCluster) error     { return nil }
+func (m *MockGapRepo) CreateCandidate(ctx context.Context, gc *model.GapCandidate) error {
+	m.Candidates = append(m.Candidates, gc)
+	return nil
+}
+func (m *MockGapRepo) CreateCluster(ctx context.Context, gc *model.GapCluster) error { return nil }
 func (m *MockGapRepo) CreateExample(ctx context.Context, gce *model.GapClusterExample) error {
 	return nil
 }
@@ -116,33 +169,87 @@ func (m *MockGapRepo) GetClusterDetail(ctx context.Context, clusterID string) (*
 	return nil, nil, nil
 }
 
-// MockStore implements storage.Store interface for testing
+// MockStore implements storage.Store interface for testing.
+// Chat repos are created lazily and shared across calls so tests can inspect writes.
 type MockStore struct {
 	StoreError error
+
+	threads   *MockThreadRepo
+	messages  *MockMessageRepo
+	answers   *MockAnswerRepo
+	citations *MockCitationRepo
+	gaps      *MockGapRepo
 }
 
-func (m *MockStore) Projects() storage.ProjectRepo    { return &MockProjectRepo{} }
-func (m *MockStore) Documents() storage.DocumentRepo  { return &MockDocumentRepo{} }
-func (m *MockStore) Chunks() storage.ChunkRepo        { return &MockChunkRepo{} }
-func (m *MockStore) Threads() storage.ThreadRepo      { return &MockThreadRepo{} }
-func (m *MockStore) Messages() storage.MessageRepo    { return &MockMessageRepo{} }
-func (m *MockStore) Answers() storage.AnswerRepo      { return &MockAnswerRepo{} }
-func (m *MockStore) Citations() storage.CitationRepo  { return &MockCitationRepo{} }
-func (m *MockStore) Analytics() storage.AnalyticsRepo { return &MockAnalyticsRepo{} }
-func (m *MockStore) Gaps() storage.GapRepo            { return &MockGapRepo{} }
+func (m *MockStore) Projects() storage.ProjectRepo   { return &MockProjectRepo{} }
+func (m *MockStore) Documents() storage.DocumentRepo { return &MockDocumentRepo{} }
+func (m *MockStore) Chunks() storage.ChunkRepo       { return &MockChunkRepo{} }
+func (m *MockStore) Threads() storage.ThreadRepo     { return m.threadRepo() }
+func (m *MockStore) Messages() storage.MessageRepo   { return m.messageRepo() }
+func (m *MockStore) Answers() storage.AnswerRepo     { return m.answerRepo() }
+func (m *MockStore) Citations() storage.CitationRepo { return m.citationRepo() }
+func (m *MockStore) Analytics() storage.AnalyticsRepo {
+	return &MockAnalyticsRepo{}
+}
+func (m *MockStore) Gaps() storage.GapRepo       { return m.gapRepo() }
+func (m *MockStore) Sources() storage.SourceRepo { return nil }
+func (m *MockStore) Jobs() storage.JobRepo       { return nil }
+func (m *MockStore) Uploads() storage.UploadRepo { return nil }
 func (m *MockStore) Close() error {
 	return m.StoreError
 }
 
+func (m *MockStore) threadRepo() *MockThreadRepo {
+	if m.threads == nil {
+		m.threads = &MockThreadRepo{}
+	}
+	return m.threads
+}
+
+func (m *MockStore) gapRepo() *MockGapRepo {
+	if m.gaps == nil {
+		m.gaps = &MockGapRepo{}
+	}
+	return m.gaps
+}
+
+func (m *MockStore) messageRepo() *MockMessageRepo {
+	if m.messages == nil {
+		m.messages = &MockMessageRepo{}
+	}
+	return m.messages
+}
+
+func (m *MockStore) answerRepo() *MockAnswerRepo {
+	if m.answers == nil {
+		m.answers = &MockAnswerRepo{}
+	}
+	return m.answers
+}
+
+func (m *MockStore) citationRepo() *MockCitationRepo {
+	if m.citations == nil {
+		m.citations = &MockCitationRepo{}
+	}
+	return m.citations
+}
+
 // MockLLM implements service.LLM interface for testing
 type MockLLM struct {
 	ChatResponse string
 	ChatError    error
 	StreamError  error
 	StreamTokens []string
+	// ChatFunc, when set, overrides ChatResponse/ChatError.
+	ChatFunc func(messages []service.Message) (string, error)
+	Calls    [][]service.Message
 }
 
 func (m *MockLLM) Chat(ctx context.Context, messages []service.Message) (string, error) {
+	m.Calls = append(m.Calls, messages)
+	if m.ChatFunc != nil {
+		return m.ChatFunc(messages)
+	}
 	if m.ChatError != nil {
 		return "", m.ChatError
 	}
@@ -166,9 +273,11 @@ func (m *MockLLM) Stream(ctx context.Context, messages []service.Message) (<-cha
 type MockSearch struct {
 	Results     []service.SearchResult
 	SearchError error
+	Queries     []string
 }
 
 func (m *MockSearch) Search(ctx context.Context, index, query string, topK int, filters map[string]any) ([]service.SearchResult, error) {
+	m.Queries = append(m.Queries, query)
 	if m.SearchError != nil {
 		return nil, m.SearchError
 	}
@@ -225,6 +334,449 @@ func TestChatService_Chat_Success(t *testing.T) {
 	}
 }
 
+func TestChatService_Chat_PersistsTurn(t *testing.T) {
+	ctx := context.Background()
+	chunkID := uuid.New().String()
+
+	mockSearch := &MockSearch{
+		Results: []service.SearchResult{
+			{ID: chunkID, Text: "Dashboards live under Analytics.", Metadata: map[string]any{"document_id": "doc-1"}, Score: 0.9},
+			{ID: chunkID, Text: "Dashboards live under Analytics.", Metadata: map[string]any{"document_id": "doc-1"}, Score: 0.9},
+		},
+	}
+	mockLLM := &MockLLM{ChatResponse: "Open Analytics and click New dashboard."}
+	mockStore := &MockStore{}
+
+	chatSvc := service.NewChatService(mockStore, mockLLM, mockSearch)
+
+	resp, err := chatSvc.Chat(ctx, api.ChatRequest{ProjectID: "test-project", Query: "Where are dashboards?"})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	if resp.ThreadID == "" {
+		t.Fatal("Expected thread ID in response")
+	}
+	thread := mockStore.threadRepo().Threads[resp.ThreadID]
+	if thread == nil {
+		t.Fatal("Expected thread to be created")
+	}
+	if thread.ProjectID != testProjectUUID {
+		t.Errorf("Expected thread project %s, got %s", testProjectUUID, thread.ProjectID)
+	}
+
+	msgs := mockStore.messageRepo().Messages
+	if len(msgs) != 2 {
+		t.Fatalf("Expected user and assistant messages, got %d", len(msgs))
+	}
+	if msgs[0].Role != "user" || msgs[0].Content != "Where are dashboards?" {
+		t.Errorf("Unexpected user message: %+v", msgs[0])
+	}
+	if msgs[1].Role != "assistant" || msgs[1].Content != resp.Answer {
+		t.Errorf("Unexpected assistant message: %+v", msgs[1])
+	}
+
+	answers := mockStore.answerRepo().Answers
+	if len(answers) != 1 || answers[0].MessageID != msgs[1].ID {
+		t.Fatalf("Expected one answer for the assistant message, got %+v", answers)
+	}
+	if answers[0].PromptVersion == "" || answers[0].Model == "" {
+		t.Errorf("Expected model and prompt version on answer, got %+v", answers[0])
+	}
+
+	citations := mockStore.citationRepo().Citations
+	if len(citations) != 1 {
+		t.Fatalf("Expected one deduplicated citation, got %d", len(citations))
+	}
+	if citations[0].ChunkID != chunkID || citations[0].AnswerID != msgs[1].ID {
+		t.Errorf("Unexpected citation: %+v", citations[0])
+	}
+}
+
+func TestChatService_Chat_InstructionsAreNotStored(t *testing.T) {
+	mockStore := &MockStore{}
+	mockLLM := &MockLLM{ChatResponse: "1. Click Settings"}
+	embedder := &MockEmbedder{}
+	chatSvc := service.NewChatService(mockStore, mockLLM, &MockSearch{}).WithEmbedder(embedder)
+
+	_, err := chatSvc.Chat(context.Background(), api.ChatRequest{
+		ProjectID:    "test-project",
+		Query:        "How do I change my password?",
+		Instructions: "Current Page: https://app.example.com/home",
+	})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	answer := mockLLM.Calls[len(mockLLM.Calls)-1]
+	if answer[1].Role != "system" || answer[1].Content != "Current Page: https://app.example.com/home" {
+		t.Errorf("Expected instructions as a system message, got %+v", answer[1])
+	}
+	if got := answer[len(answer)-1].Content; strings.Contains(got, "Current Page") || !strings.HasSuffix(got, "Question: How do I change my password?") {
+		t.Errorf("Expected only the question in the user message, got %q", got)
+	}
+	if msgs := mockStore.messageRepo().Messages; msgs[0].Content != "How do I change my password?" {
+		t.Errorf("Expected the question as the stored user message, got %q", msgs[0].Content)
+	}
+	if len(embedder.Texts) != 1 || embedder.Texts[0] != "How do I change my password?" {
+		t.Errorf("Expected only the question embedded for the gap candidate, got %q", embedder.Texts)
+	}
+}
+
+func TestChatService_Chat_ParsesCitationMarkers(t *testing.T) {
+	ctx := context.Background()
+	billingID, ssoID := uuid.New().String(), uuid.New().String()
+
+	mockSearch := &MockSearch{
+		Results: []service.SearchResult{
+			{ID: billingID, Text: "Invoices are emailed monthly. Plans can be changed under Billing settings.", Metadata: map[string]any{
+				"document_id": "doc-billing", "title": "Billing", "document_uri": "https://docs.example.com/billing", "section_path": "Plans",
+			}, Score: 0.9},
+			{ID: ssoID, Text: "SSO requires the Enterprise plan.", Metadata: map[string]any{
+				"document_id": "doc-sso", "title": "SSO", "document_uri": "https://docs.example.com/sso",
+			}, Score: 0.7},
+		},
+	}
+	mockLLM := &MockLLM{ChatResponse: "SSO is only on Enterprise [2]. You can change plans in Billing settings [1]."}
+	mockStore := &MockStore{}
+	chatSvc := service.NewChatService(mockStore, mockLLM, mockSearch)
+
+	resp, err := chatSvc.Chat(ctx, api.ChatRequest{ProjectID: "test-project", Query: "How do I get SSO?"})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	prompt := mockLLM.Calls[0][len(mockLLM.Calls[0])-1].Content
+	if !strings.Contains(prompt, "[1] Billing › Plans") || !strings.Contains(prompt, "[2] SSO") {
+		t.Errorf("Expected numbered sources in prompt, got %q", prompt)
+	}
+
+	if len(resp.Citations) != 2 {
+		t.Fatalf("Expected 2 citations, got %d", len(resp.Citations))
+	}
+	first, second := resp.Citations[0], resp.Citations[1]
+	if first.Index != 2 || first.ChunkID != ssoID || first.URI != "https://docs.example.com/sso" {
+		t.Errorf("Expected source [2] cited first, got %+v", first)
+	}
+	if second.Index != 1 || second.Title != "Billing" || second.SectionPath != "Plans" {
+		t.Errorf("Unexpected second citation: %+v", second)
+	}
+	if second.Quote != "Plans can be changed under Billing settings." {
+		t.Errorf("Expected supporting sentence as quote, got %q", second.Quote)
+	}
+	text := mockSearch.Results[0].Text
+	if text[second.StartChar:second.EndChar] != second.Quote {
+		t.Errorf("Quote span %d-%d does not match quote", second.StartChar, second.EndChar)
+	}
+
+	stored := mockStore.citationRepo().Citations
+	if len(stored) != 2 || stored[1].Quote != second.Quote || stored[1].EndChar != second.EndChar {
+		t.Errorf("Expected quote spans persisted, got %+v", stored)
+	}
+}
+
+func TestChatService_Chat_IgnoresUnknownMarkers(t *testing.T) {
+	mockSearch := &MockSearch{
+		Results: []service.SearchResult{
+			{ID: uuid.New().String(), Text: "Exports run nightly.", Metadata: map[string]any{"document_id": "doc-1"}, Score: 0.8},
+		},
+	}
+	chatSvc := service.NewChatService(&MockStore{}, &MockLLM{ChatResponse: "Exports run nightly [1][7]."}, mockSearch)
+
+	resp, err := chatSvc.Chat(context.Background(), api.ChatRequest{ProjectID: "test-project", Query: "When do exports run?"})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if len(resp.Citations) != 1 || resp.Citations[0].Index != 1 {
+		t.Errorf("Expected only source [1], got %+v", resp.Citations)
+	}
+}
+
+func TestChatService_Chat_LinksTranscriptTimestamps(t *testing.T) {
+	mockSearch := &MockSearch{
+		Results: []service.SearchResult{
+			{ID: uuid.New().String(), Text: "Open the admin panel and click Users.", Metadata: map[string]any{
+				"document_id": "doc-yt", "title": "Getting started", "document_uri": "https://www.youtube.com/watch?v=abc123",
+				"section_path": "1:05-2:10", "start_seconds": 65, "end_seconds": 130,
+			}, Score: 0.9},
+			{ID: uuid.New().String(), Text: "Users can be invited by email.", Metadata: map[string]any{
+				"document_id": "doc-talk", "document_uri": "https://cdn.example.com/talk.mp4", "start_seconds": 7, "end_seconds": 42,
+			}, Score: 0.8},
+			{ID: uuid.New().String(), Text: "Users are listed under Settings.", Metadata: map[string]any{
+				"document_id": "doc-users", "document_uri": "https://docs.example.com/users",
+			}, Score: 0.7},
+		},
+	}
+	chatSvc := service.NewChatService(&MockStore{}, &MockLLM{ChatResponse: "Open the admin panel [1], invite by email [2] or see Settings [3]."}, mockSearch)
+
+	resp, err := chatSvc.Chat(context.Background(), api.ChatRequest{ProjectID: "test-project", Query: "How do I add users?"})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if len(resp.Citations) != 3 {
+		t.Fatalf("Expected 3 citations, got %+v", resp.Citations)
+	}
+	yt, file, doc := resp.Citations[0], resp.Citations[1], resp.Citations[2]
+	if yt.URI != "https://www.youtube.com/watch?t=65&v=abc123" || yt.StartSeconds == nil || *yt.StartSeconds != 65 || *yt.EndSeconds != 130 {
+		t.Errorf("Expected YouTube deep link, got %+v", yt)
+	}
+	if file.URI != "https://cdn.example.com/talk.mp4#t=7" {
+		t.Errorf("Expected media fragment link, got %q", file.URI)
+	}
+	if doc.URI != "https://docs.example.com/users" || doc.StartSeconds != nil {
+		t.Errorf("Expected plain link for text, got %+v", doc)
+	}
+}
+
+// MockEmbedder implements service.Embedder for testing
+type MockEmbedder struct {
+	Texts []string
+}
+
+func (m *MockEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	m.Texts = append(m.Texts, text)
+	return []float32{0.1, 0.2, 0.3}, nil
+}
+
+func TestChatService_Chat_UncertainWithoutResults(t *testing.T) {
+	mockStore := &MockStore{}
+	embedder := &MockEmbedder{}
+	chatSvc := service.NewChatService(mockStore, &MockLLM{ChatResponse: "Try the settings page."}, &MockSearch{}).WithEmbedder(embedder)
+
+	resp, err := chatSvc.Chat(context.Background(), api.ChatRequest{ProjectID: "test-project", Query: "How do I rotate API keys?"})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if !resp.IsUncertain || resp.Confidence != 0 {
+		t.Errorf("Expected uncertain answer with zero confidence, got %v/%v", resp.IsUncertain, resp.Confidence)
+	}
+
+	answers := mockStore.answerRepo().Answers
+	if len(answers) != 1 || !answers[0].IsUncertain {
+		t.Fatalf("Expected answer stored as uncertain, got %+v", answers)
+	}
+
+	candidates := mockStore.gapRepo().Candidates
+	if len(candidates) != 1 {
+		t.Fatalf("Expected one gap candidate, got %d", len(candidates))
+	}
+	if candidates[0].AnswerID != answers[0].MessageID || candidates[0].UncertaintyReason != "no_results" {
+		t.Errorf("Unexpected gap candidate: %+v", candidates[0])
+	}
+	if len(candidates[0].QuestionEmbedding) == 0 || len(embedder.Texts) != 1 || embedder.Texts[0] != "How do I rotate API keys?" {
+		t.Errorf("Expected question embedding on gap candidate, embedded %v", embedder.Texts)
+	}
+}
+
+func TestChatService_Chat_UncertainWhenLLMDeclines(t *testing.T) {
+	mockStore := &MockStore{}
+	mockSearch := &MockSearch{
+		Results: []service.SearchResult{
+			{ID: uuid.New().String(), Text: "Billing overview.", Metadata: map[string]any{"document_id": "doc-1"}, Score: 0.9},
+		},
+	}
+	chatSvc := service.NewChatService(mockStore, &MockLLM{ChatResponse: "I don't know; the documentation doesn't mention refunds."}, mockSearch)
+
+	resp, err := chatSvc.Chat(context.Background(), api.ChatRequest{ProjectID: "test-project", Query: "How do refunds work?"})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if !resp.IsUncertain || resp.Confidence > 0.1 {
+		t.Errorf("Expected uncertain low-confidence answer, got %v/%v", resp.IsUncertain, resp.Confidence)
+	}
+	if c := mockStore.gapRepo().Candidates; len(c) != 1 || c[0].UncertaintyReason != "llm_declined" {
+		t.Errorf("Expected llm_declined gap candidate, got %+v", c)
+	}
+}
+
+func TestChatService_Chat_ConfidentAnswer(t *testing.T) {
+	mockStore := &MockStore{}
+	mockSearch := &MockSearch{
+		Results: []service.SearchResult{
+			{ID: uuid.New().String(), Text: "Keys rotate under Settings > API.", Metadata: map[string]any{"document_id": "doc-1"}, Score: 0.82},
+			{ID: uuid.New().String(), Text: "API overview.", Metadata: map[string]any{"document_id": "doc-2"}, Score: 0.4},
+		},
+	}
+	chatSvc := service.NewChatService(mockStore, &MockLLM{ChatResponse: "Go to Settings > API [1]."}, mockSearch)
+
+	resp, err := chatSvc.Chat(context.Background(), api.ChatRequest{ProjectID: "test-project", Query: "How do I rotate keys?"})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.IsUncertain || resp.Confidence != 0.82 {
+		t.Errorf("Expected confident answer scored by top hit, got %v/%v", resp.IsUncertain, resp.Confidence)
+	}
+	if c := mockStore.gapRepo().Candidates; len(c) != 0 {
+		t.Errorf("Expected no gap candidates, got %d", len(c))
+	}
+}
+
+func TestChatService_Chat_ReusesThread(t *testing.T) {
+	ctx := context.Background()
+	mockStore := &MockStore{}
+	chatSvc := service.NewChatService(mockStore, &MockLLM{ChatResponse: "ok"}, &MockSearch{})
+
+	first, err := chatSvc.Chat(ctx, api.ChatRequest{ProjectID: "test-project", Query: "first"})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	second, err := chatSvc.Chat(ctx, api.ChatRequest{ProjectID: "test-project", Query: "second", ThreadID: first.ThreadID})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	if second.ThreadID != first.ThreadID {
+		t.Errorf("Expected thread %s to be reused, got %s", first.ThreadID, second.ThreadID)
+	}
+	if n := len(mockStore.threadRepo().Threads); n != 1 {
+		t.Errorf("Expected 1 thread, got %d", n)
+	}
+	if n := len(mockStore.messageRepo().Messages); n != 4 {
+		t.Errorf("Expected 4 messages, got %d", n)
+	}
+}
+
+func TestChatService_Chat_UnknownThread(t *testing.T) {
+	chatSvc := service.NewChatService(&MockStore{}, &MockLLM{ChatResponse: "ok"}, &MockSearch{})
+
+	_, err := chatSvc.Chat(context.Background(), api.ChatRequest{ProjectID: "test-project", Query: "q", ThreadID: uuid.New().String()})
+	if !errors.Is(err, api.ErrThreadNotFound) {
+		t.Errorf("Expected ErrThreadNotFound, got %v", err)
+	}
+}
+
+func TestChatService_Chat_FollowUpUsesHistory(t *testing.T) {
+	ctx := context.Background()
+	mockStore := &MockStore{}
+	mockSearch := &MockSearch{}
+	mockLLM := &MockLLM{
+		ChatFunc: func(messages []service.Message) (string, error) {
+			if strings.Contains(messages[0].Content, "standalone search query") {
+				return "\"create a dashboard in Python\"", nil
+			}
+			return "Use the Python SDK.", nil
+		},
+	}
+	chatSvc := service.NewChatService(mockStore, mockLLM, mockSearch)
+
+	first, err := chatSvc.Chat(ctx, api.ChatRequest{ProjectID: "test-project", Query: "How do I create a dashboard?"})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	mockLLM.Calls = nil
+
+	if _, err := chatSvc.Chat(ctx, api.ChatRequest{ProjectID: "test-project", Query: "and how do I do that in Python?", ThreadID: first.ThreadID}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	if got := mockSearch.Queries[len(mockSearch.Queries)-1]; got != "create a dashboard in Python" {
+		t.Errorf("Expected rewritten search query, got %q", got)
+	}
+
+	if len(mockLLM.Calls) != 2 {
+		t.Fatalf("Expected rewrite + answer LLM calls, got %d", len(mockLLM.Calls))
+	}
+	answerMsgs := mockLLM.Calls[1]
+	// system, prior user, prior assistant, current user
+	if len(answerMsgs) != 4 {
+		t.Fatalf("Expected 4 messages sent to LLM, got %d", len(answerMsgs))
+	}
+	if answerMsgs[1].Role != "user" || answerMsgs[1].Content != "How do I create a dashboard?" {
+		t.Errorf("Expected prior question in history, got %+v", answerMsgs[1])
+	}
+	if answerMsgs[2].Role != "assistant" {
+		t.Errorf("Expected prior answer in history, got %+v", answerMsgs[2])
+	}
+}
+
+func TestChatService_Chat_HistoryTokenBudget(t *testing.T) {
+	ctx := context.Background()
+	mockStore := &MockStore{}
+	mockLLM := &MockLLM{ChatResponse: "ok"}
+	chatSvc := service.NewChatService(mockStore, mockLLM, &MockSearch{})
+
+	long := strings.Repeat("word ", 1000) // ~1250 tokens per turn
+	first, err := chatSvc.Chat(ctx, api.ChatRequest{ProjectID: "test-project", Query: long})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := chatSvc.Chat(ctx, api.ChatRequest{ProjectID: "test-project", Query: long, ThreadID: first.ThreadID}); err != nil {
+			t.Fatalf("Chat failed: %v", err)
+		}
+	}
+
+	last := mockLLM.Calls[len(mockLLM.Calls)-1]
+	total := 0
+	for _, m := range last[1 : len(last)-1] {
+		total += len(m.Content)
+	}
+	if total/4 > 2000 {
+		t.Errorf("Expected history within token budget, got ~%d tokens", total/4)
+	}
+	if last[1].Role != "user" {
+		t.Errorf("Expected history window to start on a user turn, got %s", last[1].Role)
+	}
+
+	// The query rewrite sees no more history than the answer.
+	rewrite := mockLLM.Calls[len(mockLLM.Calls)-2][1].Content
+	convo, _, _ := strings.Cut(strings.TrimPrefix(rewrite, "Conversation:\n"), "\nLatest question:")
+	if len(convo)/4 > 2000+10 { // plus the role labels
+		t.Errorf("Expected rewrite history within token budget, got ~%d tokens", len(convo)/4)
+	}
+}
+
+func TestChatService_Chat_LongThreadUsesLatestTurns(t *testing.T) {
+	ctx := context.Background()
+	mockStore := &MockStore{}
+	mockLLM := &MockLLM{ChatResponse: "ok"}
+	chatSvc := service.NewChatService(mockStore, mockLLM, &MockSearch{})
+
+	first, err := chatSvc.Chat(ctx, api.ChatRequest{ProjectID: "test-project", Query: "turn start"})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	repo := mockStore.messageRepo()
+	for i := range 600 {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		repo.Messages = append(repo.Messages, &model.Message{ID: uuid.New().String(), ThreadID: first.ThreadID, Role: role, Content: fmt.Sprintf("turn %d", i)})
+	}
+	mockLLM.Calls = nil
+
+	if _, err := chatSvc.Chat(ctx, api.ChatRequest{ProjectID: "test-project", Query: "and now?", ThreadID: first.ThreadID}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	answer := mockLLM.Calls[len(mockLLM.Calls)-1]
+	if got := answer[len(answer)-2].Content; got != "turn 599" {
+		t.Errorf("Expected the latest turn right before the question, got %q", got)
+	}
+	for _, m := range answer {
+		if m.Content == "turn start" || m.Content == "turn 0" {
+			t.Errorf("Expected old turns to be dropped, got %q", m.Content)
+		}
+	}
+}
+
+func TestChatService_CreateThread(t *testing.T) {
+	mockStore := &MockStore{}
+	chatSvc := service.NewChatService(mockStore, &MockLLM{}, &MockSearch{})
+
+	thread, err := chatSvc.CreateThread(context.Background(), api.ThreadCreateRequest{ProjectID: "test-project", Integration: "widget"})
+	if err != nil {
+		t.Fatalf("CreateThread failed: %v", err)
+	}
+	if thread.ID == "" || thread.ProjectID != testProjectUUID || thread.Integration != "widget" {
+		t.Errorf("Unexpected thread: %+v", thread)
+	}
+	if mockStore.threadRepo().Threads[thread.ID] == nil {
+		t.Error("Expected thread to be stored")
+	}
+}
+
 func TestChatService_Chat_SearchError(t *testing.T) {
 	ctx := context.Background()
 	projectID := "test-project"
@@ -371,6 +923,51 @@ func TestChatService_ChatStream_Success(t *testing.T) {
 	}
 }
 
+func TestChatService_ChatStream_ClientCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	mockStore := &MockStore{}
+	mockLLM := &MockLLM{StreamTokens: []string{"one", " two", " three"}}
+	chatSvc := service.NewChatService(mockStore, mockLLM, &MockSearch{})
+
+	ch, err := chatSvc.ChatStream(ctx, api.ChatRequest{ProjectID: "test-project", Query: "q"})
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+
+	<-ch // first token, then the client goes away
+	cancel()
+
+	select {
+	case <-drain(ch):
+	case <-time.After(time.Second):
+		t.Fatal("stream did not stop after cancellation")
+	}
+
+	if n := len(mockStore.answerRepo().Answers); n != 0 {
+		t.Errorf("Expected no answer recorded for cancelled stream, got %d", n)
+	}
+}
+
+func TestChatService_ChatStream_UnknownThread(t *testing.T) {
+	chatSvc := service.NewChatService(&MockStore{}, &MockLLM{}, &MockSearch{})
+
+	_, err := chatSvc.ChatStream(context.Background(), api.ChatRequest{ProjectID: "test-project", Query: "q", ThreadID: uuid.New().String()})
+	if !errors.Is(err, api.ErrThreadNotFound) {
+		t.Errorf("Expected ErrThreadNotFound, got %v", err)
+	}
+}
+
+// drain consumes a frame channel and signals once it is closed.
+func drain(ch <-chan api.StreamFrame) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+	return done
+}
+
 // ============ Search Service Tests ============
 
 func TestSearchService_Search_Success(t *testing.T) {
@@ -425,6 +1022,25 @@ func TestSearchService_Search_Success(t *testing.T) {
 	}
 }
 
+func TestSearchService_Search_LinksTranscriptTimestamps(t *testing.T) {
+	mockSearch := &MockSearch{
+		Results: []service.SearchResult{
+			{ID: "chunk-1", Text: "Open the admin panel.", Metadata: map[string]any{
+				"document_id": "doc-1", "document_uri": "https://youtu.be/abc123", "start_seconds": 3725, "end_seconds": 3790,
+			}, Score: 0.9},
+		},
+	}
+	searchSvc := service.NewSearchService(&MockStore{}, mockSearch)
+
+	hits, err := searchSvc.Search(context.Background(), "test-project", "admin panel", 10, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(hits) != 1 || hits[0].DocumentURI != "https://youtu.be/abc123?t=3725" || *hits[0].StartSeconds != 3725 || *hits[0].EndSeconds != 3790 {
+		t.Errorf("Expected timestamp deep link, got %+v", hits)
+	}
+}
+
 func TestSearchService_Search_Error(t *testing.T) {
 	ctx := context.Background()
 	projectID := "test-project"
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"cgap/internal/model"
+)
+
+// Retrieval thresholds used to flag answers that are likely not grounded in the docs.
+const (
+	// minTopScore is the lowest top-hit relevance considered a confident match.
+	minTopScore = 0.35
+	// ambiguousTopScore is the top-hit relevance below which a small score gap
+	// between the first two hits is treated as ambiguous retrieval.
+	ambiguousTopScore = 0.6
+	// minScoreGap is the smallest top-1/top-2 margin considered decisive.
+	minScoreGap = 0.02
+	// declinedConfidence caps confidence when the model says it cannot answer.
+	declinedConfidence = 0.1
+)
+
+// Uncertainty reasons recorded in gap_candidates.uncertainty_reason.
+const (
+	reasonNoResults   = "no_results"
+	reasonLowScore    = "low_retrieval_score"
+	reasonAmbiguous   = "ambiguous_retrieval"
+	reasonLLMDeclined = "llm_declined"
+)
+
+// declineRe matches common ways a model admits the context does not answer the question.
+var declineRe = regexp.MustCompile(`(?i)\b(i don'?t know|i do not know|i'?m not sure|i am not sure|` +
+	`(?:can ?not|can'?t|could ?not|couldn'?t|unable to) (?:find|determine|answer)|` +
+	`(?:context|sources?|documentation) (?:does not|doesn'?t|do not|don'?t) (?:contain|mention|say|cover|provide)|` +
+	`no (?:relevant )?information (?:about|on|regarding))`)
+
+// uncertainty is the outcome of assessing an answer against its retrieval.
+type uncertainty struct {
+	Confidence float32
+	Uncertain  bool
+	Reasons    []string
+}
+
+// Reason joins the individual signals into the stored uncertainty_reason.
+func (u uncertainty) Reason() string {
+	return strings.Join(u.Reasons, ",")
+}
+
+// assessUncertainty combines retrieval signals (empty results, top score, score
+// gap) with "I don't know" detection in the answer. Scores are expected in [0, 1].
+func assessUncertainty(answer string, sources []SearchResult) uncertainty {
+	var u uncertainty
+
+	if len(sources) == 0 {
+		u.Reasons = append(u.Reasons, reasonNoResults)
+	} else {
+		top := relevance(sources[0])
+		for _, r := range sources[1:] {
+			top = max(top, relevance(r))
+		}
+		u.Confidence = top
+
+		if top < minTopScore {
+			u.Reasons = append(u.Reasons, reasonLowScore)
+		} else if top < ambiguousTopScore && len(sources) > 1 && scoreGap(sources) < minScoreGap {
+			u.Reasons = append(u.Reasons, reasonAmbiguous)
+		}
+	}
+
+	if declineRe.MatchString(answer) {
+		u.Reasons = append(u.Reasons, reasonLLMDeclined)
+		u.Confidence = min(u.Confidence, declinedConfidence)
+	}
+
+	u.Uncertain = len(u.Reasons) > 0
+	return u
+}
+
+// scoreGap is the margin between the two highest-scoring sources.
+func scoreGap(sources []SearchResult) float32 {
+	var first, second float32
+	for _, r := range sources {
+		s := relevance(r)
+		switch {
+		case s > first:
+			first, second = s, first
+		case s > second:
+			second = s
+		}
+	}
+	return first - second
+}
+
+// relevance is a source's absolute relevance. Hybrid search reports a fused,
+// rank-relative Score and keeps the best raw backend score in Metadata["relevance"].
+func relevance(r SearchResult) float32 {
+	s := r.Score
+	if v, ok := r.Metadata["relevance"].(float32); ok {
+		s = v
+	}
+	return min(max(s, 0), 1)
+}
+
+// recordGapCandidate stores an uncertain answer for gap clustering. The question
+// embedding is best-effort: without an embedder (or on failure) the row is still
+// written so the question is not lost.
+func (s *ChatServiceImpl) recordGapCandidate(ctx context.Context, answerID, question, reason string) error {
+	var vec []float32
+	if s.embedder != nil {
+		v, err := s.embedder.Embed(ctx, question)
+		if err != nil {
+			slog.Warn("failed to embed gap question", "answer_id", answerID, "error", err)
+		} else {
+			vec = v
+		}
+	}
+
+	if err := s.store.Gaps().CreateCandidate(ctx, &model.GapCandidate{
+		AnswerID:          answerID,
+		QuestionEmbedding: vec,
+		UncertaintyReason: reason,
+	}); err != nil {
+		return fmt.Errorf("create gap candidate: %w", err)
+	}
+	return nil
+}
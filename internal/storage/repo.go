@@ -3,10 +3,14 @@ package storage
 
 import (
 	"context"
+	"errors"
 
 	"cgap/internal/model"
 )
 
+// ErrNotFound is wrapped by repositories when a lookup matches no rows.
+var ErrNotFound = errors.New("not found")
+
 // Database repository interfaces decoupled from implementation.
 
 // ProjectRepo provides access to project storage operations.
@@ -44,6 +48,8 @@ type MessageRepo interface {
 	GetByID(ctx context.Context, id string) (*model.Message, error)
 	Create(ctx context.Context, m *model.Message) error
 	ListByThread(ctx context.Context, threadID string, limit, offset int) ([]*model.Message, error)
+	// ListRecentByThread returns the newest limit messages of a thread, oldest first.
+	ListRecentByThread(ctx context.Context, threadID string, limit int) ([]*model.Message, error)
 }
 
 // AnswerRepo provides access to answer storage operations.
@@ -74,6 +80,53 @@ type GapRepo interface {
 	GetClusterDetail(ctx context.Context, clusterID string) (*model.GapCluster, []*model.GapClusterExample, error)
 }
 
+// SourceRepo provides access to ingest sources. Project IDs may be given as
+// UUID or slug.
+type SourceRepo interface {
+	// Create inserts a source; it wraps ErrNotFound if the project does not exist.
+	Create(ctx context.Context, s *model.Source) error
+	GetByID(ctx context.Context, id string) (*model.Source, error)
+	List(ctx context.Context, projectID string, limit, offset int) ([]*model.Source, error)
+	// Update replaces the editable fields of a source and reschedules it.
+	Update(ctx context.Context, s *model.Source) error
+	Delete(ctx context.Context, id string) error
+	// FindOrCreate loads the project's source with the same type and config into
+	// s, creating it (unscheduled) if there is none.
+	FindOrCreate(ctx context.Context, s *model.Source) error
+	// ClaimDue returns up to limit active sources whose sync is due and advances
+	// their next sync by their interval, so concurrent schedulers skip them.
+	ClaimDue(ctx context.Context, limit int) ([]*model.Source, error)
+	// RecordSync stores the job started to sync a source.
+	RecordSync(ctx context.Context, id, jobID string) error
+	// SaveCursor stores where a completed sync stopped, for incremental syncs.
+	SaveCursor(ctx context.Context, id, cursor string) error
+}
+
+// JobRepo provides access to ingest job history. Project IDs may be given as
+// UUID or slug.
+type JobRepo interface {
+	// Create inserts a queued job; it wraps ErrNotFound if the project does not exist.
+	Create(ctx context.Context, j *model.Job) error
+	GetByID(ctx context.Context, id string) (*model.Job, error)
+	ListByProject(ctx context.Context, projectID, status string, limit, offset int) ([]*model.Job, error)
+	// Start marks a job running with the number of units it will process.
+	Start(ctx context.Context, id string, total int) error
+	// SetStatus records a status change; terminal statuses also set finished_at.
+	SetStatus(ctx context.Context, id, status, errMsg string) error
+	AddCounters(ctx context.Context, id string, delta model.JobCounters) error
+	AddURLError(ctx context.Context, id, url, errMsg string) error
+	ListURLErrors(ctx context.Context, id string, limit int) ([]model.JobURLError, error)
+	// RequestCancel stamps cancel_requested_at on a job that has not finished.
+	RequestCancel(ctx context.Context, id string) (*model.Job, error)
+}
+
+// UploadRepo records uploaded files. Project IDs may be given as UUID or slug.
+type UploadRepo interface {
+	// Create inserts an upload; it wraps ErrNotFound if the project does not exist.
+	Create(ctx context.Context, u *model.Upload) error
+	GetByID(ctx context.Context, id string) (*model.Upload, error)
+}
+
 // Store aggregates all repos.
 type Store interface {
 	Projects() ProjectRepo
@@ -85,5 +138,8 @@ type Store interface {
 	Citations() CitationRepo
 	Analytics() AnalyticsRepo
 	Gaps() GapRepo
+	Sources() SourceRepo
+	Jobs() JobRepo
+	Uploads() UploadRepo
 	Close() error
 }
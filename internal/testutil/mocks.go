@@ -48,10 +48,16 @@ func (m *MockSearch) Search(ctx context.Context, projectID, query string, topK i
 // MockChatService provides a mock chat service for testing
 type MockChatService struct {
 	Response api.ChatResponse
+	Frames   []api.StreamFrame
 	Error    error
+	Requests []api.ChatRequest // requests passed to Chat
+	mu       sync.Mutex
 }
 
 func (m *MockChatService) Chat(ctx context.Context, req api.ChatRequest) (api.ChatResponse, error) {
+	m.mu.Lock()
+	m.Requests = append(m.Requests, req)
+	m.mu.Unlock()
 	if m.Error != nil {
 		return api.ChatResponse{}, m.Error
 	}
@@ -63,11 +69,21 @@ func (m *MockChatService) ChatStream(ctx context.Context, req api.ChatRequest) (
 		return nil, m.Error
 	}
 
-	ch := make(chan api.StreamFrame, 1)
+	ch := make(chan api.StreamFrame, len(m.Frames))
+	for _, f := range m.Frames {
+		ch <- f
+	}
 	close(ch)
 	return ch, nil
 }
 
+func (m *MockChatService) CreateThread(ctx context.Context, req api.ThreadCreateRequest) (api.Thread, error) {
+	if m.Error != nil {
+		return api.Thread{}, m.Error
+	}
+	return api.Thread{ID: m.Response.ThreadID, ProjectID: req.ProjectID}, nil
+}
+
 // MockSearchService provides a mock search service for testing
 type MockSearchService struct {
 	Hits  []api.SearchHit
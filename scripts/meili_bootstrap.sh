@@ -1,16 +1,18 @@
 #!/usr/bin/env zsh
 set -euo pipefail
 
-# Bootstrap a Meilisearch index for a project.
-# Usage: MEILI_HOST=http://localhost:7700 MEILI_KEY=master ./scripts/meili_bootstrap.sh <project_id>
-
-if [ $# -ne 1 ]; then
-  echo "usage: MEILI_HOST=... MEILI_KEY=... $0 <project_id>" >&2
+# Bootstrap the shared Meilisearch chunks index. All projects use one index and
+# are separated by the project_id/project_slug filters. The worker creates the
+# same index and settings on startup (internal/meilisearch ChunkSettings); keep
+# both in sync.
+# Usage: MEILI_HOST=http://localhost:7700 MEILI_KEY=master ./scripts/meili_bootstrap.sh
+
+if [ $# -ne 0 ]; then
+  echo "usage: MEILI_HOST=... MEILI_KEY=... $0" >&2
   exit 1
 fi
 
-PROJECT_ID="$1"
-INDEX="cgap_chunks_${PROJECT_ID}"
+INDEX="cgap_chunks"
 
 if [ -z "${MEILI_HOST:-}" ] || [ -z "${MEILI_KEY:-}" ]; then
   echo "MEILI_HOST and MEILI_KEY must be set" >&2
@@ -27,7 +29,7 @@ settings_body() {
   cat <<'EOF'
 {
   "searchableAttributes": ["title", "text", "section_path"],
-  "filterableAttributes": ["project_id", "source_type", "document_uri"],
+  "filterableAttributes": ["project_id", "project_slug", "document_id", "document_uri", "source_type"],
   "sortableAttributes": ["score_raw", "ord"],
   "rankingRules": [
     "typo",
@@ -38,10 +38,12 @@ type EmbeddingTask struct {
 	Chunks    []Chunk
 }
 
-// MeiliRecord represents a document record in Meilisearch.
+// MeiliRecord represents a document record in Meilisearch. ID is the chunk id.
 type MeiliRecord struct {
 	ID          string            `json:"id"`
 	ProjectID   string            `json:"project_id"`
+	ProjectSlug string            `json:"project_slug,omitempty"`
+	DocumentID  string            `json:"document_id"`
 	DocumentURI string            `json:"document_uri"`
 	SourceType  string            `json:"source_type"`
 	Title       string            `json:"title"`
@@ -50,6 +52,9 @@ type MeiliRecord struct {
 	Ord         int               `json:"ord"`
 	ScoreRaw    float32           `json:"score_raw"`
 	Metadata    map[string]string `json:"metadata,omitempty"`
+	// Position of a transcript chunk in its video, in seconds
+	StartSeconds *int `json:"start_seconds,omitempty"`
+	EndSeconds   *int `json:"end_seconds,omitempty"`
 }
 
 // GapClusteringJob represents a job for clustering gap candidates.